@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dotbrains/gh-identity/internal/askpass"
+	"github.com/dotbrains/gh-identity/internal/ghauth"
+)
+
+func main() {
+	prompt := strings.Join(os.Args[1:], " ")
+
+	dir, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gh-identity-askpass: %v\n", err)
+		os.Exit(1)
+	}
+
+	answer, err := askpass.Answer(ghauth.NewGHAuth(), prompt, dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gh-identity-askpass: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println(answer)
+}
@@ -3,16 +3,31 @@ package main
 import (
 	"flag"
 	"fmt"
+	"log/slog"
 	"os"
+	"runtime"
 	"strings"
 
 	"github.com/dotbrains/gh-identity/internal/hook"
+	"github.com/dotbrains/gh-identity/internal/version"
 )
 
 func main() {
-	shellFlag := flag.String("shell", "", "Shell type: fish, bash, zsh")
+	shellFlag := flag.String("shell", "", "Shell type: fish, bash, zsh, nu, powershell")
+	noCache := flag.Bool("no-cache", false, "Bypass the resolution cache")
+	verbose := flag.Bool("verbose", false, "Log debug tracing to stderr")
+	showVersion := flag.Bool("version", false, "Print the build version and exit")
 	flag.Parse()
 
+	if *showVersion {
+		fmt.Println(version.String())
+		return
+	}
+
+	if *verbose {
+		slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	}
+
 	shell := hook.ShellType(strings.ToLower(*shellFlag))
 	if shell == "" {
 		// Try to detect from SHELL env.
@@ -25,14 +40,24 @@ func main() {
 		os.Exit(1)
 	}
 
-	output, err := hook.Resolve(dir, shell)
+	resolveFn := hook.ResolveCached
+	if *noCache {
+		resolveFn = hook.Resolve
+	}
+
+	output, err := resolveFn(dir, shell)
+	if out, ok := hook.WarnInvalidConfig(shell, err, os.Stderr); ok {
+		fmt.Print(out)
+		return
+	}
 	if err != nil {
 		// Silently fail — the hook should not break the user's shell.
 		fmt.Fprintf(os.Stderr, "gh-identity-hook: %v\n", err)
 		os.Exit(0)
 	}
 
-	fmt.Print(output)
+	// Warnings go to stderr so the output stays eval-able.
+	fmt.Print(hook.WarnForeignToken(shell, output, os.Stderr))
 }
 
 func detectShell() hook.ShellType {
@@ -43,5 +68,11 @@ func detectShell() hook.ShellType {
 	if strings.HasSuffix(shellPath, "/zsh") {
 		return hook.Zsh
 	}
+	if strings.HasSuffix(shellPath, "/nu") {
+		return hook.Nu
+	}
+	if strings.HasSuffix(shellPath, "/pwsh") || (shellPath == "" && runtime.GOOS == "windows") {
+		return hook.PowerShell
+	}
 	return hook.Bash
 }
@@ -0,0 +1,86 @@
+// Package askpass implements the GIT_ASKPASS helper: it answers git's
+// username/password prompts with the gh account and token of the profile
+// active for the current directory.
+package askpass
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dotbrains/gh-identity/internal/config"
+	"github.com/dotbrains/gh-identity/internal/ghauth"
+	"github.com/dotbrains/gh-identity/internal/gitconfig"
+	"github.com/dotbrains/gh-identity/internal/resolve"
+)
+
+// Host is the only host the helper answers prompts for.
+const Host = "github.com"
+
+// Answer returns the response to a git askpass prompt issued in dir.
+// Git prompts look like "Username for 'https://github.com': " and
+// "Password for 'https://user@github.com': ".
+func Answer(auth ghauth.Auth, prompt, dir string) (string, error) {
+	if host := promptHost(prompt); host != Host {
+		return "", fmt.Errorf("not answering for host %q", host)
+	}
+
+	profile, err := activeProfile(dir)
+	if err != nil {
+		return "", err
+	}
+	if profile.GHHost() != Host {
+		return "", fmt.Errorf("the active profile is for host %q, not %q", profile.GHHost(), Host)
+	}
+
+	switch {
+	case strings.HasPrefix(prompt, "Username"):
+		return profile.GHUser, nil
+	case strings.HasPrefix(prompt, "Password"):
+		return auth.Token(profile.GHHost(), profile.GHUser)
+	default:
+		return "", fmt.Errorf("unrecognized prompt %q", prompt)
+	}
+}
+
+// activeProfile resolves the profile for dir, honoring GH_IDENTITY_PROFILE.
+func activeProfile(dir string) (config.Profile, error) {
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return config.Profile{}, err
+	}
+
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		return config.Profile{}, err
+	}
+	gitconfig.InferBindings(bindings)
+	res, err := resolve.Active(dir, bindings, profiles, os.Getenv("GH_IDENTITY_PROFILE"))
+	if err != nil {
+		return config.Profile{}, err
+	}
+	if res.Profile == "" {
+		return config.Profile{}, fmt.Errorf("no profile is active for %s", dir)
+	}
+	return profiles.GetProfile(res.Profile)
+}
+
+// promptHost extracts the host from the URL quoted in a git prompt.
+func promptHost(prompt string) string {
+	start := strings.Index(prompt, "'")
+	end := strings.LastIndex(prompt, "'")
+	if start == -1 || end <= start {
+		return ""
+	}
+	url := prompt[start+1 : end]
+	if i := strings.Index(url, "://"); i != -1 {
+		url = url[i+3:]
+	}
+	if i := strings.Index(url, "@"); i != -1 {
+		url = url[i+1:]
+	}
+	if i := strings.IndexAny(url, "/:"); i != -1 {
+		url = url[:i]
+	}
+	return url
+}
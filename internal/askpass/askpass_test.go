@@ -0,0 +1,125 @@
+package askpass
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dotbrains/gh-identity/internal/ghauth"
+)
+
+// mockAuth implements ghauth.Auth for testing.
+type mockAuth struct {
+	tokens map[string]string
+}
+
+func (m *mockAuth) Token(host, username string) (string, error) {
+	if tok, ok := m.tokens[username]; ok {
+		return tok, nil
+	}
+	return "", fmt.Errorf("no token for %s", username)
+}
+
+func (m *mockAuth) Accounts() ([]ghauth.Account, error) { return nil, nil }
+
+func (m *mockAuth) AuthenticatedUsers() ([]string, error) { return nil, nil }
+
+func (m *mockAuth) ActiveUser() (string, error) { return "", nil }
+
+func (m *mockAuth) TokenScopes(host, username string) ([]string, error) { return nil, nil }
+
+func setupConfig(t *testing.T, boundDir string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("GH_IDENTITY_CONFIG_DIR", dir)
+	t.Setenv("GH_IDENTITY_PROFILE", "")
+	profiles := `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com
+  personal:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com
+  corp:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@corp.example
+    host: ghe.corp.example
+`
+	bindings := "bindings:\n  - path: " + boundDir + "\n    profile: work\n"
+	if err := os.WriteFile(filepath.Join(dir, "profiles.yml"), []byte(profiles), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bindings.yml"), []byte(bindings), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAnswer(t *testing.T) {
+	bound := t.TempDir()
+	setupConfig(t, bound)
+	auth := &mockAuth{tokens: map[string]string{"user1": "tok-personal", "user2": "tok-work"}}
+
+	tests := []struct {
+		name   string
+		prompt string
+		env    string
+		want   string
+	}{
+		{"username", "Username for 'https://github.com': ", "", "user2"},
+		{"password", "Password for 'https://user2@github.com': ", "", "tok-work"},
+		{"env override", "Password for 'https://github.com': ", "personal", "tok-personal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GH_IDENTITY_PROFILE", tt.env)
+			got, err := Answer(auth, tt.prompt, bound)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Answer() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnswer_Errors(t *testing.T) {
+	bound := t.TempDir()
+	setupConfig(t, bound)
+	auth := &mockAuth{}
+
+	if _, err := Answer(auth, "Username for 'https://gitlab.com': ", bound); err == nil {
+		t.Error("expected error for other host")
+	}
+	if _, err := Answer(auth, "Passphrase for 'https://github.com': ", bound); err == nil {
+		t.Error("expected error for unrecognized prompt")
+	}
+	if _, err := Answer(auth, "Username for 'https://github.com': ", t.TempDir()); err == nil {
+		t.Error("expected error when no profile is active")
+	}
+	t.Setenv("GH_IDENTITY_PROFILE", "corp")
+	if _, err := Answer(auth, "Password for 'https://github.com': ", bound); err == nil {
+		t.Error("expected error when the active profile is for another host")
+	}
+}
+
+func TestPromptHost(t *testing.T) {
+	tests := []struct {
+		prompt string
+		want   string
+	}{
+		{"Username for 'https://github.com': ", "github.com"},
+		{"Password for 'https://user@github.com': ", "github.com"},
+		{"Password for 'https://ghe.corp.com:8443/path': ", "ghe.corp.com"},
+		{"no quotes here", ""},
+	}
+	for _, tt := range tests {
+		if got := promptHost(tt.prompt); got != tt.want {
+			t.Errorf("promptHost(%q) = %q, want %q", tt.prompt, got, tt.want)
+		}
+	}
+}
@@ -1,22 +1,74 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
+	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/dotbrains/gh-identity/internal/config"
+	"github.com/dotbrains/gh-identity/internal/ghauth"
 	"github.com/dotbrains/gh-identity/internal/gitconfig"
+	"github.com/dotbrains/gh-identity/internal/resolve"
 )
 
-func newBindCmd() *cobra.Command {
-	return &cobra.Command{
+func newBindCmd(auth ghauth.Auth) *cobra.Command {
+	var remote string
+	var dryRun, fromGH, local, repo, ifAbsent bool
+
+	cmd := &cobra.Command{
 		Use:   "bind [<path>] <profile>",
 		Short: "Bind a directory to an identity profile",
-		Long:  "Bind a directory (defaults to $PWD) to a profile. All gh/git operations inside that tree will use the bound identity.",
-		Args:  cobra.RangeArgs(1, 2),
+		Long: `Bind a directory (defaults to $PWD) to a profile. All gh/git operations inside that tree will use the bound identity.
+
+With --remote, bind every repository whose origin URL matches the pattern (e.g. github.com/acme) instead of a directory. Directory bindings take precedence over remote bindings. Git picks up the identity through [includeIf "hasconfig:remote.*.url:..."] directives, which need git 2.36 or newer.
+
+Without <profile>, in a repository whose origin remote names a profile's gh_user (or matches a remote binding), you are prompted with that profile as the default.
+
+With --profile-from-gh, omit <profile>: the profile whose gh_user is the account gh currently has active is used.
+
+With --repo, the root of the git repository containing <path> is bound instead of <path> itself, so binding from a subdirectory covers the whole repository. Outside a repository, <path> is bound as given.
+
+With --local, <path> must be the root of a git repository. The identity is written to that repository's .git/config with git config --local instead of adding an includeIf to the global gitconfig, which is left untouched.
+
+With --if-absent, a path or remote pattern that is already bound keeps its binding, whatever its profile, and the command exits 0 without changing anything. This makes bind safe to re-run in setup scripts.`,
+		Args: cobra.RangeArgs(0, 2),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if fromGH {
+				if len(args) > 1 || (remote != "" && len(args) > 0) {
+					return fmt.Errorf("--profile-from-gh replaces the <profile> argument")
+				}
+				profileName, err := profileForActiveUser(auth)
+				if err != nil {
+					return err
+				}
+				args = append(args, profileName)
+			}
+			if len(args) == 0 {
+				if remote != "" {
+					return fmt.Errorf("a <profile> argument is required")
+				}
+				profileName, err := promptSuggestedProfile(".")
+				if err != nil {
+					return err
+				}
+				args = append(args, profileName)
+			}
+
+			if remote != "" {
+				if local || repo {
+					return fmt.Errorf("--local and --repo cannot be combined with --remote")
+				}
+				if len(args) != 1 {
+					return fmt.Errorf("--remote takes a single <profile> argument")
+				}
+				return runBindRemote(remote, args[0], dryRun, ifAbsent)
+			}
+
 			var dirPath, profileName string
 			if len(args) == 2 {
 				dirPath = args[0]
@@ -25,12 +77,125 @@ func newBindCmd() *cobra.Command {
 				dirPath = "."
 				profileName = args[0]
 			}
-			return runBind(dirPath, profileName)
+			if repo {
+				dirPath = repoRootOr(dirPath)
+			}
+			if local {
+				return runBindLocal(dirPath, profileName, dryRun, ifAbsent)
+			}
+			return runBind(dirPath, profileName, dryRun, ifAbsent)
 		},
 	}
+
+	cmd.Flags().StringVar(&remote, "remote", "", "Bind repositories whose origin URL matches this pattern (e.g. github.com/acme)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the changes without writing anything")
+	cmd.Flags().BoolVar(&fromGH, "profile-from-gh", false, "Bind to the profile of the currently active gh account")
+	cmd.Flags().BoolVar(&local, "local", false, "Write the identity to the repository's .git/config instead of the global gitconfig")
+	cmd.Flags().BoolVar(&repo, "repo", false, "Bind the root of the enclosing git repository instead of the directory itself")
+	cmd.Flags().BoolVar(&ifAbsent, "if-absent", false, "Do nothing if the path or remote pattern is already bound")
+	return cmd
 }
 
-func runBind(dirPath, profileName string) error {
+// alreadyBound reports whether dirPath, or with it empty the remote pattern,
+// already has a binding in bindings, and notes that it is left as it is.
+// Callers hold the config lock, so the answer still holds when they save.
+func alreadyBound(bindings *config.BindingsFile, dirPath, pattern string) bool {
+	if pattern != "" {
+		for _, b := range bindings.Bindings {
+			if b.RemotePattern == pattern {
+				record("unchanged", "remote:"+pattern, b.Profile)
+				note("Remote %s is already bound to %s; leaving it unchanged.", pattern, b.Profile)
+				return true
+			}
+		}
+		return false
+	}
+	b, ok := bindings.Lookup(dirPath)
+	if !ok {
+		return false
+	}
+	record("unchanged", b.Path, b.Profile)
+	note("%s is already bound to %s; leaving it unchanged.", b.Path, b.Profile)
+	return true
+}
+
+// repoRoot returns the top level of the git work tree containing dir. Tests
+// replace it.
+var repoRoot = gitconfig.RepoRoot
+
+// repoRootOr returns the root of the repository containing dirPath, or
+// dirPath itself when it is not inside one.
+func repoRootOr(dirPath string) string {
+	expanded, err := config.ExpandPath(dirPath)
+	if err != nil {
+		return dirPath
+	}
+	root, err := repoRoot(expanded)
+	if err != nil {
+		note("%s is not in a git repository; binding it as given.", dirPath)
+		return dirPath
+	}
+	return root
+}
+
+// profileForActiveUser returns the name of the one profile whose gh_user is
+// the active gh account. GitHub usernames are case-insensitive.
+func profileForActiveUser(auth ghauth.Auth) (string, error) {
+	user, err := auth.ActiveUser()
+	if err != nil {
+		return "", fmt.Errorf("determining the active gh account: %w", err)
+	}
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return "", err
+	}
+
+	var matches []string
+	for name, p := range profiles.Profiles {
+		if strings.EqualFold(p.GHUser, user) {
+			matches = append(matches, name)
+		}
+	}
+	sort.Strings(matches)
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no profile uses the active gh account %q — create one with `gh identity profile add`", user)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("profiles %s all use the active gh account %q — name the profile to bind", strings.Join(matches, ", "), user)
+	}
+}
+
+// promptSuggestedProfile asks which profile to bind dir to, offering the
+// one its origin remote suggests as the default. Without a suggestion there
+// is nothing to offer, so a <profile> argument is required as before.
+func promptSuggestedProfile(dir string) (string, error) {
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return "", err
+	}
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		return "", err
+	}
+	suggested := resolve.Suggest(dir, bindings, profiles)
+	if suggested == "" {
+		return "", fmt.Errorf("a <profile> argument is required")
+	}
+	return promptWithDefault(bufio.NewReader(os.Stdin), "Profile to bind", suggested), nil
+}
+
+// runBind binds dirPath to profileName. With ifAbsent, an existing binding
+// of dirPath is left alone.
+func runBind(dirPath, profileName string, dryRun, ifAbsent bool) error {
+	unlock, err := config.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	// Validate profile exists.
 	profiles, err := config.LoadProfiles()
 	if err != nil {
@@ -42,7 +207,7 @@ func runBind(dirPath, profileName string) error {
 	}
 
 	// Expand and resolve the directory path.
-	expanded, err := config.ExpandPath(dirPath)
+	expanded, err := config.ResolvePath(dirPath)
 	if err != nil {
 		return err
 	}
@@ -52,9 +217,37 @@ func runBind(dirPath, profileName string) error {
 	if err != nil {
 		return err
 	}
+	if ifAbsent && alreadyBound(bindings, expanded, "") {
+		return nil
+	}
+	prev, _ := bindings.Lookup(expanded)
 	if err := bindings.AddBinding(expanded, profileName); err != nil {
 		return err
 	}
+
+	gcPath, err := gitconfig.GlobalGitconfigPath()
+	if err != nil {
+		return err
+	}
+	gitDir, err := config.GitConfigDir()
+	if err != nil {
+		return err
+	}
+	fragmentPath := filepath.Join(gitDir, profileName+".gitconfig")
+
+	if dryRun {
+		actions := []string{
+			fmt.Sprintf("Would bind %s → %s", expanded, profileName),
+			fmt.Sprintf("Would write gitconfig fragment %s", fragmentPath),
+			fmt.Sprintf("Would add includeIf \"gitdir:%s/\" to %s", expanded, gcPath),
+		}
+		if prev.IsLocal() {
+			actions = append(actions, fmt.Sprintf("Would remove the identity from the local git config of %s", expanded))
+		}
+		printDryRun(actions)
+		return nil
+	}
+
 	if err := bindings.Save(); err != nil {
 		return err
 	}
@@ -65,6 +258,116 @@ func runBind(dirPath, profileName string) error {
 	}
 
 	// Add includeIf to global gitconfig.
+	if err := gitconfig.AddIncludeIf(gcPath, expanded, fragmentPath); err != nil {
+		return fmt.Errorf("adding includeIf directive: %w", err)
+	}
+
+	// The local settings would override the includeIf.
+	if prev.IsLocal() {
+		if err := gitconfig.UnsetLocalIdentity(expanded); err != nil {
+			warn("Could not remove the previous identity from the local git config: %v", err)
+		}
+	}
+
+	done([]string{"bound", expanded, profileName}, "Bound %s → %s", expanded, profileName)
+	return nil
+}
+
+// runBindLocal binds the repository at dirPath by writing the profile's
+// identity to its .git/config, leaving the global gitconfig alone.
+func runBindLocal(dirPath, profileName string, dryRun, ifAbsent bool) error {
+	unlock, err := config.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+	profile, err := profiles.GetProfile(profileName)
+	if err != nil {
+		return fmt.Errorf("profile %q not found — run `gh identity profile list` to see available profiles", profileName)
+	}
+
+	expanded, err := config.ResolvePath(dirPath)
+	if err != nil {
+		return err
+	}
+	root, err := repoRoot(expanded)
+	if err != nil {
+		return fmt.Errorf("--local: %w", err)
+	}
+	if resolved, err := config.ResolvePath(root); err != nil || config.FoldPath(resolved) != config.FoldPath(expanded) {
+		return fmt.Errorf("--local binds a whole repository; bind its root %s instead", root)
+	}
+
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		return err
+	}
+	if ifAbsent && alreadyBound(bindings, expanded, "") {
+		return nil
+	}
+	prev, hadBinding := bindings.Lookup(expanded)
+	if err := bindings.AddScopedBinding(expanded, profileName, config.ScopeLocal); err != nil {
+		return err
+	}
+	removeIncludeIf := hadBinding && !prev.IsLocal()
+	gcPath, gcErr := gitconfig.GlobalGitconfigPath()
+
+	if dryRun {
+		actions := []string{
+			fmt.Sprintf("Would bind %s → %s", expanded, profileName),
+			fmt.Sprintf("Would set the identity in the local git config of %s", expanded),
+		}
+		if removeIncludeIf && gcErr == nil {
+			actions = append(actions, fmt.Sprintf("Would remove includeIf \"gitdir:%s/\" from %s", expanded, gcPath))
+		}
+		printDryRun(actions)
+		return nil
+	}
+
+	if err := bindings.Save(); err != nil {
+		return err
+	}
+	if err := gitconfig.SetLocalIdentity(expanded, profile); err != nil {
+		return fmt.Errorf("writing local git config: %w", err)
+	}
+	if removeIncludeIf && gcErr == nil {
+		_ = gitconfig.RemoveIncludeIf(gcPath, expanded)
+	}
+
+	done([]string{"bound", expanded, profileName}, "Bound %s → %s (local git config)", expanded, profileName)
+	return nil
+}
+
+func runBindRemote(pattern, profileName string, dryRun, ifAbsent bool) error {
+	unlock, err := config.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+	profile, err := profiles.GetProfile(profileName)
+	if err != nil {
+		return fmt.Errorf("profile %q not found — run `gh identity profile list` to see available profiles", profileName)
+	}
+
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		return err
+	}
+	if ifAbsent && alreadyBound(bindings, "", pattern) {
+		return nil
+	}
+	bindings.AddRemoteBinding(pattern, profileName)
+
 	gcPath, err := gitconfig.GlobalGitconfigPath()
 	if err != nil {
 		return err
@@ -74,10 +377,32 @@ func runBind(dirPath, profileName string) error {
 		return err
 	}
 	fragmentPath := filepath.Join(gitDir, profileName+".gitconfig")
-	if err := gitconfig.AddIncludeIf(gcPath, expanded, fragmentPath); err != nil {
+
+	if dryRun {
+		actions := []string{
+			fmt.Sprintf("Would bind remote %s → %s", pattern, profileName),
+			fmt.Sprintf("Would write gitconfig fragment %s", fragmentPath),
+		}
+		for _, glob := range gitconfig.RemoteURLGlobs(pattern) {
+			actions = append(actions, fmt.Sprintf("Would add includeIf \"hasconfig:remote.*.url:%s\" to %s", glob, gcPath))
+		}
+		printDryRun(actions)
+		return nil
+	}
+
+	if err := bindings.Save(); err != nil {
+		return err
+	}
+
+	if err := gitconfig.WriteProfileFragment(profileName, profile); err != nil {
+		return fmt.Errorf("writing gitconfig fragment: %w", err)
+	}
+
+	// Add hasconfig:remote includeIfs so git picks up the identity too.
+	if err := gitconfig.AddIncludeIfRemote(gcPath, pattern, fragmentPath); err != nil {
 		return fmt.Errorf("adding includeIf directive: %w", err)
 	}
 
-	fmt.Printf("✅ Bound %s → %s\n", expanded, profileName)
+	done([]string{"bound", "remote:" + pattern, profileName}, "Bound remote %s → %s", pattern, profileName)
 	return nil
 }
@@ -0,0 +1,291 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dotbrains/gh-identity/internal/config"
+	"github.com/dotbrains/gh-identity/internal/gitconfig"
+)
+
+func newBindingsCmd() *cobra.Command {
+	var jsonOut bool
+
+	cmd := &cobra.Command{
+		Use:   "bindings",
+		Short: "Manage directory bindings",
+		Long:  "List directory bindings. Bindings whose profile no longer exists in profiles.yml are flagged.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runBindingsList(jsonOut)
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOut, "json", false, "Output as JSON")
+
+	cmd.AddCommand(newBindingsListCmd(), newBindingsMoveCmd(), newBindingsPruneCmd())
+
+	return cmd
+}
+
+func newBindingsListCmd() *cobra.Command {
+	var jsonOut bool
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Short:   "List all directory bindings",
+		Aliases: []string{"ls"},
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runBindingsList(jsonOut)
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOut, "json", false, "Output as JSON")
+	return cmd
+}
+
+func runBindingsList(jsonOut bool) error {
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		return err
+	}
+
+	sorted := make([]config.Binding, len(bindings.Bindings))
+	copy(sorted, bindings.Bindings)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Target() < sorted[j].Target()
+	})
+
+	if jsonOut {
+		return printJSON(sorted)
+	}
+
+	if len(sorted) == 0 {
+		fmt.Println("No bindings configured. Run `gh identity bind <profile>` to create one.")
+		return nil
+	}
+
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+
+	for _, b := range sorted {
+		scope := ""
+		if b.IsLocal() {
+			scope = " (local git config)"
+		}
+		if _, exists := profiles.Profiles[b.Profile]; exists {
+			fmt.Printf("  %s → %s%s\n", b.Target(), b.Profile, scope)
+		} else {
+			fmt.Printf("  %s → %s%s ❌ (profile not found)\n", b.Target(), b.Profile, scope)
+		}
+	}
+
+	return nil
+}
+
+func newBindingsMoveCmd() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "move <old-prefix> <new-prefix>",
+		Short: "Re-point every binding under a directory to a new location",
+		Long:  "Rewrite each directory binding at or under <old-prefix> to the same relative path under <new-prefix>, e.g. after moving ~/code to ~/src. The matching includeIf directives in the global gitconfig are moved too.",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runBindingsMove(args[0], args[1], dryRun)
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the changes without writing anything")
+	return cmd
+}
+
+func runBindingsMove(oldPrefix, newPrefix string, dryRun bool) error {
+	unlock, err := config.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		return err
+	}
+	moved, err := bindings.MoveBindings(oldPrefix, newPrefix)
+	if err != nil {
+		return err
+	}
+	if len(moved) == 0 {
+		fmt.Printf("No bindings under %s.\n", oldPrefix)
+		return nil
+	}
+
+	gcPath, err := gitconfig.GlobalGitconfigPath()
+	if err != nil {
+		return err
+	}
+	gitDir, err := config.GitConfigDir()
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		var actions []string
+		for _, m := range moved {
+			actions = append(actions, fmt.Sprintf("Would move %s → %s (%s)", m.OldPath, m.NewPath, m.Profile))
+			if !m.Local {
+				actions = append(actions, fmt.Sprintf("Would replace includeIf \"gitdir:%s/\" with \"gitdir:%s/\" in %s", m.OldPath, m.NewPath, gcPath))
+			}
+		}
+		printDryRun(actions)
+		return nil
+	}
+
+	if err := bindings.Save(); err != nil {
+		return err
+	}
+
+	for _, m := range moved {
+		if m.Local {
+			// The identity lives in the repository's .git/config and
+			// moves with it.
+			continue
+		}
+		if err := gitconfig.RemoveIncludeIf(gcPath, m.OldPath); err != nil {
+			return fmt.Errorf("removing includeIf directive: %w", err)
+		}
+		fragmentPath := filepath.Join(gitDir, m.Profile+".gitconfig")
+		if err := gitconfig.AddIncludeIf(gcPath, m.NewPath, fragmentPath); err != nil {
+			return fmt.Errorf("adding includeIf directive: %w", err)
+		}
+	}
+
+	for _, m := range moved {
+		fmt.Printf("  %s → %s\n", m.OldPath, m.NewPath)
+	}
+	fmt.Printf("✅ Moved %d binding(s) from %s to %s\n", len(moved), oldPrefix, newPrefix)
+	return nil
+}
+
+func newBindingsPruneCmd() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove bindings to missing profiles or directories",
+		Long:  "Remove every binding whose profile no longer exists in profiles.yml, and every directory binding whose directory no longer exists, along with their includeIf directives. Glob bindings are only pruned for a missing profile.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runBindingsPrune(dryRun)
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the changes without writing anything")
+	return cmd
+}
+
+func runBindingsPrune(dryRun bool) error {
+	unlock, err := config.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		return err
+	}
+
+	reasons := make(map[string]string)
+	for _, b := range bindings.Bindings {
+		if reason := pruneReason(b, profiles); reason != "" {
+			reasons[b.Target()] = reason
+		}
+	}
+	if len(reasons) == 0 {
+		note("Nothing to prune.")
+		return nil
+	}
+
+	paths, remotes, locals := removeBindings(bindings, func(b config.Binding) bool {
+		return reasons[b.Target()] != ""
+	})
+	// A repository that is gone has no local git config left to clean up.
+	var existing []string
+	for _, p := range locals {
+		if dirExists(p) {
+			existing = append(existing, p)
+		}
+	}
+
+	gcPath, gcErr := gitconfig.GlobalGitconfigPath()
+
+	if dryRun {
+		var actions []string
+		for _, target := range sortedKeys(reasons) {
+			actions = append(actions, fmt.Sprintf("Would prune %s (%s)", target, reasons[target]))
+		}
+		actions = append(actions, cleanupActions(paths, remotes, existing, gcPath, gcErr)...)
+		printDryRun(actions)
+		return nil
+	}
+
+	if err := bindings.Save(); err != nil {
+		return err
+	}
+	if gcErr == nil {
+		removeIncludeIfs(gcPath, paths, remotes)
+	}
+	unsetLocalIdentities(existing)
+
+	for _, target := range sortedKeys(reasons) {
+		done([]string{"pruned", target}, "Pruned %s (%s)", target, reasons[target])
+	}
+	return nil
+}
+
+// pruneReason returns why b should be pruned, or "" if it should be kept.
+func pruneReason(b config.Binding, profiles *config.ProfilesFile) string {
+	if _, exists := profiles.Profiles[b.Profile]; !exists {
+		return fmt.Sprintf("profile %q not found", b.Profile)
+	}
+	if b.IsRemote() || strings.Contains(b.Path, "*") {
+		return ""
+	}
+	if !dirExists(b.Path) {
+		return "directory no longer exists"
+	}
+	return ""
+}
+
+// dirExists reports whether the binding path p names an existing directory.
+func dirExists(p string) bool {
+	expanded, err := config.ExpandConfigPath(p)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(expanded)
+	return err == nil && info.IsDir()
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -9,27 +9,52 @@ import (
 	gh "github.com/cli/go-gh/v2"
 	"github.com/spf13/cobra"
 
+	"github.com/dotbrains/gh-identity/internal/config"
 	"github.com/dotbrains/gh-identity/internal/ghauth"
 )
 
-func newCloneCmd(auth ghauth.Auth) *cobra.Command {
-	var profileFlag string
+// cloneExec runs `gh` with the given arguments. It is a variable so tests can
+// stub out the actual clone.
+var cloneExec = gh.Exec
 
+func newCloneCmd(auth ghauth.Auth) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "clone <repo>",
-		Short: "Clone a repo and bind it to a profile",
-		Long:  "Wraps `gh repo clone`. After cloning, automatically binds the new directory to the specified profile (or the currently active one).",
-		Args:  cobra.ExactArgs(1),
+		Use:         "clone <repo> [dir] [-- <gh flags>...]",
+		Short:       "Clone a repo and bind it to a profile",
+		Annotations: honorsProfileOverride,
+		Long: `Wraps ` + "`gh repo clone`" + `. After cloning, automatically binds the new directory to the specified profile (or the currently active one). A profile with clone_protocol set clones over that protocol (ssh or https) instead of gh's default.
+
+Use the global ` + "`--profile`" + ` flag to bind to a specific profile. An optional target directory is passed through to ` + "`gh repo clone`" + ` and is the directory that gets bound. Arguments after ` + "`--`" + ` are passed through as extra flags.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			positional, _ := splitCloneArgs(cmd, args)
+			if len(positional) < 1 || len(positional) > 2 {
+				return fmt.Errorf("accepts a repo and an optional target directory, received %d argument(s)", len(positional))
+			}
+			return nil
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runClone(auth, args[0], profileFlag)
+			positional, extra := splitCloneArgs(cmd, args)
+			var targetDir string
+			if len(positional) == 2 {
+				targetDir = positional[1]
+			}
+			return runClone(auth, positional[0], targetDir, extra, profileOverride(cmd))
 		},
 	}
 
-	cmd.Flags().StringVar(&profileFlag, "profile", "", "Profile to bind the cloned repo to (defaults to active profile)")
 	return cmd
 }
 
-func runClone(auth ghauth.Auth, repo, profileName string) error {
+// splitCloneArgs separates the positional arguments from the ones after `--`.
+func splitCloneArgs(cmd *cobra.Command, args []string) (positional, extra []string) {
+	dash := cmd.ArgsLenAtDash()
+	if dash < 0 {
+		return args, nil
+	}
+	return args[:dash], args[dash:]
+}
+
+func runClone(auth ghauth.Auth, repo, targetDir string, extra []string, profileName string) error {
 	// Determine profile.
 	if profileName == "" {
 		profileName = os.Getenv("GH_IDENTITY_PROFILE")
@@ -38,20 +63,41 @@ func runClone(auth ghauth.Auth, repo, profileName string) error {
 		return fmt.Errorf("no profile specified and no active profile — use --profile or activate a profile first")
 	}
 
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+	profile, err := profiles.GetProfile(profileName)
+	if err != nil {
+		return fmt.Errorf("profile %q not found — run `gh identity profile list` to see available profiles", profileName)
+	}
+
 	// Clone the repo.
+	ghArgs := []string{"repo", "clone", cloneSource(repo, profile)}
+	if targetDir != "" {
+		ghArgs = append(ghArgs, targetDir)
+	}
+	if len(extra) > 0 {
+		ghArgs = append(ghArgs, "--")
+		ghArgs = append(ghArgs, extra...)
+	}
+
 	fmt.Printf("Cloning %s...\n", repo)
-	_, stderr, err := gh.Exec("repo", "clone", repo)
+	_, stderr, err := cloneExec(ghArgs...)
 	if err != nil {
 		return fmt.Errorf("cloning repo: %s: %w", stderr.String(), err)
 	}
 
-	// Determine the cloned directory name.
-	cloneDir := repoToDir(repo)
-	pwd, err := os.Getwd()
-	if err != nil {
-		return err
+	// Determine the cloned directory.
+	cloneDir := repoToDir(repo, targetDir)
+	fullPath := cloneDir
+	if !filepath.IsAbs(fullPath) {
+		pwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		fullPath = filepath.Join(pwd, cloneDir)
 	}
-	fullPath := filepath.Join(pwd, cloneDir)
 
 	// Verify it exists.
 	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
@@ -60,24 +106,68 @@ func runClone(auth ghauth.Auth, repo, profileName string) error {
 	}
 
 	// Bind the directory.
-	if err := runBind(fullPath, profileName); err != nil {
+	if err := runBind(fullPath, profileName, false, false); err != nil {
 		return fmt.Errorf("binding cloned repo: %w", err)
 	}
 
 	return nil
 }
 
-// repoToDir extracts the directory name from a repo specifier.
-// e.g. "owner/repo" → "repo", "https://github.com/owner/repo.git" → "repo"
-func repoToDir(repo string) string {
-	// Remove .git suffix.
+// cloneSource returns what to pass to `gh repo clone` for repo. gh picks the
+// protocol from its git_protocol setting and offers no flag to override it,
+// so for a profile with clone_protocol set, an "owner/repo" or "repo"
+// shorthand is expanded to a URL of that protocol on the profile's host; a
+// bare repo name belongs to the profile's user. Full URLs are used as given.
+func cloneSource(repo string, p config.Profile) string {
+	if p.CloneProtocol == "" || strings.Contains(repo, ":") {
+		return repo
+	}
+	nwo := strings.TrimSuffix(strings.TrimSuffix(repo, "/"), ".git")
+	switch strings.Count(nwo, "/") {
+	case 0:
+		nwo = p.GHUser + "/" + nwo
+	case 2:
+		// HOST/OWNER/REPO; gh resolves the host itself.
+		return repo
+	}
+	if p.CloneProtocol == config.CloneSSH {
+		return fmt.Sprintf("git@%s:%s.git", p.GHHost(), nwo)
+	}
+	return fmt.Sprintf("https://%s/%s.git", p.GHHost(), nwo)
+}
+
+// repoToDir returns the directory a clone of repo ends up in. An explicit
+// targetDir always wins (it may be nested, e.g. "org/repo"); otherwise the
+// name is taken from the last path segment of the repo specifier, ignoring
+// any query string, fragment, trailing slashes, and .git suffix.
+// e.g. "owner/repo" → "repo", "https://github.com/owner/repo.git?x=1" → "repo",
+// "git@github.com:owner/repo.git" → "repo", "ssh://git@host/owner/repo/" → "repo"
+func repoToDir(repo, targetDir string) string {
+	if targetDir != "" {
+		return filepath.Clean(targetDir)
+	}
+
+	// Drop a query string or fragment.
+	if i := strings.IndexAny(repo, "?#"); i >= 0 {
+		repo = repo[:i]
+	}
+
+	// Remove trailing slashes and the .git suffix, in either order.
+	repo = strings.TrimRight(repo, "/")
 	repo = strings.TrimSuffix(repo, ".git")
+	repo = strings.TrimRight(repo, "/")
 
-	// Handle URL format.
-	if strings.Contains(repo, "/") {
-		parts := strings.Split(repo, "/")
-		return parts[len(parts)-1]
+	// Handle the scp-like form (git@host:owner/repo), where the path follows
+	// the first colon. URLs with a scheme keep their "://".
+	if !strings.Contains(repo, "://") {
+		if i := strings.Index(repo, ":"); i >= 0 {
+			repo = repo[i+1:]
+		}
 	}
 
+	// Handle URL and owner/repo formats.
+	if i := strings.LastIndex(repo, "/"); i >= 0 {
+		repo = repo[i+1:]
+	}
 	return repo
 }
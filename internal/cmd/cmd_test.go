@@ -3,21 +3,40 @@ package cmd
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/dotbrains/gh-identity/internal/config"
+	"github.com/dotbrains/gh-identity/internal/ghauth"
+	"github.com/dotbrains/gh-identity/internal/gitconfig"
+	"github.com/dotbrains/gh-identity/internal/hook"
+	"github.com/dotbrains/gh-identity/internal/resolve"
+	"github.com/dotbrains/gh-identity/internal/version"
 )
 
 // mockAuth implements ghauth.Auth for testing.
 type mockAuth struct {
 	users      []string
+	accounts   []ghauth.Account // overrides users for Accounts when set
 	activeUser string
 	tokens     map[string]string
+	scopes     map[string][]string
+	scopeErrs  map[string]error
 	err        error
 }
 
-func (m *mockAuth) Token(username string) (string, error) {
+func (m *mockAuth) Token(host, username string) (string, error) {
 	if m.err != nil {
 		return "", m.err
 	}
@@ -27,6 +46,20 @@ func (m *mockAuth) Token(username string) (string, error) {
 	return "mock-token-" + username, nil
 }
 
+func (m *mockAuth) Accounts() ([]ghauth.Account, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if m.accounts != nil {
+		return m.accounts, nil
+	}
+	var accounts []ghauth.Account
+	for _, u := range m.users {
+		accounts = append(accounts, ghauth.Account{Host: ghauth.DefaultHost, User: u})
+	}
+	return accounts, nil
+}
+
 func (m *mockAuth) AuthenticatedUsers() ([]string, error) {
 	if m.err != nil {
 		return nil, m.err
@@ -34,6 +67,19 @@ func (m *mockAuth) AuthenticatedUsers() ([]string, error) {
 	return m.users, nil
 }
 
+// TokenScopes looks up scopes and scopeErrs by user, or user@host for hosts
+// other than github.com.
+func (m *mockAuth) TokenScopes(host, username string) ([]string, error) {
+	key := username
+	if host != ghauth.DefaultHost {
+		key += "@" + host
+	}
+	if err := m.scopeErrs[key]; err != nil {
+		return nil, err
+	}
+	return m.scopes[key], nil
+}
+
 func (m *mockAuth) ActiveUser() (string, error) {
 	if m.err != nil {
 		return "", m.err
@@ -45,9 +91,32 @@ func setupTestEnv(t *testing.T) string {
 	t.Helper()
 	dir := t.TempDir()
 	t.Setenv("GH_IDENTITY_CONFIG_DIR", dir)
+	t.Setenv("GIT_CONFIG_GLOBAL", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("GH_TOKEN", "")
+	stubToolVersions(t, "gh version 2.60.0 (2024-10-30)", "git version 2.47.0")
 	return dir
 }
 
+// stubToolVersions makes doctor's tool check report the given `--version`
+// output instead of running gh and git.
+func stubToolVersions(t *testing.T, gh, git string) {
+	t.Helper()
+	orig := toolVersion
+	t.Cleanup(func() { toolVersion = orig })
+	toolVersion = func(name string) (string, error) {
+		switch name {
+		case "gh":
+			return gh, nil
+		case "git":
+			return git, nil
+		}
+		return "", exec.ErrNotFound
+	}
+}
+
 func writeProfiles(t *testing.T, dir, content string) {
 	t.Helper()
 	if err := os.WriteFile(filepath.Join(dir, "profiles.yml"), []byte(content), 0o644); err != nil {
@@ -62,6 +131,44 @@ func writeBindings(t *testing.T, dir, content string) {
 	}
 }
 
+// captureStdout runs fn with os.Stdout redirected and returns what it printed.
+func captureStdout(t *testing.T, fn func() error) (string, error) {
+	t.Helper()
+	old := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+
+	done := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		buf.ReadFrom(r)
+		done <- buf.String()
+	}()
+
+	fnErr := fn()
+
+	w.Close()
+	os.Stdout = old
+	return <-done, fnErr
+}
+
+// setStdin replaces os.Stdin with the given input for the duration of the test.
+func setStdin(t *testing.T, input string) {
+	t.Helper()
+	old := os.Stdin
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString(input)
+	w.Close()
+	os.Stdin = r
+	t.Cleanup(func() { os.Stdin = old })
+}
+
 // TestNewRootCmd verifies the command tree is properly wired.
 func TestNewRootCmd(t *testing.T) {
 	root := NewRootCmd()
@@ -73,7 +180,7 @@ func TestNewRootCmd(t *testing.T) {
 	}
 
 	// Verify all subcommands are registered.
-	wantCmds := []string{"init", "profile", "bind", "unbind", "switch", "status", "clone", "doctor"}
+	wantCmds := []string{"init", "import", "export", "profile", "bind", "unbind", "bindings", "switch", "use", "status", "clone", "doctor", "credential"}
 	cmds := make(map[string]bool)
 	for _, c := range root.Commands() {
 		cmds[c.Use] = true
@@ -95,19 +202,33 @@ func TestNewRootCmd(t *testing.T) {
 // TestRepoToDir tests the clone directory name extraction.
 func TestRepoToDir(t *testing.T) {
 	tests := []struct {
-		input string
-		want  string
+		input  string
+		target string
+		want   string
 	}{
-		{"owner/repo", "repo"},
-		{"https://github.com/owner/repo.git", "repo"},
-		{"https://github.com/owner/repo", "repo"},
-		{"myrepo", "myrepo"},
-		{"org/sub/repo.git", "repo"},
+		{"owner/repo", "", "repo"},
+		{"https://github.com/owner/repo.git", "", "repo"},
+		{"https://github.com/owner/repo", "", "repo"},
+		{"https://github.com/owner/repo/", "", "repo"},
+		{"git@github.com:owner/repo.git", "", "repo"},
+		{"myrepo", "", "myrepo"},
+		{"org/sub/repo.git", "", "repo"},
+		{"git@github.com:repo.git", "", "repo"},
+		{"ssh://git@github.com/owner/repo.git", "", "repo"},
+		{"ssh://git@github.com:22/owner/repo/", "", "repo"},
+		{"https://github.com/owner/repo.git/", "", "repo"},
+		{"https://github.com/owner/repo//", "", "repo"},
+		{"https://github.com/owner/repo?tab=readme", "", "repo"},
+		{"https://github.com/owner/repo.git#main", "", "repo"},
+		{"https://github.com/owner/repo/?x=a/b", "", "repo"},
+		{"owner/repo", "custom", "custom"},
+		{"owner/repo", "org/repo/", "org/repo"},
+		{"owner/repo", "/abs/path", "/abs/path"},
 	}
 	for _, tt := range tests {
-		got := repoToDir(tt.input)
+		got := repoToDir(tt.input, tt.target)
 		if got != tt.want {
-			t.Errorf("repoToDir(%q) = %q, want %q", tt.input, got, tt.want)
+			t.Errorf("repoToDir(%q, %q) = %q, want %q", tt.input, tt.target, got, tt.want)
 		}
 	}
 }
@@ -143,6 +264,35 @@ func TestContainsStr(t *testing.T) {
 	}
 }
 
+// TestDetectShell_Windows tests shell detection on native Windows, where
+// SHELL is not set.
+func TestDetectShell_Windows(t *testing.T) {
+	old := goos
+	goos = "windows"
+	t.Cleanup(func() { goos = old })
+
+	tests := []struct {
+		name, shell, psModulePath, comSpec string
+		want                               string
+	}{
+		{"powershell 7", "", `C:\Users\me\Documents\PowerShell\Modules;C:\Program Files\PowerShell\Modules`, `C:\Windows\system32\cmd.exe`, "powershell"},
+		{"cmd", "", `C:\Program Files\WindowsPowerShell\Modules`, `C:\Windows\system32\cmd.exe`, "cmd"},
+		{"nothing set", "", "", "", "powershell"},
+		{"git bash", "/usr/bin/bash", "", `C:\Windows\system32\cmd.exe`, "bash"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SHELL", tt.shell)
+			t.Setenv("USERPROFILE", `C:\Users\me`)
+			t.Setenv("PSModulePath", tt.psModulePath)
+			t.Setenv("ComSpec", tt.comSpec)
+			if got := detectShell(); got != tt.want {
+				t.Errorf("detectShell() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 // TestDetectShell tests shell detection from SHELL env.
 func TestDetectShell(t *testing.T) {
 	tests := []struct {
@@ -155,6 +305,8 @@ func TestDetectShell(t *testing.T) {
 		{"/usr/local/bin/fish", "fish"},
 		{"", "bash"},
 		{"/bin/sh", "bash"},
+		{"/usr/bin/nu", "nu"},
+		{"/usr/local/bin/pwsh", "powershell"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.shellEnv, func(t *testing.T) {
@@ -186,7 +338,7 @@ default: personal`)
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	err := runProfileList()
+	err := runProfileList(false)
 
 	w.Close()
 	os.Stdout = old
@@ -210,6 +362,43 @@ default: personal`)
 	}
 }
 
+// TestRunProfileListNames tests that --names prints only the sorted profile
+// names, and nothing when there are none.
+func TestRunProfileListNames(t *testing.T) {
+	dir := setupTestEnv(t)
+
+	output, err := captureStdout(t, runProfileListNames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "" {
+		t.Errorf("output with no profiles = %q, want empty", output)
+	}
+
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: Work
+    git_email: work@work.com
+  personal:
+    gh_user: user1
+    git_name: Me
+    git_email: me@me.com
+  client-b:
+    gh_user: user3
+    git_name: Me
+    git_email: me@client.com
+default: personal`)
+
+	output, err = captureStdout(t, runProfileListNames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "client-b\npersonal\nwork\n"; output != want {
+		t.Errorf("output = %q, want %q", output, want)
+	}
+}
+
 // TestRunProfileList_Empty tests list with no profiles.
 func TestRunProfileList_Empty(t *testing.T) {
 	setupTestEnv(t)
@@ -218,7 +407,7 @@ func TestRunProfileList_Empty(t *testing.T) {
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	err := runProfileList()
+	err := runProfileList(false)
 
 	w.Close()
 	os.Stdout = old
@@ -255,7 +444,7 @@ func TestRunBind(t *testing.T) {
 	_, w, _ := os.Pipe()
 	os.Stdout = w
 
-	err := runBind(bindDir, "work")
+	err := runBind(bindDir, "work", false, false)
 
 	w.Close()
 	os.Stdout = old
@@ -274,230 +463,564 @@ func TestRunBind(t *testing.T) {
 	}
 }
 
-// TestRunBind_InvalidProfile tests binding with nonexistent profile.
-func TestRunBind_InvalidProfile(t *testing.T) {
+// TestRunBind_DollarInName tests that a directory with a "$" in its name is
+// bound, and resolved, as itself rather than env-expanded.
+func TestRunBind_DollarInName(t *testing.T) {
 	dir := setupTestEnv(t)
-	writeProfiles(t, dir, `profiles: {}`)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("x", "")
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
+	tmp, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bindDir := filepath.Join(tmp, "proj$x")
+	os.MkdirAll(bindDir, 0o755)
 
-	err := runBind("/some/dir", "nonexistent")
-	if err == nil {
-		t.Error("expected error for nonexistent profile")
+	if _, err := captureStdout(t, func() error { return runBind(bindDir, "work", false, false) }); err != nil {
+		t.Fatal(err)
+	}
+	// bindings.yml paths are env-expanded, so the "$" is stored as "$$".
+	stored := strings.ReplaceAll(bindDir, "$", "$$")
+	bf, err := config.LoadBindings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bf.Bindings) != 1 || bf.Bindings[0].Path != stored {
+		t.Fatalf("bindings = %+v, want one for %s", bf.Bindings, stored)
+	}
+
+	res, err := resolve.ForDirectory(bindDir, bf, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Profile != "work" || res.BoundPath != stored {
+		t.Errorf("ForDirectory() = %+v, want work via %s", res, stored)
+	}
+	if got := bf.FindBinding(bindDir); got != "work" {
+		t.Errorf("FindBinding() = %q, want %q", got, "work")
 	}
 }
 
-// TestRunUnbind tests unbinding a directory.
-func TestRunUnbind(t *testing.T) {
+// TestPorcelainOutput tests that --porcelain switches commands to terse lines.
+func TestPorcelainOutput(t *testing.T) {
 	dir := setupTestEnv(t)
-	bindDir := t.TempDir()
+	t.Setenv("HOME", t.TempDir())
 	writeProfiles(t, dir, `profiles:
   work:
     gh_user: user2
     git_name: User Two
     git_email: user2@company.com`)
-	writeBindings(t, dir, `bindings:
-  - path: `+bindDir+`
-    profile: work`)
 
-	tmpHome := t.TempDir()
-	t.Setenv("HOME", tmpHome)
+	porcelain = true
+	t.Cleanup(func() { porcelain = false })
 
-	old := os.Stdout
-	_, w, _ := os.Pipe()
-	os.Stdout = w
+	bindDir := t.TempDir()
+	expanded, err := config.ResolvePath(bindDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newName := "Worker Two"
 
-	err := runUnbind(bindDir)
+	steps := []struct {
+		name string
+		run  func() error
+		want string
+	}{
+		{"bind", func() error { return runBind(bindDir, "work", false, false) }, "bound\t" + expanded + "\twork\n"},
+		{"bind remote", func() error { return runBindRemote("github.com/acme", "work", false, false) }, "bound\tremote:github.com/acme\twork\n"},
+		{"unbind", func() error { return runUnbind(bindDir, false) }, "unbound\t" + expanded + "\n"},
+		{"profile edit", func() error { return runProfileEdit("work", profileEditFlags{GitName: &newName}) }, "updated\twork\n"},
+		{"profile rename", func() error { return runProfileRename("work", "job") }, "renamed\twork\tjob\nbound\tremote:github.com/acme\tjob\n"},
+		{"profile remove", func() error { return runProfileRemove("job", true, false) }, "removed\tjob\nunbound\tremote:github.com/acme\n"},
+	}
+	for _, step := range steps {
+		output, err := captureStdout(t, step.run)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if output != step.want {
+			t.Errorf("%s: output = %q, want %q", step.name, output, step.want)
+		}
+	}
+}
 
-	w.Close()
-	os.Stdout = old
+// TestRunBind_DryRun tests that a dry-run bind reports its changes without writing them.
+func TestRunBind_DryRun(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	bindDir := t.TempDir()
 
+	output, err := captureStdout(t, func() error { return runBind(bindDir, "work", true, false) })
 	if err != nil {
 		t.Fatal(err)
 	}
-}
-
-// TestRunUnbind_NotBound tests unbinding a directory that isn't bound.
-func TestRunUnbind_NotBound(t *testing.T) {
-	setupTestEnv(t)
-	t.Setenv("HOME", t.TempDir())
+	for _, want := range []string{"Dry run", "Would bind", "Would write gitconfig fragment", "Would add includeIf"} {
+		if !containsStr(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
 
-	err := runUnbind("/some/unbound/dir")
-	if err == nil {
-		t.Error("expected error unbinding unbound directory")
+	if _, err := os.Stat(filepath.Join(dir, "bindings.yml")); !os.IsNotExist(err) {
+		t.Error("dry run should not write bindings.yml")
+	}
+	if _, err := os.Stat(filepath.Join(tmpHome, ".gitconfig")); !os.IsNotExist(err) {
+		t.Error("dry run should not write .gitconfig")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "git", "work.gitconfig")); !os.IsNotExist(err) {
+		t.Error("dry run should not write the gitconfig fragment")
 	}
 }
 
-// TestRunSwitch tests the switch command output.
-func TestRunSwitch(t *testing.T) {
+// TestRunBindingsMove tests re-pointing bindings and their includeIf directives.
+func TestRunBindingsMove(t *testing.T) {
 	dir := setupTestEnv(t)
 	writeProfiles(t, dir, `profiles:
-  personal:
-    gh_user: user1
-    git_name: User One
-    git_email: user1@example.com`)
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
 
-	auth := &mockAuth{
-		tokens: map[string]string{"user1": "test-token-123"},
+	root, _ := filepath.EvalSymlinks(t.TempDir())
+	oldDir := filepath.Join(root, "code", "api")
+	if _, err := captureStdout(t, func() error { return runBind(oldDir, "work", false, false) }); err != nil {
+		t.Fatal(err)
 	}
 
-	old := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
+	output, err := captureStdout(t, func() error {
+		return runBindingsMove(filepath.Join(root, "code"), filepath.Join(root, "src"), false)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsStr(output, "Moved 1 binding(s)") {
+		t.Errorf("unexpected output:\n%s", output)
+	}
 
-	err := runSwitch(auth, "personal")
+	newDir := filepath.Join(root, "src", "api")
+	bindings, _ := config.LoadBindings()
+	if len(bindings.Bindings) != 1 || bindings.Bindings[0].Path != newDir {
+		t.Errorf("bindings = %+v, want path %s", bindings.Bindings, newDir)
+	}
+	data, _ := os.ReadFile(filepath.Join(tmpHome, ".gitconfig"))
+	if containsStr(string(data), "gitdir:"+oldDir+"/") || !containsStr(string(data), "gitdir:"+newDir+"/") {
+		t.Errorf("includeIf not moved:\n%s", data)
+	}
+}
 
-	w.Close()
-	os.Stdout = old
+func TestRunBindingsPrune(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
 
-	if err != nil {
+	root, _ := filepath.EvalSymlinks(t.TempDir())
+	kept := filepath.Join(root, "kept")
+	gone := filepath.Join(root, "gone")
+	orphan := filepath.Join(root, "orphan")
+	os.MkdirAll(kept, 0o755)
+	os.MkdirAll(orphan, 0o755)
+	for _, d := range []string{kept, gone} {
+		if _, err := captureStdout(t, func() error { return runBind(d, "work", false, false) }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bindings, _ := config.LoadBindings()
+	bindings.AddBinding(orphan, "retired")
+	bindings.AddRemoteBinding("github.com/acme/*", "retired")
+	if err := bindings.Save(); err != nil {
 		t.Fatal(err)
 	}
 
-	var buf bytes.Buffer
-	buf.ReadFrom(r)
-	output := buf.String()
+	output, err := captureStdout(t, func() error { return runBindingsPrune(true) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsStr(output, "Would prune "+gone+" (directory no longer exists)") ||
+		!containsStr(output, "Would prune "+orphan+` (profile "retired" not found)`) ||
+		containsStr(output, "prune "+kept) {
+		t.Errorf("unexpected dry-run output:\n%s", output)
+	}
+	if bindings, _ := config.LoadBindings(); len(bindings.Bindings) != 4 {
+		t.Fatalf("dry run changed bindings: %+v", bindings.Bindings)
+	}
 
-	if !containsStr(output, "gh auth switch --user user1") {
-		t.Error("expected gh auth switch in switch output")
+	output, err = captureStdout(t, func() error { return runBindingsPrune(false) })
+	if err != nil {
+		t.Fatal(err)
 	}
-	if !containsStr(output, "GH_IDENTITY_PROFILE") {
-		t.Error("expected profile env var in output")
+	if !containsStr(output, "Pruned remote:github.com/acme/*") {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+	bindings, _ = config.LoadBindings()
+	if len(bindings.Bindings) != 1 || bindings.Bindings[0].Path != kept {
+		t.Errorf("bindings = %+v, want only %s", bindings.Bindings, kept)
+	}
+	data, _ := os.ReadFile(filepath.Join(tmpHome, ".gitconfig"))
+	if containsStr(string(data), "gitdir:"+gone+"/") || !containsStr(string(data), "gitdir:"+kept+"/") {
+		t.Errorf("includeIfs not pruned:\n%s", data)
 	}
 }
 
-// TestRunSwitch_InvalidProfile tests switch with nonexistent profile.
-func TestRunSwitch_InvalidProfile(t *testing.T) {
+// TestRunBind_InvalidProfile tests binding with nonexistent profile.
+func TestRunBind_InvalidProfile(t *testing.T) {
 	dir := setupTestEnv(t)
 	writeProfiles(t, dir, `profiles: {}`)
 
-	auth := &mockAuth{}
-	err := runSwitch(auth, "nonexistent")
+	err := runBind("/some/dir", "nonexistent", false, false)
 	if err == nil {
 		t.Error("expected error for nonexistent profile")
 	}
 }
 
-// TestRunStatus tests the status command.
-func TestRunStatus(t *testing.T) {
+// TestBindProfileFromGH tests binding to the profile of the active gh account.
+func TestBindProfileFromGH(t *testing.T) {
 	dir := setupTestEnv(t)
-	pwd, _ := os.Getwd()
+	t.Setenv("HOME", t.TempDir())
 	writeProfiles(t, dir, `profiles:
+  personal:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com
   work:
     gh_user: user2
     git_name: User Two
     git_email: user2@company.com
-    ssh_key: ~/.ssh/id_test
-default: work`)
-	writeBindings(t, dir, `bindings:
-  - path: `+pwd+`
-    profile: work`)
-
-	auth := &mockAuth{activeUser: "user2"}
-
-	old := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
-	err := runStatus(auth)
-
-	w.Close()
-	os.Stdout = old
+  work-alt:
+    gh_user: User2
+    git_name: User Two
+    git_email: user2@alt.com`)
 
+	name, err := profileForActiveUser(&mockAuth{activeUser: "user1"})
 	if err != nil {
 		t.Fatal(err)
 	}
+	if name != "personal" {
+		t.Errorf("profileForActiveUser() = %q, want personal", name)
+	}
 
-	var buf bytes.Buffer
-	buf.ReadFrom(r)
-	output := buf.String()
+	if _, err := profileForActiveUser(&mockAuth{activeUser: "user2"}); err == nil || !containsStr(err.Error(), "work, work-alt") {
+		t.Errorf("expected ambiguity error naming both profiles, got %v", err)
+	}
+	if _, err := profileForActiveUser(&mockAuth{activeUser: "stranger"}); err == nil {
+		t.Error("expected error when no profile uses the active account")
+	}
+	if _, err := profileForActiveUser(&mockAuth{err: fmt.Errorf("gh failed")}); err == nil {
+		t.Error("expected error when the active account is unknown")
+	}
 
-	if !containsStr(output, "work") {
-		t.Error("expected 'work' profile in status")
+	bindDir := t.TempDir()
+	cmd := newBindCmd(&mockAuth{activeUser: "user1"})
+	cmd.SetArgs([]string{bindDir, "--profile-from-gh"})
+	if _, err := captureStdout(t, cmd.Execute); err != nil {
+		t.Fatal(err)
 	}
-	if !containsStr(output, "user2") {
-		t.Error("expected 'user2' in status")
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bindings.FindBinding(bindDir); got != "personal" {
+		t.Errorf("binding for %s = %q, want personal", bindDir, got)
+	}
+
+	cmd = newBindCmd(&mockAuth{activeUser: "user1"})
+	cmd.SetArgs([]string{bindDir, "work", "--profile-from-gh"})
+	cmd.SetErr(io.Discard)
+	cmd.SetOut(io.Discard)
+	if err := cmd.Execute(); err == nil {
+		t.Error("expected error when both a profile and --profile-from-gh are given")
 	}
 }
 
-// TestRunStatus_NoProfile tests status with no active profile.
-func TestRunStatus_NoProfile(t *testing.T) {
+// TestBindRepo tests that bind --repo binds the enclosing repository root
+// and falls back to the given path outside a repository.
+func TestBindRepo(t *testing.T) {
 	dir := setupTestEnv(t)
-	writeProfiles(t, dir, `profiles: {}`)
-	writeBindings(t, dir, `bindings: []`)
-	t.Setenv("GH_IDENTITY_PROFILE", "")
+	t.Setenv("HOME", t.TempDir())
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
 
-	auth := &mockAuth{}
+	repo := t.TempDir()
+	sub := filepath.Join(repo, "src", "pkg")
+	os.MkdirAll(sub, 0o755)
+	outside := t.TempDir()
 
-	old := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
-	err := runStatus(auth)
+	old := repoRoot
+	t.Cleanup(func() { repoRoot = old })
+	repoRoot = func(dir string) (string, error) {
+		if strings.HasPrefix(dir, repo) {
+			return repo, nil
+		}
+		return "", fmt.Errorf("%s is not in a git repository", dir)
+	}
 
-	w.Close()
-	os.Stdout = old
+	for _, path := range []string{sub, outside} {
+		cmd := newBindCmd(&mockAuth{})
+		cmd.SetArgs([]string{path, "work", "--repo"})
+		if _, err := captureStdout(t, cmd.Execute); err != nil {
+			t.Fatal(err)
+		}
+	}
 
+	bindings, err := config.LoadBindings()
 	if err != nil {
 		t.Fatal(err)
 	}
+	if got := bindings.FindBinding(repo); got != "work" {
+		t.Errorf("binding for repository root = %q, want work", got)
+	}
+	if got := bindings.FindBinding(sub); got != "" {
+		t.Errorf("subdirectory was bound to %q; want only the root bound", got)
+	}
+	if got := bindings.FindBinding(outside); got != "work" {
+		t.Errorf("binding outside a repository = %q, want work", got)
+	}
+}
 
-	var buf bytes.Buffer
-	buf.ReadFrom(r)
-	output := buf.String()
+func TestBindIfAbsent(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
+	writeProfiles(t, dir, `profiles:
+  personal:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
+	target := t.TempDir()
+
+	bind := func(args ...string) string {
+		t.Helper()
+		cmd := newBindCmd(&mockAuth{})
+		cmd.SetArgs(append(args, "--if-absent"))
+		output, err := captureStdout(t, cmd.Execute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return output
+	}
 
-	if !containsStr(output, "No active profile") {
-		t.Error("expected 'No active profile' message")
+	bind(target, "work")
+	if output := bind(target, "personal"); !containsStr(output, "already bound to work") {
+		t.Errorf("expected a note that the path is already bound, got:\n%s", output)
+	}
+	bind("--remote", "github.com/acme", "work")
+	if output := bind("--remote", "github.com/acme", "personal"); !containsStr(output, "already bound to work") {
+		t.Errorf("expected a note that the remote is already bound, got:\n%s", output)
+	}
+
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bindings.FindBinding(target); got != "work" {
+		t.Errorf("binding after second --if-absent bind = %q, want work", got)
+	}
+	for _, b := range bindings.Bindings {
+		if b.RemotePattern == "github.com/acme" && b.Profile != "work" {
+			t.Errorf("remote binding after second --if-absent bind = %q, want work", b.Profile)
+		}
 	}
 }
 
-// TestRunStatus_EnvOverride tests status with GH_IDENTITY_PROFILE env override.
-func TestRunStatus_EnvOverride(t *testing.T) {
+// TestBindIfAbsentConcurrent tests that concurrent --if-absent binds of the
+// same remote leave exactly one of them bound.
+func TestBindIfAbsentConcurrent(t *testing.T) {
 	dir := setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
 	writeProfiles(t, dir, `profiles:
-  override:
-    gh_user: user3
-    git_name: User Three
-    git_email: user3@example.com`)
-	writeBindings(t, dir, `bindings: []`)
-	t.Setenv("GH_IDENTITY_PROFILE", "override")
+  personal:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
+	porcelain = true
+	t.Cleanup(func() { porcelain = false })
+
+	output, err := captureStdout(t, func() error {
+		var wg sync.WaitGroup
+		errs := make(chan error, 8)
+		for i := range 8 {
+			profile := "work"
+			if i%2 == 1 {
+				profile = "personal"
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- runBindRemote("github.com/acme", profile, false, true)
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(output, "bound\tremote:github.com/acme"); n != 1 {
+		t.Errorf("expected exactly one bind to win, got %d:\n%s", n, output)
+	}
+}
 
-	auth := &mockAuth{}
+// TestRunBindLocal tests binding a repository through its .git/config.
+func TestBindSuggestsProfileFromOrigin(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
+	writeProfiles(t, dir, `profiles:
+  personal:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
 
-	old := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
+	repo, _ := filepath.EvalSymlinks(t.TempDir())
+	for _, args := range [][]string{
+		{"init", "-q", repo},
+		{"-C", repo, "remote", "add", "origin", "git@github.com:user2/api.git"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	t.Chdir(repo)
 
-	err := runStatus(auth)
+	setStdin(t, "\n")
+	cmd := newBindCmd(&mockAuth{})
+	cmd.SetArgs([]string{})
+	output, err := captureStdout(t, cmd.Execute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsStr(output, "Profile to bind [work]") {
+		t.Errorf("expected the origin owner's profile as the default, got:\n%s", output)
+	}
+	bindings, _ := config.LoadBindings()
+	if b, ok := bindings.Lookup(repo); !ok || b.Profile != "work" {
+		t.Errorf("bindings = %+v, want %s bound to work", bindings.Bindings, repo)
+	}
+}
 
-	w.Close()
-	os.Stdout = old
+func TestRunBindLocal(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := setupTestEnv(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	gcPath := filepath.Join(home, ".gitconfig")
+	t.Setenv("GIT_CONFIG_GLOBAL", gcPath)
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
 
-	if err != nil {
+	repo, _ := filepath.EvalSymlinks(t.TempDir())
+	if out, err := exec.Command("git", "init", "-q", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	localEmail := func() string {
+		out, _ := exec.Command("git", "-C", repo, "config", "--local", "--get", "user.email").Output()
+		return strings.TrimSpace(string(out))
+	}
+	globalConfig := func() string {
+		data, _ := os.ReadFile(gcPath)
+		return string(data)
+	}
+
+	if _, err := captureStdout(t, func() error { return runBindLocal(filepath.Join(repo, "sub"), "work", false, false) }); err == nil {
+		t.Error("expected --local to refuse a path that is not a repository root")
+	}
+
+	if _, err := captureStdout(t, func() error { return runBindLocal(repo, "work", false, false) }); err != nil {
 		t.Fatal(err)
 	}
+	if got := localEmail(); got != "user2@company.com" {
+		t.Errorf("local user.email = %q, want user2@company.com", got)
+	}
+	if containsStr(globalConfig(), repo) {
+		t.Errorf("global gitconfig was touched:\n%s", globalConfig())
+	}
+	bindings, _ := config.LoadBindings()
+	if b, ok := bindings.Lookup(repo); !ok || !b.IsLocal() {
+		t.Errorf("binding = %+v, want local scope", b)
+	}
 
-	var buf bytes.Buffer
-	buf.ReadFrom(r)
-	output := buf.String()
+	// Rebinding without --local swaps the local settings for an includeIf.
+	if _, err := captureStdout(t, func() error { return runBind(repo, "work", false, false) }); err != nil {
+		t.Fatal(err)
+	}
+	if got := localEmail(); got != "" {
+		t.Errorf("local user.email = %q after rebinding globally, want unset", got)
+	}
+	if !containsStr(globalConfig(), "gitdir:"+repo) {
+		t.Errorf("expected an includeIf for %s:\n%s", repo, globalConfig())
+	}
 
-	if !containsStr(output, "override") {
-		t.Error("expected 'override' profile from env")
+	// And back again.
+	if _, err := captureStdout(t, func() error { return runBindLocal(repo, "work", false, false) }); err != nil {
+		t.Fatal(err)
 	}
-	if !containsStr(output, "environment") {
-		t.Error("expected 'environment' source indicator")
+	if containsStr(globalConfig(), "gitdir:"+repo) {
+		t.Errorf("includeIf left behind after binding locally:\n%s", globalConfig())
+	}
+
+	if _, err := captureStdout(t, func() error { return runUnbind(repo, false) }); err != nil {
+		t.Fatal(err)
+	}
+	if got := localEmail(); got != "" {
+		t.Errorf("local user.email = %q after unbind, want unset", got)
 	}
 }
 
-// TestRunProfileRemove tests removing a profile.
-func TestRunProfileRemove(t *testing.T) {
+// TestRunUnbind tests unbinding a directory.
+func TestRunUnbind(t *testing.T) {
 	dir := setupTestEnv(t)
+	bindDir := t.TempDir()
 	writeProfiles(t, dir, `profiles:
-  todelete:
-    gh_user: user1
-    git_name: Test
-    git_email: test@test.com`)
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
 	writeBindings(t, dir, `bindings:
-  - path: /some/path
-    profile: todelete`)
+  - path: `+bindDir+`
+    profile: work`)
 
 	tmpHome := t.TempDir()
 	t.Setenv("HOME", tmpHome)
@@ -506,7 +1029,7 @@ func TestRunProfileRemove(t *testing.T) {
 	_, w, _ := os.Pipe()
 	os.Stdout = w
 
-	err := runProfileRemove("todelete")
+	err := runUnbind(bindDir, false)
 
 	w.Close()
 	os.Stdout = old
@@ -514,229 +1037,266 @@ func TestRunProfileRemove(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	// Verify profile was removed.
-	data, _ := os.ReadFile(filepath.Join(dir, "profiles.yml"))
-	if containsStr(string(data), "todelete") {
-		t.Error("profile should have been removed")
-	}
 }
 
-// TestRunProfileRemove_NotFound tests removing nonexistent profile.
-func TestRunProfileRemove_NotFound(t *testing.T) {
-	dir := setupTestEnv(t)
-	writeProfiles(t, dir, `profiles: {}`)
+// TestRunUnbind_NotBound tests unbinding a directory that isn't bound.
+func TestRunUnbind_NotBound(t *testing.T) {
+	setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
 
-	err := runProfileRemove("nonexistent")
+	err := runUnbind("/some/unbound/dir", false)
 	if err == nil {
-		t.Error("expected error removing nonexistent profile")
-	}
-}
-
-// TestReadLine tests the readLine helper.
-func TestReadLine(t *testing.T) {
-	input := bytes.NewBufferString("hello world\n")
-	reader := bufio.NewReader(input)
-	got := readLine(reader)
-	if got != "hello world" {
-		t.Errorf("readLine() = %q, want %q", got, "hello world")
+		t.Error("expected error unbinding unbound directory")
 	}
 }
 
-// TestRunProfileAdd tests the profile add command with stdin input.
-func TestRunProfileAdd(t *testing.T) {
+// TestRunUnbindProfile tests removing every binding of one profile along
+// with its includeIf directives.
+func TestRunUnbindProfile(t *testing.T) {
 	dir := setupTestEnv(t)
-	tmpHome := t.TempDir()
-	t.Setenv("HOME", tmpHome)
-	writeProfiles(t, dir, `profiles: {}`)
-
-	// Provide stdin input for the interactive prompts.
-	oldStdin := os.Stdin
-	input := "testuser\nTest User\ntest@example.com\n~/.ssh/id_test\n"
-	r, w, _ := os.Pipe()
-	w.WriteString(input)
-	w.Close()
-	os.Stdin = r
-	defer func() { os.Stdin = oldStdin }()
-
-	auth := &mockAuth{users: []string{"testuser"}}
-
-	oldOut := os.Stdout
-	_, outW, _ := os.Pipe()
-	os.Stdout = outW
-
-	err := runProfileAdd(auth, "newprofile")
-
-	outW.Close()
-	os.Stdout = oldOut
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	workA, workB, personal := t.TempDir(), t.TempDir(), t.TempDir()
+	writeBindings(t, dir, `bindings:
+  - path: `+workA+`
+    profile: work
+  - path: `+personal+`
+    profile: personal
+  - path: `+workB+`
+    profile: work
+  - remote: github.com/acme
+    profile: work`)
+	gcPath := filepath.Join(home, ".gitconfig")
+	for _, d := range []string{workA, workB} {
+		if err := gitconfig.AddIncludeIf(gcPath, d, "/frag/work.gitconfig"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := gitconfig.AddIncludeIf(gcPath, personal, "/frag/personal.gitconfig"); err != nil {
+		t.Fatal(err)
+	}
 
+	output, err := captureStdout(t, func() error { return runUnbindProfile("work", false) })
 	if err != nil {
 		t.Fatal(err)
 	}
+	if !containsStr(output, "Removed 3 binding(s)") {
+		t.Errorf("expected a count, got:\n%s", output)
+	}
 
-	// Verify profile was saved.
-	data, err := os.ReadFile(filepath.Join(dir, "profiles.yml"))
+	bf, err := config.LoadBindings()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if !containsStr(string(data), "newprofile") {
-		t.Error("expected 'newprofile' in profiles.yml")
+	if len(bf.Bindings) != 1 || bf.Bindings[0].Profile != "personal" {
+		t.Errorf("remaining bindings = %+v, want only personal", bf.Bindings)
 	}
-	if !containsStr(string(data), "testuser") {
-		t.Error("expected 'testuser' in profiles.yml")
+	data, _ := os.ReadFile(gcPath)
+	if containsStr(string(data), "work.gitconfig") || !containsStr(string(data), "personal.gitconfig") {
+		t.Errorf("includeIfs not pruned to the personal one:\n%s", data)
 	}
 }
 
-// TestRunProfileAdd_Duplicate tests adding a profile that already exists.
-func TestRunProfileAdd_Duplicate(t *testing.T) {
+// TestRootCmd_ProfileOverrideUnsupported tests that commands which do not
+// honor --profile reject it instead of ignoring it.
+func TestRootCmd_ProfileOverrideUnsupported(t *testing.T) {
 	dir := setupTestEnv(t)
 	writeProfiles(t, dir, `profiles:
-  existing:
-    gh_user: user1
-    git_name: Existing
-    git_email: e@e.com`)
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
 
-	auth := &mockAuth{}
-	err := runProfileAdd(auth, "existing")
-	if err == nil {
-		t.Error("expected error for duplicate profile")
+	root := NewRootCmd()
+	root.SetArgs([]string{"which", "--profile", "work"})
+	root.SetOut(&bytes.Buffer{})
+	root.SetErr(&bytes.Buffer{})
+	_, err := captureStdout(t, root.Execute)
+	if err == nil || !containsStr(err.Error(), "not supported by `gh identity which`") {
+		t.Errorf("expected --profile to be rejected by which, got %v", err)
 	}
-	if !containsStr(err.Error(), "already exists") {
-		t.Errorf("expected 'already exists' error, got %v", err)
+
+	root = NewRootCmd()
+	root.SetArgs([]string{"current", "--profile", "work"})
+	root.SetOut(&bytes.Buffer{})
+	root.SetErr(&bytes.Buffer{})
+	output, err := captureStdout(t, root.Execute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "work\n" {
+		t.Errorf("current --profile work printed %q", output)
 	}
 }
 
-// TestInstallShellHook_Bash tests shell hook installation for bash.
-func TestInstallShellHook_Bash(t *testing.T) {
+// TestRootCmd_UnbindProfileRemoved tests that `unbind --profile` clears the
+// bindings of a profile that no longer exists: its local --profile is not
+// checked as the global override.
+func TestRootCmd_UnbindProfileRemoved(t *testing.T) {
 	dir := setupTestEnv(t)
-	tmpHome := t.TempDir()
-	t.Setenv("HOME", tmpHome)
-	t.Setenv("SHELL", "/bin/bash")
-
-	// Create bin dir with config dir.
-	binDir := filepath.Join(dir, "bin")
-	os.MkdirAll(binDir, 0o755)
+	t.Setenv("HOME", t.TempDir())
+	gone := t.TempDir()
+	writeProfiles(t, dir, `profiles: {}`)
+	writeBindings(t, dir, `bindings:
+  - path: `+gone+`
+    profile: gone`)
 
-	err := installShellHook()
-	if err != nil {
+	root := NewRootCmd()
+	root.SetArgs([]string{"unbind", "--profile", "gone"})
+	root.SetOut(&bytes.Buffer{})
+	root.SetErr(&bytes.Buffer{})
+	if _, err := captureStdout(t, root.Execute); err != nil {
 		t.Fatal(err)
 	}
 
-	// Verify .bashrc was created with hook.
-	data, err := os.ReadFile(filepath.Join(tmpHome, ".bashrc"))
+	bf, err := config.LoadBindings()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if !containsStr(string(data), "gh-identity hook") {
-		t.Error("expected 'gh-identity hook' in .bashrc")
+	if len(bf.Bindings) != 0 {
+		t.Errorf("bindings = %+v, want none", bf.Bindings)
 	}
 }
 
-// TestInstallShellHook_Zsh tests shell hook installation for zsh.
-func TestInstallShellHook_Zsh(t *testing.T) {
+// TestRunUnbindAll tests that --all asks before removing anything.
+func TestRunUnbindAll(t *testing.T) {
 	dir := setupTestEnv(t)
-	tmpHome := t.TempDir()
-	t.Setenv("HOME", tmpHome)
-	t.Setenv("SHELL", "/bin/zsh")
-
-	binDir := filepath.Join(dir, "bin")
-	os.MkdirAll(binDir, 0o755)
-
-	err := installShellHook()
+	t.Setenv("HOME", t.TempDir())
+	bindings := `bindings:
+  - path: ` + t.TempDir() + `
+    profile: work
+  - path: ` + t.TempDir() + `
+    profile: personal`
+	writeBindings(t, dir, bindings)
+
+	setStdin(t, "n\n")
+	output, err := captureStdout(t, func() error { return runUnbindAll(false, false) })
 	if err != nil {
 		t.Fatal(err)
 	}
+	if !containsStr(output, "Remove all 2 binding(s)") || !containsStr(output, "Aborted") {
+		t.Errorf("expected a declined prompt, got:\n%s", output)
+	}
+	if bf, _ := config.LoadBindings(); len(bf.Bindings) != 2 {
+		t.Fatalf("declining removed bindings: %+v", bf.Bindings)
+	}
 
-	data, err := os.ReadFile(filepath.Join(tmpHome, ".zshrc"))
+	output, err = captureStdout(t, func() error { return runUnbindAll(true, false) })
 	if err != nil {
 		t.Fatal(err)
 	}
-	if !containsStr(string(data), "gh-identity hook") {
-		t.Error("expected 'gh-identity hook' in .zshrc")
+	if containsStr(output, "[y/N]") || !containsStr(output, "Removed 2 binding(s)") {
+		t.Errorf("--yes should remove without asking, got:\n%s", output)
+	}
+	if bf, _ := config.LoadBindings(); len(bf.Bindings) != 0 {
+		t.Errorf("bindings left after --all: %+v", bf.Bindings)
 	}
 }
 
-// TestInstallShellHook_Fish tests shell hook installation for fish.
-func TestInstallShellHook_Fish(t *testing.T) {
+// TestRunSwitch tests the switch command output.
+func TestRunSwitch(t *testing.T) {
 	dir := setupTestEnv(t)
-	tmpHome := t.TempDir()
-	t.Setenv("HOME", tmpHome)
-	t.Setenv("SHELL", "/usr/bin/fish")
+	writeProfiles(t, dir, `profiles:
+  personal:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com`)
 
-	binDir := filepath.Join(dir, "bin")
-	os.MkdirAll(binDir, 0o755)
+	auth := &mockAuth{
+		tokens: map[string]string{"user1": "test-token-123"},
+	}
+
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := runSwitch(auth, "personal", hook.Bash, "")
+
+	w.Close()
+	os.Stdout = old
 
-	err := installShellHook()
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	data, err := os.ReadFile(filepath.Join(tmpHome, ".config", "fish", "conf.d", "gh-identity.fish"))
-	if err != nil {
-		t.Fatal(err)
+	var buf bytes.Buffer
+	buf.ReadFrom(r)
+	output := buf.String()
+
+	if !containsStr(output, "gh auth switch --user user1") {
+		t.Error("expected gh auth switch in switch output")
 	}
-	if !containsStr(string(data), "gh-identity hook") {
-		t.Error("expected 'gh-identity hook' in fish config")
+	if !containsStr(output, "GH_IDENTITY_PROFILE") {
+		t.Error("expected profile env var in output")
 	}
 }
 
-// TestInstallShellHook_AlreadyInstalled tests idempotency.
-func TestInstallShellHook_AlreadyInstalled(t *testing.T) {
-	dir := setupTestEnv(t)
-	tmpHome := t.TempDir()
-	t.Setenv("HOME", tmpHome)
-	t.Setenv("SHELL", "/bin/bash")
-
-	binDir := filepath.Join(dir, "bin")
-	os.MkdirAll(binDir, 0o755)
-
-	// Pre-create .bashrc with existing hook.
-	os.WriteFile(filepath.Join(tmpHome, ".bashrc"), []byte("# gh-identity hook\neval ...\n"), 0o644)
-
-	err := installShellHook()
-	if err != nil {
-		t.Fatal(err)
-	}
+// TestRunSwitch_Off tests that switch --off unsets every managed variable
+// in each shell's syntax.
+func TestRunSwitch_Off(t *testing.T) {
+	setupTestEnv(t)
 
-	// Should not duplicate the hook.
-	data, _ := os.ReadFile(filepath.Join(tmpHome, ".bashrc"))
-	count := 0
-	for i := 0; i <= len(string(data))-len("gh-identity hook"); i++ {
-		if string(data)[i:i+len("gh-identity hook")] == "gh-identity hook" {
-			count++
+	for shell, want := range map[string]string{
+		"bash": "unset GH_TOKEN GIT_AUTHOR_NAME",
+		"zsh":  "unset GH_TOKEN GIT_AUTHOR_NAME",
+		"fish": "set -e GIT_SSH_COMMAND 2>/dev/null\n",
+		"nu":   `"unset":["GH_TOKEN",`,
+	} {
+		sh, err := parseShell(shell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := captureStdout(t, func() error { return runSwitch(&mockAuth{}, "", sh, "") })
+		if err != nil {
+			t.Fatalf("%s: runSwitch() error = %v", shell, err)
+		}
+		if !containsStr(out, want) || !containsStr(out, "GH_IDENTITY_PROFILE") {
+			t.Errorf("%s: output = %q, want it to contain %q", shell, out, want)
+		}
+		if containsStr(out, "gh auth switch") {
+			t.Errorf("%s: --off must not switch accounts, got %q", shell, out)
 		}
 	}
-	if count != 1 {
-		t.Errorf("expected 1 hook entry, got %d", count)
+
+	if _, err := parseShell("tcsh"); err == nil {
+		t.Error("expected an error for an unsupported shell")
 	}
 }
 
-// TestInstallHookBinary_NotFound tests installHookBinary when binary doesn't exist.
-func TestInstallHookBinary_NotFound(t *testing.T) {
-	setupTestEnv(t)
+// TestRunSwitch_InvalidProfile tests switch with nonexistent profile.
+func TestRunSwitch_InvalidProfile(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeProfiles(t, dir, `profiles: {}`)
 
-	err := installHookBinary()
+	auth := &mockAuth{}
+	err := runSwitch(auth, "nonexistent", hook.Bash, "")
 	if err == nil {
-		t.Error("expected error when hook binary not found")
+		t.Error("expected error for nonexistent profile")
 	}
 }
 
-// TestRunProfileList_ActiveProfile tests list highlighting active profile.
-func TestRunProfileList_ActiveProfile(t *testing.T) {
+// TestRunStatus tests the status command.
+func TestRunStatus(t *testing.T) {
 	dir := setupTestEnv(t)
+	pwd, _ := os.Getwd()
 	writeProfiles(t, dir, `profiles:
   work:
     gh_user: user2
     git_name: User Two
     git_email: user2@company.com
-    ssh_key: ~/.ssh/id_work`)
-	t.Setenv("GH_IDENTITY_PROFILE", "work")
+    ssh_key: ~/.ssh/id_test
+default: work`)
+	writeBindings(t, dir, `bindings:
+  - path: `+pwd+`
+    profile: work`)
+
+	auth := &mockAuth{activeUser: "user2"}
 
 	old := os.Stdout
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	err := runProfileList()
+	err := runStatus(auth, "", "", false)
 
 	w.Close()
 	os.Stdout = old
@@ -749,41 +1309,28 @@ func TestRunProfileList_ActiveProfile(t *testing.T) {
 	buf.ReadFrom(r)
 	output := buf.String()
 
-	if !containsStr(output, "*") {
-		t.Error("expected '*' indicator for active profile")
+	if !containsStr(output, "work") {
+		t.Error("expected 'work' profile in status")
 	}
-	if !containsStr(output, "ssh_key") {
-		t.Error("expected ssh_key in output")
+	if !containsStr(output, "user2") {
+		t.Error("expected 'user2' in status")
 	}
 }
 
-// TestRunSwitch_ProfileNotFound tests switch with nonexistent profile.
-func TestRunSwitch_ProfileNotFound(t *testing.T) {
+// TestRunStatus_NoProfile tests status with no active profile.
+func TestRunStatus_NoProfile(t *testing.T) {
 	dir := setupTestEnv(t)
 	writeProfiles(t, dir, `profiles: {}`)
+	writeBindings(t, dir, `bindings: []`)
+	t.Setenv("GH_IDENTITY_PROFILE", "")
 
 	auth := &mockAuth{}
 
-	err := runSwitch(auth, "nonexistent")
-	if err == nil {
-		t.Error("expected error when profile not found")
-	}
-}
-
-// TestRunDoctor tests the doctor command with various setups.
-func TestRunDoctor_NoConfig(t *testing.T) {
-	// Point to a non-existent config dir.
-	tmp := t.TempDir()
-	t.Setenv("GH_IDENTITY_CONFIG_DIR", filepath.Join(tmp, "nonexistent"))
-	t.Setenv("HOME", t.TempDir())
-
-	auth := &mockAuth{users: []string{"user1"}}
-
 	old := os.Stdout
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	err := runDoctor(auth)
+	err := runStatus(auth, "", "", false)
 
 	w.Close()
 	os.Stdout = old
@@ -796,33 +1343,37 @@ func TestRunDoctor_NoConfig(t *testing.T) {
 	buf.ReadFrom(r)
 	output := buf.String()
 
-	if !containsStr(output, "Config directory does not exist") {
-		t.Error("expected config dir missing message")
+	if !containsStr(output, "No active profile") {
+		t.Error("expected 'No active profile' message")
 	}
 }
 
-// TestRunDoctor_ValidSetup tests doctor with a valid configuration.
-func TestRunDoctor_ValidSetup(t *testing.T) {
+// TestRunStatus_EnvOverride tests status with GH_IDENTITY_PROFILE env override.
+func TestRunStatus_EnvOverride(t *testing.T) {
 	dir := setupTestEnv(t)
-	tmpHome := t.TempDir()
-	t.Setenv("HOME", tmpHome)
-
+	pwd, _ := os.Getwd()
 	writeProfiles(t, dir, `profiles:
-  test:
-    gh_user: user1
-    git_name: Test
-    git_email: test@test.com`)
+  override:
+    gh_user: user3
+    git_name: User Three
+    git_email: user3@example.com
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
+	// The environment takes precedence over a binding for this directory.
 	writeBindings(t, dir, `bindings:
-  - path: /valid/path
-    profile: test`)
+  - path: `+pwd+`
+    profile: work`)
+	t.Setenv("GH_IDENTITY_PROFILE", "override")
 
-	auth := &mockAuth{users: []string{"user1"}}
+	auth := &mockAuth{}
 
 	old := os.Stdout
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	err := runDoctor(auth)
+	err := runStatus(auth, "", "", false)
 
 	w.Close()
 	os.Stdout = old
@@ -835,179 +1386,199 @@ func TestRunDoctor_ValidSetup(t *testing.T) {
 	buf.ReadFrom(r)
 	output := buf.String()
 
-	if !containsStr(output, "1 profile(s) configured") {
-		t.Error("expected profiles configured message")
+	if !containsStr(output, "override") {
+		t.Error("expected 'override' profile from env")
+	}
+	if !containsStr(output, "environment") {
+		t.Error("expected 'environment' source indicator")
+	}
+	if containsStr(output, "Bound by") {
+		t.Errorf("an overridden binding should not be reported as the source:\n%s", output)
 	}
 }
 
-// TestRunDoctor_InvalidProfile tests doctor with invalid profile (missing auth).
-func TestRunDoctor_InvalidProfile(t *testing.T) {
+// TestRunProfileRemove tests removing a profile.
+func TestRunProfileRemove(t *testing.T) {
 	dir := setupTestEnv(t)
-	tmpHome := t.TempDir()
-	t.Setenv("HOME", tmpHome)
-
 	writeProfiles(t, dir, `profiles:
-  bad:
-    gh_user: unknown_user
-    git_name: Bad
-    git_email: bad@bad.com`)
-	writeBindings(t, dir, `bindings: []`)
-
-	// Auth knows about user1 but profile references unknown_user.
-	auth := &mockAuth{users: []string{"user1"}}
-
-	old := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
-	err := runDoctor(auth)
+  todelete:
+    gh_user: user1
+    git_name: Test
+    git_email: test@test.com`)
+	writeBindings(t, dir, `bindings:
+  - path: /some/path
+    profile: todelete`)
 
-	w.Close()
-	os.Stdout = old
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
 
+	// Declining the prompt leaves everything in place.
+	setStdin(t, "n\n")
+	output, err := captureStdout(t, func() error { return runProfileRemove("todelete", false, false) })
 	if err != nil {
 		t.Fatal(err)
 	}
+	for _, want := range []string{`About to remove profile "todelete"`, "1 binding(s): /some/path", "todelete.gitconfig", "1 includeIf directive(s)", "Aborted"} {
+		if !containsStr(output, want) {
+			t.Errorf("prompt missing %q:\n%s", want, output)
+		}
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "profiles.yml"))
+	if !containsStr(string(data), "todelete") {
+		t.Fatal("declining should keep the profile")
+	}
 
-	var buf bytes.Buffer
-	buf.ReadFrom(r)
-	output := buf.String()
+	setStdin(t, "y\n")
+	if _, err := captureStdout(t, func() error { return runProfileRemove("todelete", false, false) }); err != nil {
+		t.Fatal(err)
+	}
 
-	if !containsStr(output, "not authenticated") {
-		t.Error("expected unauthenticated user warning")
+	// Verify profile was removed.
+	data, _ = os.ReadFile(filepath.Join(dir, "profiles.yml"))
+	if containsStr(string(data), "todelete") {
+		t.Error("profile should have been removed")
 	}
 }
 
-// TestRunDoctor_BadBinding tests doctor with a binding referencing nonexistent profile.
-func TestRunDoctor_BadBinding(t *testing.T) {
+// TestRunProfileRemove_DryRun tests that a dry-run remove leaves the profile and bindings intact.
+func TestRunProfileRemove_DryRun(t *testing.T) {
 	dir := setupTestEnv(t)
-	tmpHome := t.TempDir()
-	t.Setenv("HOME", tmpHome)
-
-	writeProfiles(t, dir, `profiles:
-  good:
+	writeProfiles(t, dir, `default: todelete
+profiles:
+  todelete:
     gh_user: user1
-    git_name: Good
-    git_email: good@good.com`)
+    git_name: Test
+    git_email: test@test.com`)
 	writeBindings(t, dir, `bindings:
   - path: /some/path
-    profile: nonexistent`)
-
-	auth := &mockAuth{users: []string{"user1"}}
+    profile: todelete
+  - remote: github.com/acme
+    profile: todelete`)
+	t.Setenv("HOME", t.TempDir())
 
-	old := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
+	output, err := captureStdout(t, func() error { return runProfileRemove("todelete", false, true) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`Would remove profile "todelete"`,
+		"Would clear the default profile",
+		"Would unbind /some/path",
+		"Would unbind remote github.com/acme",
+		"Would delete gitconfig fragment",
+		`includeIf "hasconfig:remote.*.url:https://github.com/acme/**"`,
+	} {
+		if !containsStr(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
 
-	err := runDoctor(auth)
+	profiles, _ := config.LoadProfiles()
+	if _, ok := profiles.Profiles["todelete"]; !ok || profiles.Default != "todelete" {
+		t.Error("dry run should not modify profiles.yml")
+	}
+	bindings, _ := config.LoadBindings()
+	if len(bindings.Bindings) != 2 {
+		t.Errorf("dry run should not modify bindings.yml, got %+v", bindings.Bindings)
+	}
+}
 
-	w.Close()
-	os.Stdout = old
+// TestRunProfileShow tests showing a single profile with its bindings and fragment.
+func TestRunProfileShow(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeProfiles(t, dir, `default: work
+profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
+	t.Setenv("HOME", t.TempDir())
+	bindDir := t.TempDir()
+	if _, err := captureStdout(t, func() error { return runBind(bindDir, "work", false, false) }); err != nil {
+		t.Fatal(err)
+	}
 
+	auth := &mockAuth{users: []string{"user2"}}
+	output, err := captureStdout(t, func() error { return runProfileShow(auth, "work", true) })
 	if err != nil {
 		t.Fatal(err)
 	}
+	var got profileShowJSON
+	if err := json.Unmarshal([]byte(output), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", output, err)
+	}
+	if got.Name != "work" || !got.IsDefault || !got.Authenticated {
+		t.Errorf("unexpected profile fields: %+v", got)
+	}
+	if got.FragmentPath != filepath.Join(dir, "git", "work.gitconfig") || !got.FragmentExists {
+		t.Errorf("fragment = %q (exists %v)", got.FragmentPath, got.FragmentExists)
+	}
+	if len(got.Bindings) != 1 || got.Bindings[0] != bindDir {
+		t.Errorf("bindings = %v, want [%s]", got.Bindings, bindDir)
+	}
+	if len(got.IncludeIfDirs) != 1 || got.IncludeIfDirs[0] != bindDir+"/" {
+		t.Errorf("includeif_dirs = %v", got.IncludeIfDirs)
+	}
 
-	var buf bytes.Buffer
-	buf.ReadFrom(r)
-	output := buf.String()
+	output, err = captureStdout(t, func() error { return runProfileShow(&mockAuth{}, "work", false) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Profile:   work (default)", "not authenticated", "Fragment:", bindDir} {
+		if !containsStr(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
 
-	if !containsStr(output, "non-existent profile") {
-		t.Error("expected non-existent profile warning")
+	if err := runProfileShow(auth, "missing", false); err == nil {
+		t.Error("expected error for unknown profile")
 	}
 }
 
-// TestRunDoctor_EmptyProfiles tests doctor with no profiles.
-func TestRunDoctor_EmptyProfiles(t *testing.T) {
+// TestRunProfileRemove_NotFound tests removing nonexistent profile.
+func TestRunProfileRemove_NotFound(t *testing.T) {
 	dir := setupTestEnv(t)
-	tmpHome := t.TempDir()
-	t.Setenv("HOME", tmpHome)
-
 	writeProfiles(t, dir, `profiles: {}`)
 
-	auth := &mockAuth{users: []string{}}
-
-	old := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
-	err := runDoctor(auth)
-
-	w.Close()
-	os.Stdout = old
-
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	var buf bytes.Buffer
-	buf.ReadFrom(r)
-	output := buf.String()
-
-	if !containsStr(output, "No profiles configured") {
-		t.Error("expected no profiles message")
+	err := runProfileRemove("nonexistent", true, false)
+	if err == nil {
+		t.Error("expected error removing nonexistent profile")
 	}
 }
 
-// TestRunDoctor_ValidationErrors tests doctor with invalid profile fields.
-func TestRunDoctor_ValidationErrors(t *testing.T) {
-	dir := setupTestEnv(t)
-	tmpHome := t.TempDir()
-	t.Setenv("HOME", tmpHome)
-
-	writeProfiles(t, dir, `profiles:
-  bad:
-    gh_user: ""
-    git_name: ""
-    git_email: ""`)
-
-	auth := &mockAuth{users: []string{}}
-
-	old := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
-	err := runDoctor(auth)
-
-	w.Close()
-	os.Stdout = old
-
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	var buf bytes.Buffer
-	buf.ReadFrom(r)
-	output := buf.String()
-
-	if !containsStr(output, "is required") {
-		t.Error("expected validation error messages")
+// TestReadLine tests the readLine helper.
+func TestReadLine(t *testing.T) {
+	input := bytes.NewBufferString("hello world\n")
+	reader := bufio.NewReader(input)
+	got := readLine(reader)
+	if got != "hello world" {
+		t.Errorf("readLine() = %q, want %q", got, "hello world")
 	}
 }
 
-// TestRunInit tests the init command with mock auth and stdin.
-func TestRunInit(t *testing.T) {
+// TestRunProfileAdd tests the profile add command with stdin input.
+func TestRunProfileAdd(t *testing.T) {
 	dir := setupTestEnv(t)
 	tmpHome := t.TempDir()
 	t.Setenv("HOME", tmpHome)
-	t.Setenv("SHELL", "/bin/bash")
+	writeProfiles(t, dir, `profiles: {}`)
 
-	// Provide stdin for: profile name, git name, git email, ssh key, default profile.
+	// Provide stdin input for the interactive prompts.
 	oldStdin := os.Stdin
-	input := "personal\nJohn Doe\njohn@example.com\n\npersonal\n"
+	input := "testuser\nTest User\ntest@example.com\n~/.ssh/id_test\n"
 	r, w, _ := os.Pipe()
 	w.WriteString(input)
 	w.Close()
 	os.Stdin = r
 	defer func() { os.Stdin = oldStdin }()
 
-	auth := &mockAuth{users: []string{"user1"}}
+	auth := &mockAuth{users: []string{"testuser"}}
 
 	oldOut := os.Stdout
 	_, outW, _ := os.Pipe()
 	os.Stdout = outW
 
-	err := runInit(auth)
+	err := runProfileAdd(auth, "newprofile", profileEditFlags{}, profileAddOptions{})
 
 	outW.Close()
 	os.Stdout = oldOut
@@ -1016,254 +1587,525 @@ func TestRunInit(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	// Verify profiles were saved.
+	// Verify profile was saved.
 	data, err := os.ReadFile(filepath.Join(dir, "profiles.yml"))
 	if err != nil {
 		t.Fatal(err)
 	}
-	if !containsStr(string(data), "personal") {
-		t.Error("expected 'personal' in profiles.yml")
+	if !containsStr(string(data), "newprofile") {
+		t.Error("expected 'newprofile' in profiles.yml")
 	}
-	if !containsStr(string(data), "user1") {
-		t.Error("expected 'user1' in profiles.yml")
+	if !containsStr(string(data), "testuser") {
+		t.Error("expected 'testuser' in profiles.yml")
 	}
 }
 
-// TestRunInit_NoUsers tests init when no gh accounts are authenticated.
-func TestRunInit_NoUsers(t *testing.T) {
-	setupTestEnv(t)
-
-	auth := &mockAuth{users: []string{}}
+// TestRunProfileAdd_Flags tests that a fully flagged profile add reads no stdin.
+func TestRunProfileAdd_Flags(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
+	writeProfiles(t, dir, `profiles: {}`)
 
-	oldOut := os.Stdout
-	_, outW, _ := os.Pipe()
-	os.Stdout = outW
+	// A closed stdin makes any prompt read an empty line.
+	oldStdin := os.Stdin
+	r, w, _ := os.Pipe()
+	w.Close()
+	os.Stdin = r
+	defer func() { os.Stdin = oldStdin }()
 
-	err := runInit(auth)
+	ghUser, gitName, gitEmail := "ci-bot", "CI Bot", "ci@example.com"
+	flags := profileEditFlags{GHUser: &ghUser, GitName: &gitName, GitEmail: &gitEmail}
+	output, err := captureStdout(t, func() error { return runProfileAdd(&mockAuth{}, "ci", flags, profileAddOptions{}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if containsStr(output, "Git name:") || containsStr(output, "SSH key path") {
+		t.Errorf("expected no prompts, got:\n%s", output)
+	}
 
-	outW.Close()
-	os.Stdout = oldOut
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := profiles.Profiles["ci"]
+	if p.GHUser != ghUser || p.GitName != gitName || p.GitEmail != gitEmail || p.SSHKey != "" {
+		t.Errorf("profile = %+v", p)
+	}
 
+	// Partially flagged: only the missing fields are prompted for.
+	r, w, _ = os.Pipe()
+	w.WriteString("Partial Name\n~/.ssh/id_partial\n")
+	w.Close()
+	os.Stdin = r
+	flags = profileEditFlags{GHUser: &ghUser, GitEmail: &gitEmail}
+	output, err = captureStdout(t, func() error { return runProfileAdd(&mockAuth{}, "partial", flags, profileAddOptions{}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if containsStr(output, "GitHub username") || containsStr(output, "Git email:") {
+		t.Errorf("flagged fields should not be prompted for, got:\n%s", output)
+	}
+	profiles, err = config.LoadProfiles()
 	if err != nil {
 		t.Fatal(err)
 	}
+	p = profiles.Profiles["partial"]
+	if p.GitName != "Partial Name" || p.SSHKey != "~/.ssh/id_partial" || p.GHUser != ghUser {
+		t.Errorf("profile = %+v", p)
+	}
 }
 
-// TestRunInit_AuthError tests init when auth fails.
-func TestRunInit_AuthError(t *testing.T) {
+// TestRunProfileAdd_SSHKeyWarning tests that profile add warns about an SSH
+// key that does not exist or is too permissive, and saves the profile anyway.
+func TestRunProfileAdd_SSHKeyWarning(t *testing.T) {
 	setupTestEnv(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	ghUser, gitName, gitEmail := "ci-bot", "CI Bot", "ci@example.com"
 
-	auth := &mockAuth{err: fmt.Errorf("auth failed")}
-
-	oldOut := os.Stdout
-	_, outW, _ := os.Pipe()
-	os.Stdout = outW
-
-	err := runInit(auth)
-
-	outW.Close()
-	os.Stdout = oldOut
+	missing := "~/.ssh/id_typo"
+	flags := profileEditFlags{GHUser: &ghUser, GitName: &gitName, GitEmail: &gitEmail, SSHKey: &missing}
+	output, err := captureStdout(t, func() error { return runProfileAdd(&mockAuth{}, "ci", flags, profileAddOptions{}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsStr(output, "SSH key "+filepath.Join(home, ".ssh", "id_typo")+" does not exist") {
+		t.Errorf("expected a missing key warning, got:\n%s", output)
+	}
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := profiles.Profiles["ci"].SSHKey; got != missing {
+		t.Errorf("ssh_key = %q, want %q saved despite the warning", got, missing)
+	}
 
-	if err == nil {
-		t.Error("expected error when auth fails")
+	open := filepath.Join(home, "id_open")
+	if err := os.WriteFile(open, []byte("key"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	flags.SSHKey = &open
+	output, err = captureStdout(t, func() error { return runProfileAdd(&mockAuth{}, "open", flags, profileAddOptions{}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsStr(output, "chmod 600 "+open) {
+		t.Errorf("expected a permissions warning, got:\n%s", output)
 	}
 }
 
-// TestRunInit_MultipleUsers tests init with multiple authenticated users.
-func TestRunInit_MultipleUsers(t *testing.T) {
+func TestRunProfileAdd_GenerateSSHKey(t *testing.T) {
 	dir := setupTestEnv(t)
-	tmpHome := t.TempDir()
-	t.Setenv("HOME", tmpHome)
-	t.Setenv("SHELL", "/bin/bash")
-
-	// Input for 2 users: name1, gitname1, email1, sshkey1, name2, gitname2, email2, sshkey2, default
-	oldStdin := os.Stdin
-	input := "work\nWork User\nwork@company.com\n~/.ssh/id_work\npersonal\nPersonal User\nme@home.com\n\nwork\n"
-	r, w, _ := os.Pipe()
-	w.WriteString(input)
-	w.Close()
-	os.Stdin = r
-	defer func() { os.Stdin = oldStdin }()
-
-	auth := &mockAuth{users: []string{"workuser", "personaluser"}}
-
-	oldOut := os.Stdout
-	_, outW, _ := os.Pipe()
-	os.Stdout = outW
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	writeProfiles(t, dir, `profiles: {}`)
 
-	err := runInit(auth)
+	var keygens []string
+	oldKeygen, oldUpload := sshKeygen, sshKeyUpload
+	sshKeygen = func(path, comment string, interactive bool) error {
+		if !interactive {
+			t.Error("profile add should let ssh-keygen ask for a passphrase")
+		}
+		keygens = append(keygens, path+" "+comment)
+		os.WriteFile(path, []byte("private"), 0o600)
+		return os.WriteFile(path+".pub", []byte("ssh-ed25519 AAAA "+comment+"\n"), 0o644)
+	}
+	var uploads []string
+	sshKeyUpload = func(_ ghauth.Auth, p config.Profile, pubPath, title string) error {
+		uploads = append(uploads, p.GHUser+" "+pubPath+" "+title)
+		return nil
+	}
+	t.Cleanup(func() { sshKeygen, sshKeyUpload = oldKeygen, oldUpload })
 
-	outW.Close()
-	os.Stdout = oldOut
+	ghUser, gitName, gitEmail := "worker", "Work User", "work@example.com"
+	flags := profileEditFlags{GHUser: &ghUser, GitName: &gitName, GitEmail: &gitEmail}
+	keyPath := filepath.Join(home, ".ssh", "id_work")
 
+	setStdin(t, "y\n")
+	output, err := captureStdout(t, func() error { return runProfileAdd(&mockAuth{}, "work", flags, profileAddOptions{generateKey: true}) })
 	if err != nil {
 		t.Fatal(err)
 	}
+	if len(keygens) != 1 || keygens[0] != keyPath+" work@example.com" {
+		t.Errorf("ssh-keygen calls = %v", keygens)
+	}
+	if !containsStr(output, "ssh-ed25519 AAAA work@example.com") {
+		t.Errorf("expected the public key to be printed, got:\n%s", output)
+	}
+	if len(uploads) != 1 || uploads[0] != "worker "+keyPath+".pub gh-identity work" {
+		t.Errorf("uploads = %v", uploads)
+	}
+	profiles, _ := config.LoadProfiles()
+	if got := profiles.Profiles["work"].SSHKey; got != "~/.ssh/id_work" {
+		t.Errorf("ssh_key = %q, want ~/.ssh/id_work", got)
+	}
 
-	// Verify profiles were saved.
-	data, _ := os.ReadFile(filepath.Join(dir, "profiles.yml"))
-	if !containsStr(string(data), "work") {
-		t.Error("expected 'work' in profiles.yml")
+	// An existing key is kept when overwriting is declined.
+	setStdin(t, "n\n")
+	if _, err := captureStdout(t, func() error { return runProfileAdd(&mockAuth{}, "work2", flags, profileAddOptions{generateKey: true}) }); err != nil {
+		t.Fatal(err)
 	}
-	if !containsStr(string(data), "personal") {
-		t.Error("expected 'personal' in profiles.yml")
+	os.WriteFile(filepath.Join(home, ".ssh", "id_work3"), []byte("mine"), 0o600)
+	setStdin(t, "n\n")
+	if _, err := captureStdout(t, func() error { return runProfileAdd(&mockAuth{}, "work3", flags, profileAddOptions{generateKey: true}) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(keygens) != 2 {
+		t.Errorf("expected ssh-keygen to be skipped for the existing key, calls = %v", keygens)
+	}
+	if data, _ := os.ReadFile(filepath.Join(home, ".ssh", "id_work3")); string(data) != "mine" {
+		t.Error("existing key was overwritten")
+	}
+	profiles, _ = config.LoadProfiles()
+	if got := profiles.Profiles["work3"].SSHKey; got != "~/.ssh/id_work3" {
+		t.Errorf("ssh_key = %q, want ~/.ssh/id_work3", got)
+	}
+	if len(uploads) != 1 {
+		t.Errorf("declined upload should not run, uploads = %v", uploads)
 	}
 }
 
-// TestRunDoctor_SSHKeyValid tests doctor with a valid SSH key.
-func TestRunDoctor_SSHKeyValid(t *testing.T) {
+func TestRunProfileAdd_FromGH(t *testing.T) {
 	dir := setupTestEnv(t)
-	tmpHome := t.TempDir()
-	t.Setenv("HOME", tmpHome)
-
-	// Create a fake SSH key with correct permissions.
-	sshDir := filepath.Join(tmpHome, ".ssh")
-	os.MkdirAll(sshDir, 0o700)
-	keyPath := filepath.Join(sshDir, "id_test")
-	os.WriteFile(keyPath, []byte("fake-key"), 0o600)
-
-	writeProfiles(t, dir, `profiles:
-  sshprof:
-    gh_user: user1
-    git_name: SSH
-    git_email: ssh@test.com
-    ssh_key: `+keyPath)
-	writeBindings(t, dir, `bindings: []`)
-
-	auth := &mockAuth{users: []string{"user1"}}
-
-	old := os.Stdout
-	r2, w, _ := os.Pipe()
-	os.Stdout = w
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	writeProfiles(t, dir, `profiles: {}`)
 
-	err := runDoctor(auth)
+	gc := filepath.Join(home, "gitconfig")
+	os.WriteFile(gc, []byte("[user]\n\tname = Octo Cat\n\temail = octo@example.com\n"), 0o644)
+	t.Setenv("GIT_CONFIG_GLOBAL", gc)
+	os.MkdirAll(filepath.Join(home, ".ssh"), 0o700)
+	key := filepath.Join(home, ".ssh", "id_ed25519")
+	os.WriteFile(key, []byte("key"), 0o600)
 
-	w.Close()
-	os.Stdout = old
+	auth := &mockAuth{users: []string{"octocat"}}
+	if err := runProfileAdd(auth, "x", profileEditFlags{}, profileAddOptions{fromGH: "stranger"}); err == nil {
+		t.Error("expected an error for an account gh is not logged in to")
+	}
 
+	// Accept every prefilled default.
+	setStdin(t, "\n\n\n\n")
+	output, err := captureStdout(t, func() error {
+		return runProfileAdd(auth, "octo", profileEditFlags{}, profileAddOptions{fromGH: "OctoCat"})
+	})
 	if err != nil {
 		t.Fatal(err)
 	}
+	if containsStr(output, "GitHub username") || !containsStr(output, "Git name [Octo Cat]") {
+		t.Errorf("expected confirm-only prompts, got:\n%s", output)
+	}
 
-	var buf bytes.Buffer
-	buf.ReadFrom(r2)
-	output := buf.String()
-
-	if !containsStr(output, "SSH key OK") {
-		t.Error("expected 'SSH key OK' message")
+	profiles, _ := config.LoadProfiles()
+	p := profiles.Profiles["octo"]
+	if p.GHUser != "octocat" || p.GitName != "Octo Cat" || p.GitEmail != "octo@example.com" || p.SSHKey != key {
+		t.Errorf("profile = %+v", p)
 	}
 }
 
-// TestRunDoctor_SSHKeyMissing tests doctor with a missing SSH key.
-func TestRunDoctor_SSHKeyMissing(t *testing.T) {
+// TestRunProfileAdd_FromGHEnterprise tests that --from-gh takes the host of
+// an account logged in only to an enterprise server.
+func TestRunProfileAdd_FromGHEnterprise(t *testing.T) {
 	dir := setupTestEnv(t)
-	tmpHome := t.TempDir()
-	t.Setenv("HOME", tmpHome)
-
-	writeProfiles(t, dir, `profiles:
-  sshprof:
-    gh_user: user1
-    git_name: SSH
-    git_email: ssh@test.com
-    ssh_key: /nonexistent/key`)
-	writeBindings(t, dir, `bindings: []`)
-
-	auth := &mockAuth{users: []string{"user1"}}
+	t.Setenv("HOME", t.TempDir())
+	writeProfiles(t, dir, `profiles: {}`)
 
-	old := os.Stdout
-	r2, w, _ := os.Pipe()
-	os.Stdout = w
+	gitName, gitEmail := "Octo Cat", "octo@corp.example"
+	flags := profileEditFlags{GitName: &gitName, GitEmail: &gitEmail}
+	auth := &mockAuth{accounts: []ghauth.Account{
+		{Host: "ghe.corp.example", User: "octo"},
+		{Host: ghauth.DefaultHost, User: "dual"},
+		{Host: "ghe.corp.example", User: "dual"},
+	}}
+	if _, err := captureStdout(t, func() error {
+		return runProfileAdd(auth, "corp", flags, profileAddOptions{fromGH: "octo"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+	profiles, _ := config.LoadProfiles()
+	if p := profiles.Profiles["corp"]; p.GHUser != "octo" || p.Host != "ghe.corp.example" {
+		t.Errorf("profile = %+v, want octo on ghe.corp.example", p)
+	}
 
-	err := runDoctor(auth)
+	if err := runProfileAdd(auth, "dual", flags, profileAddOptions{fromGH: "dual"}); err == nil {
+		t.Error("expected an error for a user logged in on several hosts")
+	}
+	if _, err := captureStdout(t, func() error {
+		return runProfileAdd(auth, "dual", flags, profileAddOptions{fromGH: "dual@github.com"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+	profiles, _ = config.LoadProfiles()
+	if p := profiles.Profiles["dual"]; p.GHUser != "dual" || p.Host != "" {
+		t.Errorf("profile = %+v, want dual on github.com", p)
+	}
+}
 
-	w.Close()
-	os.Stdout = old
+// TestRunProfileAdd_Duplicate tests adding a profile that already exists.
+// TestRunProfileAdd_Default tests that profile add --default sets the default
+// profile, and refuses to replace an existing default without --force.
+func TestRunProfileAdd_Default(t *testing.T) {
+	setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
 
+	ghUser, gitName, gitEmail := "user1", "User One", "one@example.com"
+	flags := profileEditFlags{GHUser: &ghUser, GitName: &gitName, GitEmail: &gitEmail}
+	if _, err := captureStdout(t, func() error {
+		return runProfileAdd(&mockAuth{}, "first", flags, profileAddOptions{setDefault: true})
+	}); err != nil {
+		t.Fatal(err)
+	}
+	profiles, err := config.LoadProfiles()
 	if err != nil {
 		t.Fatal(err)
 	}
+	if profiles.Default != "first" {
+		t.Errorf("Default = %q, want %q", profiles.Default, "first")
+	}
 
-	var buf bytes.Buffer
-	buf.ReadFrom(r2)
-	output := buf.String()
-
-	if !containsStr(output, "SSH key not found") {
-		t.Error("expected 'SSH key not found' message")
+	err = runProfileAdd(&mockAuth{}, "second", flags, profileAddOptions{setDefault: true})
+	if err == nil || !containsStr(err.Error(), "--force") {
+		t.Fatalf("expected an error pointing to --force, got %v", err)
+	}
+	profiles, err = config.LoadProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := profiles.Profiles["second"]; ok || profiles.Default != "first" {
+		t.Errorf("refused add should change nothing, got default %q and profiles %v", profiles.Default, profiles.Profiles)
+	}
+
+	if _, err := captureStdout(t, func() error {
+		return runProfileAdd(&mockAuth{}, "second", flags, profileAddOptions{setDefault: true, force: true})
+	}); err != nil {
+		t.Fatal(err)
+	}
+	profiles, err = config.LoadProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profiles.Default != "second" {
+		t.Errorf("Default = %q, want %q", profiles.Default, "second")
 	}
 }
 
-// TestRunDoctor_SSHKeyPermissive tests doctor with overly permissive SSH key.
-func TestRunDoctor_SSHKeyPermissive(t *testing.T) {
+func TestRunProfileAdd_Duplicate(t *testing.T) {
 	dir := setupTestEnv(t)
-	tmpHome := t.TempDir()
-	t.Setenv("HOME", tmpHome)
-
-	// Create a fake SSH key with overly permissive permissions.
-	sshDir := filepath.Join(tmpHome, ".ssh")
-	os.MkdirAll(sshDir, 0o700)
-	keyPath := filepath.Join(sshDir, "id_test")
-	os.WriteFile(keyPath, []byte("fake-key"), 0o644)
-
 	writeProfiles(t, dir, `profiles:
-  sshprof:
+  existing:
     gh_user: user1
-    git_name: SSH
-    git_email: ssh@test.com
-    ssh_key: `+keyPath)
-	writeBindings(t, dir, `bindings: []`)
+    git_name: Existing
+    git_email: e@e.com`)
 
-	auth := &mockAuth{users: []string{"user1"}}
+	auth := &mockAuth{}
+	err := runProfileAdd(auth, "existing", profileEditFlags{}, profileAddOptions{})
+	if err == nil {
+		t.Error("expected error for duplicate profile")
+	}
+	if !containsStr(err.Error(), "already exists") {
+		t.Errorf("expected 'already exists' error, got %v", err)
+	}
+}
 
-	old := os.Stdout
-	r2, w, _ := os.Pipe()
-	os.Stdout = w
+// TestInstallShellHook_Bash tests shell hook installation for bash.
+func TestInstallShellHook_Bash(t *testing.T) {
+	dir := setupTestEnv(t)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	t.Setenv("SHELL", "/bin/bash")
 
-	err := runDoctor(auth)
+	// Create bin dir with config dir.
+	binDir := filepath.Join(dir, "bin")
+	os.MkdirAll(binDir, 0o755)
 
-	w.Close()
-	os.Stdout = old
+	err := installShellHook(detectShell())
+	if err != nil {
+		t.Fatal(err)
+	}
 
+	// Verify .bashrc was created with hook.
+	data, err := os.ReadFile(filepath.Join(tmpHome, ".bashrc"))
 	if err != nil {
 		t.Fatal(err)
 	}
+	if !containsStr(string(data), "gh-identity hook") {
+		t.Error("expected 'gh-identity hook' in .bashrc")
+	}
+}
 
-	var buf bytes.Buffer
-	buf.ReadFrom(r2)
-	output := buf.String()
+// TestInstallShellHook_PowerShell tests shell hook installation into the
+// PowerShell profile on Windows.
+func TestInstallShellHook_PowerShell(t *testing.T) {
+	setupTestEnv(t)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	t.Setenv("PSModulePath", "")
+	old := goos
+	goos = "windows"
+	t.Cleanup(func() { goos = old })
 
-	if !containsStr(output, "permissive") {
-		t.Error("expected 'permissive' warning message")
+	if err := installShellHook("powershell"); err != nil {
+		t.Fatal(err)
+	}
+	profile := filepath.Join(tmpHome, "Documents", "PowerShell", "Microsoft.PowerShell_profile.ps1")
+	data, err := os.ReadFile(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binDir, _ := config.InstallBinDir()
+	for _, want := range []string{hookBeginMarker, "function global:prompt", "& '" + filepath.Join(binDir, "gh-identity-hook.exe") + "' --shell powershell"} {
+		if !containsStr(string(data), want) {
+			t.Errorf("expected %q in PowerShell profile, got:\n%s", want, data)
+		}
+	}
+
+	// Installing again leaves a single block.
+	if err := installShellHook("powershell"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(profile)
+	if n := strings.Count(string(data), hookBeginMarker); n != 1 {
+		t.Errorf("expected one hook block after reinstall, got %d", n)
+	}
+
+	// Windows PowerShell 5.1 has its own profile.
+	t.Setenv("PSModulePath", `C:\Users\me\Documents\WindowsPowerShell\Modules;C:\Program Files\WindowsPowerShell\Modules`)
+	if got, want := shellRCFile(tmpHome, "powershell"), filepath.Join(tmpHome, "Documents", "WindowsPowerShell", "Microsoft.PowerShell_profile.ps1"); got != want {
+		t.Errorf("shellRCFile(powershell) = %q, want %q", got, want)
+	}
+
+	if err := installShellHook("cmd"); err == nil || !containsStr(err.Error(), "--shell powershell") {
+		t.Errorf("installShellHook(cmd) error = %v, want a pointer to PowerShell", err)
+	}
+
+	if results := checkShellHook(&doctorContext{}); len(results) != 1 || results[0].Status != doctorOK {
+		t.Errorf("checkShellHook() = %+v, want the PowerShell profile found", results)
+	}
+
+	if _, err := captureStdout(t, runHookUninstall); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(profile)
+	if containsStr(string(data), hookBeginMarker) {
+		t.Errorf("expected uninstall to remove the hook from the PowerShell profile, got:\n%s", data)
 	}
 }
 
-// TestRunDoctor_AllChecksPassed tests doctor with everything configured correctly.
-func TestRunDoctor_AllChecksPassed(t *testing.T) {
+// TestInstallShellHook_Zsh tests shell hook installation for zsh.
+func TestInstallShellHook_Zsh(t *testing.T) {
 	dir := setupTestEnv(t)
 	tmpHome := t.TempDir()
 	t.Setenv("HOME", tmpHome)
+	t.Setenv("SHELL", "/bin/zsh")
 
-	writeProfiles(t, dir, `profiles:
-  good:
-    gh_user: user1
-    git_name: Good
-    git_email: good@good.com`)
-	writeBindings(t, dir, `bindings: []`)
+	binDir := filepath.Join(dir, "bin")
+	os.MkdirAll(binDir, 0o755)
+
+	err := installShellHook(detectShell())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpHome, ".zshrc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsStr(string(data), "gh-identity hook") {
+		t.Error("expected 'gh-identity hook' in .zshrc")
+	}
+}
+
+// TestInstallShellHook_Fish tests shell hook installation for fish.
+func TestInstallShellHook_Fish(t *testing.T) {
+	dir := setupTestEnv(t)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	t.Setenv("SHELL", "/usr/bin/fish")
 
-	// Create hook binary.
 	binDir := filepath.Join(dir, "bin")
 	os.MkdirAll(binDir, 0o755)
-	os.WriteFile(filepath.Join(binDir, "gh-identity-hook"), []byte("fake"), 0o755)
 
-	// Create shell hook in bashrc.
-	os.WriteFile(filepath.Join(tmpHome, ".bashrc"), []byte("# gh-identity hook\neval ..."), 0o644)
+	err := installShellHook(detectShell())
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	auth := &mockAuth{users: []string{"user1"}}
+	data, err := os.ReadFile(filepath.Join(tmpHome, ".config", "fish", "conf.d", "gh-identity.fish"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsStr(string(data), "gh-identity hook") {
+		t.Error("expected 'gh-identity hook' in fish config")
+	}
+}
+
+// TestInstallShellHook_AlreadyInstalled tests that reinstalling replaces
+// an outdated hook block in place instead of skipping or duplicating it.
+func TestInstallShellHook_AlreadyInstalled(t *testing.T) {
+	setupTestEnv(t)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	t.Setenv("SHELL", "/bin/bash")
+	bashrc := filepath.Join(tmpHome, ".bashrc")
+
+	// An outdated managed block between the user's own lines.
+	os.WriteFile(bashrc, []byte("export A=1\n"+hookBeginMarker+"\neval \"$(/old/gh-identity-hook)\"\n"+hookEndMarker+"\nexport B=2\n"), 0o644)
+	if err := installShellHook(detectShell()); err != nil {
+		t.Fatal(err)
+	}
+
+	binDir, _ := config.InstallBinDir()
+	want := "export A=1\n" + hookBeginMarker + "\neval \"$(" + filepath.Join(binDir, "gh-identity-hook") + " --shell bash)\"\n" + hookEndMarker + "\nexport B=2\n"
+	data, _ := os.ReadFile(bashrc)
+	if string(data) != want {
+		t.Errorf(".bashrc = %q, want %q", data, want)
+	}
+
+	// Installing again leaves the file unchanged.
+	if err := installShellHook(detectShell()); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(bashrc)
+	if string(data) != want {
+		t.Errorf(".bashrc after reinstall = %q, want %q", data, want)
+	}
+
+	// A legacy undelimited block is replaced by a delimited one.
+	os.WriteFile(bashrc, []byte("export A=1\n\n"+legacyHookMarker+"\neval \"$(/old/gh-identity-hook --shell bash)\"\n"), 0o644)
+	if err := installShellHook(detectShell()); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(bashrc)
+	if strings.Contains(string(data), "/old/") || strings.Count(string(data), hookBeginMarker) != 1 || !strings.HasPrefix(string(data), "export A=1\n\n"+hookBeginMarker) {
+		t.Errorf("legacy block not replaced, got:\n%s", data)
+	}
+}
+
+// TestInstallHookBinary_NotFound tests installHookBinary when binary doesn't exist.
+func TestInstallHookBinary_NotFound(t *testing.T) {
+	setupTestEnv(t)
+
+	err := installHookBinary()
+	if err == nil {
+		t.Error("expected error when hook binary not found")
+	}
+}
+
+// TestRunProfileList_ActiveProfile tests list highlighting active profile.
+func TestRunProfileList_ActiveProfile(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com
+    ssh_key: ~/.ssh/id_work`)
+	t.Setenv("GH_IDENTITY_PROFILE", "work")
 
 	old := os.Stdout
-	r2, w, _ := os.Pipe()
+	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	err := runDoctor(auth)
+	err := runProfileList(false)
 
 	w.Close()
 	os.Stdout = old
@@ -1273,85 +2115,3297 @@ func TestRunDoctor_AllChecksPassed(t *testing.T) {
 	}
 
 	var buf bytes.Buffer
-	buf.ReadFrom(r2)
+	buf.ReadFrom(r)
 	output := buf.String()
 
-	if !containsStr(output, "All checks passed") {
-		t.Errorf("expected 'All checks passed', got:\n%s", output)
+	if !containsStr(output, "*") {
+		t.Error("expected '*' indicator for active profile")
+	}
+	if !containsStr(output, "ssh_key") {
+		t.Error("expected ssh_key in output")
 	}
 }
 
-// TestRunSwitch_WithSSHKey tests switch with a profile that has an SSH key.
-func TestRunSwitch_WithSSHKey(t *testing.T) {
+// TestRunSwitch_ProfileNotFound tests switch with nonexistent profile.
+func TestRunSwitch_ProfileNotFound(t *testing.T) {
 	dir := setupTestEnv(t)
-	writeProfiles(t, dir, `profiles:
-  sshuser:
-    gh_user: user1
-    git_name: SSH User
-    git_email: ssh@example.com
-    ssh_key: ~/.ssh/id_test`)
+	writeProfiles(t, dir, `profiles: {}`)
 
-	auth := &mockAuth{
-		tokens: map[string]string{"user1": "ssh-token"},
+	auth := &mockAuth{}
+
+	err := runSwitch(auth, "nonexistent", hook.Bash, "")
+	if err == nil {
+		t.Error("expected error when profile not found")
 	}
+}
+
+// TestRunDoctor tests the doctor command with various setups.
+func TestRunDoctor_NoConfig(t *testing.T) {
+	// Point to a non-existent config dir.
+	tmp := t.TempDir()
+	t.Setenv("GH_IDENTITY_CONFIG_DIR", filepath.Join(tmp, "nonexistent"))
+	t.Setenv("HOME", t.TempDir())
+
+	auth := &mockAuth{users: []string{"user1"}}
 
 	old := os.Stdout
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	err := runSwitch(auth, "sshuser")
+	err := runDoctor(auth, "", false, false)
 
 	w.Close()
 	os.Stdout = old
 
-	if err != nil {
-		t.Fatal(err)
+	if err == nil {
+		t.Fatal("expected error when doctor finds issues")
 	}
 
 	var buf bytes.Buffer
 	buf.ReadFrom(r)
 	output := buf.String()
 
-	if !containsStr(output, "GIT_SSH_COMMAND") {
-		t.Error("expected GIT_SSH_COMMAND in output for profile with SSH key")
+	if !containsStr(output, "Config directory does not exist") {
+		t.Error("expected config dir missing message")
 	}
 }
 
-// TestRunStatus_DefaultProfile tests status with default profile fallback.
-func TestRunStatus_DefaultProfile(t *testing.T) {
+// TestRunDoctor_ValidSetup tests doctor with a valid configuration.
+func TestRunDoctor_ValidSetup(t *testing.T) {
 	dir := setupTestEnv(t)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
 	writeProfiles(t, dir, `profiles:
-  fallback:
+  test:
     gh_user: user1
-    git_name: Fallback
-    git_email: fb@example.com
-default: fallback`)
-	writeBindings(t, dir, `bindings: []`)
-	t.Setenv("GH_IDENTITY_PROFILE", "")
+    git_name: Test
+    git_email: test@test.com`)
+	writeBindings(t, dir, `bindings:
+  - path: /valid/path
+    profile: test`)
 
-	auth := &mockAuth{}
+	auth := &mockAuth{users: []string{"user1"}}
 
 	old := os.Stdout
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	err := runStatus(auth)
+	err := runDoctor(auth, "", false, false)
 
 	w.Close()
 	os.Stdout = old
 
-	if err != nil {
-		t.Fatal(err)
+	if err == nil {
+		t.Fatal("expected error when doctor finds issues")
 	}
 
 	var buf bytes.Buffer
 	buf.ReadFrom(r)
 	output := buf.String()
 
-	if !containsStr(output, "fallback") {
-		t.Error("expected 'fallback' profile")
+	if !containsStr(output, "1 profile(s) configured") {
+		t.Error("expected profiles configured message")
 	}
-	if !containsStr(output, "default profile") {
-		t.Error("expected 'default profile' source")
+}
+
+// TestRunDoctor_InvalidProfile tests doctor with invalid profile (missing auth).
+func TestRunDoctor_InvalidProfile(t *testing.T) {
+	dir := setupTestEnv(t)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	writeProfiles(t, dir, `profiles:
+  bad:
+    gh_user: unknown_user
+    git_name: Bad
+    git_email: bad@bad.com`)
+	writeBindings(t, dir, `bindings: []`)
+
+	// Auth knows about user1 but profile references unknown_user.
+	auth := &mockAuth{users: []string{"user1"}}
+
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := runDoctor(auth, "", false, false)
+
+	w.Close()
+	os.Stdout = old
+
+	if err == nil {
+		t.Fatal("expected error when doctor finds issues")
+	}
+
+	var buf bytes.Buffer
+	buf.ReadFrom(r)
+	output := buf.String()
+
+	if !containsStr(output, "not authenticated") {
+		t.Error("expected unauthenticated user warning")
+	}
+}
+
+// TestRunDoctor_BadBinding tests doctor with a binding referencing nonexistent profile.
+func TestRunDoctor_BadBinding(t *testing.T) {
+	dir := setupTestEnv(t)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	writeProfiles(t, dir, `profiles:
+  good:
+    gh_user: user1
+    git_name: Good
+    git_email: good@good.com`)
+	writeBindings(t, dir, `bindings:
+  - path: /some/path
+    profile: nonexistent`)
+
+	auth := &mockAuth{users: []string{"user1"}}
+
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := runDoctor(auth, "", false, false)
+
+	w.Close()
+	os.Stdout = old
+
+	if err == nil {
+		t.Fatal("expected error when doctor finds issues")
+	}
+
+	var buf bytes.Buffer
+	buf.ReadFrom(r)
+	output := buf.String()
+
+	if !containsStr(output, "non-existent profile") {
+		t.Error("expected non-existent profile warning")
+	}
+}
+
+func TestRunDoctor_RelativeBinding(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
+
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user1
+    git_name: Work
+    git_email: work@work.com`)
+	writeBindings(t, dir, `bindings:
+  - path: code/work
+    profile: work`)
+
+	auth := &mockAuth{users: []string{"user1"}}
+	output, err := captureStdout(t, func() error { return runDoctor(auth, "", false, false) })
+	if err == nil {
+		t.Fatal("expected doctor to report the relative binding")
+	}
+	if !containsStr(output, `Binding path "code/work" is relative`) {
+		t.Errorf("expected relative binding warning, got:\n%s", output)
+	}
+
+	if _, err := captureStdout(t, func() error { return runDoctorFix(false) }); err != nil {
+		t.Fatal(err)
+	}
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bindings.Relative()) != 0 {
+		t.Errorf("expected no relative bindings after --fix, got %+v", bindings.Relative())
+	}
+	if want := filepath.Join(dir, "code", "work"); bindings.Bindings[0].Path != want {
+		t.Errorf("Path = %q, want %q", bindings.Bindings[0].Path, want)
+	}
+}
+
+// TestRunDoctor_ConflictingBindings tests doctor with a hand-edited
+// bindings.yml that binds the same directory twice.
+func TestRunDoctor_ConflictingBindings(t *testing.T) {
+	dir := setupTestEnv(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	code := filepath.Join(home, "code")
+	if err := os.MkdirAll(code, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user1
+    git_name: Work
+    git_email: work@work.com
+  personal:
+    gh_user: user2
+    git_name: Me
+    git_email: me@me.com`)
+	writeBindings(t, dir, `bindings:
+  - path: ~/code
+    profile: work
+  - path: `+code+`/
+    profile: personal
+  - remote: github.com/acme/*
+    profile: work
+  - remote: git@github.com:acme/*
+    profile: work`)
+
+	auth := &mockAuth{users: []string{"user1", "user2"}}
+	output, err := captureStdout(t, func() error { return runDoctor(auth, "", false, false) })
+	if err == nil {
+		t.Fatal("expected doctor to report the conflicting bindings")
+	}
+	if !containsStr(output, "Conflicting bindings for the same target: ~/code → \"work\"") {
+		t.Errorf("expected conflict error, got:\n%s", output)
+	}
+	if !containsStr(output, "Duplicate bindings:") {
+		t.Errorf("expected duplicate remote warning, got:\n%s", output)
+	}
+}
+
+// TestRunDoctorRepairGitconfig tests that --repair-gitconfig rebuilds the
+// managed includeIf directives from bindings.yml, dropping stale and
+// duplicate ones and keeping the user's own.
+func TestRunDoctorRepairGitconfig(t *testing.T) {
+	dir := setupTestEnv(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	work := filepath.Join(home, "code", "work")
+	if err := os.MkdirAll(work, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	writeProfiles(t, dir, `version: 1
+profiles:
+  work:
+    gh_user: user1
+    git_name: Work
+    git_email: work@work.com
+  personal:
+    gh_user: user2
+    git_name: Me
+    git_email: me@me.com`)
+	writeBindings(t, dir, `bindings:
+  - path: `+work+`
+    profile: work
+  - remote: github.com/acme
+    profile: personal`)
+
+	gcPath := filepath.Join(home, ".gitconfig")
+	original := `[user]
+    name = Me
+
+[includeIf "gitdir:/srv/mine/"]
+    path = /srv/mine.gitconfig
+
+[includeIf "gitdir:/gone/"] # managed by gh-identity
+    path = /cfg/old.gitconfig
+
+[includeIf "gitdir:` + work + `/"] # managed by gh-identity
+    path = /cfg/personal.gitconfig
+
+[includeIf "gitdir:` + work + `/"] # managed by gh-identity
+    path = /cfg/stale.gitconfig
+`
+	if err := os.WriteFile(gcPath, []byte(original), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := captureStdout(t, func() error { return runDoctorRepairGitconfig(false) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsStr(output, "Removed 3 managed includeIf directive(s)") {
+		t.Errorf("expected removal count, got:\n%s", output)
+	}
+
+	data, err := os.ReadFile(gcPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	if !strings.HasPrefix(got, "[user]\n    name = Me\n\n[includeIf \"gitdir:/srv/mine/\"]\n    path = /srv/mine.gitconfig\n") {
+		t.Errorf("expected the user's own sections to be kept, got:\n%s", got)
+	}
+	for _, stale := range []string{"/gone/", "old.gitconfig", "stale.gitconfig", "/cfg/personal.gitconfig"} {
+		if strings.Contains(got, stale) {
+			t.Errorf("expected %q to be removed, got:\n%s", stale, got)
+		}
+	}
+	if n := strings.Count(got, `gitdir:`+work+`/`); n != 1 {
+		t.Errorf("expected one directive for %s, got %d:\n%s", work, n, got)
+	}
+	if !containsStr(got, filepath.Join(dir, "git", "work.gitconfig")) {
+		t.Errorf("expected the work directive to include the work fragment, got:\n%s", got)
+	}
+	if !containsStr(got, "hasconfig:remote.*.url:https://github.com/acme/**") {
+		t.Errorf("expected the remote directive to be added, got:\n%s", got)
+	}
+	for _, name := range []string{"work", "personal"} {
+		if _, err := os.Stat(filepath.Join(dir, "git", name+".gitconfig")); err != nil {
+			t.Errorf("expected fragment for %s: %v", name, err)
+		}
+	}
+
+	// A second run reproduces the same file.
+	if _, err := captureStdout(t, func() error { return runDoctorRepairGitconfig(true) }); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := os.ReadFile(gcPath); string(again) != got {
+		t.Errorf("second repair changed the gitconfig:\n%s\nwant:\n%s", again, got)
+	}
+}
+
+// TestRunDoctor_EmptyProfiles tests doctor with no profiles.
+func TestRunDoctor_EmptyProfiles(t *testing.T) {
+	dir := setupTestEnv(t)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	writeProfiles(t, dir, `profiles: {}`)
+
+	auth := &mockAuth{users: []string{}}
+
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := runDoctor(auth, "", false, false)
+
+	w.Close()
+	os.Stdout = old
+
+	if err == nil {
+		t.Fatal("expected error when doctor finds issues")
+	}
+
+	var buf bytes.Buffer
+	buf.ReadFrom(r)
+	output := buf.String()
+
+	if !containsStr(output, "No profiles configured") {
+		t.Error("expected no profiles message")
+	}
+}
+
+// TestRunDoctor_ValidationErrors tests doctor with invalid profile fields.
+func TestRunDoctor_ValidationErrors(t *testing.T) {
+	dir := setupTestEnv(t)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	writeProfiles(t, dir, `profiles:
+  bad:
+    gh_user: ""
+    git_name: ""
+    git_email: ""`)
+
+	auth := &mockAuth{users: []string{}}
+
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := runDoctor(auth, "", false, false)
+
+	w.Close()
+	os.Stdout = old
+
+	if err == nil {
+		t.Fatal("expected error when doctor finds issues")
+	}
+
+	var buf bytes.Buffer
+	buf.ReadFrom(r)
+	output := buf.String()
+
+	if !containsStr(output, "is required") {
+		t.Error("expected validation error messages")
+	}
+}
+
+// TestRunInit tests the init command with mock auth and stdin.
+func TestRunInit(t *testing.T) {
+	dir := setupTestEnv(t)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	t.Setenv("SHELL", "/bin/bash")
+
+	// Provide stdin for: profile name, git name, git email, ssh key, default profile.
+	oldStdin := os.Stdin
+	input := "personal\nJohn Doe\njohn@example.com\n\npersonal\n"
+	r, w, _ := os.Pipe()
+	w.WriteString(input)
+	w.Close()
+	os.Stdin = r
+	defer func() { os.Stdin = oldStdin }()
+
+	auth := &mockAuth{users: []string{"user1"}}
+
+	oldOut := os.Stdout
+	_, outW, _ := os.Pipe()
+	os.Stdout = outW
+
+	err := runInit(auth, initOptions{})
+
+	outW.Close()
+	os.Stdout = oldOut
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Verify profiles were saved.
+	data, err := os.ReadFile(filepath.Join(dir, "profiles.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsStr(string(data), "personal") {
+		t.Error("expected 'personal' in profiles.yml")
+	}
+	if !containsStr(string(data), "user1") {
+		t.Error("expected 'user1' in profiles.yml")
+	}
+}
+
+// TestRunInit_NoUsers tests init when no gh accounts are authenticated.
+func TestRunInit_NoUsers(t *testing.T) {
+	setupTestEnv(t)
+
+	auth := &mockAuth{users: []string{}}
+
+	oldOut := os.Stdout
+	_, outW, _ := os.Pipe()
+	os.Stdout = outW
+
+	err := runInit(auth, initOptions{})
+
+	outW.Close()
+	os.Stdout = oldOut
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRunInit_AuthError tests init when auth fails.
+func TestRunInit_AuthError(t *testing.T) {
+	setupTestEnv(t)
+
+	auth := &mockAuth{err: fmt.Errorf("auth failed")}
+
+	oldOut := os.Stdout
+	_, outW, _ := os.Pipe()
+	os.Stdout = outW
+
+	err := runInit(auth, initOptions{})
+
+	outW.Close()
+	os.Stdout = oldOut
+
+	if err == nil {
+		t.Error("expected error when auth fails")
+	}
+}
+
+// TestRunInit_MultipleUsers tests init with multiple authenticated users.
+func TestRunInit_MultipleUsers(t *testing.T) {
+	dir := setupTestEnv(t)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	t.Setenv("SHELL", "/bin/bash")
+
+	// Input for 2 users: name1, gitname1, email1, sshkey1, name2, gitname2, email2, sshkey2, default
+	oldStdin := os.Stdin
+	input := "work\nWork User\nwork@company.com\n~/.ssh/id_work\npersonal\nPersonal User\nme@home.com\n\nwork\n"
+	r, w, _ := os.Pipe()
+	w.WriteString(input)
+	w.Close()
+	os.Stdin = r
+	defer func() { os.Stdin = oldStdin }()
+
+	auth := &mockAuth{users: []string{"workuser", "personaluser"}}
+
+	oldOut := os.Stdout
+	_, outW, _ := os.Pipe()
+	os.Stdout = outW
+
+	err := runInit(auth, initOptions{})
+
+	outW.Close()
+	os.Stdout = oldOut
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Verify profiles were saved.
+	data, _ := os.ReadFile(filepath.Join(dir, "profiles.yml"))
+	if !containsStr(string(data), "work") {
+		t.Error("expected 'work' in profiles.yml")
+	}
+	if !containsStr(string(data), "personal") {
+		t.Error("expected 'personal' in profiles.yml")
+	}
+}
+
+// TestRunInit_Yes tests that init --yes creates profiles without reading stdin.
+func TestRunInit_Yes(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("SHELL", "/bin/bash")
+	writeProfiles(t, dir, `profiles:
+  keep:
+    gh_user: keep
+    git_name: Keep
+    git_email: keep@example.com`)
+
+	// A closed stdin makes any prompt read an empty line.
+	oldStdin := os.Stdin
+	r, w, _ := os.Pipe()
+	w.Close()
+	os.Stdin = r
+	defer func() { os.Stdin = oldStdin }()
+
+	auth := &mockAuth{accounts: []ghauth.Account{
+		{Host: ghauth.DefaultHost, User: "octo"},
+		{Host: "ghe.corp.example", User: "octo"},
+		{Host: ghauth.DefaultHost, User: "keep"},
+	}}
+	output, err := captureStdout(t, func() error { return runInit(auth, initOptions{yes: true}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if containsStr(output, "Profile name [") {
+		t.Errorf("expected no prompts, got:\n%s", output)
+	}
+	if !containsStr(output, "+ octo") || !containsStr(output, "= keep (already exists)") {
+		t.Errorf("expected a summary of created and skipped profiles, got:\n%s", output)
+	}
+
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := profiles.Profiles["octo"]; !ok {
+		t.Error("expected profile octo")
+	}
+	if p, ok := profiles.Profiles["octo@ghe.corp.example"]; !ok || p.Host != "ghe.corp.example" {
+		t.Errorf("expected enterprise profile, got %+v", p)
+	}
+	if profiles.Profiles["keep"].GitName != "Keep" {
+		t.Error("existing profile should be left alone")
+	}
+	if profiles.Default != "octo" {
+		t.Errorf("Default = %q, want %q", profiles.Default, "octo")
+	}
+	if _, err := os.Stat(filepath.Join(os.Getenv("HOME"), ".bashrc")); err != nil {
+		t.Errorf("expected the shell hook to be installed: %v", err)
+	}
+}
+
+// TestRunInit_YesGenerateSSHKey tests that init --yes --generate-ssh-key
+// creates keys without a passphrase prompt.
+func TestRunInit_YesGenerateSSHKey(t *testing.T) {
+	setupTestEnv(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SHELL", "/bin/bash")
+
+	oldKeygen := sshKeygen
+	var calls []bool
+	sshKeygen = func(path, comment string, interactive bool) error {
+		calls = append(calls, interactive)
+		os.WriteFile(path, []byte("private"), 0o600)
+		return os.WriteFile(path+".pub", []byte("ssh-ed25519 AAAA "+comment+"\n"), 0o644)
+	}
+	t.Cleanup(func() { sshKeygen = oldKeygen })
+
+	auth := &mockAuth{accounts: []ghauth.Account{{Host: ghauth.DefaultHost, User: "octo"}}}
+	if _, err := captureStdout(t, func() error { return runInit(auth, initOptions{yes: true, generateKeys: true}) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 1 || calls[0] {
+		t.Errorf("ssh-keygen calls (interactive) = %v, want one non-interactive call", calls)
+	}
+}
+
+// TestRunInit_NoTokenEnv tests that init --no-token-env makes new profiles
+// use gh's credential helper.
+func TestRunInit_NoTokenEnv(t *testing.T) {
+	setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("SHELL", "/bin/bash")
+
+	auth := &mockAuth{accounts: []ghauth.Account{{Host: ghauth.DefaultHost, User: "octo"}}}
+	if _, err := captureStdout(t, func() error { return runInit(auth, initOptions{yes: true, noTokenEnv: true}) }); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := profiles.Profiles["octo"].CredentialHelper; got != config.CredentialGH {
+		t.Errorf("credential_helper = %q, want %q", got, config.CredentialGH)
+	}
+}
+
+// TestRunInit_ImportExisting tests that init --import-existing prefills git
+// details from the includeIf fragments that belong to each account.
+func TestRunInit_ImportExisting(t *testing.T) {
+	setupTestEnv(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SHELL", "/bin/bash")
+
+	// The global email is what init infers without the GitHub API.
+	gc := filepath.Join(home, ".gitconfig")
+	t.Setenv("GIT_CONFIG_GLOBAL", gc)
+	os.WriteFile(gc, []byte(`[user]
+	email = global@example.com
+[includeIf "gitdir:~/work/"]
+	path = ~/.gitconfig-octo-work
+[includeIf "gitdir:~/oss/"]
+	path = ~/.gitconfig-oss
+`), 0o644)
+	os.WriteFile(filepath.Join(home, ".gitconfig-octo-work"), []byte("[user]\n\tname = Octo at Work\n\temail = octo@work.example\n"), 0o644)
+	os.WriteFile(filepath.Join(home, ".gitconfig-oss"), []byte("[user]\n\tname = Octo OSS\n\temail = 123+octo-oss@users.noreply.github.com\n"), 0o644)
+
+	auth := &mockAuth{accounts: []ghauth.Account{
+		{Host: ghauth.DefaultHost, User: "octo-work"},
+		{Host: ghauth.DefaultHost, User: "octo-oss"},
+		{Host: ghauth.DefaultHost, User: "stranger"},
+	}}
+	output, err := captureStdout(t, func() error {
+		return runInit(auth, initOptions{yes: true, importExisting: true})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsStr(output, "Found Octo at Work <octo@work.example>") {
+		t.Errorf("expected the matched fragment to be reported, got:\n%s", output)
+	}
+
+	profiles, _ := config.LoadProfiles()
+	for name, want := range map[string][2]string{
+		"octo-work": {"Octo at Work", "octo@work.example"},                 // matched by file name
+		"octo-oss":  {"Octo OSS", "123+octo-oss@users.noreply.github.com"}, // matched by noreply email
+		"stranger":  {"", "global@example.com"},                            // no fragment; inferred
+	} {
+		p := profiles.Profiles[name]
+		if p.GitName != want[0] || p.GitEmail != want[1] {
+			t.Errorf("profile %s = %s <%s>, want %s <%s>", name, p.GitName, p.GitEmail, want[0], want[1])
+		}
+	}
+}
+
+// TestRunDoctor_SSHKeyValid tests doctor with a valid SSH key.
+func TestRunDoctor_SSHKeyValid(t *testing.T) {
+	dir := setupTestEnv(t)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	// Create a fake SSH key with correct permissions.
+	sshDir := filepath.Join(tmpHome, ".ssh")
+	os.MkdirAll(sshDir, 0o700)
+	keyPath := filepath.Join(sshDir, "id_test")
+	os.WriteFile(keyPath, []byte("fake-key"), 0o600)
+
+	writeProfiles(t, dir, `profiles:
+  sshprof:
+    gh_user: user1
+    git_name: SSH
+    git_email: ssh@test.com
+    ssh_key: `+keyPath)
+	writeBindings(t, dir, `bindings: []`)
+
+	auth := &mockAuth{users: []string{"user1"}}
+
+	old := os.Stdout
+	r2, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := runDoctor(auth, "", false, false)
+
+	w.Close()
+	os.Stdout = old
+
+	if err == nil {
+		t.Fatal("expected error when doctor finds issues")
+	}
+
+	var buf bytes.Buffer
+	buf.ReadFrom(r2)
+	output := buf.String()
+
+	if !containsStr(output, "SSH key OK") {
+		t.Error("expected 'SSH key OK' message")
+	}
+}
+
+// TestRunDoctor_SSHKeyMissing tests doctor with a missing SSH key.
+func TestRunDoctor_SSHKeyMissing(t *testing.T) {
+	dir := setupTestEnv(t)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	writeProfiles(t, dir, `profiles:
+  sshprof:
+    gh_user: user1
+    git_name: SSH
+    git_email: ssh@test.com
+    ssh_key: /nonexistent/key`)
+	writeBindings(t, dir, `bindings: []`)
+
+	auth := &mockAuth{users: []string{"user1"}}
+
+	old := os.Stdout
+	r2, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := runDoctor(auth, "", false, false)
+
+	w.Close()
+	os.Stdout = old
+
+	if err == nil {
+		t.Fatal("expected error when doctor finds issues")
+	}
+
+	var buf bytes.Buffer
+	buf.ReadFrom(r2)
+	output := buf.String()
+
+	if !containsStr(output, "SSH key not found") {
+		t.Error("expected 'SSH key not found' message")
+	}
+}
+
+// TestRunDoctor_EnterpriseHost tests that the auth check matches accounts by host.
+func TestRunDoctor_EnterpriseHost(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
+	writeProfiles(t, dir, `profiles:
+  public:
+    gh_user: octo
+    git_name: Octo
+    git_email: octo@example.com
+  corp:
+    gh_user: octo
+    host: ghe.corp.example
+    git_name: Octo
+    git_email: octo@corp.example`)
+	writeBindings(t, dir, `bindings: []`)
+
+	auth := &mockAuth{accounts: []ghauth.Account{{Host: "github.com", User: "octo"}}}
+	output, err := captureStdout(t, func() error { return runDoctor(auth, "", false, false) })
+	if err == nil {
+		t.Fatal("expected doctor to flag the unauthenticated enterprise account")
+	}
+	if !containsStr(output, `Profile "corp" references user "octo" on ghe.corp.example`) {
+		t.Errorf("expected host-qualified auth error, got:\n%s", output)
+	}
+	if containsStr(output, `Profile "public" references`) {
+		t.Errorf("github.com profile should be authenticated, got:\n%s", output)
+	}
+}
+
+// TestRunDoctor_TokenScopes tests doctor's token validity and scope check.
+func TestRunDoctor_TokenScopes(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: worker
+    git_name: Worker
+    git_email: worker@example.com
+  old:
+    gh_user: stale
+    git_name: Stale
+    git_email: stale@example.com
+  fine:
+    gh_user: scoped
+    git_name: Scoped
+    git_email: scoped@example.com
+  corp:
+    gh_user: corpdev
+    git_name: Corp Dev
+    git_email: dev@corp.example
+    host: ghe.corp.example`)
+	writeBindings(t, dir, `bindings: []`)
+
+	auth := &mockAuth{
+		accounts: []ghauth.Account{
+			{Host: ghauth.DefaultHost, User: "worker"},
+			{Host: ghauth.DefaultHost, User: "stale"},
+			{Host: ghauth.DefaultHost, User: "scoped"},
+			{Host: "ghe.corp.example", User: "corpdev"},
+		},
+		scopes: map[string][]string{
+			"worker":                   {"gist", "read:org"},
+			"corpdev@ghe.corp.example": {"gist"},
+		},
+		scopeErrs: map[string]error{"stale": fmt.Errorf("HTTP 401: Bad credentials")},
+	}
+	output, err := captureStdout(t, func() error { return runDoctor(auth, "", false, false) })
+	if err == nil {
+		t.Fatal("expected doctor to report token issues")
+	}
+	if !containsStr(output, `Token for "worker" is missing scope(s): repo`) {
+		t.Errorf("expected missing scope warning, got:\n%s", output)
+	}
+	if !containsStr(output, "gh auth refresh -u worker -s repo") {
+		t.Errorf("expected refresh hint, got:\n%s", output)
+	}
+	if !containsStr(output, `Token for "stale" was rejected`) {
+		t.Errorf("expected rejected token error, got:\n%s", output)
+	}
+	if !containsStr(output, `Token for "corpdev" is missing scope(s): repo`) || !containsStr(output, "gh auth refresh -h ghe.corp.example -u corpdev -s repo") {
+		t.Errorf("expected the enterprise profile's token to be checked, got:\n%s", output)
+	}
+	if containsStr(output, `Token for "scoped"`) {
+		t.Errorf("tokens without scope headers should not be flagged, got:\n%s", output)
+	}
+}
+
+// TestRunDoctor_ForeignToken tests that doctor flags a GH_TOKEN gh-identity did not export.
+func TestRunDoctor_ForeignToken(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user1
+    git_name: Work
+    git_email: work@example.com`)
+	writeBindings(t, dir, `bindings: []`)
+	auth := &mockAuth{users: []string{"user1"}}
+
+	t.Setenv("GH_TOKEN", "ghp_foreign")
+	t.Setenv("GH_IDENTITY_PROFILE", "")
+	output, _ := captureStdout(t, func() error { return runDoctor(auth, "", false, false) })
+	if !containsStr(output, "GH_TOKEN is set outside gh-identity") {
+		t.Errorf("expected GH_TOKEN warning, got:\n%s", output)
+	}
+
+	t.Setenv("GH_IDENTITY_PROFILE", "work")
+	output, _ = captureStdout(t, func() error { return runDoctor(auth, "", false, false) })
+	if containsStr(output, "GH_TOKEN is set outside gh-identity") {
+		t.Errorf("a token exported alongside GH_IDENTITY_PROFILE should not be flagged, got:\n%s", output)
+	}
+}
+
+// TestRunDoctor_Tools tests doctor's gh and git version checks.
+func TestRunDoctor_Tools(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
+	writeProfiles(t, dir, `profiles: {}`)
+	writeBindings(t, dir, `bindings:
+  - remote: github.com/acme
+    profile: work`)
+
+	stubToolVersions(t, "gh version 2.30.0 (2023-05-30)", "git version 2.30.1 (Apple Git-130)")
+	output, _ := captureStdout(t, func() error { return runDoctor(&mockAuth{}, "", false, false) })
+	if !containsStr(output, "gh 2.30.0 is older than 2.40.0") {
+		t.Errorf("expected old gh warning, got:\n%s", output)
+	}
+	if !containsStr(output, "git 2.30.1 is older than 2.36.0") {
+		t.Errorf("expected old git warning, got:\n%s", output)
+	}
+
+	orig := toolVersion
+	toolVersion = func(name string) (string, error) {
+		if name == "gh" {
+			return "", &exec.Error{Name: "gh", Err: exec.ErrNotFound}
+		}
+		return orig(name)
+	}
+	output, err := captureStdout(t, func() error { return runDoctor(&mockAuth{}, "", false, false) })
+	if err == nil {
+		t.Error("expected doctor to fail when gh is missing")
+	}
+	if !containsStr(output, "gh is not installed") || !containsStr(output, "https://cli.github.com") {
+		t.Errorf("expected actionable missing-gh error, got:\n%s", output)
+	}
+}
+
+// TestRunDoctor_DuplicateIncludeIf tests that doctor warns about repeated includeIf sections.
+func TestRunDoctor_DuplicateIncludeIf(t *testing.T) {
+	dir := setupTestEnv(t)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user1
+    git_name: Work
+    git_email: work@test.com`)
+	writeBindings(t, dir, `bindings: []`)
+
+	gitconfigContent := "[includeIf \"gitdir:/tmp/work/\"]\n    path = a.gitconfig\n[includeIf \"gitdir:/tmp/work/\"]\n    path = b.gitconfig\n"
+	if err := os.WriteFile(filepath.Join(tmpHome, ".gitconfig"), []byte(gitconfigContent), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	auth := &mockAuth{users: []string{"user1"}}
+	output, err := captureStdout(t, func() error { return runDoctor(auth, "", false, false) })
+	if err == nil {
+		t.Fatal("expected doctor to report the duplicate as an issue")
+	}
+	if !containsStr(output, `Duplicate [includeIf "gitdir:/tmp/work/"]`) {
+		t.Errorf("expected duplicate warning, got:\n%s", output)
+	}
+}
+
+// TestRunDoctor_DanglingIncludeIf tests that doctor reports managed
+// includeIfs whose fragment no longer exists.
+func TestRunDoctor_DanglingIncludeIf(t *testing.T) {
+	dir := setupTestEnv(t)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user1
+    git_name: Work
+    git_email: work@test.com`)
+	writeBindings(t, dir, `bindings: []`)
+
+	gcPath := filepath.Join(tmpHome, ".gitconfig")
+	gitDir := filepath.Join(dir, "git")
+	if err := gitconfig.AddIncludeIf(gcPath, "/code/work", filepath.Join(gitDir, "work.gitconfig")); err != nil {
+		t.Fatal(err)
+	}
+	if err := gitconfig.AddIncludeIf(gcPath, "/code/old", filepath.Join(gitDir, "old.gitconfig")); err != nil {
+		t.Fatal(err)
+	}
+
+	auth := &mockAuth{users: []string{"user1"}}
+	output, err := captureStdout(t, func() error { return runDoctor(auth, "", false, false) })
+	if err == nil {
+		t.Fatal("expected doctor to fail on dangling includeIfs")
+	}
+	for _, want := range []string{
+		"points to missing fragment " + filepath.Join(gitDir, "work.gitconfig"),
+		"gh identity bind /code/work work",
+		"points to missing fragment " + filepath.Join(gitDir, "old.gitconfig"),
+		"gh identity bind /code/old <profile>",
+	} {
+		if !containsStr(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+
+	// Once the fragment exists the directive is fine.
+	if err := gitconfig.WriteProfileFragment("work", config.Profile{GitName: "Work", GitEmail: "work@test.com"}); err != nil {
+		t.Fatal(err)
+	}
+	output, _ = captureStdout(t, func() error { return runDoctor(auth, "", false, false) })
+	if containsStr(output, "missing fragment "+filepath.Join(gitDir, "work.gitconfig")) {
+		t.Errorf("restored fragment still reported:\n%s", output)
+	}
+}
+
+// TestRunDoctor_SSHKeyPermissive tests doctor with overly permissive SSH key.
+func TestRunDoctor_SSHKeyPermissive(t *testing.T) {
+	dir := setupTestEnv(t)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	// Create a fake SSH key with overly permissive permissions.
+	sshDir := filepath.Join(tmpHome, ".ssh")
+	os.MkdirAll(sshDir, 0o700)
+	keyPath := filepath.Join(sshDir, "id_test")
+	os.WriteFile(keyPath, []byte("fake-key"), 0o644)
+
+	writeProfiles(t, dir, `profiles:
+  sshprof:
+    gh_user: user1
+    git_name: SSH
+    git_email: ssh@test.com
+    ssh_key: `+keyPath)
+	writeBindings(t, dir, `bindings: []`)
+
+	auth := &mockAuth{users: []string{"user1"}}
+
+	old := os.Stdout
+	r2, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := runDoctor(auth, "", false, false)
+
+	w.Close()
+	os.Stdout = old
+
+	if err == nil {
+		t.Fatal("expected error when doctor finds issues")
+	}
+
+	var buf bytes.Buffer
+	buf.ReadFrom(r2)
+	output := buf.String()
+
+	if !containsStr(output, "permissive") {
+		t.Error("expected 'permissive' warning message")
+	}
+}
+
+func TestCheckHookBinary_Version(t *testing.T) {
+	dir := setupTestEnv(t)
+	binDir := filepath.Join(dir, "bin")
+	os.MkdirAll(binDir, 0o755)
+	os.WriteFile(filepath.Join(binDir, "gh-identity-hook"), []byte("fake"), 0o755)
+
+	old := hookVersion
+	t.Cleanup(func() { hookVersion = old })
+
+	tests := []struct {
+		name     string
+		version  string
+		err      error
+		wantWarn bool
+	}{
+		{"matching", version.String(), nil, false},
+		{"stale", "v0.0.1", nil, true},
+		{"no version flag", "", errors.New("exit status 2"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hookVersion = func(string) (string, error) { return tt.version, tt.err }
+			var warned bool
+			for _, r := range checkHookBinary(&doctorContext{}) {
+				if r.Check == "hook_version" && r.Status == doctorWarn {
+					warned = true
+					if !containsStr(r.Hint, "gh identity init") {
+						t.Errorf("hint = %q, want a reinstall suggestion", r.Hint)
+					}
+				}
+			}
+			if warned != tt.wantWarn {
+				t.Errorf("warned = %v, want %v", warned, tt.wantWarn)
+			}
+		})
+	}
+}
+
+// TestRunDoctor_AllChecksPassed tests doctor with everything configured correctly.
+func TestRunDoctor_AllChecksPassed(t *testing.T) {
+	dir := setupTestEnv(t)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	writeProfiles(t, dir, `profiles:
+  good:
+    gh_user: user1
+    git_name: Good
+    git_email: good@good.com`)
+	writeBindings(t, dir, `bindings: []`)
+
+	// Create hook binary.
+	binDir := filepath.Join(dir, "bin")
+	os.MkdirAll(binDir, 0o755)
+	os.WriteFile(filepath.Join(binDir, "gh-identity-hook"), []byte("#!/bin/sh\necho "+version.String()+"\n"), 0o755)
+	os.WriteFile(filepath.Join(binDir, "gh-identity-askpass"), []byte("fake"), 0o755)
+
+	// Create shell hook in bashrc.
+	os.WriteFile(filepath.Join(tmpHome, ".bashrc"), []byte("# gh-identity hook\neval ..."), 0o644)
+
+	auth := &mockAuth{users: []string{"user1"}}
+
+	old := os.Stdout
+	r2, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := runDoctor(auth, "", false, false)
+
+	w.Close()
+	os.Stdout = old
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	buf.ReadFrom(r2)
+	output := buf.String()
+
+	if !containsStr(output, "All checks passed") {
+		t.Errorf("expected 'All checks passed', got:\n%s", output)
+	}
+}
+
+// TestRunSwitch_WithSSHKey tests switch with a profile that has an SSH key.
+func TestRunSwitch_WithSSHKey(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeProfiles(t, dir, `profiles:
+  sshuser:
+    gh_user: user1
+    git_name: SSH User
+    git_email: ssh@example.com
+    ssh_key: ~/.ssh/id_test`)
+
+	auth := &mockAuth{
+		tokens: map[string]string{"user1": "ssh-token"},
+	}
+
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := runSwitch(auth, "sshuser", hook.Bash, "")
+
+	w.Close()
+	os.Stdout = old
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	buf.ReadFrom(r)
+	output := buf.String()
+
+	if !containsStr(output, "GIT_SSH_COMMAND") {
+		t.Error("expected GIT_SSH_COMMAND in output for profile with SSH key")
+	}
+}
+
+func TestRunSwitch_Write(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: worker
+    git_name: Work User
+    git_email: work@example.com`)
+
+	file := filepath.Join(t.TempDir(), "identity.sh")
+	out, err := captureStdout(t, func() error { return runSwitch(&mockAuth{}, "work", hook.Bash, file) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != out {
+		t.Errorf("written file differs from printed output:\n%s\nvs\n%s", data, out)
+	}
+	if !containsStr(string(data), `export GH_IDENTITY_PROFILE="work"`) {
+		t.Errorf("expected profile export in file, got:\n%s", data)
+	}
+
+	if _, err := captureStdout(t, func() error { return runSwitch(&mockAuth{}, "", hook.Bash, file) }); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(file)
+	if containsStr(string(data), "export") || !containsStr(string(data), "GH_IDENTITY_PROFILE") {
+		t.Errorf("expected only unset statements after --clear, got:\n%s", data)
+	}
+
+	cmd := newSwitchCmd(&mockAuth{})
+	cmd.SetArgs([]string{"work", "--clear"})
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	if err := cmd.Execute(); err == nil {
+		t.Error("expected --clear with a profile to fail")
+	}
+}
+
+// TestRunStatus_DefaultProfile tests status with default profile fallback.
+func TestRunStatus_DefaultProfile(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeProfiles(t, dir, `profiles:
+  fallback:
+    gh_user: user1
+    git_name: Fallback
+    git_email: fb@example.com
+default: fallback`)
+	writeBindings(t, dir, `bindings: []`)
+	t.Setenv("GH_IDENTITY_PROFILE", "")
+
+	auth := &mockAuth{}
+
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := runStatus(auth, "", "", false)
+
+	w.Close()
+	os.Stdout = old
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	buf.ReadFrom(r)
+	output := buf.String()
+
+	if !containsStr(output, "fallback") {
+		t.Error("expected 'fallback' profile")
+	}
+	if !containsStr(output, "default profile") {
+		t.Error("expected 'default profile' source")
+	}
+}
+
+// TestRunProfileEdit_Interactive tests editing with prompts, keeping defaults on empty input.
+func TestRunProfileEdit_Interactive(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
+
+	// Keep gh_user and git_name, change email, leave SSH key empty.
+	setStdin(t, "\n\nnew@company.com\n\n")
+
+	if _, err := captureStdout(t, func() error {
+		return runProfileEdit("work", profileEditFlags{})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "profiles.yml"))
+	if !containsStr(string(data), "new@company.com") {
+		t.Error("expected updated email in profiles.yml")
+	}
+	if !containsStr(string(data), "User Two") {
+		t.Error("expected git_name to be kept")
+	}
+
+	frag, err := os.ReadFile(filepath.Join(dir, "git", "work.gitconfig"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsStr(string(frag), "new@company.com") {
+		t.Error("expected gitconfig fragment to be rewritten")
+	}
+}
+
+// TestRunProfileEdit_Flags tests non-interactive editing of a single field.
+func TestRunProfileEdit_Flags(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com
+    ssh_key: ~/.ssh/id_work`)
+
+	email := "scripted@company.com"
+	if _, err := captureStdout(t, func() error {
+		return runProfileEdit("work", profileEditFlags{GitEmail: &email})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "profiles.yml"))
+	content := string(data)
+	if !containsStr(content, "scripted@company.com") {
+		t.Error("expected updated email in profiles.yml")
+	}
+	if !containsStr(content, "~/.ssh/id_work") {
+		t.Error("expected ssh_key to be untouched")
+	}
+}
+
+// TestRunProfileEdit_Description tests that a description is stored and
+// listed but kept out of the gitconfig fragment.
+func TestRunProfileEdit_Description(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
+
+	desc := "Acme day job"
+	if _, err := captureStdout(t, func() error {
+		return runProfileEdit("work", profileEditFlags{Description: &desc})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := captureStdout(t, func() error { return runProfileList(false) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsStr(output, "work — Acme day job") {
+		t.Errorf("expected description in list output, got:\n%s", output)
+	}
+
+	fragment, err := os.ReadFile(filepath.Join(dir, "git", "work.gitconfig"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if containsStr(string(fragment), desc) {
+		t.Errorf("description leaked into the gitconfig fragment:\n%s", fragment)
+	}
+}
+
+// TestRunProfileEdit_NotFound tests editing a nonexistent profile.
+func TestRunProfileEdit_NotFound(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeProfiles(t, dir, `profiles: {}`)
+
+	err := runProfileEdit("nonexistent", profileEditFlags{})
+	if err == nil {
+		t.Fatal("expected error for nonexistent profile")
+	}
+	if !containsStr(err.Error(), "profile list") {
+		t.Errorf("expected hint about `profile list`, got %v", err)
+	}
+}
+
+// TestRunProfileRename tests renaming a profile and migrating its bindings and includeIfs.
+func TestRunProfileRename(t *testing.T) {
+	dir := setupTestEnv(t)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	bindDir := t.TempDir()
+	otherDir := t.TempDir()
+
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com
+  personal:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com
+default: work`)
+
+	// Bind via runBind so the fragment and includeIf exist.
+	if _, err := captureStdout(t, func() error {
+		if err := runBind(bindDir, "work", false, false); err != nil {
+			return err
+		}
+		return runBind(otherDir, "personal", false, false)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := captureStdout(t, func() error {
+		return runProfileRename("work", "job")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := profiles.Profiles["work"]; ok {
+		t.Error("old profile should be gone")
+	}
+	if _, ok := profiles.Profiles["job"]; !ok {
+		t.Error("new profile should exist")
+	}
+	if profiles.Default != "job" {
+		t.Errorf("Default = %q, want %q", profiles.Default, "job")
+	}
+
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bindings.FindBinding(bindDir); got != "job" {
+		t.Errorf("binding for %s = %q, want %q", bindDir, got, "job")
+	}
+	if got := bindings.FindBinding(otherDir); got != "personal" {
+		t.Errorf("unrelated binding = %q, want %q", got, "personal")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "git", "work.gitconfig")); !os.IsNotExist(err) {
+		t.Error("old fragment should have been removed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "git", "job.gitconfig")); err != nil {
+		t.Errorf("new fragment should exist: %v", err)
+	}
+
+	gc, err := os.ReadFile(filepath.Join(tmpHome, ".gitconfig"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(gc)
+	if containsStr(content, "work.gitconfig") {
+		t.Error("includeIf should no longer point at old fragment")
+	}
+	if !containsStr(content, filepath.Join(dir, "git", "job.gitconfig")) {
+		t.Error("includeIf should point at new fragment")
+	}
+	if !containsStr(content, "personal.gitconfig") {
+		t.Error("unrelated includeIf should be kept")
+	}
+}
+
+// TestRunProfileRename_Exists tests renaming onto an existing profile name.
+func TestRunProfileRename_Exists(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeProfiles(t, dir, `profiles:
+  a:
+    gh_user: user1
+    git_name: A
+    git_email: a@a.com
+  b:
+    gh_user: user2
+    git_name: B
+    git_email: b@b.com`)
+
+	err := runProfileRename("a", "b")
+	if err == nil || !containsStr(err.Error(), "already exists") {
+		t.Errorf("expected 'already exists' error, got %v", err)
+	}
+}
+
+// TestRunStatus_JSON tests machine-readable status output for a bound directory.
+func TestRunStatus_JSON(t *testing.T) {
+	dir := setupTestEnv(t)
+	pwd, _ := os.Getwd()
+	t.Setenv("GH_IDENTITY_PROFILE", "")
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
+	writeBindings(t, dir, `bindings:
+  - path: `+pwd+`
+    profile: work`)
+
+	output, err := captureStdout(t, func() error {
+		return runStatus(&mockAuth{}, "", "", true)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got statusJSON
+	if err := json.Unmarshal([]byte(output), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", output, err)
+	}
+	if got.Profile == nil || *got.Profile != "work" {
+		t.Errorf("profile = %v, want work", got.Profile)
+	}
+	if got.Account != "user2" {
+		t.Errorf("account = %q, want user2", got.Account)
+	}
+	if got.Source != "binding" {
+		t.Errorf("source = %q, want binding", got.Source)
+	}
+	if got.BoundPath != pwd {
+		t.Errorf("bound_path = %q, want %q", got.BoundPath, pwd)
+	}
+}
+
+// TestRunStatus_Committer tests that status shows a committer distinct
+// from the author, and omits it when the two are the same.
+func TestRunStatus_Committer(t *testing.T) {
+	dir := setupTestEnv(t)
+	pwd, _ := os.Getwd()
+	t.Setenv("GH_IDENTITY_PROFILE", "")
+	writeProfiles(t, dir, `profiles:
+  release:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com
+    committer_name: Release Bot
+    committer_email: bot@company.com
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
+	writeBindings(t, dir, `bindings:
+  - path: `+pwd+`
+    profile: release`)
+
+	output, err := captureStdout(t, func() error { return runStatus(&mockAuth{}, "", "", false) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsStr(output, "Committer: Release Bot <bot@company.com>") {
+		t.Errorf("expected committer line, got:\n%s", output)
+	}
+	if !containsStr(output, "  Email:     ") {
+		t.Errorf("expected labels aligned with Committer:, got:\n%s", output)
+	}
+
+	output, err = captureStdout(t, func() error { return runStatus(&mockAuth{}, "", "", true) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got statusJSON
+	if err := json.Unmarshal([]byte(output), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", output, err)
+	}
+	if got.CommitterName != "Release Bot" || got.CommitterEmail != "bot@company.com" {
+		t.Errorf("committer = %q <%s>, want Release Bot <bot@company.com>", got.CommitterName, got.CommitterEmail)
+	}
+
+	output, err = captureStdout(t, func() error { return runStatus(&mockAuth{}, "", "work", false) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if containsStr(output, "Committer:") {
+		t.Errorf("expected no committer line when it mirrors the author, got:\n%s", output)
+	}
+}
+
+// TestRunStatus_InferredIncludeIf tests that status falls back to a gitconfig
+// includeIf that has no bindings.yml entry.
+func TestRunStatus_InferredIncludeIf(t *testing.T) {
+	dir := setupTestEnv(t)
+	pwd, _ := os.Getwd()
+	t.Setenv("GH_IDENTITY_PROFILE", "")
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
+	writeBindings(t, dir, `bindings: []`)
+
+	gcPath := filepath.Join(t.TempDir(), "gitconfig")
+	t.Setenv("GIT_CONFIG_GLOBAL", gcPath)
+	if err := gitconfig.AddIncludeIf(gcPath, pwd, filepath.Join(dir, "git", "work.gitconfig")); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := captureStdout(t, func() error {
+		return runStatus(&mockAuth{}, "", "", true)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got statusJSON
+	if err := json.Unmarshal([]byte(output), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", output, err)
+	}
+	if got.Profile == nil || *got.Profile != "work" {
+		t.Errorf("profile = %v, want work", got.Profile)
+	}
+	if got.Source != "includeif" || got.IncludeIf != pwd {
+		t.Errorf("source = %q, include_if = %q, want includeif %q", got.Source, got.IncludeIf, pwd)
+	}
+}
+
+// TestRunStatus_EnvDrift tests that status warns when exported variables disagree with the directory.
+func TestRunStatus_EnvDrift(t *testing.T) {
+	dir := setupTestEnv(t)
+	pwd, _ := os.Getwd()
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com
+  personal:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com`)
+	writeBindings(t, dir, `bindings:
+  - path: `+pwd+`
+    profile: work`)
+	t.Setenv("GH_IDENTITY_PROFILE", "personal")
+	t.Setenv("GIT_AUTHOR_EMAIL", "user2@company.com")
+	t.Setenv("GH_TOKEN", "tok-user2")
+
+	auth := &mockAuth{tokens: map[string]string{"user1": "tok-user1", "user2": "tok-user2"}}
+	output, err := captureStdout(t, func() error { return runStatus(auth, "", "", false) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`Environment reflects profile "personal" but this directory resolves to "work"`,
+		"GIT_AUTHOR_EMAIL is user2@company.com but profile \"personal\" uses user1@example.com",
+		"GH_TOKEN does not belong to user1",
+	} {
+		if !containsStr(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+
+	// A consistent environment produces no warnings.
+	t.Setenv("GH_IDENTITY_PROFILE", "work")
+	output, err = captureStdout(t, func() error { return runStatus(auth, "", "", true) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got statusJSON
+	if err := json.Unmarshal([]byte(output), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", output, err)
+	}
+	if len(got.Warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", got.Warnings)
+	}
+}
+
+// TestRunStatus_Path tests reporting the identity of another directory.
+func TestRunStatus_Path(t *testing.T) {
+	dir := setupTestEnv(t)
+	pwd, _ := os.Getwd()
+	other := t.TempDir()
+	writeProfiles(t, dir, `profiles:
+  personal:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
+	writeBindings(t, dir, `bindings:
+  - path: `+pwd+`
+    profile: personal
+  - path: `+other+`
+    profile: work`)
+	// The environment describes the current shell, not other paths.
+	t.Setenv("GH_IDENTITY_PROFILE", "personal")
+
+	output, err := captureStdout(t, func() error { return runStatus(&mockAuth{}, filepath.Join(other, "sub"), "", true) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got statusJSON
+	if err := json.Unmarshal([]byte(output), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", output, err)
+	}
+	if got.Profile == nil || *got.Profile != "work" || got.Source != "binding" || got.BoundPath != other {
+		t.Errorf("status --path = %+v, want work bound by %s", got, other)
+	}
+	if len(got.Warnings) != 0 {
+		t.Errorf("expected no environment warnings for another path, got %v", got.Warnings)
+	}
+
+	output, err = captureStdout(t, func() error { return runStatusShort(other, "") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "work\n" {
+		t.Errorf("status --short --path = %q, want %q", output, "work\n")
+	}
+}
+
+// TestRunStatus_ShortAndCheck tests the prompt-oriented status modes.
+func TestRunStatus_ShortAndCheck(t *testing.T) {
+	dir := setupTestEnv(t)
+	pwd, _ := os.Getwd()
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
+	writeBindings(t, dir, `bindings:
+  - path: `+pwd+`
+    profile: work`)
+
+	t.Setenv("GH_IDENTITY_PROFILE", "")
+	output, err := captureStdout(t, func() error { return runStatusShort("", "") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "work\n" {
+		t.Errorf("short output = %q, want %q", output, "work\n")
+	}
+	if err := runStatusCheck(); err == nil {
+		t.Error("expected --check to fail when GH_IDENTITY_PROFILE is unset")
+	}
+
+	t.Setenv("GH_IDENTITY_PROFILE", "work")
+	if err := runStatusCheck(); err != nil {
+		t.Errorf("expected --check to pass, got %v", err)
+	}
+
+	t.Setenv("GH_IDENTITY_PROFILE", "personal")
+	if err := runStatusCheck(); err == nil || !containsStr(err.Error(), `resolves to "work"`) {
+		t.Errorf("expected drift error, got %v", err)
+	}
+	output, _ = captureStdout(t, func() error { return runStatusShort("", "") })
+	if output != "personal\n" {
+		t.Errorf("short output = %q, want the environment's profile", output)
+	}
+}
+
+// TestRunCurrent tests that current prints exactly one line, even when empty.
+func TestRunCurrent(t *testing.T) {
+	dir := setupTestEnv(t)
+	pwd, _ := os.Getwd()
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
+	writeBindings(t, dir, `bindings: []`)
+	t.Setenv("GH_IDENTITY_PROFILE", "")
+
+	output, err := captureStdout(t, func() error { return runCurrent("") })
+	if err != nil {
+		t.Fatalf("expected success with no profile, got %v", err)
+	}
+	if output != "\n" {
+		t.Errorf("output = %q, want a bare newline", output)
+	}
+
+	writeBindings(t, dir, `bindings:
+  - path: `+pwd+`
+    profile: work`)
+	output, _ = captureStdout(t, func() error { return runCurrent("") })
+	if output != "work\n" {
+		t.Errorf("output = %q, want %q", output, "work\n")
+	}
+
+	t.Setenv("GH_IDENTITY_PROFILE", "personal")
+	output, _ = captureStdout(t, func() error { return runCurrent("") })
+	if output != "personal\n" {
+		t.Errorf("output = %q, want the environment's profile", output)
+	}
+}
+
+// TestRunStatus_JSONNoProfile tests that JSON status emits a null profile.
+func TestRunStatus_JSONNoProfile(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeProfiles(t, dir, `profiles: {}`)
+	writeBindings(t, dir, `bindings: []`)
+	t.Setenv("GH_IDENTITY_PROFILE", "")
+
+	output, err := captureStdout(t, func() error {
+		return runStatus(&mockAuth{}, "", "", true)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(output) != `{
+  "profile": null
+}` {
+		t.Errorf("unexpected output: %q", output)
+	}
+}
+
+// TestRunProfileList_JSON tests machine-readable profile listing.
+func TestRunProfileList_JSON(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("GH_IDENTITY_PROFILE", "work")
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com
+  personal:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com
+    ssh_key: ~/.ssh/id_personal
+default: personal`)
+
+	output, err := captureStdout(t, func() error { return runProfileList(true) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []profileJSON
+	if err := json.Unmarshal([]byte(output), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", output, err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d profiles, want 2", len(got))
+	}
+	if got[0].Name != "personal" || got[1].Name != "work" {
+		t.Errorf("profiles not sorted by name: %+v", got)
+	}
+	if !got[0].IsDefault || got[0].IsActive {
+		t.Errorf("personal: is_default=%v is_active=%v, want true/false", got[0].IsDefault, got[0].IsActive)
+	}
+	if got[1].IsDefault || !got[1].IsActive {
+		t.Errorf("work: is_default=%v is_active=%v, want false/true", got[1].IsDefault, got[1].IsActive)
+	}
+	if got[0].SSHKey != "~/.ssh/id_personal" {
+		t.Errorf("ssh_key = %q", got[0].SSHKey)
+	}
+}
+
+// TestRunProfileList_JSONEmpty tests that an empty config yields an empty array.
+func TestRunProfileList_JSONEmpty(t *testing.T) {
+	setupTestEnv(t)
+
+	output, err := captureStdout(t, func() error { return runProfileList(true) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(output) != "[]" {
+		t.Errorf("output = %q, want []", output)
+	}
+}
+
+// TestRunProfileListVerify tests the per-profile auth and SSH key marks.
+func TestRunProfileListVerify(t *testing.T) {
+	dir := setupTestEnv(t)
+	keyDir := t.TempDir()
+	goodKey := filepath.Join(keyDir, "id_work")
+	if err := os.WriteFile(goodKey, []byte("key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	missingKey := filepath.Join(keyDir, "id_missing")
+	writeProfiles(t, dir, fmt.Sprintf(`profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com
+    ssh_key: %s
+  personal:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com
+    ssh_key: %s`, goodKey, missingKey))
+	auth := &mockAuth{users: []string{"user2"}}
+
+	output, err := captureStdout(t, func() error { return runProfileListVerify(auth, false) })
+	if err == nil || !containsStr(err.Error(), "2 profile check(s) failed") {
+		t.Errorf("err = %v, want 2 failed checks", err)
+	}
+	for _, want := range []string{
+		"✓ gh user user2 is authenticated on github.com",
+		"✓ SSH key OK (" + goodKey + ")",
+		"✗ gh user user1 is not authenticated",
+		"✗ SSH key not found: " + missingKey,
+	} {
+		if !containsStr(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+
+	output, _ = captureStdout(t, func() error { return runProfileListVerify(auth, true) })
+	var got []profileJSON
+	if err := json.Unmarshal([]byte(output), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", output, err)
+	}
+	if len(got) != 2 || len(got[1].Checks) != 2 || got[1].Checks[0].Status != doctorOK {
+		t.Errorf("unexpected checks: %+v", got)
+	}
+}
+
+// TestRunBindingsList tests listing bindings sorted by path with stale markers.
+func TestRunBindingsList(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
+	writeBindings(t, dir, `bindings:
+  - path: /z/work
+    profile: work
+  - path: /a/old
+    profile: gone`)
+
+	output, err := captureStdout(t, func() error { return runBindingsList(false) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Index(output, "/a/old") > strings.Index(output, "/z/work") {
+		t.Error("expected bindings sorted by path")
+	}
+	if !containsStr(output, "/a/old → gone ❌") {
+		t.Errorf("expected stale marker for missing profile, got:\n%s", output)
+	}
+	if containsStr(output, "/z/work → work ❌") {
+		t.Error("valid binding should not be flagged")
+	}
+}
+
+// TestRunBindingsList_JSON tests machine-readable binding output.
+func TestRunBindingsList_JSON(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeBindings(t, dir, `bindings:
+  - path: /z/work
+    profile: work
+  - path: /a/personal
+    profile: personal`)
+
+	output, err := captureStdout(t, func() error { return runBindingsList(true) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []config.Binding
+	if err := json.Unmarshal([]byte(output), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", output, err)
+	}
+	if len(got) != 2 || got[0].Path != "/a/personal" || got[1].Profile != "work" {
+		t.Errorf("unexpected bindings: %+v", got)
+	}
+}
+
+// TestRunBindingsList_Empty tests listing with no bindings.
+func TestRunBindingsList_Empty(t *testing.T) {
+	setupTestEnv(t)
+
+	output, err := captureStdout(t, func() error { return runBindingsList(true) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(output) != "[]" {
+		t.Errorf("output = %q, want []", output)
+	}
+}
+
+// TestRunDoctor_Quiet tests that quiet mode hides passing checks but keeps failures.
+func TestRunDoctor_Quiet(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
+	writeProfiles(t, dir, `profiles:
+  test:
+    gh_user: user1
+    git_name: Test
+    git_email: test@test.com`)
+
+	output, err := captureStdout(t, func() error {
+		return runDoctor(&mockAuth{users: []string{"user1"}}, "", true, false)
+	})
+	if err == nil {
+		t.Fatal("expected error when doctor finds issues")
+	}
+	if containsStr(output, "✅") {
+		t.Errorf("quiet output should not contain passing checks, got:\n%s", output)
+	}
+	if !containsStr(output, "Hook binary not found") {
+		t.Error("expected failure line in quiet output")
+	}
+	if !containsStr(output, "issue(s)") {
+		t.Error("expected final issue count")
+	}
+}
+
+// TestRunDoctor_SigningKeyMissing tests doctor flags a missing SSH signing key.
+func TestRunDoctor_SigningKeyMissing(t *testing.T) {
+	dir := setupTestEnv(t)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user1
+    git_name: Work
+    git_email: work@work.com
+    signing_key: ~/.ssh/id_signing.pub
+    signing_format: ssh`)
+
+	output, err := captureStdout(t, func() error {
+		return runDoctor(&mockAuth{users: []string{"user1"}}, "", false, false)
+	})
+	if err == nil {
+		t.Fatal("expected error when doctor finds issues")
+	}
+	if !containsStr(output, "signing key not found") {
+		t.Errorf("expected signing key error, got:\n%s", output)
+	}
+}
+
+// TestRunBindRemote tests binding and unbinding a remote URL pattern.
+func TestRunBindRemote(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
+
+	if _, err := captureStdout(t, func() error {
+		return runBindRemote("github.com/acme", "work", false, false)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bindings.Bindings) != 1 || bindings.Bindings[0].RemotePattern != "github.com/acme" {
+		t.Fatalf("unexpected bindings: %+v", bindings.Bindings)
+	}
+	gcPath, _ := gitconfig.GlobalGitconfigPath()
+	data, _ := os.ReadFile(gcPath)
+	if !containsStr(string(data), `[includeIf "hasconfig:remote.*.url:https://github.com/acme/**"]`) {
+		t.Errorf("expected remote includeIf in gitconfig, got:\n%s", data)
+	}
+
+	if _, err := captureStdout(t, func() error {
+		return runUnbindRemote("github.com/acme", false)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	bindings, _ = config.LoadBindings()
+	if len(bindings.Bindings) != 0 {
+		t.Errorf("expected no bindings, got %+v", bindings.Bindings)
+	}
+	data, _ = os.ReadFile(gcPath)
+	if containsStr(string(data), "hasconfig:remote") {
+		t.Errorf("expected remote includeIf to be removed, got:\n%s", data)
+	}
+}
+
+// TestInstallShellHook_Nu tests shell hook installation for Nushell.
+func TestInstallShellHook_Nu(t *testing.T) {
+	setupTestEnv(t)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	t.Setenv("SHELL", "/usr/bin/nu")
+
+	if err := installShellHook(detectShell()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpHome, ".config", "nushell", "env.nu"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(data)
+	if !containsStr(content, "gh-identity hook") {
+		t.Error("expected hook marker in env.nu")
+	}
+	if !containsStr(content, "--shell nu") || !containsStr(content, "load-env") {
+		t.Errorf("expected nu hook invocation, got:\n%s", content)
+	}
+
+	// Installing again must not duplicate the hook.
+	if err := installShellHook(detectShell()); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(filepath.Join(tmpHome, ".config", "nushell", "env.nu"))
+	if strings.Count(string(data), hookBeginMarker) != 1 {
+		t.Error("hook should only be installed once")
+	}
+}
+
+// TestShippedNuHookMatchesInstalled tests that shell/hook.nu, for users who
+// source it, applies the hook's output the same way the installed block does.
+func TestShippedNuHookMatchesInstalled(t *testing.T) {
+	setupTestEnv(t)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	if err := installShellHook("nu"); err != nil {
+		t.Fatal(err)
+	}
+	installed, err := os.ReadFile(filepath.Join(tmpHome, ".config", "nushell", "env.nu"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	shipped, err := os.ReadFile(filepath.Join("..", "..", "shell", "hook.nu"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// applyLines returns the trimmed lines from parsing the hook's output
+	// through loading its environment.
+	applyLines := func(s string) []string {
+		var out []string
+		for _, line := range strings.Split(s, "\n") {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "let out = ") || len(out) > 0 {
+				out = append(out, line)
+			}
+			if strings.HasPrefix(line, "load-env ") {
+				break
+			}
+		}
+		return out
+	}
+	want := applyLines(string(installed))
+	if len(want) == 0 {
+		t.Fatalf("no apply block in the installed hook:\n%s", installed)
+	}
+	if got := applyLines(string(shipped)); !reflect.DeepEqual(got, want) {
+		t.Errorf("shell/hook.nu applies the hook output as\n%s\nbut the installed hook does\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// TestRunDoctor_InvalidEmail tests doctor reports malformed profile emails.
+func TestRunDoctor_InvalidEmail(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
+	writeProfiles(t, dir, `profiles:
+  typo:
+    gh_user: user1
+    git_name: Typo
+    git_email: john@@example`)
+
+	output, err := captureStdout(t, func() error {
+		return runDoctor(&mockAuth{users: []string{"user1"}}, "", false, false)
+	})
+	if err == nil {
+		t.Fatal("expected error when doctor finds issues")
+	}
+	if !containsStr(output, `git_email "john@@example" is invalid`) {
+		t.Errorf("expected invalid email message, got:\n%s", output)
+	}
+}
+
+// TestRunUse tests setting the default profile.
+func TestRunUse(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeProfiles(t, dir, `profiles:
+  personal:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com
+default: personal`)
+
+	output, err := captureStdout(t, func() error { return runUse("work") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsStr(output, `"work"`) {
+		t.Errorf("expected new default in output, got %q", output)
+	}
+
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profiles.Default != "work" {
+		t.Errorf("Default = %q, want %q", profiles.Default, "work")
+	}
+}
+
+// TestRunUse_NotFound tests setting a nonexistent default profile.
+func TestRunUse_NotFound(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeProfiles(t, dir, `profiles: {}`)
+
+	if err := runUse("nonexistent"); err == nil {
+		t.Error("expected error for nonexistent profile")
+	}
+}
+
+// TestProfileNameFromInclude tests deriving profile names from included file names.
+func TestProfileNameFromInclude(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/home/u/.gitconfig-work", "work"},
+		{"/home/u/personal.gitconfig", "personal"},
+		{"/home/u/.gitconfig_oss", "oss"},
+		{"/cfg/acme", "acme"},
+	}
+	for _, tt := range tests {
+		if got := profileNameFromInclude(tt.path); got != tt.want {
+			t.Errorf("profileNameFromInclude(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+// TestRunImport tests adopting hand-written includeIf directives.
+func TestRunImport(t *testing.T) {
+	dir := setupTestEnv(t)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	writeProfiles(t, dir, `profiles:
+  personal:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com`)
+
+	os.WriteFile(filepath.Join(tmpHome, ".gitconfig-work"), []byte("[user]\n    name = Work Person\n    email = work@company.com\n"), 0o644)
+	os.WriteFile(filepath.Join(tmpHome, ".gitconfig-personal"), []byte("[user]\n    name = User One\n    email = user1@example.com\n"), 0o644)
+	os.WriteFile(filepath.Join(tmpHome, ".gitconfig"), []byte(`[includeIf "gitdir:~/code/work/"]
+    path = ~/.gitconfig-work
+[includeIf "gitdir:~/code/personal/"]
+    path = ~/.gitconfig-personal
+[includeIf "gitdir:/managed/"] # managed by gh-identity
+    path = /nowhere.gitconfig
+`), 0o644)
+
+	// Accept the default profile name, give a gh_user, confirm.
+	setStdin(t, "\nworkuser\ny\n")
+
+	output, err := captureStdout(t, func() error {
+		return runImport(&mockAuth{users: []string{"user1", "workuser"}})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsStr(output, `Matches existing profile "personal"`) {
+		t.Errorf("expected personal include to match existing profile, got:\n%s", output)
+	}
+
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	work, ok := profiles.Profiles["work"]
+	if !ok {
+		t.Fatal("expected imported 'work' profile")
+	}
+	if work.GHUser != "workuser" || work.GitEmail != "work@company.com" || work.GitName != "Work Person" {
+		t.Errorf("unexpected work profile: %+v", work)
+	}
+
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bindings.FindBinding(filepath.Join(tmpHome, "code", "work")); got != "work" {
+		t.Errorf("work binding = %q, want %q", got, "work")
+	}
+	if got := bindings.FindBinding(filepath.Join(tmpHome, "code", "personal")); got != "personal" {
+		t.Errorf("personal binding = %q, want %q", got, "personal")
+	}
+	if len(bindings.Bindings) != 2 {
+		t.Errorf("managed includeIf should be skipped, got %+v", bindings.Bindings)
+	}
+}
+
+// TestRunImport_GHUser tests that import offers an authenticated account as
+// the gh_user and skips an include when none is given.
+func TestRunImport_GHUser(t *testing.T) {
+	setupTestEnv(t)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	os.WriteFile(filepath.Join(tmpHome, ".gitconfig-work"), []byte("[user]\n    email = worker@company.com\n"), 0o644)
+	os.WriteFile(filepath.Join(tmpHome, ".gitconfig"), []byte(`[includeIf "gitdir:~/code/work/"]
+    path = ~/.gitconfig-work
+`), 0o644)
+
+	// Accept the default profile name and gh_user, confirm.
+	setStdin(t, "\n\ny\n")
+	output, err := captureStdout(t, func() error { return runImport(&mockAuth{users: []string{"other", "worker"}}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsStr(output, "GitHub username (gh_user) [worker]") {
+		t.Errorf("expected the matching account as the default, got:\n%s", output)
+	}
+	profiles, _ := config.LoadProfiles()
+	if got := profiles.Profiles["work"].GHUser; got != "worker" {
+		t.Errorf("gh_user = %q, want worker", got)
+	}
+
+	// With no accounts to offer, an empty answer skips the include.
+	setupTestEnv(t)
+	setStdin(t, "\n\n")
+	output, err = captureStdout(t, func() error { return runImport(&mockAuth{}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsStr(output, "needs a gh_user") || !containsStr(output, "Nothing to import.") {
+		t.Errorf("expected the include to be skipped, got:\n%s", output)
+	}
+}
+
+// TestRunImport_Declined tests that nothing is written without confirmation.
+func TestRunImport_Declined(t *testing.T) {
+	dir := setupTestEnv(t)
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	os.WriteFile(filepath.Join(tmpHome, ".gitconfig-work"), []byte("[user]\n    email = work@company.com\n"), 0o644)
+	os.WriteFile(filepath.Join(tmpHome, ".gitconfig"), []byte(`[includeIf "gitdir:~/code/work/"]
+    path = ~/.gitconfig-work
+`), 0o644)
+
+	setStdin(t, "\nworkuser\nn\n")
+
+	if _, err := captureStdout(t, func() error { return runImport(&mockAuth{}) }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "profiles.yml")); !os.IsNotExist(err) {
+		t.Error("profiles.yml should not be written when import is declined")
+	}
+}
+
+// TestRunCredentialGet tests answering a git credential request for a bound directory.
+func TestRunCredentialGet(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("GH_IDENTITY_PROFILE", "")
+	bound := t.TempDir()
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
+	writeBindings(t, dir, `bindings:
+  - path: `+bound+`
+    profile: work`)
+
+	auth := &mockAuth{tokens: map[string]string{"user2": "tok-work"}}
+	in := strings.NewReader("protocol=https\nhost=github.com\n\n")
+
+	output, err := captureStdout(t, func() error {
+		return runCredentialGet(auth, in, bound)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "username=user2\npassword=tok-work\n" {
+		t.Errorf("unexpected credential output: %q", output)
+	}
+}
+
+// TestRunCredentialGet_OtherHost tests that requests for other hosts are ignored.
+func TestRunCredentialGet_OtherHost(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com
+default: work`)
+
+	in := strings.NewReader("protocol=https\nhost=gitlab.com\n")
+	output, err := captureStdout(t, func() error {
+		return runCredentialGet(&mockAuth{}, in, t.TempDir())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "" {
+		t.Errorf("expected no output for other host, got %q", output)
+	}
+}
+
+// TestRunCredentialGet_EnterpriseProfile tests that a github.com request is
+// not answered with a profile for another host.
+func TestRunCredentialGet_EnterpriseProfile(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("GH_IDENTITY_PROFILE", "")
+	writeProfiles(t, dir, `profiles:
+  corp:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@corp.example
+    host: ghe.corp.example
+default: corp`)
+
+	in := strings.NewReader("protocol=https\nhost=github.com\n\n")
+	output, err := captureStdout(t, func() error {
+		return runCredentialGet(&mockAuth{}, in, t.TempDir())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "" {
+		t.Errorf("expected no output for a profile on another host, got %q", output)
+	}
+}
+
+// TestSetupCredentialHelper tests registering the helper in a gitconfig file.
+func TestSetupCredentialHelper(t *testing.T) {
+	gcPath := filepath.Join(t.TempDir(), ".gitconfig")
+	os.WriteFile(gcPath, []byte("[credential \"https://github.com\"]\n\thelper = !gh auth git-credential\n"), 0o644)
+
+	if err := setupCredentialHelper(gcPath); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(gcPath)
+	content := string(data)
+	if containsStr(content, "gh auth git-credential") {
+		t.Errorf("previous helper should be replaced:\n%s", content)
+	}
+	if !containsStr(content, "helper = !gh identity credential") {
+		t.Errorf("expected gh-identity helper:\n%s", content)
+	}
+}
+
+// TestRunDoctor_AskPassNotExecutable tests doctor flags a non-executable askpass helper.
+func TestRunDoctor_AskPassNotExecutable(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
+	writeProfiles(t, dir, `profiles: {}`)
+
+	binDir := filepath.Join(dir, "bin")
+	os.MkdirAll(binDir, 0o755)
+	os.WriteFile(filepath.Join(binDir, "gh-identity-askpass"), []byte("fake"), 0o644)
+
+	output, err := captureStdout(t, func() error {
+		return runDoctor(&mockAuth{}, "", false, false)
+	})
+	if err == nil {
+		t.Fatal("expected error when doctor finds issues")
+	}
+	if !containsStr(output, "Askpass helper is not executable") {
+		t.Errorf("expected askpass permission error, got:\n%s", output)
+	}
+}
+
+// TestRunClone_TargetDir tests clone passes the target dir and extra args
+// through to gh and binds the target directory.
+func TestRunClone_TargetDir(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
+
+	target := filepath.Join(t.TempDir(), "org", "repo")
+	var gotArgs []string
+	oldExec := cloneExec
+	cloneExec = func(args ...string) (bytes.Buffer, bytes.Buffer, error) {
+		gotArgs = args
+		return bytes.Buffer{}, bytes.Buffer{}, os.MkdirAll(target, 0o755)
+	}
+	t.Cleanup(func() { cloneExec = oldExec })
+
+	_, err := captureStdout(t, func() error {
+		return runClone(&mockAuth{}, "owner/repo", target, []string{"--depth", "1"}, "work")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"repo", "clone", "owner/repo", target, "--", "--depth", "1"}
+	if strings.Join(gotArgs, " ") != strings.Join(want, " ") {
+		t.Errorf("gh args = %v, want %v", gotArgs, want)
+	}
+
+	bf, err := config.LoadBindings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bf.Bindings) != 1 || bf.Bindings[0].Path != target {
+		t.Errorf("expected binding for %s, got %+v", target, bf.Bindings)
+	}
+}
+
+func TestCloneSource(t *testing.T) {
+	ssh := config.Profile{GHUser: "me", CloneProtocol: "ssh"}
+	https := config.Profile{GHUser: "me", Host: "github.corp.example", CloneProtocol: "https"}
+
+	tests := []struct {
+		repo string
+		p    config.Profile
+		want string
+	}{
+		{"owner/repo", config.Profile{GHUser: "me"}, "owner/repo"},
+		{"owner/repo", ssh, "git@github.com:owner/repo.git"},
+		{"repo", ssh, "git@github.com:me/repo.git"},
+		{"owner/repo.git", https, "https://github.corp.example/owner/repo.git"},
+		{"https://github.com/owner/repo", ssh, "https://github.com/owner/repo"},
+		{"git@github.com:owner/repo.git", https, "git@github.com:owner/repo.git"},
+		{"github.com/owner/repo", ssh, "github.com/owner/repo"},
+	}
+	for _, tt := range tests {
+		if got := cloneSource(tt.repo, tt.p); got != tt.want {
+			t.Errorf("cloneSource(%q, %s) = %q, want %q", tt.repo, tt.p.CloneProtocol, got, tt.want)
+		}
+	}
+}
+
+func TestRunStatusWatch(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("GH_IDENTITY_PROFILE", "")
+	writeProfiles(t, dir, `profiles:
+  personal:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com
+default: personal`)
+
+	// An unbuffered tick is only received once the loop is waiting for it,
+	// so each send marks the end of the previous check.
+	ticks := make(chan time.Time)
+	old := watchTicks
+	watchTicks = func() (<-chan time.Time, func()) { return ticks, func() {} }
+	t.Cleanup(func() { watchTicks = old })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	output, err := captureStdout(t, func() error {
+		go func() {
+			ticks <- time.Time{} // the initial status is printed; nothing changed
+			config.WriteFileAtomic(filepath.Join(dir, "profiles.yml"), []byte(`profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com
+default: work`), 0o644)
+			ticks <- time.Time{} // notices the change and reprints
+			ticks <- time.Time{} // the reprint is done
+			cancel()
+		}()
+		return runStatusWatch(ctx, &mockAuth{}, "", "", false)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := strings.Index(output, "Profile:   personal")
+	changed := strings.Index(output, "config changed")
+	second := strings.Index(output, "Profile:   work")
+	if first < 0 || changed < first || second < changed {
+		t.Errorf("expected status, a change notice, then the new status; got:\n%s", output)
+	}
+	if n := strings.Count(output, "config changed"); n != 1 {
+		t.Errorf("expected exactly one change notice, got %d:\n%s", n, output)
+	}
+}
+
+// TestRunStatusFormat tests that --format renders the resolved identity and
+// rejects unknown fields up front.
+func TestRunStatusFormat(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("GH_IDENTITY_PROFILE", "")
+	writeProfiles(t, dir, `profiles:
+  personal:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com
+default: personal`)
+
+	output, err := captureStdout(t, func() error {
+		return runStatusFormat("", "", "{{.Profile}} {{.Account}} <{{.Email}}> via {{.Source}}")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "personal user1 <user1@example.com> via default\n"; output != want {
+		t.Errorf("output = %q, want %q", output, want)
+	}
+
+	if err := runStatusFormat("", "", "{{.Nope}}"); err == nil || !containsStr(err.Error(), "Nope") {
+		t.Errorf("expected an error naming the unknown field, got %v", err)
+	}
+	if err := runStatusFormat("", "", "{{.Profile"); err == nil {
+		t.Error("expected an error for a malformed template")
+	}
+
+	writeProfiles(t, dir, `profiles: {}`)
+	output, err = captureStdout(t, func() error { return runStatusFormat("", "", "[{{.Profile}}]") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "[]\n" {
+		t.Errorf("output with no active profile = %q, want %q", output, "[]\n")
+	}
+}
+
+// TestRunStatus_ProfileOverride tests that --profile wins over the environment.
+func TestRunStatus_ProfileOverride(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("GH_IDENTITY_PROFILE", "personal")
+	writeProfiles(t, dir, `profiles:
+  personal:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
+
+	output, err := captureStdout(t, func() error {
+		return runStatus(&mockAuth{}, "", "work", true)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got statusJSON
+	if err := json.Unmarshal([]byte(output), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", output, err)
+	}
+	if got.Profile == nil || *got.Profile != "work" {
+		t.Errorf("profile = %v, want work", got.Profile)
+	}
+	if got.Source != "flag" {
+		t.Errorf("source = %q, want flag", got.Source)
+	}
+}
+
+// TestRootCmd_ProfileOverrideNotFound tests that an unknown --profile is rejected.
+func TestRootCmd_ProfileOverrideNotFound(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeProfiles(t, dir, `profiles: {}`)
+
+	root := NewRootCmd()
+	root.SetArgs([]string{"status", "--profile", "ghost"})
+	root.SetOut(&bytes.Buffer{})
+	root.SetErr(&bytes.Buffer{})
+
+	err := root.Execute()
+	if err == nil {
+		t.Fatal("expected error for unknown --profile")
+	}
+	if !containsStr(err.Error(), `"ghost" not found`) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// TestRootCmd_DoctorProfileNotFound tests that `doctor --profile` with an
+// unknown profile reaches doctor, whose local --profile shadows the global
+// override, instead of failing the override check.
+func TestRootCmd_DoctorProfileNotFound(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
+	writeProfiles(t, dir, `profiles: {}`)
+
+	root := NewRootCmd()
+	root.SetArgs([]string{"doctor", "--profile", "ghost"})
+	root.SetOut(&bytes.Buffer{})
+	root.SetErr(&bytes.Buffer{})
+	_, err := captureStdout(t, root.Execute)
+	if err == nil {
+		t.Fatal("expected error for unknown profile")
+	}
+	if containsStr(err.Error(), "--profile:") || !containsStr(err.Error(), `profile "ghost" not found`) {
+		t.Errorf("expected doctor's own error, got %v", err)
+	}
+}
+
+// TestRunDoctor_SSHKeysList tests doctor checks every entry of ssh_keys.
+func TestRunDoctor_SSHKeysList(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
+
+	keyPath := filepath.Join(t.TempDir(), "id_ok")
+	os.WriteFile(keyPath, []byte("key"), 0o600)
+
+	writeProfiles(t, dir, `profiles:
+  sshprof:
+    gh_user: user1
+    git_name: SSH
+    git_email: ssh@test.com
+    ssh_keys:
+      - `+keyPath+`
+      - /nonexistent/second`)
+	writeBindings(t, dir, `bindings: []`)
+
+	output, err := captureStdout(t, func() error {
+		return runDoctor(&mockAuth{users: []string{"user1"}}, "", false, false)
+	})
+	if err == nil {
+		t.Fatal("expected error when doctor finds issues")
+	}
+	if !containsStr(output, "SSH key OK ("+keyPath+")") {
+		t.Errorf("expected first key to pass, got:\n%s", output)
+	}
+	if !containsStr(output, "SSH key not found: /nonexistent/second") {
+		t.Errorf("expected second key to fail, got:\n%s", output)
+	}
+}
+
+// TestRunDoctor_JSON tests the structured doctor report.
+func TestRunDoctor_JSON(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
+	writeBindings(t, dir, `bindings: []`)
+
+	output, err := captureStdout(t, func() error {
+		return runDoctor(&mockAuth{users: []string{"user1"}}, "", false, true)
+	})
+	if err == nil {
+		t.Fatal("expected error when doctor finds issues")
+	}
+
+	var report doctorReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("invalid JSON %q: %v", output, err)
+	}
+	if report.Error+report.Warn == 0 {
+		t.Error("expected issues to be counted")
+	}
+	found := false
+	for _, r := range report.Checks {
+		if r.Check == "auth" && r.Status == doctorError && containsStr(r.Message, `"user2"`) {
+			found = true
+			if r.Hint == "" {
+				t.Error("expected a hint for the auth failure")
+			}
+		}
+	}
+	if !found {
+		t.Errorf("expected auth error for user2, got %+v", report.Checks)
+	}
+	if containsStr(output, "🩺") {
+		t.Error("JSON output should not contain the human header")
+	}
+}
+
+// countingAuth counts calls to Accounts.
+type countingAuth struct {
+	*mockAuth
+	accountsCalls int
+}
+
+func (a *countingAuth) Accounts() ([]ghauth.Account, error) {
+	a.accountsCalls++
+	return a.mockAuth.Accounts()
+}
+
+// TestRunDoctor_Profile tests scoping doctor to a single profile.
+func TestRunDoctor_Profile(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
+	goodDir, badDir := t.TempDir(), t.TempDir()
+	writeProfiles(t, dir, `profiles:
+  good:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com
+  bad:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com
+    ssh_key: /nonexistent/key`)
+	writeBindings(t, dir, `bindings:
+  - path: `+goodDir+`
+    profile: good
+  - path: `+badDir+`
+    profile: bad`)
+
+	auth := &countingAuth{mockAuth: &mockAuth{users: []string{"user1"}}}
+	output, err := captureStdout(t, func() error { return runDoctor(auth, "good", false, true) })
+	if err != nil {
+		t.Fatalf("expected the good profile to pass: %v\n%s", err, output)
+	}
+	var report doctorReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("invalid JSON %q: %v", output, err)
+	}
+	for _, r := range report.Checks {
+		switch r.Check {
+		case "tools", "config_dir", "hook_binary", "askpass", "shell_hook":
+			t.Errorf("global check %q ran under --profile", r.Check)
+		}
+		if containsStr(r.Message, `"bad"`) || containsStr(r.Message, "user2") {
+			t.Errorf("check about another profile: %+v", r)
+		}
+	}
+	if auth.accountsCalls != 1 {
+		t.Errorf("Accounts called %d times, want 1", auth.accountsCalls)
+	}
+
+	output, err = captureStdout(t, func() error { return runDoctor(auth.mockAuth, "bad", false, false) })
+	if err == nil {
+		t.Fatal("expected the bad profile to fail")
+	}
+	for _, want := range []string{"SSH key not found", `references user "user2"`} {
+		if !containsStr(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+
+	if _, err := captureStdout(t, func() error { return runDoctor(auth.mockAuth, "ghost", false, false) }); err == nil || !containsStr(err.Error(), `profile "ghost" not found`) {
+		t.Errorf("expected not-found error, got %v", err)
+	}
+}
+
+// TestExportImportBundle tests that an exported bundle re-creates profiles,
+// bindings, fragments, and includeIf directives on import.
+func TestExportImportBundle(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com
+default: work`)
+	boundDir := t.TempDir()
+	writeBindings(t, dir, `bindings:
+  - path: `+boundDir+`
+    profile: work
+  - remote: github.com/acme
+    profile: work`)
+
+	bundlePath := filepath.Join(t.TempDir(), "bundle.yml")
+	if _, err := captureStdout(t, func() error { return runExport(bundlePath) }); err != nil {
+		t.Fatal(err)
+	}
+
+	// Import into a fresh config directory and home.
+	newDir := setupTestEnv(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	if _, err := captureStdout(t, func() error { return runImportBundle(bundlePath, false) }); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profiles.Profiles["work"].GHUser != "user2" || profiles.Default != "work" {
+		t.Errorf("profiles not imported: %+v", profiles)
+	}
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bindings.Bindings) != 2 {
+		t.Errorf("expected 2 bindings, got %+v", bindings.Bindings)
+	}
+	if _, err := os.Stat(filepath.Join(newDir, "git", "work.gitconfig")); err != nil {
+		t.Errorf("fragment not written: %v", err)
+	}
+	gc, err := os.ReadFile(filepath.Join(home, ".gitconfig"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved, _ := config.ResolvePath(boundDir)
+	if !containsStr(string(gc), "gitdir:"+resolved+"/") {
+		t.Errorf("includeIf not written, got:\n%s", gc)
+	}
+}
+
+// TestImportBundle_Collision tests that existing profiles are kept unless --overwrite.
+func TestImportBundle_Collision(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: local
+    git_name: Local
+    git_email: local@example.com`)
+
+	bundlePath := filepath.Join(t.TempDir(), "bundle.yml")
+	os.WriteFile(bundlePath, []byte(`version: 1
+profiles:
+  work:
+    gh_user: remote
+    git_name: Remote
+    git_email: remote@example.com
+bindings:
+  - path: /some/dir
+    profile: work
+`), 0o644)
+
+	output, err := captureStdout(t, func() error { return runImportBundle(bundlePath, false) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsStr(output, `Profile "work" already exists`) {
+		t.Errorf("expected collision warning, got:\n%s", output)
+	}
+	profiles, _ := config.LoadProfiles()
+	if profiles.Profiles["work"].GHUser != "local" {
+		t.Error("existing profile should be kept without --overwrite")
+	}
+	bindings, _ := config.LoadBindings()
+	if len(bindings.Bindings) != 0 {
+		t.Errorf("binding to a skipped profile should not be imported, got %+v", bindings.Bindings)
+	}
+
+	if _, err := captureStdout(t, func() error { return runImportBundle(bundlePath, true) }); err != nil {
+		t.Fatal(err)
+	}
+	profiles, _ = config.LoadProfiles()
+	if profiles.Profiles["work"].GHUser != "remote" {
+		t.Error("profile should be replaced with --overwrite")
+	}
+}
+
+// TestExportImportBundle_MovesHome tests that bindings under the home
+// directory follow it to a machine with a different home.
+func TestExportImportBundle_MovesHome(t *testing.T) {
+	dir := setupTestEnv(t)
+	oldHome := t.TempDir()
+	t.Setenv("HOME", oldHome)
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
+	if _, err := captureStdout(t, func() error { return runBind(filepath.Join(oldHome, "code", "work"), "work", false, false) }); err != nil {
+		t.Fatal(err)
+	}
+
+	bundlePath := filepath.Join(t.TempDir(), "bundle.yml")
+	if _, err := captureStdout(t, func() error { return runExport(bundlePath) }); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(bundlePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsStr(string(data), "path: ~/code/work") {
+		t.Errorf("expected a home-relative path in the bundle, got:\n%s", data)
+	}
+
+	setupTestEnv(t)
+	newHome := t.TempDir()
+	t.Setenv("HOME", newHome)
+	if _, err := captureStdout(t, func() error { return runImportBundle(bundlePath, false) }); err != nil {
+		t.Fatal(err)
+	}
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bindings.FindBinding(filepath.Join(newHome, "code", "work")); got != "work" {
+		t.Errorf("binding under the new home = %q, want work (bindings: %+v)", got, bindings.Bindings)
+	}
+}
+
+// TestImportBundle_InvalidLocalProfile tests that a broken profile already
+// on the machine does not block importing valid ones, while a broken
+// profile in the bundle does.
+func TestImportBundle_InvalidLocalProfile(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
+	writeProfiles(t, dir, `profiles:
+  broken:
+    gh_user: local`)
+
+	bundlePath := filepath.Join(t.TempDir(), "bundle.yml")
+	os.WriteFile(bundlePath, []byte(`version: 1
+profiles:
+  work:
+    gh_user: remote
+    git_name: Remote
+    git_email: remote@example.com
+bindings: []
+`), 0o644)
+	if _, err := captureStdout(t, func() error { return runImportBundle(bundlePath, false) }); err != nil {
+		t.Fatalf("import should ignore the local broken profile: %v", err)
+	}
+	profiles, _ := config.LoadProfiles()
+	if _, ok := profiles.Profiles["work"]; !ok {
+		t.Error("work profile not imported")
+	}
+
+	os.WriteFile(bundlePath, []byte(`version: 1
+profiles:
+  other:
+    gh_user: remote
+bindings: []
+`), 0o644)
+	if _, err := captureStdout(t, func() error { return runImportBundle(bundlePath, false) }); err == nil {
+		t.Error("expected an error for an invalid profile in the bundle")
+	}
+}
+
+// TestHookInstallUninstall tests that uninstall reverses install and is idempotent.
+func TestHookInstallUninstall(t *testing.T) {
+	dir := setupTestEnv(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	bashrc := filepath.Join(home, ".bashrc")
+	os.WriteFile(bashrc, []byte("export EDITOR=vim\n"), 0o644)
+	for _, shell := range []string{"/bin/bash", "/usr/bin/nu", "/usr/bin/fish"} {
+		t.Setenv("SHELL", shell)
+		if err := installShellHook(detectShell()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	binDir := filepath.Join(dir, "bin")
+	os.MkdirAll(binDir, 0o755)
+	for _, name := range helperBinaries {
+		os.WriteFile(filepath.Join(binDir, name), []byte("fake"), 0o755)
+	}
+
+	output, err := captureStdout(t, runHookUninstall)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsStr(output, "Removed hook from "+bashrc) {
+		t.Errorf("expected bashrc removal to be reported, got:\n%s", output)
+	}
+
+	data, _ := os.ReadFile(bashrc)
+	if string(data) != "export EDITOR=vim\n" {
+		t.Errorf(".bashrc = %q, want original content", data)
+	}
+	nu, _ := os.ReadFile(filepath.Join(home, ".config", "nushell", "env.nu"))
+	if containsStr(string(nu), "gh-identity") {
+		t.Errorf("env.nu still contains the hook:\n%s", nu)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".config", "fish", "conf.d", "gh-identity.fish")); !os.IsNotExist(err) {
+		t.Error("fish hook file should be removed")
+	}
+	for _, name := range helperBinaries {
+		if _, err := os.Stat(filepath.Join(binDir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed", name)
+		}
+	}
+
+	// Running again is a no-op.
+	output, err = captureStdout(t, runHookUninstall)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsStr(output, "Nothing to remove") {
+		t.Errorf("expected no-op message, got:\n%s", output)
+	}
+}
+
+// TestStripHookBlock tests hook block removal keeps surrounding content.
+func TestStripHookBlock(t *testing.T) {
+	for _, block := range []string{
+		"# gh-identity hook\neval \"$(/x/gh-identity-hook --shell zsh)\"\n",
+		hookBeginMarker + "\neval \"$(/x/gh-identity-hook --shell zsh)\"\n" + hookEndMarker + "\n",
+	} {
+		in := "alias ll='ls -l'\n\n" + block + "export PATH=$PATH:/y\n"
+		got, ok := stripHookBlock(in)
+		if !ok {
+			t.Fatal("expected hook block to be found")
+		}
+		if want := "alias ll='ls -l'\nexport PATH=$PATH:/y\n"; got != want {
+			t.Errorf("stripHookBlock() = %q, want %q", got, want)
+		}
+	}
+
+	if _, ok := stripHookBlock("no hook here\n"); ok {
+		t.Error("expected no hook block")
+	}
+}
+
+// TestRunHookStatus tests that hook status reports each piece the hook
+// needs.
+func TestRunHookStatus(t *testing.T) {
+	setupTestEnv(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SHELL", "/bin/zsh")
+	t.Setenv("GH_IDENTITY_PROFILE", "")
+	os.Unsetenv("GH_IDENTITY_PROFILE")
+
+	out, err := captureStdout(t, func() error { return runHookStatus("") })
+	if err != nil {
+		t.Fatalf("runHookStatus() error = %v", err)
+	}
+	for _, want := range []string{"❌ Hook binary not found", "❌ Shell hook not found in " + filepath.Join(home, ".zshrc"), "❌ GH_IDENTITY_PROFILE is not set"} {
+		if !containsStr(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	binDir, _ := config.InstallBinDir()
+	os.MkdirAll(binDir, 0o755)
+	os.WriteFile(filepath.Join(binDir, "gh-identity-hook"), []byte("#!/bin/sh\n"), 0o755)
+	os.WriteFile(filepath.Join(home, ".zshrc"), []byte("\n"+hookBeginMarker+"\neval \"$(gh-identity-hook --shell zsh)\"\n"+hookEndMarker+"\n"), 0o644)
+	t.Setenv("GH_IDENTITY_PROFILE", "work")
+
+	out, err = captureStdout(t, func() error { return runHookStatus("") })
+	if err != nil {
+		t.Fatalf("runHookStatus() error = %v", err)
+	}
+	for _, want := range []string{"✅ Hook binary installed", "✅ Shell hook loaded by", "GH_IDENTITY_PROFILE=work"} {
+		if !containsStr(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+// TestRunMap tests adding, listing, and removing org mappings, and that
+// removing or renaming a profile keeps them consistent.
+func TestRunMap(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: worker
+    git_name: Worker
+    git_email: w@example.com
+  oss:
+    gh_user: dev
+    git_name: Dev
+    git_email: d@example.com
+`)
+
+	if err := runMapAdd("acme", "missing"); err == nil {
+		t.Error("expected an error mapping to an unknown profile")
+	}
+	for org, profile := range map[string]string{"acme": "work", "openthings": "oss"} {
+		if _, err := captureStdout(t, func() error { return runMapAdd(org, profile) }); err != nil {
+			t.Fatalf("runMapAdd(%s) error = %v", org, err)
+		}
+	}
+
+	out, err := captureStdout(t, func() error { return runMapList(false) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsStr(out, "acme → work") || !containsStr(out, "openthings → oss") {
+		t.Errorf("unexpected list output:\n%s", out)
+	}
+
+	if err := runProfileRename("work", "job"); err != nil {
+		t.Fatal(err)
+	}
+	profiles, _ := config.LoadProfiles()
+	if profiles.Orgs["acme"] != "job" {
+		t.Errorf("rename left orgs = %v", profiles.Orgs)
+	}
+
+	if _, err := captureStdout(t, func() error { return runMapRemove("acme") }); err != nil {
+		t.Fatal(err)
+	}
+	if err := runMapRemove("acme"); err == nil {
+		t.Error("expected an error removing an unmapped org")
+	}
+	profiles, _ = config.LoadProfiles()
+	if _, ok := profiles.Orgs["acme"]; ok || profiles.Orgs["openthings"] != "oss" {
+		t.Errorf("orgs after remove = %v", profiles.Orgs)
+	}
+}
+
+// TestRunProfileSet tests that profile set updates one field and rewrites
+// the gitconfig fragment, and rejects unknown fields and invalid values.
+func TestRunProfileSet(t *testing.T) {
+	dir := setupTestEnv(t)
+	t.Setenv("HOME", t.TempDir())
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`)
+
+	if _, err := captureStdout(t, func() error { return runProfileSet("work", "git_email", "two@company.com") }); err != nil {
+		t.Fatal(err)
+	}
+	profiles, _ := config.LoadProfiles()
+	if p := profiles.Profiles["work"]; p.GitEmail != "two@company.com" || p.GitName != "User Two" {
+		t.Errorf("profile after set = %+v", p)
+	}
+	fragment, err := os.ReadFile(filepath.Join(dir, "git", "work.gitconfig"))
+	if err != nil || !containsStr(string(fragment), "two@company.com") {
+		t.Errorf("fragment not rewritten: %v\n%s", err, fragment)
+	}
+
+	if err := runProfileSet("work", "email", "x@y.z"); err == nil || !containsStr(err.Error(), "git_email") {
+		t.Errorf("expected unknown-field error listing valid fields, got %v", err)
+	}
+	if err := runProfileSet("work", "git_email", "broken"); err == nil {
+		t.Error("expected an error for an invalid email")
+	}
+	if err := runProfileSet("ghost", "git_name", "X"); err == nil {
+		t.Error("expected an error for an unknown profile")
+	}
+	profiles, _ = config.LoadProfiles()
+	if got := profiles.Profiles["work"].GitEmail; got != "two@company.com" {
+		t.Errorf("failed set changed git_email to %q", got)
+	}
+}
+
+// TestRunWhich tests that which lists every candidate and marks the winner.
+func TestRunWhich(t *testing.T) {
+	dir := setupTestEnv(t)
+	root := t.TempDir()
+	org := filepath.Join(root, "org")
+	repo := filepath.Join(org, "repo")
+	other := filepath.Join(root, "other")
+	os.MkdirAll(repo, 0o755)
+
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com
+  personal:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com`)
+	writeBindings(t, dir, `bindings:
+  - path: `+root+`
+    profile: personal
+  - path: `+org+`
+    profile: work
+  - path: `+other+`
+    profile: personal`)
+
+	output, err := captureStdout(t, func() error { return runWhich(repo, false) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsStr(output, "Profile:  work") {
+		t.Errorf("expected work to win, got:\n%s", output)
+	}
+	if !containsStr(output, "✅ "+org+" → work") || !containsStr(output, "selected") {
+		t.Errorf("expected org binding to be marked selected, got:\n%s", output)
+	}
+	if !containsStr(output, "➖ "+root+" → personal") {
+		t.Errorf("expected shallower matching binding to be listed, got:\n%s", output)
+	}
+	if !containsStr(output, other+" → personal (path, no match)") {
+		t.Errorf("expected non-matching binding to be listed, got:\n%s", output)
+	}
+}
+
+func TestRunWhichJSON(t *testing.T) {
+	dir := setupTestEnv(t)
+	root := t.TempDir()
+	org := filepath.Join(root, "org")
+	repo := filepath.Join(org, "repo")
+	unbound := t.TempDir()
+	os.MkdirAll(repo, 0o755)
+
+	writeProfiles(t, dir, `profiles:
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com
+  personal:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com`)
+	writeBindings(t, dir, `bindings:
+  - path: `+root+`
+    profile: personal
+  - path: `+org+`
+    profile: work`)
+
+	which := func(path string) whichJSON {
+		t.Helper()
+		output, err := captureStdout(t, func() error { return runWhich(path, true) })
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got whichJSON
+		if err := json.Unmarshal([]byte(output), &got); err != nil {
+			t.Fatalf("invalid JSON %q: %v", output, err)
+		}
+		return got
+	}
+
+	got := which(repo)
+	if got.Profile == nil || *got.Profile != "work" {
+		t.Errorf("profile = %v, want work", got.Profile)
+	}
+	if got.BoundPath != org || got.IsDefault {
+		t.Errorf("bound_path = %q, is_default = %v; want %q, false", got.BoundPath, got.IsDefault, org)
+	}
+	want := []whichCandidateJSON{
+		{Path: root, Profile: "personal", Matches: true, Depth: got.Candidates[0].Depth},
+		{Path: org, Profile: "work", Matches: true, Depth: got.Candidates[1].Depth, Selected: true},
+	}
+	if !reflect.DeepEqual(got.Candidates, want) {
+		t.Errorf("candidates = %+v, want %+v", got.Candidates, want)
+	}
+	if got.Candidates[1].Depth <= got.Candidates[0].Depth {
+		t.Errorf("depths = %d, %d; want the deeper binding to rank higher", got.Candidates[0].Depth, got.Candidates[1].Depth)
+	}
+
+	// An unbound directory with no default still gives a complete object.
+	output, err := captureStdout(t, func() error { return runWhich(unbound, true) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsStr(output, `"profile": null`) || !containsStr(output, `"is_default": false`) || !containsStr(output, `"candidates": [`) {
+		t.Errorf("expected a well-formed object with a null profile, got:\n%s", output)
+	}
+	if got := which(unbound); got.Profile != nil || got.BoundPath != "" || len(got.Candidates) != 2 || got.Candidates[0].Matches || got.Candidates[1].Matches {
+		t.Errorf("unbound directory = %+v", got)
 	}
 }
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dotbrains/gh-identity/internal/config"
+	"github.com/dotbrains/gh-identity/internal/ghauth"
+	"github.com/dotbrains/gh-identity/internal/gitconfig"
+	"github.com/dotbrains/gh-identity/internal/resolve"
+)
+
+const (
+	// credentialHost is the host the credential helper answers for.
+	credentialHost = "github.com"
+
+	// credentialHelper is the helper command registered in gitconfig.
+	credentialHelper = "!gh identity credential"
+)
+
+func newCredentialCmd(auth ghauth.Auth) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "credential",
+		Short: "Git credential helper that supplies the bound profile's token",
+		Long: `Implements git's credential helper protocol. When git asks for github.com credentials, the profile for the current directory is resolved and its gh token is returned, so no GH_TOKEN needs to be exported.
+
+Run ` + "`gh identity credential setup`" + ` to register the helper in your global gitconfig.`,
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:    "get",
+			Short:  "Print credentials for the request on stdin",
+			Hidden: true,
+			Args:   cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				pwd, err := os.Getwd()
+				if err != nil {
+					return err
+				}
+				return runCredentialGet(auth, os.Stdin, pwd)
+			},
+		},
+		// gh-identity never stores or erases tokens; gh owns them.
+		&cobra.Command{
+			Use:    "store",
+			Hidden: true,
+			RunE:   func(cmd *cobra.Command, args []string) error { return nil },
+		},
+		&cobra.Command{
+			Use:    "erase",
+			Hidden: true,
+			RunE:   func(cmd *cobra.Command, args []string) error { return nil },
+		},
+		&cobra.Command{
+			Use:   "setup",
+			Short: "Register gh-identity as the git credential helper for github.com",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				gcPath, err := gitconfig.GlobalGitconfigPath()
+				if err != nil {
+					return err
+				}
+				if err := setupCredentialHelper(gcPath); err != nil {
+					return err
+				}
+				fmt.Printf("✅ Configured git to use gh-identity for https://%s credentials.\n", credentialHost)
+				return nil
+			},
+		},
+	)
+
+	return cmd
+}
+
+// runCredentialGet answers a git credential "get" request read from in.
+// It prints nothing when the request is not for github.com or no profile
+// applies, which tells git to fall through to the next helper. A profile
+// for another host, such as a GitHub Enterprise Server, does not apply:
+// the same username there can be a different person on github.com.
+func runCredentialGet(auth ghauth.Auth, in io.Reader, dir string) error {
+	req := parseCredentialRequest(in)
+	if req["protocol"] != "" && req["protocol"] != "https" {
+		return nil
+	}
+	if req["host"] != credentialHost {
+		return nil
+	}
+
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		return err
+	}
+	gitconfig.InferBindings(bindings)
+	res, err := resolve.Active(dir, bindings, profiles, os.Getenv("GH_IDENTITY_PROFILE"))
+	if err != nil {
+		return err
+	}
+	if res.Profile == "" {
+		return nil
+	}
+
+	profile, err := profiles.GetProfile(res.Profile)
+	if err != nil {
+		return err
+	}
+	if profile.GHHost() != req["host"] {
+		return nil
+	}
+	token, err := auth.Token(profile.GHHost(), profile.GHUser)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("username=%s\n", profile.GHUser)
+	fmt.Printf("password=%s\n", token)
+	return nil
+}
+
+// parseCredentialRequest reads key=value lines until a blank line or EOF.
+func parseCredentialRequest(in io.Reader) map[string]string {
+	req := make(map[string]string)
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			req[key] = value
+		}
+	}
+	return req
+}
+
+// setupCredentialHelper registers the helper for github.com in the given
+// gitconfig. The leading empty helper resets any previously configured
+// helpers for that URL, mirroring `gh auth setup-git`.
+func setupCredentialHelper(gitconfigPath string) error {
+	key := fmt.Sprintf("credential.https://%s.helper", credentialHost)
+	if out, err := exec.Command("git", "config", "--file", gitconfigPath, "--replace-all", key, "").CombinedOutput(); err != nil {
+		return fmt.Errorf("git config: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	if out, err := exec.Command("git", "config", "--file", gitconfigPath, "--add", key, credentialHelper).CombinedOutput(); err != nil {
+		return fmt.Errorf("git config: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
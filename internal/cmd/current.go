@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func newCurrentCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:         "current",
+		Short:       "Print the active profile name for scripts",
+		Annotations: honorsProfileOverride,
+		Long:        "Print only the active profile name: GH_IDENTITY_PROFILE if set, otherwise the profile the current directory resolves to. The output is always exactly one line, empty when no profile applies, and the command exits 0 in that case too. It never calls gh.",
+		Args:        cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCurrent(profileOverride(cmd))
+		},
+	}
+}
+
+func runCurrent(override string) error {
+	name, err := activeProfileName(override)
+	if err != nil {
+		return err
+	}
+	fmt.Println(name)
+	return nil
+}
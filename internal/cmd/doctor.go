@@ -1,180 +1,941 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/dotbrains/gh-identity/internal/config"
 	"github.com/dotbrains/gh-identity/internal/ghauth"
 	"github.com/dotbrains/gh-identity/internal/gitconfig"
+	"github.com/dotbrains/gh-identity/internal/hook"
+	"github.com/dotbrains/gh-identity/internal/resolve"
+	"github.com/dotbrains/gh-identity/internal/version"
 )
 
+// Doctor check statuses.
+const (
+	doctorOK    = "ok"
+	doctorWarn  = "warn"
+	doctorError = "error"
+)
+
+// doctorResult is the outcome of a single doctor check.
+type doctorResult struct {
+	Check   string `json:"check"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// doctorReport is the machine-readable form of `doctor --json`.
+type doctorReport struct {
+	Checks []doctorResult `json:"checks"`
+	OK     int            `json:"ok"`
+	Warn   int            `json:"warn"`
+	Error  int            `json:"error"`
+}
+
+func okResult(check, format string, a ...any) doctorResult {
+	return doctorResult{Check: check, Status: doctorOK, Message: fmt.Sprintf(format, a...)}
+}
+
+func warnResult(check, format string, a ...any) doctorResult {
+	return doctorResult{Check: check, Status: doctorWarn, Message: fmt.Sprintf(format, a...)}
+}
+
+func errorResult(check, format string, a ...any) doctorResult {
+	return doctorResult{Check: check, Status: doctorError, Message: fmt.Sprintf(format, a...)}
+}
+
+// forProfile prefixes the result's message with the profile it is about.
+func (r doctorResult) forProfile(name string) doctorResult {
+	r.Message = fmt.Sprintf("Profile %q: %s", name, r.Message)
+	return r
+}
+
+// withHint attaches a suggested fix to a result.
+func (r doctorResult) withHint(format string, a ...any) doctorResult {
+	r.Hint = fmt.Sprintf(format, a...)
+	return r
+}
+
+// doctorContext is the state shared by doctor checks.
+type doctorContext struct {
+	auth        ghauth.Auth
+	profiles    *config.ProfilesFile
+	profilesErr error
+
+	// only restricts per-profile checks to one profile (doctor --profile).
+	only string
+
+	accountsDone bool
+	accountList  []ghauth.Account
+	accountsErr  error
+}
+
+// accounts returns the authenticated gh accounts, asking gh only once per
+// doctor run.
+func (c *doctorContext) accounts() ([]ghauth.Account, error) {
+	if !c.accountsDone {
+		c.accountList, c.accountsErr = c.auth.Accounts()
+		c.accountsDone = true
+	}
+	return c.accountList, c.accountsErr
+}
+
+// includes reports whether checks should cover the named profile.
+func (c *doctorContext) includes(profile string) bool {
+	return c.only == "" || c.only == profile
+}
+
+// profileNames returns the configured profile names in sorted order, or
+// just the --profile one.
+func (c *doctorContext) profileNames() []string {
+	if c.profiles == nil {
+		return nil
+	}
+	if c.only != "" {
+		return []string{c.only}
+	}
+	names := make([]string, 0, len(c.profiles.Profiles))
+	for name := range c.profiles.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// doctorCheck runs one group of checks.
+type doctorCheck func(c *doctorContext) []doctorResult
+
+// doctorChecks are run in order by `gh identity doctor`.
+var doctorChecks = []doctorCheck{
+	checkTools,
+	checkConfigDir,
+	checkProfiles,
+	checkProfileAuth,
+	checkTokenScopes,
+	checkEnvToken,
+	checkSSHKeys,
+	checkSigningKeys,
+	checkHookBinary,
+	checkAskPass,
+	checkShellHook,
+	checkBindings,
+	checkIncludeIfs,
+}
+
+// profileDoctorChecks are the doctorChecks that run under --profile. The
+// rest check installation-wide state that no single profile owns.
+var profileDoctorChecks = []doctorCheck{
+	checkProfiles,
+	checkProfileAuth,
+	checkTokenScopes,
+	checkSSHKeys,
+	checkSigningKeys,
+	checkBindings,
+	checkIncludeIfs,
+}
+
+var (
+	// minGHVersion is the first gh release with `gh auth switch --user` and
+	// multi-account `gh auth status`.
+	minGHVersion = [3]int{2, 40, 0}
+	// minGitVersion is the first git release that understands the
+	// hasconfig:remote.*.url includeIfs written for remote bindings.
+	minGitVersion = [3]int{2, 36, 0}
+)
+
+// toolVersion runs `<name> --version` and returns the first line of output.
+// It is a variable so tests do not depend on the installed tools.
+var toolVersion = func(name string) (string, error) {
+	out, err := exec.Command(name, "--version").Output()
+	if err != nil {
+		return "", err
+	}
+	line, _, _ := strings.Cut(string(out), "\n")
+	return strings.TrimSpace(line), nil
+}
+
+var versionPattern = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// parseVersion extracts the first major.minor[.patch] number from s.
+func parseVersion(s string) ([3]int, bool) {
+	var v [3]int
+	m := versionPattern.FindStringSubmatch(s)
+	if m == nil {
+		return v, false
+	}
+	for i := range v {
+		v[i], _ = strconv.Atoi(m[i+1])
+	}
+	return v, true
+}
+
+// versionAtLeast reports whether v >= min.
+func versionAtLeast(v, min [3]int) bool {
+	for i := range v {
+		if v[i] != min[i] {
+			return v[i] > min[i]
+		}
+	}
+	return true
+}
+
+func formatVersion(v [3]int) string {
+	return fmt.Sprintf("%d.%d.%d", v[0], v[1], v[2])
+}
+
+// checkTools verifies gh and git are installed and new enough.
+func checkTools(c *doctorContext) []doctorResult {
+	var results []doctorResult
+
+	out, err := toolVersion("gh")
+	switch {
+	case errors.Is(err, exec.ErrNotFound):
+		results = append(results, errorResult("tools", "gh is not installed or not on PATH.").
+			withHint("Install the GitHub CLI from https://cli.github.com, then run `gh auth login`."))
+	case err != nil:
+		results = append(results, errorResult("tools", "Cannot run `gh --version`: %v", err))
+	default:
+		if v, ok := parseVersion(out); ok && !versionAtLeast(v, minGHVersion) {
+			results = append(results, warnResult("tools", "gh %s is older than %s; `gh auth switch --user` and multi-account `gh auth status` may not work.", formatVersion(v), formatVersion(minGHVersion)).
+				withHint("Upgrade the GitHub CLI: https://cli.github.com"))
+		} else {
+			results = append(results, okResult("tools", "%s", out))
+		}
+	}
+
+	out, err = toolVersion("git")
+	switch {
+	case errors.Is(err, exec.ErrNotFound):
+		results = append(results, errorResult("tools", "git is not installed or not on PATH.").
+			withHint("Install git from https://git-scm.com/downloads."))
+	case err != nil:
+		results = append(results, errorResult("tools", "Cannot run `git --version`: %v", err))
+	default:
+		if v, ok := parseVersion(out); ok && !versionAtLeast(v, minGitVersion) && hasRemoteBindings() {
+			results = append(results, warnResult("tools", "git %s is older than %s; remote bindings will not apply to plain git commands.", formatVersion(v), formatVersion(minGitVersion)).
+				withHint("Upgrade git, or use directory bindings instead."))
+		} else {
+			results = append(results, okResult("tools", "%s", out))
+		}
+	}
+
+	return results
+}
+
+// hasRemoteBindings reports whether bindings.yml contains any remote binding.
+func hasRemoteBindings() bool {
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		return false
+	}
+	for _, b := range bindings.Bindings {
+		if b.IsRemote() {
+			return true
+		}
+	}
+	return false
+}
+
 func newDoctorCmd(auth ghauth.Auth) *cobra.Command {
-	return &cobra.Command{
-		Use:   "doctor",
-		Short: "Validate the full gh-identity setup",
+	var quiet, jsonOut, fix, repairGitconfig bool
+	var profile string
+
+	cmd := &cobra.Command{
+		Use:          "doctor",
+		Short:        "Validate the full gh-identity setup",
+		Long:         "Validate the full gh-identity setup. Exits non-zero if any issues are found.\n\nWith --profile, only that profile's checks run: validation, auth, token, SSH and signing keys, bindings, and includeIf directives. Tool, config directory, hook, and askpass checks are skipped.",
+		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runDoctor(auth)
+			if fix {
+				if err := runDoctorFix(jsonOut); err != nil {
+					return err
+				}
+			}
+			if repairGitconfig {
+				if err := runDoctorRepairGitconfig(jsonOut); err != nil {
+					return err
+				}
+			}
+			return runDoctor(auth, profile, quiet, jsonOut)
 		},
 	}
+
+	cmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Only print failures and the final count")
+	cmd.Flags().BoolVar(&jsonOut, "json", false, "Output as JSON")
+	cmd.Flags().BoolVar(&fix, "fix", false, "Repair issues that can be fixed automatically before checking")
+	cmd.Flags().BoolVar(&repairGitconfig, "repair-gitconfig", false, "Rebuild every managed includeIf directive from bindings.yml before checking")
+	cmd.Flags().StringVar(&profile, "profile", "", "Only check this profile")
+	return cmd
 }
 
-func runDoctor(auth ghauth.Auth) error {
-	fmt.Println("🩺 gh-identity doctor")
-	fmt.Println()
+// runDoctorFix repairs what doctor can fix without asking: relative binding
+// paths are rewritten as the absolute paths they resolve to. Nothing is
+// printed in JSON mode so the report stays parseable.
+func runDoctorFix(jsonOut bool) error {
+	unlock, err := config.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
-	issues := 0
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		return err
+	}
+	relative := bindings.Relative()
+	if len(relative) == 0 {
+		return nil
+	}
+	if err := bindings.Save(); err != nil {
+		return err
+	}
+	if jsonOut {
+		return nil
+	}
+	for _, r := range relative {
+		fmt.Printf("🔧 Rewrote binding %s → %s\n", r.Original, r.Resolved)
+	}
+	fmt.Println()
+	return nil
+}
 
-	// Check 1: Config directory exists.
-	configDir, err := config.Dir()
+// runDoctorRepairGitconfig reconciles the global gitconfig with
+// bindings.yml: it removes every managed includeIf directive, rewrites each
+// profile's fragment, and adds one directive per binding. Directives without
+// the gh-identity marker are never touched.
+func runDoctorRepairGitconfig(jsonOut bool) error {
+	unlock, err := config.Lock()
 	if err != nil {
-		fmt.Printf("❌ Cannot determine config directory: %v\n", err)
-		issues++
-	} else if _, err := os.Stat(configDir); os.IsNotExist(err) {
-		fmt.Printf("❌ Config directory does not exist: %s\n", configDir)
-		fmt.Println("   Run `gh identity init` to set up.")
-		issues++
-	} else {
-		fmt.Printf("✅ Config directory: %s\n", configDir)
+		return err
 	}
+	defer unlock()
 
-	// Check 2: Profiles file.
 	profiles, err := config.LoadProfiles()
 	if err != nil {
-		fmt.Printf("❌ Cannot load profiles: %v\n", err)
-		issues++
-	} else if len(profiles.Profiles) == 0 {
-		fmt.Println("⚠️  No profiles configured.")
-		issues++
-	} else {
-		fmt.Printf("✅ %d profile(s) configured.\n", len(profiles.Profiles))
+		return err
+	}
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		return err
+	}
+	gcPath, err := gitconfig.GlobalGitconfigPath()
+	if err != nil {
+		return err
+	}
+	gitDir, err := config.GitConfigDir()
+	if err != nil {
+		return err
+	}
 
-		// Validate required fields.
-		if errs := profiles.Validate(); len(errs) > 0 {
-			for _, e := range errs {
-				fmt.Printf("❌ %s\n", e)
-				issues++
-			}
+	removed, err := gitconfig.RemoveManagedIncludeIfs(gcPath)
+	if err != nil {
+		return fmt.Errorf("removing includeIf directives: %w", err)
+	}
+	for name, p := range profiles.Profiles {
+		if err := gitconfig.WriteProfileFragment(name, p); err != nil {
+			return fmt.Errorf("writing gitconfig fragment: %w", err)
 		}
 	}
 
-	// Check 3: All profiles reference authenticated gh accounts.
-	if profiles != nil {
-		authedUsers, err := auth.AuthenticatedUsers()
-		if err != nil {
-			fmt.Printf("⚠️  Cannot list authenticated users: %v\n", err)
-		} else {
-			authedSet := make(map[string]bool)
-			for _, u := range authedUsers {
-				authedSet[u] = true
+	// A hand-written directive for a bound directory stays as it is.
+	own := make(map[string]bool)
+	includes, err := gitconfig.ParseIncludeIfs(gcPath)
+	if err != nil {
+		return err
+	}
+	for _, inc := range includes {
+		own[strings.TrimSuffix(inc.Dir, "/")] = true
+	}
+
+	var actions []string
+	seen := make(map[string]bool)
+	for _, b := range bindings.Bindings {
+		if b.IsLocal() {
+			continue
+		}
+		if _, ok := profiles.Profiles[b.Profile]; !ok {
+			continue
+		}
+		fragmentPath := filepath.Join(gitDir, b.Profile+".gitconfig")
+		if b.IsRemote() {
+			// Only the first binding for a target is used, so it alone
+			// gets a directive.
+			key := "remote:" + resolve.NormalizeRemote(b.RemotePattern)
+			if seen[key] {
+				continue
 			}
-			for name, p := range profiles.Profiles {
-				if !authedSet[p.GHUser] {
-					fmt.Printf("❌ Profile %q references user %q which is not authenticated.\n", name, p.GHUser)
-					fmt.Printf("   Run `gh auth login` to authenticate as %s.\n", p.GHUser)
-					issues++
-				}
+			seen[key] = true
+			if err := gitconfig.AddIncludeIfRemote(gcPath, b.RemotePattern, fragmentPath); err != nil {
+				return fmt.Errorf("adding includeIf directive: %w", err)
 			}
+			actions = append(actions, fmt.Sprintf("Added includeIf for remote %s → %s", b.RemotePattern, b.Profile))
+			continue
+		}
+
+		resolved, err := config.ResolveConfigPath(b.Path)
+		if err != nil {
+			continue
+		}
+		key := "path:" + config.FoldPath(filepath.Clean(resolved))
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if own[resolved] {
+			actions = append(actions, fmt.Sprintf("Left your own includeIf for %s alone", resolved))
+			continue
 		}
+		if err := gitconfig.AddIncludeIf(gcPath, resolved, fragmentPath); err != nil {
+			return fmt.Errorf("adding includeIf directive: %w", err)
+		}
+		actions = append(actions, fmt.Sprintf("Added includeIf for %s → %s", resolved, b.Profile))
 	}
 
-	// Check 4: SSH keys exist.
-	if profiles != nil {
-		for name, p := range profiles.Profiles {
-			if p.SSHKey != "" {
-				expanded, err := config.ExpandPath(p.SSHKey)
-				if err != nil {
-					fmt.Printf("❌ Profile %q: cannot expand SSH key path %q: %v\n", name, p.SSHKey, err)
-					issues++
-					continue
-				}
-				info, err := os.Stat(expanded)
-				if os.IsNotExist(err) {
-					fmt.Printf("❌ Profile %q: SSH key not found: %s\n", name, expanded)
-					issues++
-				} else if err != nil {
-					fmt.Printf("❌ Profile %q: cannot stat SSH key: %v\n", name, err)
-					issues++
-				} else if info.Mode().Perm()&0o077 != 0 {
-					fmt.Printf("⚠️  Profile %q: SSH key %s has overly permissive permissions (%o).\n", name, expanded, info.Mode().Perm())
-					fmt.Println("   Run: chmod 600", expanded)
-					issues++
-				} else {
-					fmt.Printf("✅ Profile %q: SSH key OK (%s)\n", name, expanded)
-				}
+	if jsonOut {
+		return nil
+	}
+	fmt.Printf("🔧 Removed %d managed includeIf directive(s) from %s\n", removed, gcPath)
+	for _, a := range actions {
+		fmt.Printf("🔧 %s\n", a)
+	}
+	fmt.Println()
+	return nil
+}
+
+// runDoctor runs every check, or with profile set only the checks of that
+// profile.
+func runDoctor(auth ghauth.Auth, profile string, quiet, jsonOut bool) error {
+	c := &doctorContext{auth: auth, only: profile}
+	c.profiles, c.profilesErr = config.LoadProfiles()
+	if c.profilesErr != nil {
+		c.profiles = nil
+	}
+
+	checks := doctorChecks
+	if profile != "" {
+		if c.profilesErr != nil {
+			return c.profilesErr
+		}
+		if _, err := c.profiles.GetProfile(profile); err != nil {
+			return fmt.Errorf("profile %q not found — run `gh identity profile list` to see available profiles", profile)
+		}
+		checks = profileDoctorChecks
+	}
+
+	report := doctorReport{Checks: []doctorResult{}}
+	for _, check := range checks {
+		for _, r := range check(c) {
+			report.Checks = append(report.Checks, r)
+			switch r.Status {
+			case doctorOK:
+				report.OK++
+			case doctorWarn:
+				report.Warn++
+			case doctorError:
+				report.Error++
 			}
 		}
 	}
+	issues := report.Warn + report.Error
 
-	// Check 5: Shell hook binary.
-	binDir, err := config.BinDir()
-	if err == nil {
-		hookBin := filepath.Join(binDir, "gh-identity-hook")
-		if _, err := os.Stat(hookBin); os.IsNotExist(err) {
-			fmt.Printf("❌ Hook binary not found: %s\n", hookBin)
-			fmt.Println("   Run `gh identity init` to install it.")
-			issues++
+	if jsonOut {
+		if err := printJSON(report); err != nil {
+			return err
+		}
+	} else {
+		printDoctorReport(report, quiet)
+	}
+
+	if issues > 0 {
+		return fmt.Errorf("doctor found %d issue(s)", issues)
+	}
+	return nil
+}
+
+// printDoctorReport renders a report for humans.
+func printDoctorReport(report doctorReport, quiet bool) {
+	if !quiet {
+		fmt.Println("🩺 gh-identity doctor")
+		fmt.Println()
+	}
+
+	for _, r := range report.Checks {
+		var icon string
+		switch r.Status {
+		case doctorOK:
+			if quiet {
+				continue
+			}
+			icon = "✅"
+		case doctorWarn:
+			icon = "⚠️ "
+		default:
+			icon = "❌"
+		}
+		fmt.Printf("%s %s\n", icon, r.Message)
+		if r.Hint != "" {
+			fmt.Printf("   %s\n", r.Hint)
+		}
+	}
+
+	if !quiet {
+		fmt.Println()
+	}
+	if issues := report.Warn + report.Error; issues > 0 {
+		fmt.Printf("Found %d issue(s).\n", issues)
+	} else {
+		fmt.Println("✅ All checks passed!")
+	}
+}
+
+func checkConfigDir(c *doctorContext) []doctorResult {
+	configDir, err := config.Dir()
+	if err != nil {
+		return []doctorResult{errorResult("config_dir", "Cannot determine config directory: %v", err)}
+	}
+	if _, err := os.Stat(configDir); os.IsNotExist(err) {
+		return []doctorResult{errorResult("config_dir", "Config directory does not exist: %s", configDir).
+			withHint("Run `gh identity init` to set up.")}
+	}
+	return []doctorResult{okResult("config_dir", "Config directory: %s", configDir)}
+}
+
+func checkProfiles(c *doctorContext) []doctorResult {
+	if c.profilesErr != nil {
+		return []doctorResult{errorResult("profiles", "Cannot load profiles: %v", c.profilesErr)}
+	}
+	if len(c.profiles.Profiles) == 0 {
+		return []doctorResult{warnResult("profiles", "No profiles configured.")}
+	}
+
+	pf := c.profiles
+	results := []doctorResult{okResult("profiles", "%d profile(s) configured.", len(pf.Profiles))}
+	if c.only != "" {
+		pf = &config.ProfilesFile{Profiles: map[string]config.Profile{c.only: c.profiles.Profiles[c.only]}}
+		results = []doctorResult{okResult("profiles", "Profile %q configured.", c.only)}
+	}
+	errs := pf.Validate()
+	sort.Strings(errs)
+	for _, e := range errs {
+		results = append(results, errorResult("profiles", "%s", e))
+	}
+	return results
+}
+
+// checkProfileAuth verifies all profiles reference authenticated gh accounts.
+func checkProfileAuth(c *doctorContext) []doctorResult {
+	if c.profiles == nil {
+		return nil
+	}
+	accounts, err := c.accounts()
+	if err != nil {
+		return []doctorResult{warnResult("auth", "Cannot list authenticated users: %v", err)}
+	}
+	authedSet := make(map[ghauth.Account]bool)
+	for _, a := range accounts {
+		authedSet[a] = true
+	}
+
+	var results []doctorResult
+	for _, name := range c.profileNames() {
+		p := c.profiles.Profiles[name]
+		if authedSet[ghauth.Account{Host: p.GHHost(), User: p.GHUser}] {
+			continue
+		}
+		if p.GHHost() == config.DefaultHost {
+			results = append(results, errorResult("auth", "Profile %q references user %q which is not authenticated.", name, p.GHUser).
+				withHint("Run `gh auth login` to authenticate as %s.", p.GHUser))
 		} else {
-			fmt.Printf("✅ Hook binary: %s\n", hookBin)
+			results = append(results, errorResult("auth", "Profile %q references user %q on %s which is not authenticated.", name, p.GHUser, p.Host).
+				withHint("Run `gh auth login --hostname %s` to authenticate as %s.", p.Host, p.GHUser))
 		}
 	}
+	return results
+}
 
-	// Check 6: Shell hook installed.
-	home, err := os.UserHomeDir()
-	if err == nil {
-		hookInstalled := false
-		shellConfigs := []string{
-			filepath.Join(home, ".config", "fish", "conf.d", "gh-identity.fish"),
-			filepath.Join(home, ".bashrc"),
-			filepath.Join(home, ".zshrc"),
-		}
-		for _, rc := range shellConfigs {
-			content, err := os.ReadFile(rc)
-			if err == nil && contains(string(content), "gh-identity") {
-				hookInstalled = true
-				fmt.Printf("✅ Shell hook installed in %s\n", rc)
+// requiredScopes are the OAuth scopes a classic token needs for gh-identity's
+// users to clone and push private repositories.
+var requiredScopes = []string{"repo"}
+
+// checkTokenScopes verifies that each profile's token for its host is still
+// accepted and carries requiredScopes.
+func checkTokenScopes(c *doctorContext) []doctorResult {
+	if c.profiles == nil {
+		return nil
+	}
+	accounts, err := c.accounts()
+	if err != nil {
+		return nil // reported by checkProfileAuth
+	}
+	authed := make(map[ghauth.Account]bool)
+	for _, a := range accounts {
+		authed[a] = true
+	}
+
+	var results []doctorResult
+	checked := make(map[ghauth.Account]bool)
+	for _, name := range c.profileNames() {
+		p := c.profiles.Profiles[name]
+		account := ghauth.Account{Host: p.GHHost(), User: p.GHUser}
+		if !authed[account] || checked[account] {
+			continue
+		}
+		checked[account] = true
+
+		// gh needs the host named for accounts off github.com.
+		refresh := "gh auth refresh -u " + p.GHUser
+		if account.Host != ghauth.DefaultHost {
+			refresh = "gh auth refresh -h " + account.Host + " -u " + p.GHUser
+		}
+		scopes, err := c.auth.TokenScopes(account.Host, p.GHUser)
+		if err != nil {
+			results = append(results, errorResult("token", "Token for %q was rejected: %v", p.GHUser, err).
+				withHint("Run `%s` or `gh auth login` to renew it.", refresh))
+			continue
+		}
+		if scopes == nil {
+			continue // fine-grained tokens don't report scopes
+		}
+		var missing []string
+		for _, want := range requiredScopes {
+			if !slices.Contains(scopes, want) {
+				missing = append(missing, want)
 			}
 		}
-		if !hookInstalled {
-			fmt.Println("⚠️  Shell hook not detected in any shell config.")
-			fmt.Println("   Run `gh identity init` to install it.")
-			issues++
+		if len(missing) > 0 {
+			results = append(results, warnResult("token", "Token for %q is missing scope(s): %s", p.GHUser, strings.Join(missing, ", ")).
+				withHint("Run `%s -s %s` to grant them.", refresh, strings.Join(missing, ",")))
+		}
+	}
+	return results
+}
+
+// checkEnvToken warns about a GH_TOKEN that gh-identity did not export. gh
+// prefers it over the keyring, so it wins over the bound account.
+func checkEnvToken(c *doctorContext) []doctorResult {
+	if !hook.ForeignToken() {
+		return nil
+	}
+	return []doctorResult{warnResult("gh_token", "GH_TOKEN is set outside gh-identity; gh uses it instead of the bound profile's account.").
+		withHint("Remove the GH_TOKEN export from your shell startup files or CI environment.")}
+}
+
+func checkSSHKeys(c *doctorContext) []doctorResult {
+	var results []doctorResult
+	for _, name := range c.profileNames() {
+		for _, r := range sshKeyResults(c.profiles.Profiles[name]) {
+			results = append(results, r.forProfile(name))
+		}
+	}
+	return results
+}
+
+// sshKeyResults checks that a profile's SSH keys exist with safe permissions
+// and that its host alias resolves to an existing key. Messages omit the
+// profile name; see forProfile.
+func sshKeyResults(p config.Profile) []doctorResult {
+	var results []doctorResult
+	for _, key := range p.AllSSHKeys() {
+		expanded, err := config.ExpandConfigPath(key)
+		if err != nil {
+			results = append(results, errorResult("ssh_key", "cannot expand SSH key path %q: %v", key, err))
+			continue
+		}
+		info, err := os.Stat(expanded)
+		if os.IsNotExist(err) {
+			results = append(results, errorResult("ssh_key", "SSH key not found: %s", expanded))
+		} else if err != nil {
+			results = append(results, errorResult("ssh_key", "cannot stat SSH key: %v", err))
+		} else if info.Mode().Perm()&0o077 != 0 {
+			results = append(results, warnResult("ssh_key", "SSH key %s has overly permissive permissions (%o).", expanded, info.Mode().Perm()).
+				withHint("Run: chmod 600 %s", expanded))
+		} else {
+			results = append(results, okResult("ssh_key", "SSH key OK (%s)", expanded))
+		}
+	}
+	if p.SSHHostAlias != "" {
+		ids, err := hook.SSHHostIdentities(p.SSHHostAlias)
+		if err != nil {
+			results = append(results, errorResult("ssh_key", "cannot resolve SSH host alias %q: %v", p.SSHHostAlias, err))
+		} else if len(ids) == 0 {
+			results = append(results, errorResult("ssh_key", "SSH host alias %q has no existing IdentityFile in ~/.ssh/config", p.SSHHostAlias))
+		} else {
+			results = append(results, okResult("ssh_key", "SSH host alias %s → %s", p.SSHHostAlias, strings.Join(ids, ", ")))
 		}
 	}
+	return results
+}
 
-	// Check 7: Bindings reference valid profiles.
+// checkSigningKeys verifies SSH signing keys exist. openpgp/x509 keys are
+// key IDs, not paths.
+func checkSigningKeys(c *doctorContext) []doctorResult {
+	var results []doctorResult
+	for _, name := range c.profileNames() {
+		p := c.profiles.Profiles[name]
+		if p.SigningKey == "" || p.SigningFormat != "ssh" {
+			continue
+		}
+		expanded, err := config.ExpandConfigPath(p.SigningKey)
+		if err != nil {
+			results = append(results, errorResult("signing_key", "Profile %q: cannot expand signing key path %q: %v", name, p.SigningKey, err))
+			continue
+		}
+		if _, err := os.Stat(expanded); err != nil {
+			results = append(results, errorResult("signing_key", "Profile %q: signing key not found: %s", name, expanded))
+		} else {
+			results = append(results, okResult("signing_key", "Profile %q: signing key OK (%s)", name, expanded))
+		}
+	}
+	return results
+}
+
+func checkHookBinary(c *doctorContext) []doctorResult {
+	binDir, err := config.BinDir()
+	if err != nil {
+		return nil
+	}
+	hookBin := filepath.Join(binDir, "gh-identity-hook")
+	if _, err := os.Stat(hookBin); os.IsNotExist(err) {
+		return []doctorResult{errorResult("hook_binary", "Hook binary not found: %s", hookBin).
+			withHint("Run `gh identity init` to install it.")}
+	}
+	results := []doctorResult{okResult("hook_binary", "Hook binary: %s", hookBin)}
+
+	want := version.String()
+	got, err := hookVersion(hookBin)
+	switch {
+	case err != nil:
+		results = append(results, warnResult("hook_version", "Hook binary does not report a version; it predates this gh-identity (%s)", want).
+			withHint("Run `gh identity init` to reinstall it."))
+	case got != want:
+		results = append(results, warnResult("hook_version", "Hook binary version %s differs from gh-identity %s", got, want).
+			withHint("Run `gh identity init` to reinstall it."))
+	}
+	return results
+}
+
+// hookVersion runs the hook binary at path with --version. Tests replace it.
+var hookVersion = func(path string) (string, error) {
+	out, err := exec.Command(path, "--version").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// checkAskPass verifies the GIT_ASKPASS helper is installed and executable.
+func checkAskPass(c *doctorContext) []doctorResult {
+	askPass, err := config.AskPassPath()
+	if err != nil {
+		return nil
+	}
+	info, err := os.Stat(askPass)
+	if os.IsNotExist(err) {
+		return []doctorResult{errorResult("askpass", "Askpass helper not found: %s", askPass).
+			withHint("Run `gh identity init` to install it.")}
+	} else if err != nil {
+		return []doctorResult{errorResult("askpass", "Cannot stat askpass helper: %v", err)}
+	} else if info.Mode().Perm()&0o111 == 0 {
+		return []doctorResult{errorResult("askpass", "Askpass helper is not executable: %s", askPass).
+			withHint("Run: chmod +x %s", askPass)}
+	}
+	return []doctorResult{okResult("askpass", "Askpass helper: %s", askPass)}
+}
+
+func checkShellHook(c *doctorContext) []doctorResult {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	shellConfigs := []string{
+		filepath.Join(home, ".config", "fish", "conf.d", "gh-identity.fish"),
+		filepath.Join(home, ".bashrc"),
+		filepath.Join(home, ".zshrc"),
+		filepath.Join(home, ".config", "nushell", "env.nu"),
+	}
+	shellConfigs = append(shellConfigs, powerShellProfiles(home)...)
+
+	var results []doctorResult
+	for _, rc := range shellConfigs {
+		content, err := os.ReadFile(rc)
+		if err == nil && contains(string(content), "gh-identity") {
+			results = append(results, okResult("shell_hook", "Shell hook installed in %s", rc))
+		}
+	}
+	if len(results) == 0 {
+		return []doctorResult{warnResult("shell_hook", "Shell hook not detected in any shell config.").
+			withHint("Run `gh identity init` to install it.")}
+	}
+	return results
+}
+
+// checkBindings verifies bindings reference valid profiles.
+func checkBindings(c *doctorContext) []doctorResult {
 	bindings, err := config.LoadBindings()
 	if err != nil {
-		fmt.Printf("⚠️  Cannot load bindings: %v\n", err)
-	} else if profiles != nil {
-		for _, b := range bindings.Bindings {
-			if _, exists := profiles.Profiles[b.Profile]; !exists {
-				fmt.Printf("❌ Binding %s → %q references non-existent profile.\n", b.Path, b.Profile)
-				issues++
+		return []doctorResult{warnResult("bindings", "Cannot load bindings: %v", err)}
+	}
+	var results []doctorResult
+	for _, r := range bindings.Relative() {
+		if !c.includes(r.Profile) {
+			continue
+		}
+		results = append(results, warnResult("bindings", "Binding path %q is relative; resolving it to %s.", r.Original, r.Resolved).
+			withHint("Run `gh identity doctor --fix` to store it as an absolute path."))
+	}
+	if c.profiles == nil {
+		return results
+	}
+
+	for _, b := range bindings.Bindings {
+		if !c.includes(b.Profile) {
+			continue
+		}
+		if _, exists := c.profiles.Profiles[b.Profile]; !exists {
+			results = append(results, errorResult("bindings", "Binding %s → %q references non-existent profile.", b.Target(), b.Profile).
+				withHint("Run `gh identity bindings prune` to remove it."))
+		}
+	}
+
+	for _, group := range duplicateBindings(bindings.Bindings) {
+		included := false
+		profilesSeen := make(map[string]bool)
+		var entries []string
+		for _, b := range group {
+			included = included || c.includes(b.Profile)
+			profilesSeen[b.Profile] = true
+			entries = append(entries, fmt.Sprintf("%s → %q", b.Target(), b.Profile))
+		}
+		if !included {
+			continue
+		}
+		if len(profilesSeen) > 1 {
+			results = append(results, errorResult("bindings", "Conflicting bindings for the same target: %s; only the first is used.", strings.Join(entries, ", ")).
+				withHint("Edit bindings.yml to keep the one you want."))
+		} else {
+			results = append(results, warnResult("bindings", "Duplicate bindings: %s.", strings.Join(entries, ", ")).
+				withHint("Edit bindings.yml to remove the extra entries."))
+		}
+	}
+	return results
+}
+
+// duplicateBindings groups bindings that bind the same directory, once
+// expanded and with symlinks resolved, or the same normalized remote
+// pattern. Groups and their members are in bindings.yml order, and only
+// groups of two or more are returned.
+func duplicateBindings(list []config.Binding) [][]config.Binding {
+	var keys []string
+	groups := make(map[string][]config.Binding)
+	for _, b := range list {
+		var key string
+		if b.IsRemote() {
+			key = "remote:" + resolve.NormalizeRemote(b.RemotePattern)
+		} else {
+			p, err := config.ResolveConfigPath(b.Path)
+			if err != nil {
+				continue
 			}
+			key = "path:" + config.FoldPath(filepath.Clean(p))
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
 		}
+		groups[key] = append(groups[key], b)
 	}
 
-	// Check 8: includeIf directives.
+	var dups [][]config.Binding
+	for _, key := range keys {
+		if len(groups[key]) > 1 {
+			dups = append(dups, groups[key])
+		}
+	}
+	return dups
+}
+
+func checkIncludeIfs(c *doctorContext) []doctorResult {
 	gcPath, err := gitconfig.GlobalGitconfigPath()
-	if err == nil {
-		managed, err := gitconfig.ListManagedIncludeIfs(gcPath)
-		if err == nil && len(managed) > 0 {
-			fmt.Printf("✅ %d managed includeIf directive(s) in %s\n", len(managed), gcPath)
+	if err != nil {
+		return nil
+	}
+	var results []doctorResult
+	if c.only != "" {
+		// Duplicates and remote directives can't be attributed to a profile
+		// cheaply; the fragment check below covers what can.
+		var n int
+		includes, _ := gitconfig.ListManagedIncludeIfsWithPaths(gcPath)
+		for _, inc := range includes {
+			if fragmentProfile(inc.Path) == c.only {
+				n++
+			}
 		}
+		if n > 0 {
+			results = append(results, okResult("includeif", "%d managed includeIf directive(s) for %q in %s", n, c.only, gcPath))
+		}
+		return append(results, checkIncludeIfFragments(c, gcPath)...)
 	}
 
-	fmt.Println()
-	if issues == 0 {
-		fmt.Println("✅ All checks passed!")
-	} else {
-		fmt.Printf("Found %d issue(s).\n", issues)
+	managed, _ := gitconfig.ListManagedIncludeIfs(gcPath)
+	remotes, _ := gitconfig.ListManagedRemoteIncludeIfs(gcPath)
+	if n := len(managed) + len(remotes); n > 0 {
+		results = append(results, okResult("includeif", "%d managed includeIf directive(s) in %s", n, gcPath))
 	}
+	dups, _ := gitconfig.DuplicateIncludeIfs(gcPath)
+	for _, header := range dups {
+		results = append(results, warnResult("includeif", "Duplicate %s in %s; git only uses the first", header, gcPath).
+			withHint("Run `gh identity bind` for that directory again to merge them"))
+	}
+	results = append(results, checkIncludeIfFragments(c, gcPath)...)
+	return results
+}
 
-	return nil
+// checkIncludeIfFragments reports managed includeIf directives whose
+// fragment is missing, e.g. after an interrupted `profile remove`. git
+// skips missing include files silently, so the directory falls back to the
+// global identity.
+func checkIncludeIfFragments(c *doctorContext, gcPath string) []doctorResult {
+	includes, err := gitconfig.ListManagedIncludeIfsWithPaths(gcPath)
+	if err != nil {
+		return nil
+	}
+	var results []doctorResult
+	for _, inc := range includes {
+		profile := fragmentProfile(inc.Path)
+		if inc.Path == "" || !c.includes(profile) {
+			continue
+		}
+		if _, err := os.Stat(inc.Path); !os.IsNotExist(err) {
+			continue
+		}
+		dir := strings.TrimSuffix(inc.Dir, "/")
+		r := errorResult("includeif", "includeIf \"gitdir:%s\" in %s points to missing fragment %s", inc.Dir, gcPath, inc.Path)
+		if c.profiles != nil && c.profiles.Profiles[profile].GHUser != "" {
+			r = r.withHint("Run `gh identity bind %s %s` to recreate the fragment.", dir, profile)
+		} else {
+			r = r.withHint("Remove the section from %s, or run `gh identity bind %s <profile>` to point it at an existing profile.", gcPath, dir)
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// fragmentProfile returns the profile a gitconfig fragment belongs to, from
+// its <profile>.gitconfig file name.
+func fragmentProfile(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), ".gitconfig")
 }
 
 func contains(s, substr string) bool {
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dotbrains/gh-identity/internal/config"
+)
+
+func newExportCmd() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export profiles and bindings as a single bundle",
+		Long:  "Writes profiles.yml and bindings.yml as one YAML bundle, to stdout or a file. Restore it on another machine with `gh identity import <file>`. The bundle contains no secrets; tokens stay in gh's keyring.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runExport(output)
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write the bundle to this file instead of stdout")
+	return cmd
+}
+
+func runExport(output string) error {
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		return err
+	}
+
+	data, err := config.NewBundle(profiles, bindings).Marshal()
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(output, data, 0o644); err != nil {
+		return fmt.Errorf("writing bundle: %w", err)
+	}
+	fmt.Printf("✅ Exported %d profile(s) and %d binding(s) to %s\n", len(profiles.Profiles), len(bindings.Bindings), output)
+	return nil
+}
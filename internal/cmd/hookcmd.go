@@ -0,0 +1,344 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dotbrains/gh-identity/internal/config"
+)
+
+// hookBeginMarker and hookEndMarker delimit the block installShellHook
+// writes to shell rc files, so it can be found and replaced on upgrade.
+const (
+	hookBeginMarker = "# >>> gh-identity hook >>>"
+	hookEndMarker   = "# <<< gh-identity hook <<<"
+)
+
+// legacyHookMarker is the comment line that started the undelimited hook
+// block written by earlier versions.
+const legacyHookMarker = "# gh-identity hook"
+
+func newHookCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hook",
+		Short: "Install or remove the shell hook",
+	}
+
+	var installShell, statusShell string
+	installCmd := &cobra.Command{
+		Use:   "install",
+		Short: "Install the shell hook and helper binaries",
+		Long:  "Installs the helper binaries and adds the hook to the rc file of the current shell, detected from $SHELL. On native Windows, where $SHELL is not set, PowerShell is detected and the hook goes into its profile ($PROFILE). Pass --shell to choose the shell instead.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runHookInstall(installShell)
+		},
+	}
+	installCmd.Flags().StringVar(&installShell, "shell", "", "Shell to install for: bash, zsh, fish, nu, or powershell (default: detected)")
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show whether the shell hook is active",
+		Long:  "Checks the three things the hook needs: the hook binary is installed, the rc file of the current shell loads it, and GH_IDENTITY_PROFILE is exported in this shell, which means the hook has run at least once. For a full check of the setup, run `gh identity doctor`.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runHookStatus(statusShell)
+		},
+	}
+	statusCmd.Flags().StringVar(&statusShell, "shell", "", "Shell to check: bash, zsh, fish, nu, or powershell (default: detected)")
+
+	cmd.AddCommand(
+		installCmd,
+		statusCmd,
+		&cobra.Command{
+			Use:   "uninstall",
+			Short: "Remove the shell hook and helper binaries",
+			Long:  "Removes the gh-identity hook block from ~/.bashrc, ~/.zshrc, Nushell's env.nu, and the PowerShell profile, deletes the fish conf.d file, and removes the helper binaries. Safe to run when nothing is installed.",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runHookUninstall()
+			},
+		},
+	)
+
+	return cmd
+}
+
+// hookShell returns the shell named by a --shell value, or the detected
+// shell when it is empty.
+func hookShell(name string) (string, error) {
+	if name == "" {
+		return detectShell(), nil
+	}
+	shell, err := parseShell(name)
+	return string(shell), err
+}
+
+func runHookInstall(shellName string) error {
+	shell, err := hookShell(shellName)
+	if err != nil {
+		return err
+	}
+	if err := installHookBinary(); err != nil {
+		return fmt.Errorf("installing hook binaries: %w", err)
+	}
+	fmt.Println("✅ Hook binaries installed.")
+
+	if err := installShellHook(shell); err != nil {
+		return fmt.Errorf("installing shell hook: %w", err)
+	}
+	fmt.Printf("✅ Shell hook installed for %s.\n", shell)
+	return nil
+}
+
+func runHookStatus(shellName string) error {
+	shell, err := hookShell(shellName)
+	if err != nil {
+		return err
+	}
+	binDir, err := config.BinDir()
+	if err != nil {
+		return err
+	}
+	hookBin := filepath.Join(binDir, "gh-identity-hook")
+	if goos == "windows" {
+		hookBin += ".exe"
+	}
+	if _, err := os.Stat(hookBin); err == nil {
+		fmt.Printf("✅ Hook binary installed: %s\n", hookBin)
+	} else {
+		fmt.Printf("❌ Hook binary not found: %s\n", hookBin)
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	rc := shellRCFile(home, shell)
+	content, err := os.ReadFile(rc)
+	switch {
+	case shell == "cmd":
+		fmt.Println("❌ cmd.exe cannot run the hook; use PowerShell")
+	case err == nil && strings.Contains(string(content), hookBeginMarker):
+		fmt.Printf("✅ Shell hook loaded by %s (%s)\n", rc, shell)
+	case err == nil && strings.Contains(string(content), legacyHookMarker):
+		fmt.Printf("⚠️  Shell hook in %s (%s) is outdated; run `gh identity hook install` to update it\n", rc, shell)
+	default:
+		fmt.Printf("❌ Shell hook not found in %s (%s)\n", rc, shell)
+	}
+
+	if profile, ok := os.LookupEnv("GH_IDENTITY_PROFILE"); ok {
+		if profile == "" {
+			profile = "none"
+		}
+		fmt.Printf("✅ Hook has run in this shell (GH_IDENTITY_PROFILE=%s)\n", profile)
+	} else {
+		fmt.Println("❌ GH_IDENTITY_PROFILE is not set; the hook has not run in this shell")
+	}
+	return nil
+}
+
+// shellRCFile returns the file installShellHook writes the hook to for
+// shell.
+func shellRCFile(home, shell string) string {
+	switch shell {
+	case "fish":
+		return filepath.Join(home, ".config", "fish", "conf.d", "gh-identity.fish")
+	case "zsh":
+		return filepath.Join(home, ".zshrc")
+	case "nu":
+		return filepath.Join(home, ".config", "nushell", "env.nu")
+	case "powershell":
+		return powerShellProfile(home)
+	default:
+		return filepath.Join(home, ".bashrc")
+	}
+}
+
+// powerShellProfiles returns every file powerShellProfile may return, for
+// whichever OS and PowerShell version.
+func powerShellProfiles(home string) []string {
+	const name = "Microsoft.PowerShell_profile.ps1"
+	return []string{
+		filepath.Join(home, ".config", "powershell", name),
+		filepath.Join(home, "Documents", "PowerShell", name),
+		filepath.Join(home, "Documents", "WindowsPowerShell", name),
+	}
+}
+
+// powerShellProfile returns the current user's PowerShell profile for the
+// current host, the file $PROFILE names. On Windows, PowerShell 7 and
+// Windows PowerShell 5.1 keep separate profiles; 5.1 lists its own module
+// directory first in PSModulePath.
+func powerShellProfile(home string) string {
+	const name = "Microsoft.PowerShell_profile.ps1"
+	if goos != "windows" {
+		return filepath.Join(home, ".config", "powershell", name)
+	}
+	first, _, _ := strings.Cut(os.Getenv("PSModulePath"), ";")
+	if strings.Contains(strings.ToLower(first), `\windowspowershell\`) {
+		return filepath.Join(home, "Documents", "WindowsPowerShell", name)
+	}
+	return filepath.Join(home, "Documents", "PowerShell", name)
+}
+
+func runHookUninstall() error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+
+	removed := 0
+
+	// The fish hook lives in its own file.
+	fishFile := filepath.Join(home, ".config", "fish", "conf.d", "gh-identity.fish")
+	if err := os.Remove(fishFile); err == nil {
+		fmt.Printf("✅ Removed %s\n", fishFile)
+		removed++
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	// Other shells get a block appended to their rc file.
+	rcFiles := []string{
+		filepath.Join(home, ".bashrc"),
+		filepath.Join(home, ".zshrc"),
+		filepath.Join(home, ".config", "nushell", "env.nu"),
+	}
+	for _, rc := range append(rcFiles, powerShellProfiles(home)...) {
+		data, err := os.ReadFile(rc)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		stripped, ok := stripHookBlock(string(data))
+		if !ok {
+			continue
+		}
+		if err := config.WriteFileAtomic(rc, []byte(stripped), 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("✅ Removed hook from %s\n", rc)
+		removed++
+	}
+
+	// Binaries may be in the current or the pre-XDG location.
+	binDir, err := config.InstallBinDir()
+	if err != nil {
+		return err
+	}
+	legacyBinDir, err := config.LegacyBinDir()
+	if err != nil {
+		return err
+	}
+	for _, dir := range []string{binDir, legacyBinDir} {
+		for _, name := range helperBinaries {
+			if goos == "windows" {
+				name += ".exe"
+			}
+			path := filepath.Join(dir, name)
+			if err := os.Remove(path); err == nil {
+				fmt.Printf("✅ Removed %s\n", path)
+				removed++
+			} else if !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
+	if removed == 0 {
+		fmt.Println("Nothing to remove; the hook is not installed.")
+	}
+	return nil
+}
+
+// upsertHookBlock returns content with its managed hook block replaced by
+// block. Content without a managed block has any legacy blocks removed and
+// block appended, after a blank line.
+func upsertHookBlock(content, block string) string {
+	lines := strings.Split(content, "\n")
+	begin, end := -1, -1
+	for i, line := range lines {
+		switch strings.TrimSpace(line) {
+		case hookBeginMarker:
+			if begin < 0 {
+				begin = i
+			}
+		case hookEndMarker:
+			if begin >= 0 && end < 0 {
+				end = i
+			}
+		}
+	}
+	if begin >= 0 && end >= 0 {
+		replaced := append([]string{}, lines[:begin]...)
+		replaced = append(replaced, strings.Split(strings.TrimSuffix(block, "\n"), "\n")...)
+		replaced = append(replaced, lines[end+1:]...)
+		return strings.Join(replaced, "\n")
+	}
+
+	stripped, _ := stripHookBlock(content)
+	if stripped != "" {
+		if !strings.HasSuffix(stripped, "\n") {
+			stripped += "\n"
+		}
+		stripped += "\n"
+	}
+	return stripped + block
+}
+
+// stripHookBlock removes every hook block written by installShellHook from
+// an rc file's content, along with the blank line before it. Both the
+// delimited blocks and the legacy undelimited ones are recognized. It
+// reports whether anything was removed.
+func stripHookBlock(content string) (string, bool) {
+	lines := strings.Split(content, "\n")
+	var out []string
+	found := false
+
+	for i := 0; i < len(lines); i++ {
+		marker := strings.TrimSpace(lines[i])
+		if marker != hookBeginMarker && marker != legacyHookMarker {
+			out = append(out, lines[i])
+			continue
+		}
+		found = true
+		if len(out) > 0 && strings.TrimSpace(out[len(out)-1]) == "" {
+			out = out[:len(out)-1]
+		}
+
+		switch {
+		case marker == hookBeginMarker:
+			// Skip through the end marker; an unterminated block loses
+			// only its begin marker.
+			for j := i + 1; j < len(lines); j++ {
+				if strings.TrimSpace(lines[j]) == hookEndMarker {
+					i = j
+					break
+				}
+			}
+		case i+1 < len(lines) && strings.HasPrefix(lines[i+1], "$env.config.hooks"):
+			// The legacy Nushell closure runs up to its closing "})".
+			i++
+			for i < len(lines) && strings.TrimSpace(lines[i]) != "})" {
+				i++
+			}
+		case i+1 < len(lines) && strings.Contains(lines[i+1], "gh-identity-hook"):
+			// The legacy POSIX shell blocks are a single eval line.
+			i++
+		}
+	}
+
+	if !found {
+		return content, false
+	}
+	result := strings.Join(out, "\n")
+	if result != "" && !strings.HasSuffix(result, "\n") {
+		result += "\n"
+	}
+	return result, true
+}
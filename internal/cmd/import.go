@@ -0,0 +1,362 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dotbrains/gh-identity/internal/config"
+	"github.com/dotbrains/gh-identity/internal/ghauth"
+	"github.com/dotbrains/gh-identity/internal/gitconfig"
+)
+
+func newImportCmd(auth ghauth.Auth) *cobra.Command {
+	var overwrite bool
+
+	cmd := &cobra.Command{
+		Use:   "import [file]",
+		Short: "Import identities from a bundle or from existing includeIf blocks",
+		Long: `With a file argument, merges a bundle written by ` + "`gh identity export`" + ` and re-creates the gitconfig fragments and includeIf directives for its bindings.
+
+Without arguments, finds hand-written [includeIf "gitdir:..."] directives in your global gitconfig, reads the user name and email from each included file, and offers to create a profile and binding for each one.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				return runImportBundle(args[0], overwrite)
+			}
+			return runImport(auth)
+		},
+	}
+
+	cmd.Flags().BoolVar(&overwrite, "overwrite", false, "Replace existing profiles with the same name when importing a bundle")
+	return cmd
+}
+
+// importPlan is a binding (and possibly a new profile) to be created by import.
+type importPlan struct {
+	dir         string
+	profileName string
+	newProfile  bool
+}
+
+func runImport(auth ghauth.Auth) error {
+	unlock, err := config.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	gcPath, err := gitconfig.GlobalGitconfigPath()
+	if err != nil {
+		return err
+	}
+	includes, err := gitconfig.ParseIncludeIfs(gcPath)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", gcPath, err)
+	}
+
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		return err
+	}
+
+	users, err := auth.AuthenticatedUsers()
+	if err == nil && len(users) > 0 {
+		fmt.Printf("Authenticated accounts: %s\n", strings.Join(users, ", "))
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	var plans []importPlan
+	for _, inc := range includes {
+		if inc.Managed {
+			continue
+		}
+
+		dir := strings.TrimSuffix(inc.Dir, "/")
+		if !strings.HasPrefix(dir, "/") && !strings.HasPrefix(dir, "~/") {
+			fmt.Printf("⚠️  Skipping gitdir:%s — only absolute and ~/ patterns can be imported.\n", inc.Dir)
+			continue
+		}
+		expanded, err := config.ExpandPath(dir)
+		if err != nil {
+			continue
+		}
+		if existing := bindings.FindBinding(expanded); existing != "" {
+			fmt.Printf("Skipping %s — already bound to %q.\n", expanded, existing)
+			continue
+		}
+
+		gitName, gitEmail, err := gitconfig.ReadFragmentUser(inc.Path)
+		if err != nil {
+			fmt.Printf("⚠️  Skipping gitdir:%s — cannot read %s: %v\n", inc.Dir, inc.Path, err)
+			continue
+		}
+		if gitEmail == "" {
+			fmt.Printf("⚠️  Skipping gitdir:%s — %s has no user.email.\n", inc.Dir, inc.Path)
+			continue
+		}
+
+		fmt.Printf("\n--- %s (%s <%s>) ---\n", expanded, gitName, gitEmail)
+
+		// Reuse a profile that already carries this identity.
+		if name := profileByEmail(profiles, gitEmail); name != "" {
+			fmt.Printf("Matches existing profile %q.\n", name)
+			plans = append(plans, importPlan{dir: expanded, profileName: name})
+			continue
+		}
+
+		name := promptWithDefault(reader, "Profile name", profileNameFromInclude(inc.Path))
+		if _, exists := profiles.Profiles[name]; exists {
+			fmt.Printf("⚠️  Profile %q already exists with a different email; skipping.\n", name)
+			continue
+		}
+		var ghUser string
+		if def := defaultGHUser(users, name, gitEmail); def != "" {
+			ghUser = promptWithDefault(reader, "GitHub username (gh_user)", def)
+		} else {
+			fmt.Printf("GitHub username (gh_user): ")
+			ghUser = readLine(reader)
+		}
+		if ghUser == "" {
+			fmt.Printf("⚠️  Skipping gitdir:%s — a profile needs a gh_user.\n", inc.Dir)
+			continue
+		}
+
+		profiles.AddProfile(name, config.Profile{
+			GHUser:   ghUser,
+			GitName:  gitName,
+			GitEmail: gitEmail,
+		})
+		plans = append(plans, importPlan{dir: expanded, profileName: name, newProfile: true})
+	}
+
+	if len(plans) == 0 {
+		fmt.Println("Nothing to import.")
+		return nil
+	}
+
+	fmt.Println("\nAbout to import:")
+	for _, p := range plans {
+		suffix := ""
+		if p.newProfile {
+			suffix = " (new profile)"
+		}
+		fmt.Printf("  %s → %s%s\n", p.dir, p.profileName, suffix)
+	}
+	fmt.Printf("Write changes? [y/N]: ")
+	if !strings.EqualFold(readLine(reader), "y") {
+		fmt.Println("Aborted; nothing was written.")
+		return nil
+	}
+
+	for _, p := range plans {
+		if err := bindings.AddBinding(p.dir, p.profileName); err != nil {
+			return err
+		}
+	}
+	if err := profiles.Save(); err != nil {
+		return err
+	}
+	if err := bindings.Save(); err != nil {
+		return err
+	}
+	for _, p := range plans {
+		if !p.newProfile {
+			continue
+		}
+		if err := gitconfig.WriteProfileFragment(p.profileName, profiles.Profiles[p.profileName]); err != nil {
+			return fmt.Errorf("writing gitconfig fragment: %w", err)
+		}
+	}
+
+	fmt.Printf("✅ Imported %d binding(s).\n", len(plans))
+	return nil
+}
+
+// profileByEmail returns the name of a profile using the given git email, or "".
+func profileByEmail(profiles *config.ProfilesFile, email string) string {
+	for name, p := range profiles.Profiles {
+		if strings.EqualFold(p.GitEmail, email) {
+			return name
+		}
+	}
+	return ""
+}
+
+// profileNameFromInclude derives a profile name from an included file's name.
+// e.g. "~/.gitconfig-work" → "work", "personal.gitconfig" → "personal"
+// defaultGHUser picks the authenticated account to offer for a profile
+// named name with email: one named like the profile or the email's local
+// part, otherwise the first account. It returns "" when there are none.
+func defaultGHUser(users []string, name, email string) string {
+	local, _, _ := strings.Cut(email, "@")
+	for _, u := range users {
+		if strings.EqualFold(u, name) || strings.EqualFold(u, local) {
+			return u
+		}
+	}
+	if len(users) > 0 {
+		return users[0]
+	}
+	return ""
+}
+
+func profileNameFromInclude(path string) string {
+	name := filepath.Base(path)
+	name = strings.TrimPrefix(name, ".")
+	name = strings.TrimSuffix(name, ".gitconfig")
+	name = strings.TrimPrefix(name, "gitconfig-")
+	name = strings.TrimPrefix(name, "gitconfig_")
+	return name
+}
+
+func runImportBundle(path string, overwrite bool) error {
+	unlock, err := config.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	bundle, err := config.LoadBundleFrom(path)
+	if err != nil {
+		return err
+	}
+
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		return err
+	}
+
+	// Check only the bundle's profiles, so a broken profile already on
+	// this machine does not block the import.
+	incoming := &config.ProfilesFile{Profiles: bundle.Profiles}
+	if errs := incoming.Validate(); len(errs) > 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("bundle contains invalid profiles:\n  %s", strings.Join(errs, "\n  "))
+	}
+
+	// Merge profiles, remembering which ones were skipped on collision.
+	names := make([]string, 0, len(bundle.Profiles))
+	for name := range bundle.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	skipped := make(map[string]bool)
+	var imported []string
+	for _, name := range names {
+		if _, exists := profiles.Profiles[name]; exists && !overwrite {
+			fmt.Printf("⚠️  Profile %q already exists; skipping (use --overwrite to replace it).\n", name)
+			skipped[name] = true
+			continue
+		}
+		profiles.AddProfile(name, bundle.Profiles[name])
+		imported = append(imported, name)
+	}
+	if bundle.Default != "" && !skipped[bundle.Default] && (profiles.Default == "" || overwrite) {
+		profiles.Default = bundle.Default
+	}
+
+	// Merge bindings that point at a profile we now have.
+	var paths []string
+	var remotes, locals []config.Binding
+	boundCount := 0
+	for _, b := range bundle.Bindings {
+		if skipped[b.Profile] {
+			fmt.Printf("⚠️  Skipping binding %s — profile %q was not imported.\n", b.Target(), b.Profile)
+			continue
+		}
+		if _, exists := profiles.Profiles[b.Profile]; !exists {
+			fmt.Printf("⚠️  Skipping binding %s — profile %q is not defined.\n", b.Target(), b.Profile)
+			continue
+		}
+		if b.IsRemote() {
+			bindings.AddRemoteBinding(b.RemotePattern, b.Profile)
+			remotes = append(remotes, b)
+		} else {
+			// Bundle paths are in bindings.yml form, often relative to ~.
+			dir, err := config.ExpandConfigPath(b.Path)
+			if err != nil {
+				return err
+			}
+			if err := bindings.AddScopedBinding(dir, b.Profile, b.Scope); err != nil {
+				return err
+			}
+			if b.IsLocal() {
+				b.Path = dir
+				locals = append(locals, b)
+			} else {
+				paths = append(paths, dir)
+			}
+		}
+		boundCount++
+	}
+
+	if err := profiles.Save(); err != nil {
+		return err
+	}
+	if err := bindings.Save(); err != nil {
+		return err
+	}
+
+	// Reconstruct the derived gitconfig state.
+	for _, name := range imported {
+		if err := gitconfig.WriteProfileFragment(name, profiles.Profiles[name]); err != nil {
+			return fmt.Errorf("writing gitconfig fragment: %w", err)
+		}
+	}
+	gcPath, err := gitconfig.GlobalGitconfigPath()
+	if err != nil {
+		return err
+	}
+	gitDir, err := config.GitConfigDir()
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		resolved, err := config.ResolvePath(p)
+		if err != nil {
+			continue
+		}
+		profileName := bindings.FindBinding(resolved)
+		fragmentPath := filepath.Join(gitDir, profileName+".gitconfig")
+		if err := gitconfig.AddIncludeIf(gcPath, resolved, fragmentPath); err != nil {
+			return fmt.Errorf("adding includeIf directive: %w", err)
+		}
+	}
+
+	for _, b := range remotes {
+		fragmentPath := filepath.Join(gitDir, b.Profile+".gitconfig")
+		if err := gitconfig.AddIncludeIfRemote(gcPath, b.RemotePattern, fragmentPath); err != nil {
+			return fmt.Errorf("adding includeIf directive: %w", err)
+		}
+	}
+
+	// The repository may not be cloned on this machine yet; re-run
+	// `bind --local` once it is.
+	for _, b := range locals {
+		resolved, err := config.ResolvePath(b.Path)
+		if err != nil {
+			continue
+		}
+		if err := gitconfig.SetLocalIdentity(resolved, profiles.Profiles[b.Profile]); err != nil {
+			fmt.Printf("⚠️  Could not write the local git config of %s: %v\n", resolved, err)
+		}
+	}
+
+	fmt.Printf("✅ Imported %d profile(s) and %d binding(s) from %s\n", len(imported), boundCount, path)
+	return nil
+}
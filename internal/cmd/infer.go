@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/dotbrains/gh-identity/internal/ghauth"
+)
+
+// inferGitDetails tries to infer git name and email from:
+// 1. GitHub API
+// 2. Global git config
+func inferGitDetails(auth ghauth.Auth, account ghauth.Account) (string, string) {
+	var name, email string
+
+	// Try GitHub API first
+	if ghAuth, ok := auth.(*ghauth.GHAuth); ok {
+		if info, err := ghAuth.GetUserInfo(account.Host, account.User); err == nil {
+			if info.Name != "" {
+				name = info.Name
+			}
+			if info.Email != "" {
+				email = info.Email
+			}
+		}
+	}
+
+	// Fallback to global git config if not found
+	if name == "" {
+		if output, err := exec.Command("git", "config", "--global", "user.name").Output(); err == nil {
+			name = strings.TrimSpace(string(output))
+		}
+	}
+	if email == "" {
+		if output, err := exec.Command("git", "config", "--global", "user.email").Output(); err == nil {
+			email = strings.TrimSpace(string(output))
+		}
+	}
+
+	return name, email
+}
+
+// detectSSHKey tries to find a default SSH key in ~/.ssh/
+func detectSSHKey() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	sshDir := filepath.Join(home, ".ssh")
+	// Check common key names in order of preference
+	keyNames := []string{"id_ed25519", "id_rsa", "id_ecdsa"}
+	for _, keyName := range keyNames {
+		keyPath := filepath.Join(sshDir, keyName)
+		if _, err := os.Stat(keyPath); err == nil {
+			return keyPath
+		}
+	}
+
+	return ""
+}
@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -13,62 +12,153 @@ import (
 
 	"github.com/dotbrains/gh-identity/internal/config"
 	"github.com/dotbrains/gh-identity/internal/ghauth"
+	"github.com/dotbrains/gh-identity/internal/gitconfig"
 )
 
+// initOptions are the flags of init.
+type initOptions struct {
+	yes            bool // accept inferred defaults without prompting
+	generateKeys   bool // generate a dedicated SSH key per new profile
+	importExisting bool // prefill git details from existing includeIf fragments
+	noTokenEnv     bool // authenticate git through gh's credential helper
+}
+
 func newInitCmd(auth ghauth.Auth) *cobra.Command {
-	return &cobra.Command{
+	var opts initOptions
+
+	cmd := &cobra.Command{
 		Use:   "init",
 		Short: "Interactive first-time setup",
-		Long:  "Discovers existing gh authenticated accounts, creates profiles for each, and installs the shell hook.",
+		Long:  "Discovers existing gh authenticated accounts, creates profiles for each, and installs the shell hook. With --yes, profiles are created from the inferred defaults without prompting and the first account becomes the default. With --generate-ssh-key, each new profile gets a dedicated key at ~/.ssh/id_<name> instead of an existing one. With --import-existing, the git name and email are prefilled from the gitconfig files your global gitconfig already includes through includeIf, where one belongs to the account.\n\nWith --no-token-env, new profiles set credential_helper: gh. Git then authenticates HTTPS through gh's own credential helper (gh auth git-credential), configured per profile in the gitconfig fragment its bindings include, and the hook no longer exports GIT_ASKPASS. Tokens stay entirely within gh and it works on every host, but gh answers only for its active account: outside a shell where the hook has switched accounts, such as an editor, a push to a bound repository fails instead of using the right account as the askpass helper would.",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runInit(auth)
+			return runInit(auth, opts)
 		},
 	}
+
+	cmd.Flags().BoolVarP(&opts.yes, "yes", "y", false, "Accept the inferred defaults without prompting")
+	cmd.Flags().BoolVar(&opts.yes, "non-interactive", false, "Alias for --yes")
+	cmd.Flags().BoolVar(&opts.generateKeys, "generate-ssh-key", false, "Generate a dedicated SSH key at ~/.ssh/id_<name> for each new profile")
+	cmd.Flags().BoolVar(&opts.importExisting, "import-existing", false, "Prefill git name and email from existing includeIf fragments in the global gitconfig")
+	cmd.Flags().BoolVar(&opts.noTokenEnv, "no-token-env", false, "Authenticate git through gh's credential helper instead of the askpass helper")
+	return cmd
 }
 
-func runInit(auth ghauth.Auth) error {
-	fmt.Println("🔧 gh-identity init")
-	fmt.Println()
+// runInit creates a profile per authenticated account and installs the hook.
+// When yes is set, nothing is read from stdin: each profile takes its
+// inferred defaults, existing profiles are left alone, and the first
+// account's profile becomes the default if none is set. When generateKeys is
+// set, each new profile gets its own SSH key; with yes, existing key files
+// are reused rather than overwritten, new keys have no passphrase, and
+// nothing is uploaded. When
+// importExisting is set, an identity from an includeIf fragment that belongs
+// to the account replaces the inferred git name and email; if its email
+// differs, the user is asked whether to keep the inferred one instead.
+func runInit(auth ghauth.Auth, opts initOptions) error {
+	yes, generateKeys := opts.yes, opts.generateKeys
+
+	note("🔧 gh-identity init")
+	note("")
 
 	// Step 1: Discover authenticated accounts.
-	users, err := auth.AuthenticatedUsers()
+	accounts, err := auth.Accounts()
 	if err != nil {
 		return fmt.Errorf("listing authenticated accounts: %w", err)
 	}
-	if len(users) == 0 {
-		fmt.Println("No authenticated gh accounts found.")
-		fmt.Println("Run `gh auth login` to authenticate, then re-run `gh identity init`.")
+	if len(accounts) == 0 {
+		note("No authenticated gh accounts found.")
+		note("Run `gh auth login` to authenticate, then re-run `gh identity init`.")
 		return nil
 	}
 
-	fmt.Printf("Found %d authenticated account(s): %s\n", len(users), strings.Join(users, ", "))
-	fmt.Println()
+	labels := make([]string, len(accounts))
+	for i, a := range accounts {
+		labels[i] = accountLabel(a)
+	}
+	note("Found %d authenticated account(s): %s", len(accounts), strings.Join(labels, ", "))
+	note("")
 
 	// Step 2: Ensure config directory exists.
 	dir, err := config.EnsureDir()
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Config directory: %s\n", dir)
+	note("Config directory: %s", dir)
 
 	// Step 3: Create profiles for each account.
+	unlock, err := config.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	profiles, err := config.LoadProfiles()
 	if err != nil {
 		return err
 	}
 
+	var existing []existingIdentity
+	if opts.importExisting {
+		if existing, err = existingIdentities(); err != nil {
+			warn("Could not read existing includeIf identities: %v", err)
+		}
+	}
+
 	reader := bufio.NewReader(os.Stdin)
-	for _, user := range users {
-		fmt.Printf("\n--- Profile for %s ---\n", user)
+	ask := askYesNo(func() string { return readLine(reader) })
+	if yes {
+		ask = func(string) bool { return false }
+	}
+	var created, skipped []string
+	for _, account := range accounts {
+		user := account.User
 
 		// Infer defaults
-		defaultGitName, defaultGitEmail := inferGitDetails(auth, user)
+		defaultGitName, defaultGitEmail := inferGitDetails(auth, account)
 		defaultSSHKey := detectSSHKey()
 
-		fmt.Printf("Profile name [%s]: ", user)
+		defaultName := user
+		if account.Host != ghauth.DefaultHost {
+			defaultName = user + "@" + account.Host
+		}
+
+		if id, ok := matchExistingIdentity(existing, user, defaultName, defaultGitEmail); ok {
+			note("Found %s <%s> in %s for %s.", id.Name, id.Email, id.Source, accountLabel(account))
+			if id.Name != "" {
+				defaultGitName = id.Name
+			}
+			if defaultGitEmail == "" || strings.EqualFold(id.Email, defaultGitEmail) ||
+				!ask(fmt.Sprintf("Use %s, the email GitHub reports, instead of %s from %s?", defaultGitEmail, id.Email, id.Source)) {
+				defaultGitEmail = id.Email
+			}
+		}
+
+		if yes {
+			if _, exists := profiles.Profiles[defaultName]; exists {
+				skipped = append(skipped, defaultName)
+				continue
+			}
+			p := initProfile(account, defaultGitName, defaultGitEmail, defaultSSHKey)
+			if opts.noTokenEnv {
+				p.CredentialHelper = config.CredentialGH
+			}
+			if generateKeys {
+				if err := generateSSHKey(auth, defaultName, &p, ask, false); err != nil {
+					return fmt.Errorf("generating SSH key for %s: %w", defaultName, err)
+				}
+			}
+			profiles.AddProfile(defaultName, p)
+			created = append(created, defaultName)
+			if profiles.Default == "" {
+				profiles.Default = defaultName
+			}
+			continue
+		}
+
+		fmt.Printf("\n--- Profile for %s ---\n", accountLabel(account))
+		fmt.Printf("Profile name [%s]: ", defaultName)
 		name := readLine(reader)
 		if name == "" {
-			name = user
+			name = defaultName
 		}
 
 		fmt.Printf("Git name [%s]: ", defaultGitName)
@@ -83,22 +173,30 @@ func runInit(auth ghauth.Auth) error {
 			gitEmail = defaultGitEmail
 		}
 
-		fmt.Printf("SSH key path [%s]: ", defaultSSHKey)
-		sshKey := readLine(reader)
-		if sshKey == "" {
-			sshKey = defaultSSHKey
+		var sshKey string
+		if !generateKeys {
+			fmt.Printf("SSH key path [%s]: ", defaultSSHKey)
+			sshKey = readLine(reader)
+			if sshKey == "" {
+				sshKey = defaultSSHKey
+			}
 		}
 
-		profiles.AddProfile(name, config.Profile{
-			GHUser:   user,
-			GitName:  gitName,
-			GitEmail: gitEmail,
-			SSHKey:   sshKey,
-		})
+		p := initProfile(account, gitName, gitEmail, sshKey)
+		if opts.noTokenEnv {
+			p.CredentialHelper = config.CredentialGH
+		}
+		if generateKeys {
+			if err := generateSSHKey(auth, name, &p, ask, true); err != nil {
+				return fmt.Errorf("generating SSH key for %s: %w", name, err)
+			}
+		}
+		profiles.AddProfile(name, p)
+		created = append(created, name)
 	}
 
 	// Set default profile.
-	if len(profiles.Profiles) > 0 && profiles.Default == "" {
+	if !yes && len(profiles.Profiles) > 0 && profiles.Default == "" {
 		fmt.Printf("\nDefault profile name: ")
 		profiles.Default = readLine(reader)
 	}
@@ -106,87 +204,222 @@ func runInit(auth ghauth.Auth) error {
 	if err := profiles.Save(); err != nil {
 		return fmt.Errorf("saving profiles: %w", err)
 	}
-	fmt.Println("\n✅ Profiles saved.")
+	if yes {
+		note("")
+		for _, name := range created {
+			p := profiles.Profiles[name]
+			note("  + %s (%s <%s>)", name, p.GitName, p.GitEmail)
+		}
+		for _, name := range skipped {
+			note("  = %s (already exists)", name)
+		}
+		if profiles.Default != "" {
+			note("  Default profile: %s", profiles.Default)
+		}
+	}
+	for _, name := range created {
+		record("created", name)
+	}
+	for _, name := range skipped {
+		record("skipped", name)
+	}
+	if profiles.Default != "" {
+		record("default", profiles.Default)
+	}
+	note("")
+	note("✅ Profiles saved.")
 
 	// Step 4: Install shell hook.
-	if err := installShellHook(); err != nil {
-		fmt.Printf("⚠️  Could not install shell hook: %v\n", err)
-		fmt.Println("   You can install it manually later. See `gh identity doctor` for details.")
+	if err := installShellHook(detectShell()); err != nil {
+		warn("Could not install shell hook: %v", err)
+		note("   You can install it manually later. See `gh identity doctor` for details.")
 	} else {
-		fmt.Println("✅ Shell hook installed.")
+		done([]string{"installed", "hook"}, "Shell hook installed.")
 	}
 
 	// Step 5: Install hook binary.
 	if err := installHookBinary(); err != nil {
-		fmt.Printf("⚠️  Could not install hook binary: %v\n", err)
+		warn("Could not install hook binaries: %v", err)
 	} else {
-		fmt.Println("✅ Hook binary installed.")
+		done([]string{"installed", "binaries"}, "Hook binaries installed.")
 	}
 
-	fmt.Println("\n🎉 Setup complete! Open a new terminal or source your shell config to activate.")
+	note("")
+	note("🎉 Setup complete! Open a new terminal or source your shell config to activate.")
 	return nil
 }
 
+// existingIdentity is the git name and email in a file that the global
+// gitconfig includes through an includeIf.
+type existingIdentity struct {
+	Source string // the included file
+	Name   string
+	Email  string
+}
+
+// existingIdentities reads the identity of every file the global gitconfig
+// includes through includeIf, whether gh-identity manages it or not. Files
+// that cannot be read or set no email are skipped.
+func existingIdentities() ([]existingIdentity, error) {
+	gcPath, err := gitconfig.GlobalGitconfigPath()
+	if err != nil {
+		return nil, err
+	}
+	includes, err := gitconfig.ParseIncludeIfs(gcPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []existingIdentity
+	seen := make(map[string]bool)
+	for _, inc := range includes {
+		if seen[inc.Path] {
+			continue
+		}
+		seen[inc.Path] = true
+		name, email, err := gitconfig.ReadFragmentUser(inc.Path)
+		if err != nil || email == "" {
+			continue
+		}
+		ids = append(ids, existingIdentity{Source: inc.Path, Name: name, Email: email})
+	}
+	return ids, nil
+}
+
+// matchExistingIdentity picks the identity in ids that belongs to the gh
+// account user, whose profile would be named profileName and whose email
+// GitHub reports as email. In order of preference: the same email, a file
+// named after the user or profile (e.g. ~/.gitconfig-octo or the managed
+// git/<profile>.gitconfig), or a GitHub noreply address for the user.
+func matchExistingIdentity(ids []existingIdentity, user, profileName, email string) (existingIdentity, bool) {
+	matchers := []func(existingIdentity) bool{
+		func(id existingIdentity) bool { return email != "" && strings.EqualFold(id.Email, email) },
+		func(id existingIdentity) bool {
+			base := profileNameFromInclude(id.Source)
+			return strings.EqualFold(base, user) || strings.EqualFold(base, profileName)
+		},
+		func(id existingIdentity) bool {
+			local, domain, _ := strings.Cut(strings.ToLower(id.Email), "@")
+			_, login, _ := strings.Cut(local, "+")
+			return domain == "users.noreply.github.com" && (local == strings.ToLower(user) || login == strings.ToLower(user))
+		},
+	}
+	for _, matches := range matchers {
+		for _, id := range ids {
+			if matches(id) {
+				return id, true
+			}
+		}
+	}
+	return existingIdentity{}, false
+}
+
+// initProfile builds the profile init creates for account.
+func initProfile(account ghauth.Account, gitName, gitEmail, sshKey string) config.Profile {
+	p := config.Profile{
+		GHUser:   account.User,
+		GitName:  gitName,
+		GitEmail: gitEmail,
+		SSHKey:   sshKey,
+	}
+	if account.Host != ghauth.DefaultHost {
+		p.Host = account.Host
+	}
+	return p
+}
+
 func readLine(reader *bufio.Reader) string {
 	line, _ := reader.ReadString('\n')
 	return strings.TrimSpace(line)
 }
 
-func installShellHook() error {
-	shell := detectShell()
-	binDir, err := config.BinDir()
+// installShellHook writes the hook block for shell, as detectShell names
+// it, into the shell's rc file.
+func installShellHook(shell string) error {
+	binDir, err := config.InstallBinDir()
 	if err != nil {
 		return err
 	}
 	hookBinary := filepath.Join(binDir, "gh-identity-hook")
+	if goos == "windows" {
+		hookBinary += ".exe"
+	}
 
-	var rcFile, hookLine string
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return err
 	}
+	rcFile := shellRCFile(home, shell)
 
+	var body string
 	switch shell {
 	case "fish":
-		rcFile = filepath.Join(home, ".config", "fish", "conf.d", "gh-identity.fish")
-		hookLine = fmt.Sprintf(`# gh-identity hook
-function __gh_identity_hook --on-variable PWD
+		body = fmt.Sprintf(`function __gh_identity_hook --on-variable PWD
     eval (%s --shell fish)
 end
 __gh_identity_hook
 `, hookBinary)
-		// For fish, write directly to conf.d.
-		if err := os.MkdirAll(filepath.Dir(rcFile), 0o755); err != nil {
-			return err
-		}
-		return os.WriteFile(rcFile, []byte(hookLine), 0o644)
-	case "bash":
-		rcFile = filepath.Join(home, ".bashrc")
-		hookLine = fmt.Sprintf("\n# gh-identity hook\neval \"$(%s --shell bash)\"\n", hookBinary)
-	case "zsh":
-		rcFile = filepath.Join(home, ".zshrc")
-		hookLine = fmt.Sprintf("\n# gh-identity hook\neval \"$(%s --shell zsh)\"\n", hookBinary)
+	case "bash", "zsh":
+		body = fmt.Sprintf("eval \"$(%s --shell %s)\"\n", hookBinary, shell)
+	case "nu":
+		body = fmt.Sprintf(`$env.config.hooks.env_change.PWD = ($env.config.hooks.env_change.PWD? | default [] | append {|before, after|
+    let raw = (^'%s' --shell nu | str trim)
+    if ($raw | is-not-empty) {
+        let out = ($raw | from json)
+        hide-env -i GH_TOKEN ...($out.unset? | default [])
+        if ($out.gh_user | is-not-empty) {
+            ^gh auth switch --user $out.gh_user | complete | ignore
+        }
+        load-env $out.env
+    }
+})
+`, hookBinary)
+	case "powershell":
+		// PowerShell has no directory-change hook, so the prompt function
+		// is wrapped instead. The original is saved only once, so reloading
+		// the profile does not make the wrapper call itself.
+		body = fmt.Sprintf(`if (-not $global:__GhIdentityPrompt) { $global:__GhIdentityPrompt = $function:prompt }
+function global:prompt {
+    if ($PWD.Path -ne $global:__GhIdentityDir) {
+        $global:__GhIdentityDir = $PWD.Path
+        $out = & '%s' --shell powershell | Out-String
+        if ($out.Trim()) { Invoke-Expression $out }
+    }
+    & $global:__GhIdentityPrompt
+}
+`, strings.ReplaceAll(hookBinary, "'", "''"))
+	case "cmd":
+		return fmt.Errorf("cmd.exe cannot run a hook on directory change; use PowerShell with `gh identity hook install --shell powershell`")
 	default:
 		return fmt.Errorf("unsupported shell: %s", shell)
 	}
+	block := hookBeginMarker + "\n" + body + hookEndMarker + "\n"
 
-	// Check if hook is already installed.
-	content, err := os.ReadFile(rcFile)
-	if err == nil && strings.Contains(string(content), "gh-identity hook") {
-		return nil // Already installed.
+	if err := os.MkdirAll(filepath.Dir(rcFile), 0o755); err != nil {
+		return err
+	}
+	// The fish hook lives in its own file, so it is simply rewritten.
+	if shell == "fish" {
+		return config.WriteFileAtomic(rcFile, []byte(block), 0o644)
 	}
 
-	f, err := os.OpenFile(rcFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
-	if err != nil {
+	content, err := os.ReadFile(rcFile)
+	if err != nil && !os.IsNotExist(err) {
 		return err
 	}
-	defer f.Close()
-	_, err = f.WriteString(hookLine)
-	return err
+	updated := upsertHookBlock(string(content), block)
+	if updated == string(content) {
+		return nil // Already up to date.
+	}
+	return config.WriteFileAtomic(rcFile, []byte(updated), 0o644)
 }
 
+// helperBinaries are the binaries installed into config.InstallBinDir()
+// alongside the extension.
+var helperBinaries = []string{"gh-identity-hook", "gh-identity-askpass"}
+
 func installHookBinary() error {
-	binDir, err := config.BinDir()
+	binDir, err := config.InstallBinDir()
 	if err != nil {
 		return err
 	}
@@ -194,95 +427,77 @@ func installHookBinary() error {
 		return err
 	}
 
-	// Check if we can find the hook binary next to the current executable.
+	// Check if we can find the helper binaries next to the current executable.
 	exe, err := os.Executable()
 	if err != nil {
 		return fmt.Errorf("finding current executable: %w", err)
 	}
 
-	hookSrc := filepath.Join(filepath.Dir(exe), "gh-identity-hook")
-	if runtime.GOOS == "windows" {
-		hookSrc += ".exe"
-	}
-
-	if _, err := os.Stat(hookSrc); os.IsNotExist(err) {
-		return fmt.Errorf("hook binary not found at %s — build it with `make build`", hookSrc)
-	}
+	for _, name := range helperBinaries {
+		if goos == "windows" {
+			name += ".exe"
+		}
 
-	hookDst := filepath.Join(binDir, "gh-identity-hook")
-	if runtime.GOOS == "windows" {
-		hookDst += ".exe"
-	}
+		src := filepath.Join(filepath.Dir(exe), name)
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			return fmt.Errorf("%s not found at %s — build it with `make build`", name, src)
+		}
 
-	src, err := os.ReadFile(hookSrc)
-	if err != nil {
-		return err
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(binDir, name), data, 0o755); err != nil {
+			return err
+		}
 	}
-	return os.WriteFile(hookDst, src, 0o755)
+	return nil
 }
 
+// goos is runtime.GOOS, replaceable in tests.
+var goos = runtime.GOOS
+
 func detectShell() string {
 	// Check SHELL env var.
 	shellPath := os.Getenv("SHELL")
 	if shellPath != "" {
 		base := filepath.Base(shellPath)
 		switch base {
-		case "fish", "bash", "zsh":
+		case "fish", "bash", "zsh", "nu":
 			return base
+		case "pwsh":
+			return "powershell"
 		}
 	}
+	// Native Windows shells do not set SHELL.
+	if shellPath == "" && goos == "windows" {
+		return detectWindowsShell()
+	}
 	return "bash" // default fallback
 }
 
-// inferGitDetails tries to infer git name and email from:
-// 1. GitHub API
-// 2. Global git config
-func inferGitDetails(auth ghauth.Auth, username string) (string, string) {
-	var name, email string
-
-	// Try GitHub API first
-	if ghAuth, ok := auth.(*ghauth.GHAuth); ok {
-		if info, err := ghAuth.GetUserInfo(username); err == nil {
-			if info.Name != "" {
-				name = info.Name
+// detectWindowsShell tells PowerShell from cmd.exe. PSModulePath is set
+// system-wide, but PowerShell adds a module directory under the user's
+// profile to it at startup; cmd.exe has only ComSpec to go on.
+func detectWindowsShell() string {
+	if userProfile := strings.ToLower(os.Getenv("USERPROFILE")); userProfile != "" {
+		for _, dir := range strings.Split(os.Getenv("PSModulePath"), ";") {
+			if strings.HasPrefix(strings.ToLower(dir), userProfile) {
+				return "powershell"
 			}
-			if info.Email != "" {
-				email = info.Email
-			}
-		}
-	}
-
-	// Fallback to global git config if not found
-	if name == "" {
-		if output, err := exec.Command("git", "config", "--global", "user.name").Output(); err == nil {
-			name = strings.TrimSpace(string(output))
 		}
 	}
-	if email == "" {
-		if output, err := exec.Command("git", "config", "--global", "user.email").Output(); err == nil {
-			email = strings.TrimSpace(string(output))
-		}
+	if os.Getenv("ComSpec") != "" {
+		return "cmd"
 	}
-
-	return name, email
+	return "powershell"
 }
 
-// detectSSHKey tries to find a default SSH key in ~/.ssh/
-func detectSSHKey() string {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return ""
-	}
-
-	sshDir := filepath.Join(home, ".ssh")
-	// Check common key names in order of preference
-	keyNames := []string{"id_ed25519", "id_rsa", "id_ecdsa"}
-	for _, keyName := range keyNames {
-		keyPath := filepath.Join(sshDir, keyName)
-		if _, err := os.Stat(keyPath); err == nil {
-			return keyPath
-		}
+// accountLabel names an account for display, qualifying it with its host
+// unless it is on github.com.
+func accountLabel(a ghauth.Account) string {
+	if a.Host == ghauth.DefaultHost {
+		return a.User
 	}
-
-	return ""
+	return a.User + " (" + a.Host + ")"
 }
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dotbrains/gh-identity/internal/config"
+)
+
+func newMapCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "map",
+		Short: "Map GitHub orgs to profiles",
+		Long: `Map a GitHub org to a profile, so every repository whose origin remote the org owns uses it without binding each directory.
+
+An org mapping applies only when no directory, remote, or includeIf binding matches. Prefix the org with a host, e.g. github.example.com/acme, to limit the mapping to that host.`,
+	}
+
+	var jsonOut bool
+	listCmd := &cobra.Command{
+		Use:     "list",
+		Short:   "List org mappings",
+		Aliases: []string{"ls"},
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMapList(jsonOut)
+		},
+	}
+	listCmd.Flags().BoolVar(&jsonOut, "json", false, "Output as JSON")
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "add <org> <profile>",
+			Short: "Use a profile for every repository owned by an org",
+			Args:  cobra.ExactArgs(2),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runMapAdd(args[0], args[1])
+			},
+		},
+		listCmd,
+		&cobra.Command{
+			Use:     "remove <org>",
+			Short:   "Remove an org mapping",
+			Aliases: []string{"rm"},
+			Args:    cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runMapRemove(args[0])
+			},
+		},
+	)
+
+	return cmd
+}
+
+func runMapAdd(org, profileName string) error {
+	unlock, err := config.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+	if _, err := profiles.GetProfile(profileName); err != nil {
+		return fmt.Errorf("profile %q not found — run `gh identity profile list` to see available profiles", profileName)
+	}
+
+	if profiles.Orgs == nil {
+		profiles.Orgs = make(map[string]string)
+	}
+	profiles.Orgs[org] = profileName
+	if err := profiles.Save(); err != nil {
+		return err
+	}
+
+	done([]string{"mapped", org, profileName}, "Mapped org %s to profile %s", org, profileName)
+	return nil
+}
+
+func runMapList(jsonOut bool) error {
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+
+	if jsonOut {
+		orgs := profiles.Orgs
+		if orgs == nil {
+			orgs = map[string]string{}
+		}
+		return printJSON(orgs)
+	}
+
+	if len(profiles.Orgs) == 0 {
+		fmt.Println("No org mappings configured. Run `gh identity map add <org> <profile>` to create one.")
+		return nil
+	}
+
+	for _, org := range sortedKeys(profiles.Orgs) {
+		profile := profiles.Orgs[org]
+		if _, ok := profiles.Profiles[profile]; !ok {
+			fmt.Printf("  %s → %s ❌ (profile not found)\n", org, profile)
+			continue
+		}
+		fmt.Printf("  %s → %s\n", org, profile)
+	}
+	return nil
+}
+
+func runMapRemove(org string) error {
+	unlock, err := config.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+	if _, ok := profiles.Orgs[org]; !ok {
+		return fmt.Errorf("org %q is not mapped — run `gh identity map list` to see mappings", org)
+	}
+	delete(profiles.Orgs, org)
+	if err := profiles.Save(); err != nil {
+		return err
+	}
+
+	done([]string{"unmapped", org}, "Removed mapping for org %s", org)
+	return nil
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// porcelain is set by the global --porcelain flag. Commands that change state
+// report through done, note, and warn so the choice between human and
+// porcelain output is made here rather than at every call site.
+var porcelain bool
+
+// verbose is set by the global --verbose flag.
+var verbose bool
+
+// enableVerbose routes debug logging from every package to stderr. Without
+// it, slog's default handler drops the debug records the packages emit.
+func enableVerbose() {
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})))
+}
+
+// done reports a completed change. Humans see the formatted message after a
+// ✅; porcelain mode prints fields as one tab-separated line instead, e.g.
+// "bound\t/path\tprofile".
+func done(fields []string, format string, a ...any) {
+	if porcelain {
+		record(fields...)
+		return
+	}
+	fmt.Printf("✅ "+format+"\n", a...)
+}
+
+// record prints fields as a porcelain line. Human output omits it; use it for
+// details that humans get summarized elsewhere.
+func record(fields ...string) {
+	if porcelain {
+		fmt.Println(strings.Join(fields, "\t"))
+	}
+}
+
+// note prints a detail line for humans. Porcelain mode omits it.
+func note(format string, a ...any) {
+	if porcelain {
+		return
+	}
+	fmt.Printf(format+"\n", a...)
+}
+
+// warn reports a non-fatal problem: after a ⚠️ on stdout for humans, or as
+// a "warning: " line on stderr in porcelain mode so stdout stays parseable.
+func warn(format string, a ...any) {
+	if porcelain {
+		fmt.Fprintf(os.Stderr, "warning: "+format+"\n", a...)
+		return
+	}
+	fmt.Printf("⚠️  "+format+"\n", a...)
+}
+
+// printJSON writes v to stdout as indented JSON.
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// printDryRun lists the changes a --dry-run invocation would have made. In
+// porcelain mode each action is printed alone on its line.
+func printDryRun(actions []string) {
+	if porcelain {
+		for _, a := range actions {
+			fmt.Println(a)
+		}
+		return
+	}
+	fmt.Println("🔍 Dry run — nothing was written:")
+	for _, a := range actions {
+		fmt.Printf("   • %s\n", a)
+	}
+}
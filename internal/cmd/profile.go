@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 
@@ -22,7 +24,11 @@ func newProfileCmd(auth ghauth.Auth) *cobra.Command {
 
 	cmd.AddCommand(
 		newProfileAddCmd(auth),
-		newProfileListCmd(),
+		newProfileListCmd(auth),
+		newProfileShowCmd(auth),
+		newProfileEditCmd(),
+		newProfileSetCmd(),
+		newProfileRenameCmd(),
 		newProfileRemoveCmd(),
 	)
 
@@ -30,17 +36,75 @@ func newProfileCmd(auth ghauth.Auth) *cobra.Command {
 }
 
 func newProfileAddCmd(auth ghauth.Auth) *cobra.Command {
-	return &cobra.Command{
+	var ghUser, gitName, gitEmail, sshKey, description, cloneProtocol string
+	var opts profileAddOptions
+
+	cmd := &cobra.Command{
 		Use:   "add <name>",
 		Short: "Create a new identity profile",
+		Long:  "Create a new profile. Fields given as flags are not prompted for; when --gh-user, --git-name, and --git-email are all given, nothing is read from stdin.\n\nWith --generate-ssh-key, a dedicated ed25519 key is created at ~/.ssh/id_<name> and used as the profile's ssh_key. Its public key is printed, and you are offered to upload it with `gh ssh-key add`.\n\nWith --from-gh <user>, the profile is for that authenticated gh account, on its host (use user@host when it is logged in on several): the git name and email are prefilled from its GitHub profile (or your global gitconfig) and the SSH key from ~/.ssh, so you only confirm them.",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runProfileAdd(auth, args[0])
+			var flags profileEditFlags
+			if cmd.Flags().Changed("gh-user") {
+				flags.GHUser = &ghUser
+			}
+			if cmd.Flags().Changed("git-name") {
+				flags.GitName = &gitName
+			}
+			if cmd.Flags().Changed("git-email") {
+				flags.GitEmail = &gitEmail
+			}
+			if cmd.Flags().Changed("ssh-key") {
+				flags.SSHKey = &sshKey
+			}
+			if cmd.Flags().Changed("description") {
+				flags.Description = &description
+			}
+			if cmd.Flags().Changed("clone-protocol") {
+				flags.CloneProtocol = &cloneProtocol
+			}
+			if opts.generateKey && flags.SSHKey != nil {
+				return fmt.Errorf("--generate-ssh-key cannot be combined with --ssh-key")
+			}
+			if opts.fromGH != "" && flags.GHUser != nil {
+				return fmt.Errorf("--from-gh cannot be combined with --gh-user")
+			}
+			if opts.force && !opts.setDefault {
+				return fmt.Errorf("--force requires --default")
+			}
+			return runProfileAdd(auth, args[0], flags, opts)
 		},
 	}
+
+	cmd.Flags().StringVar(&ghUser, "gh-user", "", "GitHub username")
+	cmd.Flags().StringVar(&gitName, "git-name", "", "Git author name")
+	cmd.Flags().StringVar(&gitEmail, "git-email", "", "Git author email")
+	cmd.Flags().StringVar(&sshKey, "ssh-key", "", "SSH key path (optional)")
+	cmd.Flags().StringVar(&description, "description", "", "Free-form label shown next to the profile (optional)")
+	cmd.Flags().StringVar(&cloneProtocol, "clone-protocol", "", "Protocol gh identity clone uses for this profile: ssh or https (optional)")
+	cmd.Flags().BoolVar(&opts.generateKey, "generate-ssh-key", false, "Generate a dedicated SSH key at ~/.ssh/id_<name> for the profile")
+	cmd.Flags().StringVar(&opts.fromGH, "from-gh", "", "Prefill the profile from this authenticated gh account")
+	cmd.Flags().BoolVar(&opts.setDefault, "default", false, "Make the new profile the default")
+	cmd.Flags().BoolVar(&opts.force, "force", false, "With --default, replace an existing default profile")
+	return cmd
+}
+
+// profileAddOptions are the `profile add` flags that are not profile fields.
+type profileAddOptions struct {
+	generateKey bool   // --generate-ssh-key
+	fromGH      string // --from-gh: the gh account to prefill from
+	setDefault  bool   // --default: make the new profile the default
+	force       bool   // --force: replace an existing default with --default
 }
 
-func runProfileAdd(auth ghauth.Auth, name string) error {
+func runProfileAdd(auth ghauth.Auth, name string, flags profileEditFlags, opts profileAddOptions) error {
+	unlock, err := config.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	profiles, err := config.LoadProfiles()
 	if err != nil {
 		return err
@@ -49,35 +113,82 @@ func runProfileAdd(auth ghauth.Auth, name string) error {
 	if _, exists := profiles.Profiles[name]; exists {
 		return fmt.Errorf("profile %q already exists", name)
 	}
+	// Checked before prompting so nothing is asked for in vain.
+	if opts.setDefault && profiles.Default != "" && !opts.force {
+		return fmt.Errorf("%q is already the default profile — pass --force to replace it", profiles.Default)
+	}
+	if flags.CloneProtocol != nil {
+		if err := config.CheckCloneProtocol(*flags.CloneProtocol); err != nil {
+			return err
+		}
+	}
 
-	// List authenticated users for reference.
-	users, err := auth.AuthenticatedUsers()
-	if err == nil && len(users) > 0 {
-		fmt.Printf("Authenticated accounts: %s\n", strings.Join(users, ", "))
+	// With --from-gh, the account is given and the other fields are
+	// prefilled, so the prompts only ask for confirmation.
+	var defaults config.Profile
+	if opts.fromGH != "" {
+		account, err := findAccount(auth, opts.fromGH)
+		if err != nil {
+			return err
+		}
+		flags.GHUser = &account.User
+		if account.Host != ghauth.DefaultHost {
+			defaults.Host = account.Host
+		}
+		defaults.GitName, defaults.GitEmail = inferGitDetails(auth, account)
+		defaults.SSHKey = detectSSHKey()
 	}
 
+	// Only prompt for fields not given as flags. The SSH key is optional, so
+	// it is not asked for once every required field was passed.
+	interactive := flags.GHUser == nil || flags.GitName == nil || flags.GitEmail == nil
 	reader := bufio.NewReader(os.Stdin)
+	prompt := func(field *string, label, def string) string {
+		if field != nil {
+			return *field
+		}
+		if def != "" {
+			return promptWithDefault(reader, label, def)
+		}
+		fmt.Printf("%s: ", label)
+		return readLine(reader)
+	}
 
-	fmt.Printf("GitHub username (gh_user): ")
-	ghUser := readLine(reader)
-
-	fmt.Printf("Git name: ")
-	gitName := readLine(reader)
-
-	fmt.Printf("Git email: ")
-	gitEmail := readLine(reader)
-
-	fmt.Printf("SSH key path (optional): ")
-	sshKey := readLine(reader)
+	if flags.GHUser == nil {
+		// List authenticated users for reference.
+		users, err := auth.AuthenticatedUsers()
+		if err == nil && len(users) > 0 {
+			note("Authenticated accounts: %s", strings.Join(users, ", "))
+		}
+	}
 
 	p := config.Profile{
-		GHUser:   ghUser,
-		GitName:  gitName,
-		GitEmail: gitEmail,
-		SSHKey:   sshKey,
+		GHUser:   prompt(flags.GHUser, "GitHub username (gh_user)", ""),
+		GitName:  prompt(flags.GitName, "Git name", defaults.GitName),
+		GitEmail: prompt(flags.GitEmail, "Git email", defaults.GitEmail),
+		Host:     defaults.Host,
+	}
+	if opts.generateKey {
+		if err := generateSSHKey(auth, name, &p, askYesNo(func() string { return readLine(reader) }), true); err != nil {
+			return fmt.Errorf("generating SSH key: %w", err)
+		}
+	} else if flags.SSHKey != nil || interactive {
+		p.SSHKey = prompt(flags.SSHKey, "SSH key path (optional)", defaults.SSHKey)
+		if p.SSHKey != "" {
+			warnSSHKey(p.SSHKey)
+		}
+	}
+	if flags.Description != nil || interactive {
+		p.Description = prompt(flags.Description, "Description (optional)", "")
+	}
+	if flags.CloneProtocol != nil {
+		p.CloneProtocol = *flags.CloneProtocol
 	}
 
 	profiles.AddProfile(name, p)
+	if opts.setDefault {
+		profiles.Default = name
+	}
 	if err := profiles.Save(); err != nil {
 		return err
 	}
@@ -87,30 +198,179 @@ func runProfileAdd(auth ghauth.Auth, name string) error {
 		return fmt.Errorf("writing gitconfig fragment: %w", err)
 	}
 
-	fmt.Printf("✅ Profile %q created.\n", name)
+	done([]string{"created", name}, "Profile %q created.", name)
+	if opts.setDefault {
+		done([]string{"default", name}, "Default profile is now %q.", name)
+	}
 	return nil
 }
 
-func newProfileListCmd() *cobra.Command {
-	return &cobra.Command{
+// findAccount returns the authenticated gh account named by spec, a user or,
+// to pick one host among several, user@host as init names profiles.
+func findAccount(auth ghauth.Auth, spec string) (ghauth.Account, error) {
+	accounts, err := auth.Accounts()
+	if err != nil {
+		return ghauth.Account{}, fmt.Errorf("listing authenticated accounts: %w", err)
+	}
+	user, host, _ := strings.Cut(spec, "@")
+	var matches []ghauth.Account
+	for _, a := range accounts {
+		if strings.EqualFold(a.User, user) && (host == "" || strings.EqualFold(a.Host, host)) {
+			matches = append(matches, a)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return ghauth.Account{}, fmt.Errorf("%q is not an authenticated gh account — run `gh auth login` first", spec)
+	case 1:
+		return matches[0], nil
+	}
+	hosts := make([]string, len(matches))
+	for i, a := range matches {
+		hosts[i] = a.User + "@" + a.Host
+	}
+	return ghauth.Account{}, fmt.Errorf("%q is logged in on several hosts; pass one of %s", spec, strings.Join(hosts, ", "))
+}
+
+// warnSSHKey warns right away about an SSH key path that does not exist or
+// that others can read, problems doctor would otherwise report later. The
+// profile is saved regardless, since the key may be created afterwards.
+func warnSSHKey(key string) {
+	expanded, err := config.ExpandConfigPath(key)
+	if err != nil {
+		warn("Cannot expand SSH key path %q: %v", key, err)
+		return
+	}
+	info, err := os.Stat(expanded)
+	switch {
+	case os.IsNotExist(err):
+		warn("SSH key %s does not exist; saving the profile anyway.", expanded)
+	case err != nil:
+		warn("Cannot check SSH key %s: %v", expanded, err)
+	case info.Mode().Perm()&0o077 != 0:
+		warn("SSH key %s has overly permissive permissions (%o); run: chmod 600 %s", expanded, info.Mode().Perm(), expanded)
+	}
+}
+
+// profileJSON is the machine-readable form of a profile in `profile list --json`.
+type profileJSON struct {
+	Name         string   `json:"name"`
+	GHUser       string   `json:"gh_user"`
+	Host         string   `json:"host,omitempty"`
+	GitName      string   `json:"git_name"`
+	GitEmail     string   `json:"git_email"`
+	SSHKey       string   `json:"ssh_key,omitempty"`
+	SSHKeys      []string `json:"ssh_keys,omitempty"`
+	SSHHostAlias string   `json:"ssh_host_alias,omitempty"`
+	SigningKey   string   `json:"signing_key,omitempty"`
+	Description  string   `json:"description,omitempty"`
+	IsDefault    bool     `json:"is_default"`
+	IsActive     bool     `json:"is_active"`
+
+	// Checks holds the results of `profile list --verify`.
+	Checks []doctorResult `json:"checks,omitempty"`
+}
+
+func newProfileListCmd(auth ghauth.Auth) *cobra.Command {
+	var jsonOut, verify, names bool
+
+	cmd := &cobra.Command{
 		Use:     "list",
 		Short:   "List all configured profiles",
 		Aliases: []string{"ls"},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runProfileList()
+			if names {
+				return runProfileListNames()
+			}
+			if verify {
+				return runProfileListVerify(auth, jsonOut)
+			}
+			return runProfileList(jsonOut)
 		},
 	}
+
+	cmd.Flags().BoolVar(&jsonOut, "json", false, "Output as JSON")
+	cmd.Flags().BoolVar(&verify, "verify", false, "Check each profile's gh auth and SSH keys")
+	cmd.Flags().BoolVar(&names, "names", false, "Print only the profile names, one per line")
+	cmd.MarkFlagsMutuallyExclusive("names", "json")
+	cmd.MarkFlagsMutuallyExclusive("names", "verify")
+	return cmd
 }
 
-func runProfileList() error {
+func runProfileList(jsonOut bool) error {
+	return listProfiles(nil, jsonOut)
+}
+
+// runProfileListNames prints the profile names, sorted, one per line and
+// nothing else, for shell completion and scripts.
+func runProfileListNames() error {
 	profiles, err := config.LoadProfiles()
 	if err != nil {
 		return err
 	}
+	names := make([]string, 0, len(profiles.Profiles))
+	for name := range profiles.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Println(name)
+	}
+	return nil
+}
 
-	if len(profiles.Profiles) == 0 {
-		fmt.Println("No profiles configured. Run `gh identity profile add <name>` to create one.")
-		return nil
+// runProfileListVerify lists profiles along with whether each one's gh user
+// is authenticated and its SSH keys are usable. It is the per-profile subset
+// of doctor and likewise fails when any check does not pass.
+func runProfileListVerify(auth ghauth.Auth, jsonOut bool) error {
+	accounts, err := auth.Accounts()
+	if err != nil {
+		return fmt.Errorf("listing authenticated users: %w", err)
+	}
+	authed := make(map[ghauth.Account]bool)
+	for _, a := range accounts {
+		authed[a] = true
+	}
+
+	failed := 0
+	err = listProfiles(func(p config.Profile) []doctorResult {
+		results := append([]doctorResult{profileAuthResult(p, authed)}, sshKeyResults(p)...)
+		for _, r := range results {
+			if r.Status != doctorOK {
+				failed++
+			}
+		}
+		return results
+	}, jsonOut)
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d profile check(s) failed", failed)
+	}
+	return nil
+}
+
+// profileAuthResult reports whether the profile's gh user is logged in on
+// its host.
+func profileAuthResult(p config.Profile, authed map[ghauth.Account]bool) doctorResult {
+	if authed[ghauth.Account{Host: p.GHHost(), User: p.GHUser}] {
+		return okResult("auth", "gh user %s is authenticated on %s", p.GHUser, p.GHHost())
+	}
+	if p.GHHost() == config.DefaultHost {
+		return errorResult("auth", "gh user %s is not authenticated", p.GHUser).
+			withHint("Run `gh auth login` to authenticate as %s.", p.GHUser)
+	}
+	return errorResult("auth", "gh user %s is not authenticated on %s", p.GHUser, p.Host).
+		withHint("Run `gh auth login --hostname %s` to authenticate as %s.", p.Host, p.GHUser)
+}
+
+// listProfiles prints every profile. When verify is non-nil its results are
+// shown under each profile.
+func listProfiles(verify func(config.Profile) []doctorResult, jsonOut bool) error {
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
 	}
 
 	activeProfile := os.Getenv("GH_IDENTITY_PROFILE")
@@ -122,6 +382,37 @@ func runProfileList() error {
 	}
 	sort.Strings(names)
 
+	if jsonOut {
+		out := make([]profileJSON, 0, len(names))
+		for _, name := range names {
+			p := profiles.Profiles[name]
+			pj := profileJSON{
+				Name:         name,
+				GHUser:       p.GHUser,
+				Host:         p.Host,
+				GitName:      p.GitName,
+				GitEmail:     p.GitEmail,
+				SSHKey:       p.SSHKey,
+				SSHKeys:      p.SSHKeys,
+				SSHHostAlias: p.SSHHostAlias,
+				SigningKey:   p.SigningKey,
+				Description:  p.Description,
+				IsDefault:    name == profiles.Default,
+				IsActive:     name == activeProfile,
+			}
+			if verify != nil {
+				pj.Checks = verify(p)
+			}
+			out = append(out, pj)
+		}
+		return printJSON(out)
+	}
+
+	if len(names) == 0 {
+		fmt.Println("No profiles configured. Run `gh identity profile add <name>` to create one.")
+		return nil
+	}
+
 	for _, name := range names {
 		p := profiles.Profiles[name]
 		indicator := "  "
@@ -130,82 +421,649 @@ func runProfileList() error {
 		} else if name == profiles.Default {
 			indicator = "→ "
 		}
-		fmt.Printf("%s%s\n", indicator, name)
+		fmt.Printf("%s%s%s\n", indicator, name, describe(p))
 		fmt.Printf("    gh_user:   %s\n", p.GHUser)
+		if p.GHHost() != config.DefaultHost {
+			fmt.Printf("    host:      %s\n", p.Host)
+		}
 		fmt.Printf("    git_name:  %s\n", p.GitName)
 		fmt.Printf("    git_email: %s\n", p.GitEmail)
-		if p.SSHKey != "" {
-			fmt.Printf("    ssh_key:   %s\n", p.SSHKey)
+		if keys := p.AllSSHKeys(); len(keys) > 0 {
+			fmt.Printf("    ssh_key:   %s\n", strings.Join(keys, ", "))
+		}
+		if p.SSHHostAlias != "" {
+			fmt.Printf("    ssh_host:  %s\n", p.SSHHostAlias)
+		}
+		if p.SigningKey != "" {
+			fmt.Printf("    signing:   %s\n", signingDescription(p))
+		}
+		if verify != nil {
+			for _, r := range verify(p) {
+				mark := "✓"
+				if r.Status != doctorOK {
+					mark = "✗"
+				}
+				fmt.Printf("    %s %s\n", mark, r.Message)
+				if r.Hint != "" {
+					fmt.Printf("      %s\n", r.Hint)
+				}
+			}
 		}
 	}
 
 	return nil
 }
 
-func newProfileRemoveCmd() *cobra.Command {
+// profileShowJSON is the machine-readable form of `profile show --json`.
+type profileShowJSON struct {
+	profileJSON
+	SigningFormat  string   `json:"signing_format,omitempty"`
+	CloneProtocol  string   `json:"clone_protocol,omitempty"`
+	FragmentPath   string   `json:"fragment_path"`
+	FragmentExists bool     `json:"fragment_exists"`
+	Bindings       []string `json:"bindings"`
+	IncludeIfDirs  []string `json:"includeif_dirs"`
+	Authenticated  bool     `json:"authenticated"`
+}
+
+func newProfileShowCmd(auth ghauth.Auth) *cobra.Command {
+	var jsonOut bool
+
+	cmd := &cobra.Command{
+		Use:   "show <name>",
+		Short: "Show a profile with its bindings, gitconfig fragment, and auth state",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runProfileShow(auth, args[0], jsonOut)
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOut, "json", false, "Output as JSON")
+	return cmd
+}
+
+func runProfileShow(auth ghauth.Auth, name string, jsonOut bool) error {
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+	p, err := profiles.GetProfile(name)
+	if err != nil {
+		return fmt.Errorf("profile %q not found — run `gh identity profile list` to see available profiles", name)
+	}
+
+	gitDir, err := config.GitConfigDir()
+	if err != nil {
+		return err
+	}
+	fragmentPath := filepath.Join(gitDir, name+".gitconfig")
+	_, statErr := os.Stat(fragmentPath)
+
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		return err
+	}
+	targets := []string{}
+	for _, b := range bindings.Bindings {
+		if b.Profile == name {
+			targets = append(targets, b.Target())
+		}
+	}
+
+	// includeIf directives that pull in this profile's fragment.
+	includeDirs := []string{}
+	if gcPath, err := gitconfig.GlobalGitconfigPath(); err == nil {
+		includes, _ := gitconfig.ParseIncludeIfs(gcPath)
+		for _, inc := range includes {
+			if inc.Path == fragmentPath {
+				includeDirs = append(includeDirs, inc.Dir)
+			}
+		}
+	}
+
+	authenticated := false
+	if accounts, err := auth.Accounts(); err == nil {
+		for _, a := range accounts {
+			if a.Host == p.GHHost() && a.User == p.GHUser {
+				authenticated = true
+				break
+			}
+		}
+	}
+
+	if jsonOut {
+		return printJSON(profileShowJSON{
+			profileJSON: profileJSON{
+				Name:         name,
+				GHUser:       p.GHUser,
+				Host:         p.Host,
+				GitName:      p.GitName,
+				GitEmail:     p.GitEmail,
+				SSHKey:       p.SSHKey,
+				SSHKeys:      p.SSHKeys,
+				SSHHostAlias: p.SSHHostAlias,
+				SigningKey:   p.SigningKey,
+				Description:  p.Description,
+				IsDefault:    name == profiles.Default,
+				IsActive:     name == os.Getenv("GH_IDENTITY_PROFILE"),
+			},
+			SigningFormat:  p.SigningFormat,
+			CloneProtocol:  p.CloneProtocol,
+			FragmentPath:   fragmentPath,
+			FragmentExists: statErr == nil,
+			Bindings:       targets,
+			IncludeIfDirs:  includeDirs,
+			Authenticated:  authenticated,
+		})
+	}
+
+	fmt.Printf("  Profile:   %s", name)
+	if name == profiles.Default {
+		fmt.Print(" (default)")
+	}
+	fmt.Println(describe(p))
+	fmt.Printf("  Account:   %s", p.GHUser)
+	if authenticated {
+		fmt.Println(" ✅ authenticated")
+	} else {
+		fmt.Println(" ❌ not authenticated — run `gh auth login`")
+	}
+	if p.GHHost() != config.DefaultHost {
+		fmt.Printf("  Host:      %s\n", p.Host)
+	}
+	fmt.Printf("  Name:      %s\n", p.GitName)
+	fmt.Printf("  Email:     %s\n", p.GitEmail)
+	if keys := p.AllSSHKeys(); len(keys) > 0 {
+		fmt.Printf("  SSH Key:   %s\n", strings.Join(keys, ", "))
+	}
+	if p.SSHHostAlias != "" {
+		fmt.Printf("  SSH Host:  %s\n", p.SSHHostAlias)
+	}
+	if p.SigningKey != "" {
+		fmt.Printf("  Signing:   %s\n", signingDescription(p))
+	}
+	if p.CloneProtocol != "" {
+		fmt.Printf("  Clone:     %s\n", p.CloneProtocol)
+	}
+	fmt.Printf("  Fragment:  %s", fragmentPath)
+	if statErr != nil {
+		fmt.Print(" (missing)")
+	}
+	fmt.Println()
+
+	if len(targets) == 0 {
+		fmt.Println("  Bindings:  none")
+	} else {
+		fmt.Println("  Bindings:")
+		for _, t := range targets {
+			fmt.Printf("    %s\n", t)
+		}
+	}
+	if len(includeDirs) > 0 {
+		fmt.Println("  includeIf:")
+		for _, d := range includeDirs {
+			fmt.Printf("    %s\n", d)
+		}
+	}
+	return nil
+}
+
+// describe returns " — <description>" for a profile with a description, for
+// printing after its name, or "" otherwise.
+func describe(p config.Profile) string {
+	if p.Description == "" {
+		return ""
+	}
+	return " — " + p.Description
+}
+
+// signingDescription formats a profile's signing key with its format, if any.
+func signingDescription(p config.Profile) string {
+	if p.SigningFormat == "" {
+		return p.SigningKey
+	}
+	return fmt.Sprintf("%s (%s)", p.SigningKey, p.SigningFormat)
+}
+
+// profileEditFlags holds field values given as flags to `profile add` and
+// `profile edit`. A nil field was not given.
+type profileEditFlags struct {
+	GHUser        *string
+	GitName       *string
+	GitEmail      *string
+	SSHKey        *string
+	Description   *string
+	CloneProtocol *string
+}
+
+// isSet reports whether any field override was provided.
+func (f profileEditFlags) isSet() bool {
+	return f.GHUser != nil || f.GitName != nil || f.GitEmail != nil || f.SSHKey != nil || f.Description != nil ||
+		f.CloneProtocol != nil
+}
+
+func newProfileEditCmd() *cobra.Command {
+	var ghUser, gitName, gitEmail, sshKey, description, cloneProtocol string
+
+	cmd := &cobra.Command{
+		Use:   "edit <name>",
+		Short: "Edit an existing identity profile",
+		Long:  "Edit an existing profile interactively, using the current values as defaults. Pass one or more field flags to update only those fields without prompting.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var flags profileEditFlags
+			if cmd.Flags().Changed("gh-user") {
+				flags.GHUser = &ghUser
+			}
+			if cmd.Flags().Changed("git-name") {
+				flags.GitName = &gitName
+			}
+			if cmd.Flags().Changed("git-email") {
+				flags.GitEmail = &gitEmail
+			}
+			if cmd.Flags().Changed("ssh-key") {
+				flags.SSHKey = &sshKey
+			}
+			if cmd.Flags().Changed("description") {
+				flags.Description = &description
+			}
+			if cmd.Flags().Changed("clone-protocol") {
+				flags.CloneProtocol = &cloneProtocol
+			}
+			return runProfileEdit(args[0], flags)
+		},
+	}
+
+	cmd.Flags().StringVar(&ghUser, "gh-user", "", "Set the GitHub username")
+	cmd.Flags().StringVar(&gitName, "git-name", "", "Set the git author name")
+	cmd.Flags().StringVar(&gitEmail, "git-email", "", "Set the git author email")
+	cmd.Flags().StringVar(&sshKey, "ssh-key", "", "Set the SSH key path (empty to clear)")
+	cmd.Flags().StringVar(&description, "description", "", "Set the description (empty to clear)")
+	cmd.Flags().StringVar(&cloneProtocol, "clone-protocol", "", "Set the protocol gh identity clone uses: ssh or https (empty to clear)")
+	return cmd
+}
+
+func runProfileEdit(name string, flags profileEditFlags) error {
+	unlock, err := config.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+
+	p, err := profiles.GetProfile(name)
+	if err != nil {
+		return fmt.Errorf("profile %q not found — run `gh identity profile list` to see available profiles", name)
+	}
+
+	if flags.isSet() {
+		if flags.GHUser != nil {
+			p.GHUser = *flags.GHUser
+		}
+		if flags.GitName != nil {
+			p.GitName = *flags.GitName
+		}
+		if flags.GitEmail != nil {
+			p.GitEmail = *flags.GitEmail
+		}
+		if flags.SSHKey != nil {
+			p.SSHKey = *flags.SSHKey
+		}
+		if flags.Description != nil {
+			p.Description = *flags.Description
+		}
+		if flags.CloneProtocol != nil {
+			if err := config.CheckCloneProtocol(*flags.CloneProtocol); err != nil {
+				return err
+			}
+			p.CloneProtocol = *flags.CloneProtocol
+		}
+	} else {
+		reader := bufio.NewReader(os.Stdin)
+		p.GHUser = promptWithDefault(reader, "GitHub username (gh_user)", p.GHUser)
+		p.GitName = promptWithDefault(reader, "Git name", p.GitName)
+		p.GitEmail = promptWithDefault(reader, "Git email", p.GitEmail)
+		p.SSHKey = promptWithDefault(reader, "SSH key path", p.SSHKey)
+		p.Description = promptWithDefault(reader, "Description", p.Description)
+		p.CloneProtocol = promptWithDefault(reader, "Clone protocol (ssh/https)", p.CloneProtocol)
+		if err := config.CheckCloneProtocol(p.CloneProtocol); err != nil {
+			return err
+		}
+	}
+
+	if err := saveEditedProfile(profiles, name, p); err != nil {
+		return err
+	}
+
+	done([]string{"updated", name}, "Profile %q updated.", name)
+	return nil
+}
+
+// saveEditedProfile stores the changed profile p under name and propagates
+// the change to its gitconfig fragment and to repositories bound with
+// --local.
+func saveEditedProfile(profiles *config.ProfilesFile, name string, p config.Profile) error {
+	profiles.AddProfile(name, p)
+	if err := profiles.Save(); err != nil {
+		return err
+	}
+
+	// Rewrite gitconfig fragment so includeIf targets pick up the changes.
+	if err := gitconfig.WriteProfileFragment(name, p); err != nil {
+		return fmt.Errorf("writing gitconfig fragment: %w", err)
+	}
+	// Repositories bound with --local hold their own copy.
+	if bindings, err := config.LoadBindings(); err == nil {
+		for _, b := range bindings.Bindings {
+			if b.Profile == name && b.IsLocal() {
+				if err := gitconfig.SetLocalIdentity(b.Path, p); err != nil {
+					warn("Could not update the local git config of %s: %v", b.Path, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func newProfileSetCmd() *cobra.Command {
 	return &cobra.Command{
+		Use:   "set <name> <field> <value>",
+		Short: "Set a single field of a profile",
+		Long: fmt.Sprintf(`Set one field of a profile without prompting, e.g. gh identity profile set work git_email me@acme.com. The value is validated the way doctor validates profiles.yml, and the profile's gitconfig fragment is rewritten.
+
+Fields are named by their profiles.yml key: %s. ssh_keys takes a comma-separated list. An empty value clears an optional field.`, strings.Join(config.ProfileFields(), ", ")),
+		Args: cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runProfileSet(args[0], args[1], args[2])
+		},
+	}
+}
+
+func runProfileSet(name, field, value string) error {
+	unlock, err := config.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+	p, err := profiles.GetProfile(name)
+	if err != nil {
+		return fmt.Errorf("profile %q not found — run `gh identity profile list` to see available profiles", name)
+	}
+	if err := p.SetField(field, value); err != nil {
+		return err
+	}
+	if err := saveEditedProfile(profiles, name, p); err != nil {
+		return err
+	}
+
+	done([]string{"set", name, field, value}, "Profile %q: %s set to %q.", name, field, value)
+	return nil
+}
+
+// promptWithDefault prints "label [def]: " and returns the entered value,
+// or def if the input is empty.
+func promptWithDefault(reader *bufio.Reader, label, def string) string {
+	fmt.Printf("%s [%s]: ", label, def)
+	if v := readLine(reader); v != "" {
+		return v
+	}
+	return def
+}
+
+func newProfileRenameCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "rename <old> <new>",
+		Short:   "Rename a profile, updating its bindings and gitconfig",
+		Aliases: []string{"mv"},
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runProfileRename(args[0], args[1])
+		},
+	}
+}
+
+func runProfileRename(oldName, newName string) error {
+	unlock, err := config.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+
+	p, err := profiles.GetProfile(oldName)
+	if err != nil {
+		return fmt.Errorf("profile %q not found — run `gh identity profile list` to see available profiles", oldName)
+	}
+	if _, exists := profiles.Profiles[newName]; exists {
+		return fmt.Errorf("profile %q already exists", newName)
+	}
+
+	delete(profiles.Profiles, oldName)
+	profiles.AddProfile(newName, p)
+	if profiles.Default == oldName {
+		profiles.Default = newName
+	}
+	for org, profile := range profiles.Orgs {
+		if profile == oldName {
+			profiles.Orgs[org] = newName
+		}
+	}
+	if err := profiles.Save(); err != nil {
+		return err
+	}
+
+	// Re-point bindings.
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		return err
+	}
+	var movedPaths, movedRemotes, movedTargets []string
+	movedCount := 0
+	for i, b := range bindings.Bindings {
+		if b.Profile != oldName {
+			continue
+		}
+		bindings.Bindings[i].Profile = newName
+		movedCount++
+		movedTargets = append(movedTargets, b.Target())
+		switch {
+		case b.IsRemote():
+			movedRemotes = append(movedRemotes, b.RemotePattern)
+		case !b.IsLocal(): // local bindings don't reference the fragment
+			movedPaths = append(movedPaths, b.Path)
+		}
+	}
+	if err := bindings.Save(); err != nil {
+		return err
+	}
+
+	// Replace the gitconfig fragment.
+	if err := gitconfig.RemoveProfileFragment(oldName); err != nil {
+		warn("Could not remove old gitconfig fragment: %v", err)
+	}
+	if err := gitconfig.WriteProfileFragment(newName, p); err != nil {
+		return fmt.Errorf("writing gitconfig fragment: %w", err)
+	}
+
+	// Re-point includeIf directives at the new fragment.
+	if len(movedPaths) > 0 || len(movedRemotes) > 0 {
+		gcPath, err := gitconfig.GlobalGitconfigPath()
+		if err != nil {
+			return err
+		}
+		gitDir, err := config.GitConfigDir()
+		if err != nil {
+			return err
+		}
+		fragmentPath := filepath.Join(gitDir, newName+".gitconfig")
+		for _, bp := range movedPaths {
+			expanded, err := config.ExpandConfigPath(bp)
+			if err != nil {
+				continue
+			}
+			if err := gitconfig.RemoveIncludeIf(gcPath, expanded); err != nil {
+				return fmt.Errorf("removing includeIf directive: %w", err)
+			}
+			if err := gitconfig.AddIncludeIf(gcPath, expanded, fragmentPath); err != nil {
+				return fmt.Errorf("adding includeIf directive: %w", err)
+			}
+		}
+		for _, pattern := range movedRemotes {
+			if err := gitconfig.AddIncludeIfRemote(gcPath, pattern, fragmentPath); err != nil {
+				return fmt.Errorf("adding includeIf directive: %w", err)
+			}
+		}
+	}
+
+	done([]string{"renamed", oldName, newName}, "Profile %q renamed to %q.", oldName, newName)
+	for _, target := range movedTargets {
+		record("bound", target, newName)
+	}
+	if movedCount > 0 {
+		note("   Updated %d binding(s).", movedCount)
+	}
+	return nil
+}
+
+func newProfileRemoveCmd() *cobra.Command {
+	var yes, dryRun bool
+
+	cmd := &cobra.Command{
 		Use:     "remove <name>",
 		Short:   "Remove a profile and its associated bindings",
+		Long:    "Remove a profile along with its bindings, gitconfig fragment, and includeIf directives. Lists what will be removed and asks for confirmation unless --yes is passed.",
 		Aliases: []string{"rm"},
 		Args:    cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runProfileRemove(args[0])
+			return runProfileRemove(args[0], yes, dryRun)
 		},
 	}
+
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Do not ask for confirmation")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the changes without writing anything")
+	return cmd
 }
 
-func runProfileRemove(name string) error {
-	profiles, err := config.LoadProfiles()
+func runProfileRemove(name string, yes, dryRun bool) error {
+	unlock, err := config.Lock()
 	if err != nil {
 		return err
 	}
+	defer unlock()
 
-	if err := profiles.RemoveProfile(name); err != nil {
+	profiles, err := config.LoadProfiles()
+	if err != nil {
 		return err
 	}
-	if err := profiles.Save(); err != nil {
+
+	wasDefault := profiles.Default == name
+	if err := profiles.RemoveProfile(name); err != nil {
 		return err
 	}
 
-	// Remove associated bindings.
+	// Collect associated bindings.
 	bindings, err := config.LoadBindings()
 	if err != nil {
 		return err
 	}
 
-	var remaining []config.Binding
-	var removedPaths []string
-	for _, b := range bindings.Bindings {
-		if b.Profile == name {
-			removedPaths = append(removedPaths, b.Path)
+	removedPaths, removedRemotes, removedLocals := removeBindings(bindings, func(b config.Binding) bool { return b.Profile == name })
+	removedCount := len(removedPaths) + len(removedRemotes) + len(removedLocals)
+
+	gcPath, gcErr := gitconfig.GlobalGitconfigPath()
+	fragmentPath := ""
+	if gitDir, err := config.GitConfigDir(); err == nil {
+		fragmentPath = filepath.Join(gitDir, name+".gitconfig")
+	}
+
+	if dryRun {
+		actions := []string{fmt.Sprintf("Would remove profile %q", name)}
+		if wasDefault {
+			actions = append(actions, "Would clear the default profile")
+		}
+		if fragmentPath != "" {
+			actions = append(actions, fmt.Sprintf("Would delete gitconfig fragment %s", fragmentPath))
+		}
+		actions = append(actions, unbindActions(removedPaths, removedRemotes, removedLocals, gcPath, gcErr)...)
+		printDryRun(actions)
+		return nil
+	}
+
+	if !yes {
+		fmt.Printf("About to remove profile %q:\n", name)
+		if wasDefault {
+			fmt.Println("   • the profile, which is the default")
 		} else {
-			remaining = append(remaining, b)
+			fmt.Println("   • the profile")
+		}
+		if removedCount > 0 {
+			targets := append(slices.Clone(removedPaths), removedLocals...)
+			for _, pattern := range removedRemotes {
+				targets = append(targets, "remote "+pattern)
+			}
+			fmt.Printf("   • %d binding(s): %s\n", removedCount, strings.Join(targets, ", "))
+		}
+		if fragmentPath != "" {
+			fmt.Printf("   • gitconfig fragment %s\n", fragmentPath)
+		}
+		if gcErr == nil {
+			directives := len(removedPaths)
+			for _, pattern := range removedRemotes {
+				directives += len(gitconfig.RemoteURLGlobs(pattern))
+			}
+			if directives > 0 {
+				fmt.Printf("   • %d includeIf directive(s) in %s\n", directives, gcPath)
+			}
+		}
+		if len(removedLocals) > 0 {
+			fmt.Printf("   • the identity in the local git config of %s\n", strings.Join(removedLocals, ", "))
 		}
+		reader := bufio.NewReader(os.Stdin)
+		if !askYesNo(func() string { return readLine(reader) })("Remove?") {
+			note("Aborted; nothing was removed.")
+			return nil
+		}
+	}
+
+	if err := profiles.Save(); err != nil {
+		return err
 	}
-	bindings.Bindings = remaining
 	if err := bindings.Save(); err != nil {
 		return err
 	}
 
 	// Remove gitconfig fragment and includeIf entries.
 	if err := gitconfig.RemoveProfileFragment(name); err != nil {
-		fmt.Printf("⚠️  Could not remove gitconfig fragment: %v\n", err)
+		warn("Could not remove gitconfig fragment: %v", err)
 	}
 
-	gcPath, err := gitconfig.GlobalGitconfigPath()
-	if err == nil {
-		for _, p := range removedPaths {
-			expanded, err := config.ExpandPath(p)
-			if err != nil {
-				continue
-			}
-			_ = gitconfig.RemoveIncludeIf(gcPath, expanded)
-		}
+	if gcErr == nil {
+		removeIncludeIfs(gcPath, removedPaths, removedRemotes)
 	}
+	unsetLocalIdentities(removedLocals)
 
-	fmt.Printf("✅ Profile %q removed.\n", name)
-	if len(removedPaths) > 0 {
-		fmt.Printf("   Also removed %d binding(s).\n", len(removedPaths))
+	done([]string{"removed", name}, "Profile %q removed.", name)
+	for _, p := range append(removedPaths, removedLocals...) {
+		record("unbound", p)
+	}
+	for _, pattern := range removedRemotes {
+		record("unbound", "remote:"+pattern)
+	}
+	if removedCount > 0 {
+		note("   Also removed %d binding(s).", removedCount)
 	}
 	return nil
 }
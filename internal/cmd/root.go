@@ -2,9 +2,13 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
 
+	"github.com/dotbrains/gh-identity/internal/config"
 	"github.com/dotbrains/gh-identity/internal/ghauth"
+	"github.com/dotbrains/gh-identity/internal/version"
 )
 
 // NewRootCmd creates the root command for gh identity.
@@ -12,21 +16,86 @@ func NewRootCmd() *cobra.Command {
 	auth := ghauth.NewGHAuth()
 
 	root := &cobra.Command{
-		Use:   "identity",
-		Short: "Manage multiple GitHub identities",
-		Long:  `gh-identity provides seamless multi-account management, automatic context-based account switching, and per-directory identity binding.`,
+		Use:     "identity",
+		Short:   "Manage multiple GitHub identities",
+		Long:    `gh-identity provides seamless multi-account management, automatic context-based account switching, and per-directory identity binding.`,
+		Version: version.String(),
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if verbose {
+				enableVerbose()
+			}
+			override := profileOverride(cmd)
+			if override != "" && cmd.Annotations[profileOverrideAnnotation] == "" {
+				return fmt.Errorf("--profile is not supported by `gh %s`; it applies to status, current, and clone", cmd.CommandPath())
+			}
+			return validateProfileOverride(override)
+		},
 	}
 
+	root.PersistentFlags().String("profile", "", "Run this command as if the given profile were active")
+	root.PersistentFlags().BoolVar(&porcelain, "porcelain", false, "Print terse, stable, tab-separated output for scripts")
+	root.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Log debug tracing to stderr")
+	root.PersistentFlags().BoolVar(&config.Strict, "strict", false, "Reject unknown keys in profiles.yml and bindings.yml")
+
 	root.AddCommand(
 		newInitCmd(auth),
+		newImportCmd(auth),
+		newExportCmd(),
 		newProfileCmd(auth),
-		newBindCmd(),
+		newBindCmd(auth),
 		newUnbindCmd(),
+		newBindingsCmd(),
 		newSwitchCmd(auth),
+		newUseCmd(),
 		newStatusCmd(auth),
+		newCurrentCmd(),
+		newWhichCmd(),
 		newCloneCmd(auth),
 		newDoctorCmd(auth),
+		newMapCmd(),
+		newHookCmd(),
+		newCredentialCmd(auth),
 	)
 
 	return root
 }
+
+// profileOverrideAnnotation marks the commands that honor the global
+// --profile flag. Any other command rejects it rather than silently running
+// as the active profile.
+const profileOverrideAnnotation = "profile-override"
+
+// honorsProfileOverride is the Annotations value for commands that read
+// profileOverride.
+var honorsProfileOverride = map[string]string{profileOverrideAnnotation: "true"}
+
+// profileOverride returns the value of the global --profile flag, or "" when
+// it is unset or the command is not attached to the root. Commands such as
+// `unbind --profile` define a local --profile of their own, which shadows the
+// global one and is not an override.
+func profileOverride(cmd *cobra.Command) string {
+	if cmd.LocalNonPersistentFlags().Lookup("profile") != nil {
+		return ""
+	}
+	f := cmd.Flags().Lookup("profile")
+	if f == nil {
+		return ""
+	}
+	return f.Value.String()
+}
+
+// validateProfileOverride checks that a --profile override names an existing
+// profile.
+func validateProfileOverride(name string) error {
+	if name == "" {
+		return nil
+	}
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+	if _, err := profiles.GetProfile(name); err != nil {
+		return fmt.Errorf("--profile: profile %q not found — run `gh identity profile list` to see available profiles", name)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	gh "github.com/cli/go-gh/v2"
+
+	"github.com/dotbrains/gh-identity/internal/config"
+	"github.com/dotbrains/gh-identity/internal/ghauth"
+)
+
+// sshKeygen creates an ed25519 key pair at path. When interactive,
+// ssh-keygen runs attached to the terminal so it can ask for a passphrase;
+// otherwise the key gets none. Its output goes to stderr so it cannot mix
+// with --porcelain records. It is a variable so tests don't shell out.
+var sshKeygen = func(path, comment string, interactive bool) error {
+	args := []string{"-t", "ed25519", "-f", path, "-C", comment}
+	if !interactive {
+		args = append(args, "-N", "")
+	}
+	cmd := exec.Command("ssh-keygen", args...)
+	if interactive {
+		cmd.Stdin = os.Stdin
+	}
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("running ssh-keygen: %w", err)
+	}
+	return nil
+}
+
+// sshKeyUpload adds the public key at pubPath to the GitHub account of p
+// with `gh ssh-key add`, authenticated as that account rather than whichever
+// one gh has active. It is a variable so tests don't shell out.
+var sshKeyUpload = func(auth ghauth.Auth, p config.Profile, pubPath, title string) error {
+	token, err := auth.Token(p.GHHost(), p.GHUser)
+	if err != nil {
+		return err
+	}
+	ghExe, err := gh.Path()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(ghExe, "ssh-key", "add", pubPath, "--title", title)
+	cmd.Env = append(os.Environ(), "GH_TOKEN="+token, "GH_HOST="+p.GHHost())
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("gh ssh-key add: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
+
+// sshKeyPath returns where `--generate-ssh-key` puts the key for profile
+// name, in the ~ form stored in profiles.yml.
+func sshKeyPath(name string) string {
+	return "~/.ssh/id_" + name
+}
+
+// generateSSHKey creates a dedicated SSH key for profile name, points
+// p.SSHKey at it, prints the public key, and offers to upload it. ask
+// answers yes/no questions: whether to overwrite an existing key (declining
+// keeps and uses it) and whether to upload the new one. Unless interactive,
+// nothing is read from stdin and the key has no passphrase.
+func generateSSHKey(auth ghauth.Auth, name string, p *config.Profile, ask func(question string) bool, interactive bool) error {
+	stored := sshKeyPath(name)
+	path, err := config.ExpandPath(stored)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		if !ask(fmt.Sprintf("%s already exists. Overwrite it?", path)) {
+			note("Keeping the existing key %s.", path)
+			p.SSHKey = stored
+			return nil
+		}
+		// ssh-keygen would ask again; the question was already answered.
+		for _, f := range []string{path, path + ".pub"} {
+			if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	if err := sshKeygen(path, p.GitEmail, interactive); err != nil {
+		return err
+	}
+	p.SSHKey = stored
+
+	pub, err := os.ReadFile(path + ".pub")
+	if err != nil {
+		return err
+	}
+	done([]string{"generated", path}, "Generated SSH key %s", path)
+	note("%s", strings.TrimSpace(string(pub)))
+
+	if ask(fmt.Sprintf("Upload it to %s with `gh ssh-key add`?", p.GHUser)) {
+		title := "gh-identity " + name
+		if err := sshKeyUpload(auth, *p, path+".pub", title); err != nil {
+			warn("Could not upload the key: %v", err)
+			note("   The token may lack the admin:public_key scope; run `gh auth refresh -s admin:public_key` and retry with `gh ssh-key add %s.pub`.", path)
+		} else {
+			done([]string{"uploaded", path + ".pub"}, "Uploaded the key to %s.", p.GHUser)
+		}
+	}
+	return nil
+}
+
+// askYesNo returns an ask function for generateSSHKey that reads y/N answers
+// with readLine.
+func askYesNo(readLine func() string) func(string) bool {
+	return func(question string) bool {
+		fmt.Printf("%s [y/N]: ", question)
+		return strings.EqualFold(readLine(), "y")
+	}
+}
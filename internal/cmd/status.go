@@ -1,54 +1,259 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"strings"
+	"text/template"
 
 	"github.com/spf13/cobra"
 
 	"github.com/dotbrains/gh-identity/internal/config"
 	"github.com/dotbrains/gh-identity/internal/ghauth"
+	"github.com/dotbrains/gh-identity/internal/gitconfig"
 	"github.com/dotbrains/gh-identity/internal/resolve"
 )
 
+// statusJSON is the machine-readable form of `status --json`.
+type statusJSON struct {
+	Profile        *string  `json:"profile"`
+	Account        string   `json:"account,omitempty"`
+	GitName        string   `json:"git_name,omitempty"`
+	GitEmail       string   `json:"git_email,omitempty"`
+	CommitterName  string   `json:"committer_name,omitempty"`
+	CommitterEmail string   `json:"committer_email,omitempty"`
+	SSHKey         string   `json:"ssh_key,omitempty"`
+	SSHKeys        []string `json:"ssh_keys,omitempty"`
+	SSHHostAlias   string   `json:"ssh_host_alias,omitempty"`
+	SigningKey     string   `json:"signing_key,omitempty"`
+	Description    string   `json:"description,omitempty"`
+	BoundPath      string   `json:"bound_path,omitempty"`
+	Remote         string   `json:"remote,omitempty"`
+	IncludeIf      string   `json:"include_if,omitempty"`
+	Org            string   `json:"org,omitempty"`
+	RepoFile       string   `json:"repo_file,omitempty"`
+	Source         string   `json:"source,omitempty"`
+	Warnings       []string `json:"warnings,omitempty"`
+}
+
 func newStatusCmd(auth ghauth.Auth) *cobra.Command {
-	return &cobra.Command{
-		Use:   "status",
-		Short: "Display the active identity",
+	var jsonOut, short, check, watch bool
+	var path, format string
+
+	cmd := &cobra.Command{
+		Use:         "status",
+		Short:       "Display the active identity",
+		Annotations: honorsProfileOverride,
+		Long: `Display the active identity.
+
+For shell prompts, --short prints only the active profile name (or nothing),
+and --check compares the profile this directory resolves to with
+GH_IDENTITY_PROFILE. Neither calls gh.
+
+--check exit codes:
+  0  the environment matches this directory's profile
+  1  they differ (the shell hook has not run since the last cd), or an error occurred
+
+--path reports the identity another directory resolves to, e.g. the
+repository of a file open in an editor. GH_IDENTITY_PROFILE only describes
+the current shell, so it is ignored for other paths.
+
+--watch keeps running and prints the status again whenever profiles.yml or
+bindings.yml changes, to confirm config edits take effect. Stop it with Ctrl-C.
+
+--format renders a Go text/template instead, for prompts and one-liners, e.g.
+--format '{{.Profile}} ({{.Email}})'. Like --short, it does not call gh.
+Fields: .Profile, .Account, .Name, .Email, .Description, .SSHKey,
+.SigningKey, .Source, and .BoundBy. All are empty when no profile is active.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runStatus(auth)
+			switch {
+			case watch:
+				ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+				defer stop()
+				return runStatusWatch(ctx, auth, path, profileOverride(cmd), jsonOut)
+			case short:
+				return runStatusShort(path, profileOverride(cmd))
+			case check:
+				cmd.SilenceUsage = true
+				cmd.SilenceErrors = true
+				return runStatusCheck()
+			case cmd.Flags().Changed("format"):
+				return runStatusFormat(path, profileOverride(cmd), format)
+			}
+			return runStatus(auth, path, profileOverride(cmd), jsonOut)
 		},
 	}
+
+	cmd.Flags().BoolVar(&jsonOut, "json", false, "Output as JSON")
+	cmd.Flags().BoolVar(&short, "short", false, "Print only the active profile name")
+	cmd.Flags().BoolVar(&check, "check", false, "Exit non-zero if GH_IDENTITY_PROFILE differs from this directory's profile")
+	cmd.Flags().StringVar(&path, "path", "", "Report the identity of this directory instead of the current one")
+	cmd.Flags().StringVar(&format, "format", "", "Render the status with a Go template, e.g. '{{.Profile}} {{.Email}}'")
+	cmd.MarkFlagsMutuallyExclusive("json", "short", "check", "format")
+	cmd.Flags().BoolVar(&watch, "watch", false, "Print the status again whenever profiles.yml or bindings.yml changes")
+	cmd.MarkFlagsMutuallyExclusive("path", "check")
+	cmd.MarkFlagsMutuallyExclusive("watch", "short", "check", "format")
+	return cmd
 }
 
-func runStatus(auth ghauth.Auth) error {
+// resolveWorkingDir loads the config and resolves the active profile for
+// the current directory. override is the --profile flag value; when it is
+// empty GH_IDENTITY_PROFILE applies.
+func resolveWorkingDir(override string) (*config.ProfilesFile, resolve.Resolution, error) {
+	return resolveDir("", override)
+}
+
+// resolveDir is resolveWorkingDir for path, or for the current directory
+// when path is "". GH_IDENTITY_PROFILE describes the current shell, so it
+// only applies to the current directory.
+func resolveDir(path, override string) (*config.ProfilesFile, resolve.Resolution, error) {
 	profiles, err := config.LoadProfiles()
 	if err != nil {
-		return err
+		return nil, resolve.Resolution{}, err
 	}
 
 	bindings, err := config.LoadBindings()
+	if err != nil {
+		return nil, resolve.Resolution{}, err
+	}
+	gitconfig.InferBindings(bindings)
+
+	var dir string
+	if path == "" {
+		if dir, err = os.Getwd(); err != nil {
+			return nil, resolve.Resolution{}, fmt.Errorf("getting working directory: %w", err)
+		}
+		if override == "" {
+			override = os.Getenv("GH_IDENTITY_PROFILE")
+		}
+	} else if dir, err = config.ExpandPath(path); err != nil {
+		return nil, resolve.Resolution{}, err
+	}
+
+	res, err := resolve.Active(dir, bindings, profiles, override)
+	if err != nil {
+		return nil, resolve.Resolution{}, err
+	}
+	return profiles, res, nil
+}
+
+// runStatusShort prints the active profile name, or nothing, for prompts.
+func runStatusShort(path, override string) error {
+	_, res, err := resolveDir(path, override)
+	if err != nil {
+		return err
+	}
+	name := res.Profile
+	if name != "" {
+		fmt.Println(name)
+	}
+	return nil
+}
+
+// statusFormatData is what `status --format` templates render.
+type statusFormatData struct {
+	Profile     string
+	Account     string
+	Name        string
+	Email       string
+	Description string
+	SSHKey      string
+	SigningKey  string
+	Source      string // as in --json: binding, remote, default, ...
+	BoundBy     string // the path, remote pattern, includeIf, org, or file behind Source
+}
+
+// parseStatusFormat parses text as a template and renders it once against
+// empty data, so unknown fields are reported before anything is resolved.
+func parseStatusFormat(text string) (*template.Template, error) {
+	tmpl, err := template.New("format").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("--format: %w", err)
+	}
+	if err := tmpl.Execute(io.Discard, statusFormatData{}); err != nil {
+		return nil, fmt.Errorf("--format: %w", err)
+	}
+	return tmpl, nil
+}
+
+// runStatusFormat renders the status with the --format template text,
+// followed by a newline.
+func runStatusFormat(path, override, text string) error {
+	tmpl, err := parseStatusFormat(text)
+	if err != nil {
+		return err
+	}
+	profiles, res, err := resolveDir(path, override)
 	if err != nil {
 		return err
 	}
 
-	pwd, err := os.Getwd()
+	var data statusFormatData
+	if res.Profile != "" {
+		profile, err := profiles.GetProfile(res.Profile)
+		if err != nil {
+			return fmt.Errorf("profile %q configured but not found in profiles.yml", res.Profile)
+		}
+		out := newStatusJSON(res, profile, override, nil)
+		data = statusFormatData{
+			Profile:     res.Profile,
+			Account:     out.Account,
+			Name:        out.GitName,
+			Email:       out.GitEmail,
+			Description: out.Description,
+			SSHKey:      out.SSHKey,
+			SigningKey:  out.SigningKey,
+			Source:      out.Source,
+			// At most one of these is set, depending on Source.
+			BoundBy: out.BoundPath + out.Remote + out.IncludeIf + out.Org + out.RepoFile,
+		}
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return fmt.Errorf("--format: %w", err)
+	}
+	fmt.Println(b.String())
+	return nil
+}
+
+// activeProfileName returns the --profile override, else GH_IDENTITY_PROFILE,
+// else the profile the current directory resolves to, or "" if none applies.
+func activeProfileName(override string) (string, error) {
+	_, res, err := resolveWorkingDir(override)
 	if err != nil {
-		return fmt.Errorf("getting working directory: %w", err)
+		return "", err
 	}
+	return res.Profile, nil
+}
 
-	result, err := resolve.ForDirectory(pwd, bindings, profiles.Default)
+// runStatusCheck returns an error when GH_IDENTITY_PROFILE does not match
+// the profile the current directory resolves to.
+func runStatusCheck() error {
+	_, res, err := resolveWorkingDir("")
 	if err != nil {
 		return err
 	}
+	if env, dir := os.Getenv("GH_IDENTITY_PROFILE"), res.Directory.Profile; env != dir {
+		return fmt.Errorf("environment reflects profile %q but this directory resolves to %q", env, dir)
+	}
+	return nil
+}
 
-	// Check if there's an override from environment.
-	envProfile := os.Getenv("GH_IDENTITY_PROFILE")
-	if envProfile != "" {
-		result.Profile = envProfile
+func runStatus(auth ghauth.Auth, path, override string, jsonOut bool) error {
+	profiles, res, err := resolveDir(path, override)
+	if err != nil {
+		return err
 	}
+	result := res.Result
 
 	if result.Profile == "" {
+		if jsonOut {
+			return printJSON(statusJSON{})
+		}
 		fmt.Println("No active profile.")
 		fmt.Println("Run `gh identity bind <profile>` or `gh identity switch <profile>` to activate one.")
 		return nil
@@ -59,20 +264,130 @@ func runStatus(auth ghauth.Auth) error {
 		return fmt.Errorf("profile %q configured but not found in profiles.yml", result.Profile)
 	}
 
-	fmt.Printf("  Profile:  %s\n", result.Profile)
-	fmt.Printf("  Account:  %s\n", profile.GHUser)
-	fmt.Printf("  Name:     %s\n", profile.GitName)
-	fmt.Printf("  Email:    %s\n", profile.GitEmail)
-	if profile.SSHKey != "" {
-		fmt.Printf("  SSH Key:  %s\n", profile.SSHKey)
+	// The shell's exported variables only say something about the current
+	// directory.
+	var warnings []string
+	if override == "" && path == "" {
+		warnings = envDrift(auth, res.Directory.Profile, result.Profile, profile)
+	}
+
+	if jsonOut {
+		return printJSON(newStatusJSON(res, profile, override, warnings))
+	}
+
+	fmt.Printf("  Profile:   %s%s\n", result.Profile, describe(profile))
+	fmt.Printf("  Account:   %s\n", profile.GHUser)
+	fmt.Printf("  Name:      %s\n", profile.GitName)
+	fmt.Printf("  Email:     %s\n", profile.GitEmail)
+	if committer := committerDescription(profile); committer != "" {
+		fmt.Printf("  Committer: %s\n", committer)
 	}
-	if result.BoundPath != "" {
-		fmt.Printf("  Bound by: %s\n", result.BoundPath)
-	} else if result.IsDefault {
-		fmt.Printf("  Source:   default profile\n")
-	} else if envProfile != "" {
-		fmt.Printf("  Source:   environment (GH_IDENTITY_PROFILE)\n")
+	if keys := profile.AllSSHKeys(); len(keys) > 0 {
+		fmt.Printf("  SSH Key:   %s\n", strings.Join(keys, ", "))
+	}
+	if profile.SSHHostAlias != "" {
+		fmt.Printf("  SSH Host:  %s\n", profile.SSHHostAlias)
+	}
+	if profile.SigningKey != "" {
+		fmt.Printf("  Signing:   %s\n", signingDescription(profile))
+	}
+	switch {
+	case override != "":
+		fmt.Printf("  Source:    --profile flag\n")
+	case res.Source == resolve.SourceEnvironment:
+		fmt.Printf("  Source:    environment (GH_IDENTITY_PROFILE)\n")
+	case res.Source == resolve.SourceRepoFile:
+		fmt.Printf("  Bound by:  %s (repository file)\n", result.RepoFile)
+	case result.BoundPath != "":
+		fmt.Printf("  Bound by:  %s\n", result.BoundPath)
+	case result.RemotePattern != "":
+		fmt.Printf("  Bound by:  remote %s\n", result.RemotePattern)
+	case result.IncludeIf != "":
+		fmt.Printf("  Bound by:  includeIf gitdir:%s (no entry in bindings.yml)\n", result.IncludeIf)
+	case result.Org != "":
+		fmt.Printf("  Bound by:  org %s\n", result.Org)
+	case res.Source == resolve.SourceDefault:
+		fmt.Printf("  Source:    default profile\n")
+	}
+	for _, w := range warnings {
+		fmt.Printf("\n⚠️  %s\n", w)
 	}
 
 	return nil
 }
+
+// newStatusJSON describes res, which resolved to profile, for --json and
+// --format. override is the --profile flag value.
+func newStatusJSON(res resolve.Resolution, profile config.Profile, override string, warnings []string) statusJSON {
+	result := res.Result
+	out := statusJSON{
+		Profile:      &result.Profile,
+		Account:      profile.GHUser,
+		GitName:      profile.GitName,
+		GitEmail:     profile.GitEmail,
+		SSHKey:       profile.SSHKey,
+		SSHKeys:      profile.SSHKeys,
+		SSHHostAlias: profile.SSHHostAlias,
+		SigningKey:   profile.SigningKey,
+		Description:  profile.Description,
+		Warnings:     warnings,
+	}
+	if committerDescription(profile) != "" {
+		out.CommitterName, out.CommitterEmail = profile.Committer()
+	}
+	switch {
+	case override != "":
+		out.Source = "flag"
+	case res.Source == resolve.SourceEnvironment:
+		out.Source = "environment"
+	case res.Source == resolve.SourceRepoFile:
+		out.Source = "repo_file"
+		out.RepoFile = result.RepoFile
+	case result.BoundPath != "":
+		out.Source = "binding"
+		out.BoundPath = result.BoundPath
+	case result.RemotePattern != "":
+		out.Source = "remote"
+		out.Remote = result.RemotePattern
+	case result.IncludeIf != "":
+		out.Source = "includeif"
+		out.IncludeIf = result.IncludeIf
+	case result.Org != "":
+		out.Source = "org"
+		out.Org = result.Org
+	case result.IsDefault:
+		out.Source = "default"
+	}
+	return out
+}
+
+// envDrift compares the identity variables exported into this shell with
+// the profile this directory resolves to and the profile status reports.
+// Mismatches usually mean the shell hook did not run after the last cd.
+func envDrift(auth ghauth.Auth, dirProfile, active string, profile config.Profile) []string {
+	var warnings []string
+	const fix = "open a new shell or re-run the hook"
+
+	if env := os.Getenv("GH_IDENTITY_PROFILE"); env != "" && dirProfile != "" && env != dirProfile {
+		warnings = append(warnings, fmt.Sprintf("Environment reflects profile %q but this directory resolves to %q — %s.", env, dirProfile, fix))
+	}
+	if email := os.Getenv("GIT_AUTHOR_EMAIL"); email != "" && profile.GitEmail != "" && !strings.EqualFold(email, profile.GitEmail) {
+		warnings = append(warnings, fmt.Sprintf("GIT_AUTHOR_EMAIL is %s but profile %q uses %s — commits will use the wrong email; %s.", email, active, profile.GitEmail, fix))
+	}
+	if token := os.Getenv("GH_TOKEN"); token != "" && profile.GHUser != "" {
+		if want, err := auth.Token(profile.GHHost(), profile.GHUser); err == nil && want != "" && token != want {
+			warnings = append(warnings, fmt.Sprintf("GH_TOKEN does not belong to %s (profile %q) — %s.", profile.GHUser, active, fix))
+		}
+	}
+	return warnings
+}
+
+// committerDescription returns "Name <email>" for the committer of p's
+// commits, or "" when it is the same as the author.
+func committerDescription(p config.Profile) string {
+	name, email := p.Committer()
+	if name == p.GitName && email == p.GitEmail {
+		return ""
+	}
+	return fmt.Sprintf("%s <%s>", name, email)
+}
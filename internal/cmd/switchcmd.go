@@ -1,51 +1,107 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/dotbrains/gh-identity/internal/config"
 	"github.com/dotbrains/gh-identity/internal/ghauth"
+	"github.com/dotbrains/gh-identity/internal/hook"
 )
 
 func newSwitchCmd(auth ghauth.Auth) *cobra.Command {
-	return &cobra.Command{
-		Use:   "switch <profile>",
+	var (
+		writePath string
+		shell     string
+		off       bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "switch [<profile>]",
 		Short: "Manually activate a profile for the current session",
-		Long:  "Activate a profile for the current session, overriding any directory binding until the next directory change.",
-		Args:  cobra.ExactArgs(1),
+		Long: `Activate a profile for the current session, overriding any directory binding until the next directory change.
+
+Pass --write <file> to also save the statements to a file your shell can source on startup, so new shells start with the profile. --off prints (or writes) statements that unset every variable gh-identity manages instead, undoing a switch so the shell hook's directory bindings apply again:
+
+  eval "$(gh identity switch --off)"
+
+Statements are printed for bash unless --shell names zsh, fish, nu, or powershell.`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runSwitch(auth, args[0])
+			switch {
+			case off && len(args) > 0:
+				return errors.New("--off does not take a profile")
+			case !off && len(args) == 0:
+				return errors.New("specify a profile, or --off to unset the session identity")
+			}
+			sh, err := parseShell(shell)
+			if err != nil {
+				return err
+			}
+			profile := ""
+			if len(args) > 0 {
+				profile = args[0]
+			}
+			return runSwitch(auth, profile, sh, writePath)
 		},
 	}
+
+	cmd.Flags().StringVar(&writePath, "write", "", "Also write the statements to this file, for sourcing from shell startup")
+	cmd.Flags().StringVar(&shell, "shell", "bash", "Shell to print statements for: bash, zsh, fish, nu, or powershell")
+	cmd.Flags().BoolVar(&off, "off", false, "Unset the managed variables instead of activating a profile")
+	// --clear is the original name of --off.
+	cmd.Flags().BoolVar(&off, "clear", false, "Unset the managed variables instead of activating a profile")
+	_ = cmd.Flags().MarkHidden("clear")
+	return cmd
 }
 
-func runSwitch(_ ghauth.Auth, profileName string) error {
-	profiles, err := config.LoadProfiles()
-	if err != nil {
-		return err
+// parseShell validates a --shell value.
+func parseShell(name string) (hook.ShellType, error) {
+	switch sh := hook.ShellType(strings.ToLower(name)); sh {
+	case hook.Bash, hook.Zsh, hook.Fish, hook.Nu, hook.PowerShell:
+		return sh, nil
 	}
+	return "", fmt.Errorf("unsupported shell %q: use bash, zsh, fish, nu, or powershell", name)
+}
+
+// runSwitch prints the statements for shell that activate profileName, or
+// that clear the managed variables when profileName is "". When writePath is
+// set, the same statements are written there too.
+func runSwitch(_ ghauth.Auth, profileName string, shell hook.ShellType, writePath string) error {
+	var out string
+	if profileName == "" {
+		out = hook.FormatClear(shell)
+	} else {
+		profiles, err := config.LoadProfiles()
+		if err != nil {
+			return err
+		}
 
-	profile, err := profiles.GetProfile(profileName)
-	if err != nil {
-		return err
+		profile, err := profiles.GetProfile(profileName)
+		if err != nil {
+			return err
+		}
+		out = hook.Format(shell, hook.ProfileEnv(profileName, profile))
 	}
 
-	// Print commands for the user to eval.
-	fmt.Println("unset GH_TOKEN 2>/dev/null")
-	fmt.Printf("gh auth switch --user %s 2>/dev/null\n", profile.GHUser)
-	fmt.Printf("export GIT_AUTHOR_NAME=%q\n", profile.GitName)
-	fmt.Printf("export GIT_AUTHOR_EMAIL=%q\n", profile.GitEmail)
-	fmt.Printf("export GIT_COMMITTER_NAME=%q\n", profile.GitName)
-	fmt.Printf("export GIT_COMMITTER_EMAIL=%q\n", profile.GitEmail)
-	fmt.Printf("export GH_IDENTITY_PROFILE=%q\n", profileName)
-	if profile.SSHKey != "" {
-		expanded, err := config.ExpandPath(profile.SSHKey)
-		if err == nil {
-			fmt.Printf("export GIT_SSH_COMMAND=%q\n", fmt.Sprintf("ssh -i %s -o IdentitiesOnly=yes", expanded))
+	if writePath != "" {
+		path, err := config.ExpandPath(writePath)
+		if err != nil {
+			return err
+		}
+		if err := config.WriteFileAtomic(path, []byte(out), 0o644); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
 		}
+		// stdout stays eval-able, so the confirmation goes to stderr.
+		fmt.Fprintf(os.Stderr, "✅ Wrote %s — source it from your shell startup file.\n", path)
 	}
 
+	// Print commands for the user to eval. This output is meant for scripts
+	// already, so --porcelain leaves it unchanged.
+	fmt.Print(out)
 	return nil
 }
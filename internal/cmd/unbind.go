@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
+	"os"
+	"slices"
 
 	"github.com/spf13/cobra"
 
@@ -10,23 +13,56 @@ import (
 )
 
 func newUnbindCmd() *cobra.Command {
-	return &cobra.Command{
+	var remote, profile string
+	var all, yes, dryRun bool
+
+	cmd := &cobra.Command{
 		Use:   "unbind [<path>]",
 		Short: "Remove the binding for a directory",
-		Long:  "Remove the binding for a directory (defaults to $PWD).",
+		Long:  "Remove the binding for a directory (defaults to $PWD), or for a remote pattern with --remote. --profile removes every binding of one profile and --all removes every binding; --all asks for confirmation unless --yes is passed.",
 		Args:  cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if all || profile != "" {
+				if len(args) != 0 {
+					return fmt.Errorf("--all and --profile do not take a <path> argument")
+				}
+				if all {
+					return runUnbindAll(yes, dryRun)
+				}
+				return runUnbindProfile(profile, dryRun)
+			}
+			if remote != "" {
+				if len(args) != 0 {
+					return fmt.Errorf("--remote does not take a <path> argument")
+				}
+				return runUnbindRemote(remote, dryRun)
+			}
+
 			dirPath := "."
 			if len(args) == 1 {
 				dirPath = args[0]
 			}
-			return runUnbind(dirPath)
+			return runUnbind(dirPath, dryRun)
 		},
 	}
+
+	cmd.Flags().StringVar(&remote, "remote", "", "Remove the binding for this remote pattern")
+	cmd.Flags().StringVar(&profile, "profile", "", "Remove every binding for this profile")
+	cmd.Flags().BoolVar(&all, "all", false, "Remove every binding")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Do not ask for confirmation with --all")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the changes without writing anything")
+	cmd.MarkFlagsMutuallyExclusive("remote", "profile", "all")
+	return cmd
 }
 
-func runUnbind(dirPath string) error {
-	expanded, err := config.ExpandPath(dirPath)
+func runUnbind(dirPath string, dryRun bool) error {
+	unlock, err := config.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	expanded, err := config.ResolvePath(dirPath)
 	if err != nil {
 		return err
 	}
@@ -36,19 +72,238 @@ func runUnbind(dirPath string) error {
 		return err
 	}
 
+	prev, _ := bindings.Lookup(expanded)
 	if err := bindings.RemoveBinding(expanded); err != nil {
 		return err
 	}
+
+	gcPath, gcErr := gitconfig.GlobalGitconfigPath()
+
+	if dryRun {
+		actions := []string{fmt.Sprintf("Would unbind %s", expanded)}
+		switch {
+		case prev.IsLocal():
+			actions = append(actions, fmt.Sprintf("Would remove the identity from the local git config of %s", expanded))
+		case gcErr == nil:
+			actions = append(actions, fmt.Sprintf("Would remove includeIf \"gitdir:%s/\" from %s", expanded, gcPath))
+		}
+		printDryRun(actions)
+		return nil
+	}
+
 	if err := bindings.Save(); err != nil {
 		return err
 	}
 
-	// Remove includeIf from global gitconfig.
-	gcPath, err := gitconfig.GlobalGitconfigPath()
-	if err == nil {
+	// Undo whatever told git about the identity.
+	switch {
+	case prev.IsLocal():
+		if err := gitconfig.UnsetLocalIdentity(expanded); err != nil {
+			warn("Could not remove the identity from the local git config: %v", err)
+		}
+	case gcErr == nil:
 		_ = gitconfig.RemoveIncludeIf(gcPath, expanded)
 	}
 
-	fmt.Printf("✅ Unbound %s\n", expanded)
+	done([]string{"unbound", expanded}, "Unbound %s", expanded)
+	return nil
+}
+
+func runUnbindRemote(pattern string, dryRun bool) error {
+	unlock, err := config.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		return err
+	}
+	if err := bindings.RemoveRemoteBinding(pattern); err != nil {
+		return err
+	}
+
+	gcPath, gcErr := gitconfig.GlobalGitconfigPath()
+
+	if dryRun {
+		actions := []string{fmt.Sprintf("Would unbind remote %s", pattern)}
+		if gcErr == nil {
+			for _, glob := range gitconfig.RemoteURLGlobs(pattern) {
+				actions = append(actions, fmt.Sprintf("Would remove includeIf \"hasconfig:remote.*.url:%s\" from %s", glob, gcPath))
+			}
+		}
+		printDryRun(actions)
+		return nil
+	}
+
+	if err := bindings.Save(); err != nil {
+		return err
+	}
+
+	if gcErr == nil {
+		_ = gitconfig.RemoveIncludeIfRemote(gcPath, pattern)
+	}
+
+	done([]string{"unbound", "remote:" + pattern}, "Unbound remote %s", pattern)
 	return nil
 }
+
+// runUnbindAll removes every binding after confirming, unless yes is set.
+func runUnbindAll(yes, dryRun bool) error {
+	unlock, err := config.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		return err
+	}
+	if len(bindings.Bindings) == 0 {
+		note("No bindings to remove.")
+		return nil
+	}
+	if !yes && !dryRun {
+		reader := bufio.NewReader(os.Stdin)
+		if !askYesNo(func() string { return readLine(reader) })(fmt.Sprintf("Remove all %d binding(s) and their includeIf directives?", len(bindings.Bindings))) {
+			note("Aborted; nothing was removed.")
+			return nil
+		}
+	}
+	return unbindMatching(bindings, func(config.Binding) bool { return true }, dryRun)
+}
+
+// runUnbindProfile removes every binding for the named profile. The profile
+// itself is kept; it need not even exist, so orphaned bindings can be cleared.
+func runUnbindProfile(name string, dryRun bool) error {
+	unlock, err := config.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		return err
+	}
+	return unbindMatching(bindings, func(b config.Binding) bool { return b.Profile == name }, dryRun)
+}
+
+// unbindMatching removes the bindings match selects, saves, and prunes
+// their includeIf directives. The caller holds the config lock.
+func unbindMatching(bindings *config.BindingsFile, match func(config.Binding) bool, dryRun bool) error {
+	paths, remotes, locals := removeBindings(bindings, match)
+	count := len(paths) + len(remotes) + len(locals)
+	if count == 0 {
+		note("No matching bindings.")
+		return nil
+	}
+
+	gcPath, gcErr := gitconfig.GlobalGitconfigPath()
+
+	if dryRun {
+		printDryRun(unbindActions(paths, remotes, locals, gcPath, gcErr))
+		return nil
+	}
+
+	if err := bindings.Save(); err != nil {
+		return err
+	}
+	if gcErr == nil {
+		removeIncludeIfs(gcPath, paths, remotes)
+	}
+	unsetLocalIdentities(locals)
+
+	for _, p := range append(paths, locals...) {
+		record("unbound", p)
+	}
+	for _, pattern := range remotes {
+		record("unbound", "remote:"+pattern)
+	}
+	note("✅ Removed %d binding(s).", count)
+	return nil
+}
+
+// removeBindings drops the bindings match selects from bindings and returns
+// their directory paths and remote patterns. Directory bindings with local
+// scope are returned in locals instead of paths.
+func removeBindings(bindings *config.BindingsFile, match func(config.Binding) bool) (paths, remotes, locals []string) {
+	var remaining []config.Binding
+	for _, b := range bindings.Bindings {
+		switch {
+		case !match(b):
+			remaining = append(remaining, b)
+		case b.IsRemote():
+			remotes = append(remotes, b.RemotePattern)
+		case b.IsLocal():
+			locals = append(locals, b.Path)
+		default:
+			paths = append(paths, b.Path)
+		}
+	}
+	bindings.Bindings = remaining
+	return paths, remotes, locals
+}
+
+// unbindActions describes, for --dry-run, removing the given bindings and
+// their includeIf directives or local git config.
+func unbindActions(paths, remotes, locals []string, gcPath string, gcErr error) []string {
+	var actions []string
+	for _, p := range append(slices.Clone(paths), locals...) {
+		actions = append(actions, fmt.Sprintf("Would unbind %s", p))
+	}
+	for _, pattern := range remotes {
+		actions = append(actions, fmt.Sprintf("Would unbind remote %s", pattern))
+	}
+	return append(actions, cleanupActions(paths, remotes, locals, gcPath, gcErr)...)
+}
+
+// cleanupActions describes, for --dry-run, removing the includeIf
+// directives or local git config of the given bindings.
+func cleanupActions(paths, remotes, locals []string, gcPath string, gcErr error) []string {
+	var actions []string
+	for _, p := range locals {
+		actions = append(actions, fmt.Sprintf("Would remove the identity from the local git config of %s", p))
+	}
+	if gcErr != nil {
+		return actions
+	}
+	for _, p := range paths {
+		if expanded, err := config.ExpandConfigPath(p); err == nil {
+			actions = append(actions, fmt.Sprintf("Would remove includeIf \"gitdir:%s/\" from %s", expanded, gcPath))
+		}
+	}
+	for _, pattern := range remotes {
+		for _, glob := range gitconfig.RemoteURLGlobs(pattern) {
+			actions = append(actions, fmt.Sprintf("Would remove includeIf \"hasconfig:remote.*.url:%s\" from %s", glob, gcPath))
+		}
+	}
+	return actions
+}
+
+// unsetLocalIdentities removes the identity from the local git config of
+// each repository in locals.
+func unsetLocalIdentities(locals []string) {
+	for _, p := range locals {
+		if err := gitconfig.UnsetLocalIdentity(p); err != nil {
+			warn("Could not remove the identity from the local git config of %s: %v", p, err)
+		}
+	}
+}
+
+// removeIncludeIfs removes the includeIf directives for the given directory
+// bindings and remote patterns from the global gitconfig.
+func removeIncludeIfs(gcPath string, paths, remotes []string) {
+	for _, p := range paths {
+		expanded, err := config.ExpandConfigPath(p)
+		if err != nil {
+			continue
+		}
+		_ = gitconfig.RemoveIncludeIf(gcPath, expanded)
+	}
+	for _, pattern := range remotes {
+		_ = gitconfig.RemoveIncludeIfRemote(gcPath, pattern)
+	}
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dotbrains/gh-identity/internal/config"
+)
+
+func newUseCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "use <profile>",
+		Short: "Set the default profile",
+		Long:  "Set the default profile used wherever no directory binding applies. Unlike `switch`, this persists across shells.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runUse(args[0])
+		},
+	}
+}
+
+func runUse(profileName string) error {
+	unlock, err := config.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+
+	if _, err := profiles.GetProfile(profileName); err != nil {
+		return fmt.Errorf("profile %q not found — run `gh identity profile list` to see available profiles", profileName)
+	}
+
+	profiles.Default = profileName
+	if err := profiles.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Default profile is now %q.\n", profileName)
+	return nil
+}
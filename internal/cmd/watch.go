@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dotbrains/gh-identity/internal/config"
+	"github.com/dotbrains/gh-identity/internal/ghauth"
+)
+
+// watchInterval is how often `status --watch` checks the config files for
+// changes.
+const watchInterval = 500 * time.Millisecond
+
+// watchTicks returns the channel that paces `status --watch` checks and a
+// function that stops it. Tests replace it to drive the loop one check at a
+// time.
+var watchTicks = func() (<-chan time.Time, func()) {
+	ticker := time.NewTicker(watchInterval)
+	return ticker.C, ticker.Stop
+}
+
+// runStatusWatch prints the status, then prints it again each time
+// profiles.yml or bindings.yml changes, until ctx is done. Changes are
+// detected by polling mtimes and sizes, as the hook cache does, rather than
+// with a filesystem notification library. A config file that fails to load
+// mid-edit is reported and watching continues.
+func runStatusWatch(ctx context.Context, auth ghauth.Auth, path, override string, jsonOut bool) error {
+	profilesPath, err := config.ProfilesPath()
+	if err != nil {
+		return err
+	}
+	bindingsPath, err := config.BindingsPath()
+	if err != nil {
+		return err
+	}
+
+	show := func() {
+		if err := runStatus(auth, path, override, jsonOut); err != nil {
+			fmt.Printf("❌ %v\n", err)
+		}
+	}
+
+	if !jsonOut {
+		fmt.Printf("Watching %s and %s; press Ctrl-C to stop.\n\n", profilesPath, bindingsPath)
+	}
+	last := configStamp(profilesPath, bindingsPath)
+	show()
+
+	ticks, stop := watchTicks()
+	defer stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticks:
+		}
+		stamp := configStamp(profilesPath, bindingsPath)
+		if stamp == last {
+			continue
+		}
+		last = stamp
+		if !jsonOut {
+			fmt.Printf("\n— %s: config changed —\n", time.Now().Format(time.TimeOnly))
+		}
+		show()
+	}
+}
+
+// configStamp summarizes the modification time and size of each file, so a
+// change to any of them changes the stamp. Missing files contribute zeros.
+func configStamp(paths ...string) string {
+	var stamp string
+	for _, p := range paths {
+		var mod, size int64
+		if info, err := os.Stat(p); err == nil {
+			mod, size = info.ModTime().UnixNano(), info.Size()
+		}
+		stamp += fmt.Sprintf("%d:%d;", mod, size)
+	}
+	return stamp
+}
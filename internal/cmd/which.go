@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dotbrains/gh-identity/internal/config"
+	"github.com/dotbrains/gh-identity/internal/gitconfig"
+	"github.com/dotbrains/gh-identity/internal/resolve"
+)
+
+// whichJSON is the machine-readable form of `which --json`. Profile is null
+// when the directory resolves to no profile.
+type whichJSON struct {
+	Path       string               `json:"path"`
+	Profile    *string              `json:"profile"`
+	BoundPath  string               `json:"bound_path,omitempty"`
+	Remote     string               `json:"remote,omitempty"`
+	IncludeIf  string               `json:"include_if,omitempty"`
+	Org        string               `json:"org,omitempty"`
+	RepoFile   string               `json:"repo_file,omitempty"`
+	IsDefault  bool                 `json:"is_default"`
+	Candidates []whichCandidateJSON `json:"candidates"`
+}
+
+// whichCandidateJSON is one directory binding considered by `which --json`.
+// Depth is only meaningful when Matches is true.
+type whichCandidateJSON struct {
+	Path     string `json:"path"`
+	Profile  string `json:"profile"`
+	Glob     bool   `json:"glob"`
+	Matches  bool   `json:"matches"`
+	Depth    int    `json:"depth"`
+	Selected bool   `json:"selected"`
+}
+
+func newWhichCmd() *cobra.Command {
+	var jsonOut bool
+	cmd := &cobra.Command{
+		Use:   "which [path]",
+		Short: "Explain which profile a directory resolves to and why",
+		Long:  "Resolves the profile for a directory (default: the current one) and lists every directory binding considered, with its match and depth, so you can see why the deepest match won. A .gh-identity file at the repository root that names a configured profile wins over every binding. Unlike `status`, this ignores GH_IDENTITY_PROFILE.\n\nWith --json, the same is printed as an object with the resolved profile (null when none), the matched binding, is_default, and the candidates.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := ""
+			if len(args) == 1 {
+				dir = args[0]
+			}
+			return runWhich(dir, jsonOut)
+		},
+	}
+	cmd.Flags().BoolVar(&jsonOut, "json", false, "Output as JSON")
+	return cmd
+}
+
+func runWhich(dir string, jsonOut bool) error {
+	if dir == "" {
+		pwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("getting working directory: %w", err)
+		}
+		dir = pwd
+	}
+	resolved, err := config.ResolvePath(dir)
+	if err != nil {
+		return err
+	}
+
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+	bindings, err := config.LoadBindings()
+	if err != nil {
+		return err
+	}
+	gitconfig.InferBindings(bindings)
+
+	res, err := resolve.Active(resolved, bindings, profiles, "")
+	if err != nil {
+		return err
+	}
+	result := res.Result
+	candidates, err := resolve.Candidates(resolved, bindings)
+	if err != nil {
+		return err
+	}
+
+	if jsonOut {
+		return printJSON(newWhichJSON(resolved, result, candidates))
+	}
+
+	fmt.Printf("  Path:     %s\n", resolved)
+	if result.Profile == "" {
+		fmt.Println("  Profile:  (none)")
+	} else {
+		fmt.Printf("  Profile:  %s%s\n", result.Profile, describe(profiles.Profiles[result.Profile]))
+	}
+	switch {
+	case result.RepoFile != "":
+		fmt.Printf("  Bound by: %s (repository file)\n", result.RepoFile)
+	case result.BoundPath != "":
+		fmt.Printf("  Bound by: %s\n", result.BoundPath)
+	case result.RemotePattern != "":
+		fmt.Printf("  Bound by: remote %s\n", result.RemotePattern)
+	case result.IncludeIf != "":
+		fmt.Printf("  Bound by: includeIf gitdir:%s (no entry in bindings.yml)\n", result.IncludeIf)
+	case result.Org != "":
+		fmt.Printf("  Bound by: org %s\n", result.Org)
+	case result.IsDefault:
+		fmt.Println("  Source:   default profile (no binding matched)")
+	default:
+		fmt.Println("  Source:   no binding matched and no default profile is set")
+	}
+
+	if len(candidates) == 0 {
+		fmt.Println("\nNo directory bindings configured.")
+		return nil
+	}
+
+	fmt.Println("\nCandidates:")
+	for _, c := range candidates {
+		kind := "path"
+		if c.Glob {
+			kind = "glob"
+		}
+		switch {
+		case !c.Matches:
+			fmt.Printf("  ·  %s → %s (%s, no match)\n", c.Binding.Path, c.Binding.Profile, kind)
+		case c.Binding.Path == result.BoundPath:
+			fmt.Printf("  ✅ %s → %s (%s, depth %d, selected)\n", c.Binding.Path, c.Binding.Profile, kind, c.Depth)
+		default:
+			fmt.Printf("  ➖ %s → %s (%s, depth %d)\n", c.Binding.Path, c.Binding.Profile, kind, c.Depth)
+		}
+	}
+	return nil
+}
+
+// newWhichJSON describes the resolution of path for --json.
+func newWhichJSON(path string, result resolve.Result, candidates []resolve.Candidate) whichJSON {
+	out := whichJSON{
+		Path:       path,
+		BoundPath:  result.BoundPath,
+		Remote:     result.RemotePattern,
+		IncludeIf:  result.IncludeIf,
+		Org:        result.Org,
+		RepoFile:   result.RepoFile,
+		IsDefault:  result.IsDefault,
+		Candidates: []whichCandidateJSON{},
+	}
+	if result.Profile != "" {
+		out.Profile = &result.Profile
+	}
+	for _, c := range candidates {
+		out.Candidates = append(out.Candidates, whichCandidateJSON{
+			Path:     c.Binding.Path,
+			Profile:  c.Binding.Profile,
+			Glob:     c.Glob,
+			Matches:  c.Matches,
+			Depth:    c.Depth,
+			Selected: c.Matches && c.Binding.Path == result.BoundPath,
+		})
+	}
+	return out
+}
@@ -3,21 +3,72 @@ package config
 import (
 	"fmt"
 	"os"
+	"os/user"
 	"path/filepath"
+	"runtime"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
-// Binding ties a directory path to a profile name.
+// Binding ties a directory path, or a git remote URL pattern, to a profile name.
+// Exactly one of Path and RemotePattern is set.
 type Binding struct {
-	Path    string `yaml:"path"`
-	Profile string `yaml:"profile"`
+	Path          string `yaml:"path,omitempty" json:"path,omitempty"`
+	RemotePattern string `yaml:"remote,omitempty" json:"remote,omitempty"`
+	Profile       string `yaml:"profile" json:"profile"`
+	// Scope is how git learns the identity: "" for an includeIf in the
+	// global gitconfig, ScopeLocal for settings in the repository's own
+	// .git/config.
+	Scope string `yaml:"scope,omitempty" json:"scope,omitempty"`
 }
 
+// ScopeLocal marks a binding whose identity is written to the repository's
+// .git/config instead of the global gitconfig.
+const ScopeLocal = "local"
+
+// IsLocal reports whether the binding's identity lives in the repository's
+// .git/config.
+func (b Binding) IsLocal() bool {
+	return b.Scope == ScopeLocal
+}
+
+// IsRemote reports whether the binding matches on the origin remote URL
+// rather than a directory path.
+func (b Binding) IsRemote() bool {
+	return b.RemotePattern != ""
+}
+
+// Target returns a display string for what the binding matches.
+func (b Binding) Target() string {
+	if b.IsRemote() {
+		return "remote:" + b.RemotePattern
+	}
+	return b.Path
+}
+
+// BindingsVersion is the current bindings.yml schema version. Files written
+// before versioning was introduced have no version and load as 0.
+const BindingsVersion = 1
+
 // BindingsFile is the top-level structure of bindings.yml.
 type BindingsFile struct {
+	Version  int       `yaml:"version,omitempty"`
 	Bindings []Binding `yaml:"bindings"`
+
+	// Inferred holds directory bindings recovered from gitconfig includeIf
+	// directives that have no entry in Bindings. Resolution consults them
+	// only after every real binding, and they are never saved.
+	Inferred []Binding `yaml:"-"`
+
+	relative []RelativeBinding // relative paths rewritten on load
+}
+
+// RelativeBinding records a binding whose path was relative in bindings.yml.
+type RelativeBinding struct {
+	Original string // the path as written
+	Resolved string // the absolute path it was resolved to
+	Profile  string
 }
 
 // BindingsPath returns the path to bindings.yml.
@@ -44,18 +95,43 @@ func LoadBindingsFrom(path string) (*BindingsFile, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return &BindingsFile{}, nil
+			return &BindingsFile{Version: BindingsVersion}, nil
 		}
 		return nil, fmt.Errorf("reading bindings: %w", err)
 	}
 
 	var bf BindingsFile
-	if err := yaml.Unmarshal(data, &bf); err != nil {
-		return nil, fmt.Errorf("parsing bindings: %w", err)
+	if err := unmarshalYAML(data, &bf); err != nil {
+		return nil, &ParseError{Path: path, Kind: "bindings", Err: err}
 	}
+	bf.absolutize(filepath.Dir(path))
 	return &bf, nil
 }
 
+// absolutize rewrites relative binding paths against base, the directory of
+// bindings.yml, as git does for relative include paths. Without this they
+// would be expanded against whatever directory gh-identity runs in.
+func (bf *BindingsFile) absolutize(base string) {
+	for i, b := range bf.Bindings {
+		if b.IsRemote() || b.Path == "" {
+			continue
+		}
+		p := expandEnv(b.Path)
+		if strings.HasPrefix(p, "~") || filepath.IsAbs(p) {
+			continue
+		}
+		resolved := escapePath(filepath.Join(base, p))
+		bf.Bindings[i].Path = resolved
+		bf.relative = append(bf.relative, RelativeBinding{Original: b.Path, Resolved: resolved, Profile: b.Profile})
+	}
+}
+
+// Relative returns the bindings whose paths were relative in bindings.yml.
+// Saving the file stores them as absolute paths.
+func (bf *BindingsFile) Relative() []RelativeBinding {
+	return bf.relative
+}
+
 // Save writes the bindings file to disk.
 func (bf *BindingsFile) Save() error {
 	path, err := BindingsPath()
@@ -65,8 +141,14 @@ func (bf *BindingsFile) Save() error {
 	return bf.SaveTo(path)
 }
 
-// SaveTo writes the bindings file to the given path.
+// SaveTo writes the bindings file to the given path, stamped with
+// BindingsVersion. Like ProfilesFile.SaveTo, it refuses to overwrite a file
+// from a newer schema version.
 func (bf *BindingsFile) SaveTo(path string) error {
+	if err := checkVersion("bindings.yml", bf.Version, BindingsVersion); err != nil {
+		return err
+	}
+	bf.Version = BindingsVersion
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return fmt.Errorf("creating directory: %w", err)
 	}
@@ -75,20 +157,38 @@ func (bf *BindingsFile) SaveTo(path string) error {
 	if err != nil {
 		return fmt.Errorf("marshalling bindings: %w", err)
 	}
-	if err := os.WriteFile(path, data, 0o644); err != nil {
+	if err := WriteFileAtomic(path, data, 0o644); err != nil {
 		return fmt.Errorf("writing bindings: %w", err)
 	}
 	return nil
 }
 
-// ExpandPath resolves ~ and cleans a path for storage.
+// lookupUser finds a user account by name. It is a variable so tests can
+// stub it out.
+var lookupUser = user.Lookup
+
+// ExpandPath resolves a leading ~ or ~user and cleans a path for storage.
+// It is for paths that name the filesystem directly, such as command-line
+// arguments and the working directory, so a "$" is taken literally; use
+// ExpandConfigPath for paths read from profiles.yml or bindings.yml.
 func ExpandPath(p string) (string, error) {
-	if strings.HasPrefix(p, "~/") || p == "~" {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return "", fmt.Errorf("resolving home directory: %w", err)
+	if strings.HasPrefix(p, "~") {
+		name, rest, _ := strings.Cut(p[1:], "/")
+		var home string
+		if name == "" {
+			h, err := os.UserHomeDir()
+			if err != nil {
+				return "", fmt.Errorf("resolving home directory: %w", err)
+			}
+			home = h
+		} else {
+			u, err := lookupUser(name)
+			if err != nil {
+				return "", fmt.Errorf("resolving home directory of %q: %w", name, err)
+			}
+			home = u.HomeDir
 		}
-		p = filepath.Join(home, p[1:])
+		p = filepath.Join(home, rest)
 	}
 	abs, err := filepath.Abs(p)
 	if err != nil {
@@ -97,64 +197,302 @@ func ExpandPath(p string) (string, error) {
 	return filepath.Clean(abs), nil
 }
 
-// AddBinding adds or replaces a binding for the given path.
+// ExpandConfigPath is ExpandPath for a path read from profiles.yml or
+// bindings.yml: $VAR/${VAR} references are expanded first, and "$$" stands
+// for a literal "$".
+func ExpandConfigPath(p string) (string, error) {
+	return ExpandPath(expandEnv(p))
+}
+
+// expandEnv expands $VAR/${VAR} references in a config path, keeping "$$"
+// as a literal "$".
+func expandEnv(p string) string {
+	return os.Expand(p, func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		return os.Getenv(name)
+	})
+}
+
+// escapePath quotes each "$" in a filesystem path so that it reads back as
+// itself from bindings.yml.
+func escapePath(p string) string {
+	return strings.ReplaceAll(p, "$", "$$")
+}
+
+// ResolvePath expands p like ExpandPath and then resolves symlinks, so that
+// e.g. /tmp and /private/tmp on macOS compare equal. If p does not exist yet,
+// its deepest existing ancestor is resolved and the remainder re-appended.
+func ResolvePath(p string) (string, error) {
+	expanded, err := ExpandPath(p)
+	if err != nil {
+		return "", err
+	}
+	return resolveSymlinks(expanded, filepath.EvalSymlinks), nil
+}
+
+// ResolveConfigPath is ResolvePath for a path read from profiles.yml or
+// bindings.yml, expanded like ExpandConfigPath.
+func ResolveConfigPath(p string) (string, error) {
+	return ResolvePath(expandEnv(p))
+}
+
+// A PathCache memoizes ResolvePath and ResolveConfigPath for a batch of
+// paths, such as every binding considered while resolving one directory.
+// Bindings tend to share ancestors, and each directory's symlink resolution
+// is cached, so shared ancestors are only looked up once. The zero value is
+// ready to use. A PathCache does not notice filesystem changes, so it should
+// not outlive the batch.
+type PathCache struct {
+	links map[string]symlinkResult
+}
+
+type symlinkResult struct {
+	resolved string
+	err      error
+}
+
+// ResolvePath is ResolvePath, memoized.
+func (c *PathCache) ResolvePath(p string) (string, error) {
+	expanded, err := ExpandPath(p)
+	if err != nil {
+		return "", err
+	}
+	return resolveSymlinks(expanded, c.evalSymlinks), nil
+}
+
+// ResolveConfigPath is ResolveConfigPath, memoized.
+func (c *PathCache) ResolveConfigPath(p string) (string, error) {
+	return c.ResolvePath(expandEnv(p))
+}
+
+func (c *PathCache) evalSymlinks(p string) (string, error) {
+	if r, ok := c.links[p]; ok {
+		return r.resolved, r.err
+	}
+	resolved, err := c.walkSymlinks(p)
+	if c.links == nil {
+		c.links = make(map[string]symlinkResult)
+	}
+	c.links[p] = symlinkResult{resolved, err}
+	return resolved, err
+}
+
+// walkSymlinks is filepath.EvalSymlinks for a clean absolute path, built on
+// the cached resolution of its parent so that only the last element needs a
+// lookup.
+func (c *PathCache) walkSymlinks(p string) (string, error) {
+	parent := filepath.Dir(p)
+	// On Windows, EvalSymlinks also normalizes the case of every element,
+	// which appending the base name would skip.
+	if parent == p || runtime.GOOS == "windows" {
+		return filepath.EvalSymlinks(p)
+	}
+	dir, err := c.evalSymlinks(parent)
+	if err != nil {
+		return "", err
+	}
+	joined := filepath.Join(dir, filepath.Base(p))
+	fi, err := os.Lstat(joined)
+	if err != nil {
+		return "", err
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		return filepath.EvalSymlinks(joined)
+	}
+	return joined, nil
+}
+
+// resolveSymlinks resolves the deepest existing ancestor of the expanded
+// path with evalSymlinks and re-appends the remainder.
+func resolveSymlinks(expanded string, evalSymlinks func(string) (string, error)) string {
+	existing, rest := expanded, ""
+	for {
+		if resolved, err := evalSymlinks(existing); err == nil {
+			return filepath.Join(resolved, rest)
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return expanded
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+}
+
+// CaseInsensitiveFS reports whether paths are compared without regard to case,
+// as on the default macOS and Windows filesystems. It is a variable so tests
+// can exercise both behaviours.
+var CaseInsensitiveFS = runtime.GOOS == "darwin" || runtime.GOOS == "windows"
+
+// FoldPath returns p in the form used for path comparisons: lowercased when
+// CaseInsensitiveFS is set, unchanged otherwise.
+func FoldPath(p string) string {
+	if CaseInsensitiveFS {
+		return strings.ToLower(p)
+	}
+	return p
+}
+
+// AddBinding adds or replaces a binding for the given path. The stored path
+// has symlinks resolved.
 func (bf *BindingsFile) AddBinding(dirPath, profile string) error {
-	expanded, err := ExpandPath(dirPath)
+	return bf.AddScopedBinding(dirPath, profile, "")
+}
+
+// AddScopedBinding is AddBinding with the binding's Scope. Rebinding a path
+// replaces its scope too.
+func (bf *BindingsFile) AddScopedBinding(dirPath, profile, scope string) error {
+	expanded, err := ResolvePath(dirPath)
 	if err != nil {
 		return err
 	}
 
 	// Replace existing binding for the same path.
+	if i := bf.indexOf(expanded); i >= 0 {
+		bf.Bindings[i].Profile = profile
+		bf.Bindings[i].Scope = scope
+		return nil
+	}
+
+	bf.Bindings = append(bf.Bindings, Binding{Path: escapePath(expanded), Profile: profile, Scope: scope})
+	return nil
+}
+
+// Lookup returns the directory binding for exactly dirPath, if any.
+func (bf *BindingsFile) Lookup(dirPath string) (Binding, bool) {
+	expanded, err := ResolvePath(dirPath)
+	if err != nil {
+		return Binding{}, false
+	}
+	if i := bf.indexOf(expanded); i >= 0 {
+		return bf.Bindings[i], true
+	}
+	return Binding{}, false
+}
+
+// indexOf returns the index of the directory binding for the resolved path
+// expanded, or -1.
+func (bf *BindingsFile) indexOf(expanded string) int {
 	for i, b := range bf.Bindings {
-		existingExpanded, err := ExpandPath(b.Path)
+		if b.IsRemote() {
+			continue
+		}
+		existingExpanded, err := ResolveConfigPath(b.Path)
 		if err != nil {
 			continue
 		}
-		if existingExpanded == expanded {
-			bf.Bindings[i].Profile = profile
-			return nil
+		if FoldPath(existingExpanded) == FoldPath(expanded) {
+			return i
 		}
 	}
-
-	bf.Bindings = append(bf.Bindings, Binding{Path: expanded, Profile: profile})
-	return nil
+	return -1
 }
 
 // RemoveBinding removes the binding for the given path.
 func (bf *BindingsFile) RemoveBinding(dirPath string) error {
-	expanded, err := ExpandPath(dirPath)
+	expanded, err := ResolvePath(dirPath)
 	if err != nil {
 		return err
 	}
 
+	if i := bf.indexOf(expanded); i >= 0 {
+		bf.Bindings = append(bf.Bindings[:i], bf.Bindings[i+1:]...)
+		return nil
+	}
+	return fmt.Errorf("no binding found for %q", dirPath)
+}
+
+// MovedBinding records a directory binding rewritten by MoveBindings.
+type MovedBinding struct {
+	OldPath string
+	NewPath string
+	Profile string
+	Local   bool // the binding has local scope, so no includeIf follows it
+}
+
+// MoveBindings re-points every directory binding at or under oldPrefix to
+// the same relative location under newPrefix. It fails without changing
+// anything if a moved binding would land on a path that is already bound.
+func (bf *BindingsFile) MoveBindings(oldPrefix, newPrefix string) ([]MovedBinding, error) {
+	from, err := ResolvePath(oldPrefix)
+	if err != nil {
+		return nil, err
+	}
+	to, err := ResolvePath(newPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var moved []MovedBinding
+	var indexes []int
 	for i, b := range bf.Bindings {
-		existingExpanded, err := ExpandPath(b.Path)
+		if b.IsRemote() {
+			continue
+		}
+		existing, err := ResolveConfigPath(b.Path)
 		if err != nil {
 			continue
 		}
-		if existingExpanded == expanded {
-			bf.Bindings = append(bf.Bindings[:i], bf.Bindings[i+1:]...)
-			return nil
+		var rel string
+		switch {
+		case FoldPath(existing) == FoldPath(from):
+		case strings.HasPrefix(FoldPath(existing), FoldPath(from+string(filepath.Separator))):
+			rel = existing[len(from)+1:]
+		default:
+			continue
 		}
+		moved = append(moved, MovedBinding{OldPath: existing, NewPath: filepath.Join(to, rel), Profile: b.Profile, Local: b.IsLocal()})
+		indexes = append(indexes, i)
 	}
-	return fmt.Errorf("no binding found for %q", dirPath)
+
+	// Refuse to clobber a binding that is not itself being moved.
+	movedSet := make(map[int]bool, len(indexes))
+	for _, i := range indexes {
+		movedSet[i] = true
+	}
+	for _, m := range moved {
+		for i, b := range bf.Bindings {
+			if movedSet[i] || b.IsRemote() {
+				continue
+			}
+			if existing, err := ResolveConfigPath(b.Path); err == nil && FoldPath(existing) == FoldPath(m.NewPath) {
+				return nil, fmt.Errorf("cannot move %s: %s is already bound to %q", m.OldPath, m.NewPath, b.Profile)
+			}
+		}
+	}
+
+	for j, i := range indexes {
+		bf.Bindings[i].Path = escapePath(moved[j].NewPath)
+	}
+	return moved, nil
 }
 
 // FindBinding returns the profile name bound to the given path, or "".
 func (bf *BindingsFile) FindBinding(dirPath string) string {
-	expanded, err := ExpandPath(dirPath)
-	if err != nil {
-		return ""
-	}
+	b, _ := bf.Lookup(dirPath)
+	return b.Profile
+}
 
-	for _, b := range bf.Bindings {
-		existingExpanded, err := ExpandPath(b.Path)
-		if err != nil {
-			continue
+// AddRemoteBinding adds or replaces a binding for the given remote URL pattern.
+func (bf *BindingsFile) AddRemoteBinding(pattern, profile string) {
+	for i, b := range bf.Bindings {
+		if b.RemotePattern == pattern {
+			bf.Bindings[i].Profile = profile
+			return
 		}
-		if existingExpanded == expanded {
-			return b.Profile
+	}
+	bf.Bindings = append(bf.Bindings, Binding{RemotePattern: pattern, Profile: profile})
+}
+
+// RemoveRemoteBinding removes the binding for the given remote URL pattern.
+func (bf *BindingsFile) RemoveRemoteBinding(pattern string) error {
+	for i, b := range bf.Bindings {
+		if b.RemotePattern == pattern {
+			bf.Bindings = append(bf.Bindings[:i], bf.Bindings[i+1:]...)
+			return nil
 		}
 	}
-	return ""
+	return fmt.Errorf("no binding found for remote %q", pattern)
 }
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"os"
 	"path/filepath"
 	"testing"
 )
@@ -31,6 +32,33 @@ func TestBindingsRoundTrip(t *testing.T) {
 	if loaded.Bindings[0].Profile != "personal" {
 		t.Errorf("expected profile %q, got %q", "personal", loaded.Bindings[0].Profile)
 	}
+	if loaded.Version != BindingsVersion {
+		t.Errorf("Version = %d, want %d", loaded.Version, BindingsVersion)
+	}
+}
+
+func TestBindingsSaveTo_FutureVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bindings.yml")
+	future := "version: 99\nbindings:\n  - path: /home/user/code/work\n    profile: work\n    priority: 5\n"
+	if err := os.WriteFile(path, []byte(future), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	bf, err := LoadBindingsFrom(path)
+	if err != nil {
+		t.Fatalf("a newer file should still load: %v", err)
+	}
+	if len(bf.Bindings) != 1 {
+		t.Fatalf("expected 1 binding, got %d", len(bf.Bindings))
+	}
+
+	bf.AddRemoteBinding("github.com/acme/*", "work")
+	if err := bf.SaveTo(path); err == nil {
+		t.Fatal("expected SaveTo to refuse a newer file")
+	}
+	if data, _ := os.ReadFile(path); string(data) != future {
+		t.Errorf("future file was modified:\n%s", data)
+	}
 }
 
 func TestLoadBindingsFrom_NotExist(t *testing.T) {
@@ -96,6 +124,88 @@ func TestRemoveBinding(t *testing.T) {
 	}
 }
 
+func TestMoveBindings(t *testing.T) {
+	tmp, _ := filepath.EvalSymlinks(t.TempDir())
+	oldRoot := filepath.Join(tmp, "code")
+	newRoot := filepath.Join(tmp, "src")
+
+	bf := &BindingsFile{}
+	_ = bf.AddBinding(oldRoot, "personal")
+	_ = bf.AddBinding(filepath.Join(oldRoot, "work", "api"), "work")
+	_ = bf.AddBinding(filepath.Join(tmp, "codebase"), "other")
+	bf.AddRemoteBinding("github.com/acme", "work")
+
+	moved, err := bf.MoveBindings(oldRoot, newRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moved) != 2 {
+		t.Fatalf("expected 2 moved bindings, got %+v", moved)
+	}
+	if got := bf.FindBinding(filepath.Join(newRoot, "work", "api")); got != "work" {
+		t.Errorf("FindBinding(new api) = %q, want work", got)
+	}
+	if got := bf.FindBinding(newRoot); got != "personal" {
+		t.Errorf("FindBinding(new root) = %q, want personal", got)
+	}
+	if got := bf.FindBinding(filepath.Join(tmp, "codebase")); got != "other" {
+		t.Error("sibling with a shared name prefix should not move")
+	}
+
+	// Moving onto an existing binding is refused and changes nothing.
+	_ = bf.AddBinding(filepath.Join(tmp, "dest"), "other")
+	if _, err := bf.MoveBindings(newRoot, filepath.Join(tmp, "dest")); err == nil {
+		t.Error("expected error when the destination is already bound")
+	}
+	if got := bf.FindBinding(newRoot); got != "personal" {
+		t.Error("failed move should leave bindings unchanged")
+	}
+}
+
+func TestLoadBindingsFrom_Relative(t *testing.T) {
+	tmp := t.TempDir()
+	path := filepath.Join(tmp, "bindings.yml")
+	data := `bindings:
+  - path: code/work
+    profile: work
+  - path: /abs/personal
+    profile: personal
+  - remote: github.com/acme
+    profile: work
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	bf, err := LoadBindingsFrom(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(tmp, "code", "work")
+	if bf.Bindings[0].Path != want {
+		t.Errorf("Path = %q, want %q", bf.Bindings[0].Path, want)
+	}
+	if bf.Bindings[1].Path != "/abs/personal" {
+		t.Errorf("absolute path changed to %q", bf.Bindings[1].Path)
+	}
+	rel := bf.Relative()
+	if len(rel) != 1 || rel[0].Original != "code/work" || rel[0].Resolved != want {
+		t.Fatalf("Relative() = %+v", rel)
+	}
+
+	// Saving stores the absolute path, so nothing is relative on reload.
+	if err := bf.SaveTo(path); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := LoadBindingsFrom(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reloaded.Relative()) != 0 || reloaded.Bindings[0].Path != want {
+		t.Errorf("after save: Relative() = %+v, Path = %q", reloaded.Relative(), reloaded.Bindings[0].Path)
+	}
+}
+
 func TestFindBinding(t *testing.T) {
 	tmp := t.TempDir()
 	dir1 := filepath.Join(tmp, "proj1")
@@ -134,3 +244,37 @@ func TestExpandPath(t *testing.T) {
 		})
 	}
 }
+
+func TestRemoteBindings(t *testing.T) {
+	bf := &BindingsFile{}
+	bf.AddRemoteBinding("github.com/acme", "work")
+	bf.AddRemoteBinding("github.com/acme", "job")
+
+	if len(bf.Bindings) != 1 {
+		t.Fatalf("expected 1 binding, got %d", len(bf.Bindings))
+	}
+	if bf.Bindings[0].Profile != "job" {
+		t.Errorf("Profile = %q, want %q", bf.Bindings[0].Profile, "job")
+	}
+	if !bf.Bindings[0].IsRemote() {
+		t.Error("expected remote binding")
+	}
+	if got := bf.Bindings[0].Target(); got != "remote:github.com/acme" {
+		t.Errorf("Target() = %q", got)
+	}
+
+	// Remote bindings must not be mistaken for a path binding on the cwd.
+	if got := bf.FindBinding("."); got != "" {
+		t.Errorf("FindBinding(.) = %q, want empty", got)
+	}
+
+	if err := bf.RemoveRemoteBinding("github.com/acme"); err != nil {
+		t.Fatal(err)
+	}
+	if len(bf.Bindings) != 0 {
+		t.Error("expected remote binding to be removed")
+	}
+	if err := bf.RemoveRemoteBinding("github.com/acme"); err == nil {
+		t.Error("expected error removing missing remote binding")
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BundleVersion is the current format version of an exported Bundle.
+const BundleVersion = 1
+
+// Bundle combines profiles.yml and bindings.yml into a single portable
+// document, used by `gh identity export` and `gh identity import <file>`.
+type Bundle struct {
+	Version  int                `yaml:"version"`
+	Profiles map[string]Profile `yaml:"profiles"`
+	Default  string             `yaml:"default,omitempty"`
+	Bindings []Binding          `yaml:"bindings"`
+}
+
+// NewBundle builds a Bundle from the given profiles and bindings. Binding
+// paths under the home directory are written relative to ~, so the bundle
+// can be imported on a machine with a different home.
+func NewBundle(pf *ProfilesFile, bf *BindingsFile) *Bundle {
+	bindings := make([]Binding, len(bf.Bindings))
+	for i, b := range bf.Bindings {
+		if !b.IsRemote() {
+			b.Path = homeRelative(b.Path)
+		}
+		bindings[i] = b
+	}
+	return &Bundle{
+		Version:  BundleVersion,
+		Profiles: pf.Profiles,
+		Default:  pf.Default,
+		Bindings: bindings,
+	}
+}
+
+// homeRelative returns the bindings.yml path p with a leading home
+// directory replaced by ~. Paths that already start with ~, use $VAR
+// references, or lie outside the home directory are returned unchanged.
+func homeRelative(p string) string {
+	if strings.HasPrefix(p, "~") || expandEnv(p) != strings.ReplaceAll(p, "$$", "$") {
+		return p
+	}
+	expanded, err := ExpandConfigPath(p)
+	if err != nil {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return p
+	}
+	// Stored paths have their symlinks resolved, so try the resolved home too.
+	homes := []string{filepath.Clean(home)}
+	if resolved, err := ResolvePath(home); err == nil {
+		homes = append(homes, resolved)
+	}
+	for _, h := range homes {
+		rel, err := filepath.Rel(h, expanded)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if rel == "." {
+			return "~"
+		}
+		return "~/" + escapePath(filepath.ToSlash(rel))
+	}
+	return p
+}
+
+// Marshal encodes the bundle as YAML.
+func (b *Bundle) Marshal() ([]byte, error) {
+	data, err := yaml.Marshal(b)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling bundle: %w", err)
+	}
+	return data, nil
+}
+
+// LoadBundleFrom reads a bundle from the given path. JSON bundles are
+// accepted too, since JSON is valid YAML.
+func LoadBundleFrom(path string) (*Bundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading bundle: %w", err)
+	}
+
+	var b Bundle
+	if err := yaml.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("parsing bundle: %w", err)
+	}
+	if b.Version > BundleVersion {
+		return nil, fmt.Errorf("bundle version %d is newer than supported version %d — upgrade gh-identity", b.Version, BundleVersion)
+	}
+	if b.Profiles == nil {
+		b.Profiles = make(map[string]Profile)
+	}
+	return &b, nil
+}
@@ -3,16 +3,58 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+
+	"gopkg.in/yaml.v3"
 )
 
 const (
-	// DefaultConfigDir is the subdirectory under XDG_CONFIG_HOME / ~/.config.
+	// DefaultConfigDir is the subdirectory gh-identity uses under each XDG
+	// base directory (config, data, and cache).
 	DefaultConfigDir = "gh-identity"
 )
 
+// ParseError reports a configuration file that exists but is not valid
+// YAML for its schema. Callers that must not fail hard, like the shell
+// hook, check for it with errors.As to tell a broken file from other errors.
+type ParseError struct {
+	Path string // the file that failed to parse
+	Kind string // "profiles" or "bindings"
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parsing %s: %v", e.Kind, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Strict makes LoadProfilesFrom and LoadBindingsFrom reject keys that match
+// no field, so a typo like gh_users is reported instead of leaving the field
+// empty. It is off by default because files may carry extra keys on purpose.
+var Strict bool
+
+// unmarshalYAML decodes data into v, rejecting unknown fields when Strict is
+// set.
+func unmarshalYAML(data []byte, v any) error {
+	if !Strict {
+		return yaml.Unmarshal(data, v)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
 // Dir returns the configuration directory for gh-identity.
 // It respects GH_IDENTITY_CONFIG_DIR, then XDG_CONFIG_HOME, then ~/.config.
 func Dir() (string, error) {
@@ -20,13 +62,31 @@ func Dir() (string, error) {
 		return d, nil
 	}
 
-	base := os.Getenv("XDG_CONFIG_HOME")
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// DataDir returns the directory for installed data such as the helper
+// binaries. It respects XDG_DATA_HOME, then ~/.local/share.
+func DataDir() (string, error) {
+	return xdgDir("XDG_DATA_HOME", ".local", "share")
+}
+
+// CacheDir returns the directory for disposable caches such as the hook's
+// resolution cache. It respects XDG_CACHE_HOME, then ~/.cache.
+func CacheDir() (string, error) {
+	return xdgDir("XDG_CACHE_HOME", ".cache")
+}
+
+// xdgDir returns the gh-identity subdirectory of the base directory named by
+// env, or of the fallback path under the home directory when env is unset.
+func xdgDir(env string, fallback ...string) (string, error) {
+	base := os.Getenv(env)
 	if base == "" {
 		home, err := os.UserHomeDir()
 		if err != nil {
 			return "", fmt.Errorf("resolving home directory: %w", err)
 		}
-		base = filepath.Join(home, ".config")
+		base = filepath.Join(append([]string{home}, fallback...)...)
 	}
 	return filepath.Join(base, DefaultConfigDir), nil
 }
@@ -64,11 +124,52 @@ func EnsureGitConfigDir() (string, error) {
 	return dir, nil
 }
 
-// BinDir returns the directory where the hook binary is installed.
-func BinDir() (string, error) {
+// InstallBinDir returns the directory new helper binaries are installed
+// into: bin/ under DataDir.
+func InstallBinDir() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "bin"), nil
+}
+
+// LegacyBinDir returns bin/ under the config directory, where helper
+// binaries were installed before they moved to DataDir.
+func LegacyBinDir() (string, error) {
 	dir, err := Dir()
 	if err != nil {
 		return "", err
 	}
 	return filepath.Join(dir, "bin"), nil
 }
+
+// BinDir returns the directory the helper binaries are installed in. It
+// prefers InstallBinDir and falls back to LegacyBinDir only when that is the
+// one that exists, so installs from older versions keep working.
+func BinDir() (string, error) {
+	dir, err := InstallBinDir()
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(dir); err == nil {
+		return dir, nil
+	}
+	legacy, err := LegacyBinDir()
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(legacy); err == nil {
+		return legacy, nil
+	}
+	return dir, nil
+}
+
+// AskPassPath returns the path of the GIT_ASKPASS helper binary.
+func AskPassPath() (string, error) {
+	dir, err := BinDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gh-identity-askpass"), nil
+}
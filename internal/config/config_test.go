@@ -57,15 +57,103 @@ func TestGitConfigDir(t *testing.T) {
 	}
 }
 
+func TestDataDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	t.Setenv("XDG_DATA_HOME", "")
+	dir, err := DataDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(home, ".local", "share", DefaultConfigDir); dir != want {
+		t.Errorf("DataDir() = %q, want %q", dir, want)
+	}
+
+	xdg := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", xdg)
+	dir, err = DataDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(xdg, DefaultConfigDir); dir != want {
+		t.Errorf("DataDir() = %q, want %q", dir, want)
+	}
+}
+
+func TestCacheDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	t.Setenv("XDG_CACHE_HOME", "")
+	dir, err := CacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(home, ".cache", DefaultConfigDir); dir != want {
+		t.Errorf("CacheDir() = %q, want %q", dir, want)
+	}
+
+	xdg := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", xdg)
+	dir, err = CacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(xdg, DefaultConfigDir); dir != want {
+		t.Errorf("CacheDir() = %q, want %q", dir, want)
+	}
+}
+
 func TestBinDir(t *testing.T) {
-	tmp := t.TempDir()
-	t.Setenv("GH_IDENTITY_CONFIG_DIR", tmp)
-	dir, err := BinDir()
+	configDir := t.TempDir()
+	dataHome := t.TempDir()
+	t.Setenv("GH_IDENTITY_CONFIG_DIR", configDir)
+	t.Setenv("XDG_DATA_HOME", dataHome)
+	installDir := filepath.Join(dataHome, DefaultConfigDir, "bin")
+	legacyDir := filepath.Join(configDir, "bin")
+
+	check := func(want string) {
+		t.Helper()
+		dir, err := BinDir()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dir != want {
+			t.Errorf("BinDir() = %q, want %q", dir, want)
+		}
+	}
+
+	// Nothing installed yet: new installs go to the data directory.
+	check(installDir)
+
+	// Only the legacy directory exists: keep using it.
+	if err := os.MkdirAll(legacyDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	check(legacyDir)
+
+	// Both exist: the data directory wins.
+	if err := os.MkdirAll(installDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	check(installDir)
+}
+
+func TestLoadBundleFrom(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bundle.json")
+	os.WriteFile(path, []byte(`{"version": 1, "profiles": {"p": {"gh_user": "u", "git_name": "n", "git_email": "e@example.com"}}, "bindings": [{"path": "/x", "profile": "p"}]}`), 0o644)
+
+	b, err := LoadBundleFrom(path)
 	if err != nil {
 		t.Fatal(err)
 	}
-	want := filepath.Join(tmp, "bin")
-	if dir != want {
-		t.Errorf("BinDir() = %q, want %q", dir, want)
+	if b.Profiles["p"].GHUser != "u" || len(b.Bindings) != 1 {
+		t.Errorf("unexpected bundle: %+v", b)
+	}
+
+	os.WriteFile(path, []byte("version: 99\n"), 0o644)
+	if _, err := LoadBundleFrom(path); err == nil {
+		t.Error("expected error for a newer bundle version")
 	}
 }
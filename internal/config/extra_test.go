@@ -1,8 +1,12 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"os/user"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -214,8 +218,9 @@ func TestLoadProfilesFrom_InvalidYAML(t *testing.T) {
 	os.WriteFile(badFile, []byte("{{{invalid yaml"), 0o644)
 
 	_, err := LoadProfilesFrom(badFile)
-	if err == nil {
-		t.Error("expected error for invalid YAML")
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.Path != badFile {
+		t.Errorf("expected a ParseError for %s, got %v", badFile, err)
 	}
 }
 
@@ -225,7 +230,252 @@ func TestLoadBindingsFrom_InvalidYAML(t *testing.T) {
 	os.WriteFile(badFile, []byte("{{{invalid yaml"), 0o644)
 
 	_, err := LoadBindingsFrom(badFile)
-	if err == nil {
-		t.Error("expected error for invalid YAML")
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.Path != badFile {
+		t.Errorf("expected a ParseError for %s, got %v", badFile, err)
+	}
+}
+
+func TestLoad_Strict(t *testing.T) {
+	tmp := t.TempDir()
+	profilesFile := filepath.Join(tmp, "profiles.yml")
+	os.WriteFile(profilesFile, []byte("version: 1\nprofiles:\n  work:\n    gh_users: alice\n    git_name: Alice\n    git_email: a@b.c\n"), 0o644)
+	bindingsFile := filepath.Join(tmp, "bindings.yml")
+	os.WriteFile(bindingsFile, []byte("version: 1\nbindings:\n  - path: /x\n    profle: work\n"), 0o644)
+
+	// Lenient by default: the typo is ignored.
+	if _, err := LoadProfilesFrom(profilesFile); err != nil {
+		t.Fatalf("lenient LoadProfilesFrom() error = %v", err)
+	}
+	if _, err := LoadBindingsFrom(bindingsFile); err != nil {
+		t.Fatalf("lenient LoadBindingsFrom() error = %v", err)
+	}
+
+	Strict = true
+	t.Cleanup(func() { Strict = false })
+
+	_, err := LoadProfilesFrom(profilesFile)
+	var pe *ParseError
+	if !errors.As(err, &pe) || !strings.Contains(err.Error(), "gh_users") {
+		t.Errorf("strict LoadProfilesFrom() error = %v, want a ParseError naming gh_users", err)
+	}
+	_, err = LoadBindingsFrom(bindingsFile)
+	if !errors.As(err, &pe) || !strings.Contains(err.Error(), "profle") {
+		t.Errorf("strict LoadBindingsFrom() error = %v, want a ParseError naming profle", err)
+	}
+
+	// An empty file is still valid.
+	empty := filepath.Join(tmp, "empty.yml")
+	os.WriteFile(empty, nil, 0o644)
+	if _, err := LoadProfilesFrom(empty); err != nil {
+		t.Errorf("strict LoadProfilesFrom(empty) error = %v", err)
+	}
+}
+
+func TestResolvePath_Symlink(t *testing.T) {
+	tmp, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	real := filepath.Join(tmp, "real")
+	os.MkdirAll(real, 0o755)
+	link := filepath.Join(tmp, "link")
+	if err := os.Symlink(real, link); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+
+	got, err := ResolvePath(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != real {
+		t.Errorf("ResolvePath(%q) = %q, want %q", link, got, real)
+	}
+
+	// A path that does not exist yet resolves through its existing ancestor.
+	got, err = ResolvePath(filepath.Join(link, "new", "dir"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(real, "new", "dir"); got != want {
+		t.Errorf("ResolvePath() = %q, want %q", got, want)
+	}
+}
+
+// TestPathCache tests that PathCache.ResolvePath returns what ResolvePath
+// does, including through symlinks, missing paths, and repeated lookups.
+func TestPathCache(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("HOME", tmp)
+	real := filepath.Join(tmp, "real")
+	os.MkdirAll(filepath.Join(real, "sub"), 0o755)
+	os.MkdirAll(filepath.Join(tmp, "target"), 0o755)
+	if err := os.Symlink(real, filepath.Join(tmp, "link")); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+	// A symlink inside a symlinked directory, pointing relatively.
+	os.Symlink("../target", filepath.Join(real, "rel"))
+
+	var c PathCache
+	paths := []string{
+		tmp,
+		real,
+		filepath.Join(tmp, "link"),
+		filepath.Join(tmp, "link", "sub"),
+		filepath.Join(tmp, "link", "rel"),
+		filepath.Join(tmp, "link", "rel", "new", "dir"),
+		filepath.Join(tmp, "link", "missing", "*"),
+		"~/link/sub",
+		"~/real/sub/../sub",
+		"/",
+	}
+	for range 2 {
+		for _, p := range paths {
+			want, err := ResolvePath(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := c.ResolvePath(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("PathCache.ResolvePath(%q) = %q, want %q", p, got, want)
+			}
+		}
+	}
+}
+
+func TestFindBinding_Symlink(t *testing.T) {
+	tmp := t.TempDir()
+	real := filepath.Join(tmp, "real")
+	os.MkdirAll(real, 0o755)
+	link := filepath.Join(tmp, "link")
+	if err := os.Symlink(real, link); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+
+	bf := &BindingsFile{}
+	if err := bf.AddBinding(link, "work"); err != nil {
+		t.Fatal(err)
+	}
+	if got := bf.FindBinding(real); got != "work" {
+		t.Errorf("FindBinding(real) = %q, want %q", got, "work")
+	}
+	if err := bf.AddBinding(real, "personal"); err != nil {
+		t.Fatal(err)
+	}
+	if len(bf.Bindings) != 1 {
+		t.Errorf("expected symlink and real path to share one binding, got %d", len(bf.Bindings))
+	}
+}
+
+func TestFindBinding_CaseFolding(t *testing.T) {
+	old := CaseInsensitiveFS
+	t.Cleanup(func() { CaseInsensitiveFS = old })
+
+	bf := &BindingsFile{
+		Bindings: []Binding{{Path: "/Nonexistent/Users/Me/Code", Profile: "work"}},
+	}
+
+	CaseInsensitiveFS = true
+	if got := bf.FindBinding("/nonexistent/users/me/code"); got != "work" {
+		t.Errorf("FindBinding() = %q, want %q on a case-insensitive filesystem", got, "work")
+	}
+
+	CaseInsensitiveFS = false
+	if got := bf.FindBinding("/nonexistent/users/me/code"); got != "" {
+		t.Errorf("FindBinding() = %q, want no match on a case-sensitive filesystem", got)
+	}
+	if got := bf.FindBinding("/Nonexistent/Users/Me/Code"); got != "work" {
+		t.Errorf("FindBinding() = %q, want %q for an exact match", got, "work")
+	}
+}
+
+func TestExpandConfigPath_EnvVars(t *testing.T) {
+	t.Setenv("GH_IDENTITY_TEST_ROOT", "/srv/code")
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"$GH_IDENTITY_TEST_ROOT/work", "/srv/code/work"},
+		{"${GH_IDENTITY_TEST_ROOT}/work", "/srv/code/work"},
+		{"/plain/absolute", "/plain/absolute"},
+		{"/literal/proj$$x", "/literal/proj$x"},
+	}
+	for _, tt := range tests {
+		got, err := ExpandConfigPath(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("ExpandConfigPath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	home, _ := os.UserHomeDir()
+	got, err := ExpandConfigPath("$HOME/.ssh/id_ed25519")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(home, ".ssh", "id_ed25519"); got != want {
+		t.Errorf("ExpandConfigPath($HOME/...) = %q, want %q", got, want)
+	}
+}
+
+// TestResolvePath_DollarInName tests that filesystem paths are taken
+// literally: a directory named with a "$" is not env-expanded.
+func TestResolvePath_DollarInName(t *testing.T) {
+	tmp, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("x", "")
+	dir := filepath.Join(tmp, "proj$x")
+	os.MkdirAll(dir, 0o755)
+
+	for name, resolve := range map[string]func(string) (string, error){
+		"ExpandPath":            ExpandPath,
+		"ResolvePath":           ResolvePath,
+		"PathCache.ResolvePath": new(PathCache).ResolvePath,
+	} {
+		got, err := resolve(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != dir {
+			t.Errorf("%s(%q) = %q, want it unchanged", name, dir, got)
+		}
+	}
+}
+
+func TestExpandPath_TildeUser(t *testing.T) {
+	old := lookupUser
+	t.Cleanup(func() { lookupUser = old })
+	lookupUser = func(name string) (*user.User, error) {
+		if name == "alice" {
+			return &user.User{Username: "alice", HomeDir: "/home/alice"}, nil
+		}
+		return nil, fmt.Errorf("unknown user %s", name)
+	}
+
+	got, err := ExpandPath("~alice/.ssh/id_work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "/home/alice/.ssh/id_work" {
+		t.Errorf("ExpandPath(~alice/...) = %q, want %q", got, "/home/alice/.ssh/id_work")
+	}
+
+	got, err = ExpandPath("~alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "/home/alice" {
+		t.Errorf("ExpandPath(~alice) = %q, want %q", got, "/home/alice")
+	}
+
+	if _, err := ExpandPath("~nobody/x"); err == nil {
+		t.Error("expected error for unknown user")
 	}
 }
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ProfileFields returns the YAML keys of Profile's fields in declaration
+// order, e.g. gh_user, git_name, git_email.
+func ProfileFields() []string {
+	t := reflect.TypeOf(Profile{})
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		keys = append(keys, yamlKey(t.Field(i)))
+	}
+	return keys
+}
+
+// SetField sets the field whose YAML key is key to value, leaving p
+// unchanged if the key is unknown or the value invalid. List fields such
+// as ssh_keys take a comma-separated value. An empty value clears an
+// optional field.
+func (p *Profile) SetField(key, value string) error {
+	updated := *p
+	v := reflect.ValueOf(&updated).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if yamlKey(v.Type().Field(i)) != key {
+			continue
+		}
+		switch f := v.Field(i); f.Kind() {
+		case reflect.String:
+			f.SetString(value)
+		case reflect.Slice:
+			var list []string
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					list = append(list, item)
+				}
+			}
+			f.Set(reflect.ValueOf(list))
+		default:
+			return fmt.Errorf("field %s cannot be set", key)
+		}
+		if err := updated.checkField(key); err != nil {
+			return err
+		}
+		*p = updated
+		return nil
+	}
+	return fmt.Errorf("unknown field %q; valid fields are %s", key, strings.Join(ProfileFields(), ", "))
+}
+
+// yamlKey returns the key f is stored under in profiles.yml.
+func yamlKey(f reflect.StructField) string {
+	key, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	return key
+}
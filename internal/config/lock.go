@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// lockFileName is the lock file guarding profiles.yml and bindings.yml.
+const lockFileName = ".lock"
+
+// locksHeld counts the config locks this process holds. Loaders only write
+// migrations back while it is non-zero.
+var locksHeld atomic.Int32
+
+// Lock takes an exclusive lock on the config directory, blocking until any
+// other gh-identity process releases it. Hold it across a load-modify-save
+// of profiles.yml or bindings.yml so concurrent commands cannot overwrite
+// each other's changes. Readers such as the shell hook do not lock.
+//
+// The returned function releases the lock.
+func Lock() (func(), error) {
+	dir, err := EnsureDir()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, lockFileName), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening lock file: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("locking config: %w", err)
+	}
+	locksHeld.Add(1)
+	return func() {
+		locksHeld.Add(-1)
+		_ = unlockFile(f)
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package config
+
+import "os"
+
+// Advisory locking is only implemented on Unix; elsewhere Lock is a no-op.
+
+func lockFile(*os.File) error { return nil }
+
+func unlockFile(*os.File) error { return nil }
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestLock_ConcurrentBindings(t *testing.T) {
+	t.Setenv("GH_IDENTITY_CONFIG_DIR", t.TempDir())
+	root := t.TempDir()
+
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			unlock, err := Lock()
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer unlock()
+
+			bf, err := LoadBindings()
+			if err != nil {
+				errs <- err
+				return
+			}
+			if err := bf.AddBinding(filepath.Join(root, fmt.Sprint(i)), "work"); err != nil {
+				errs <- err
+				return
+			}
+			errs <- bf.Save()
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bf, err := LoadBindings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bf.Bindings) != n {
+		t.Errorf("got %d bindings, want %d", len(bf.Bindings), n)
+	}
+}
@@ -1,9 +1,11 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -11,15 +13,120 @@ import (
 // Profile represents a named identity bundle.
 type Profile struct {
 	GHUser   string `yaml:"gh_user"`
+	Host     string `yaml:"host,omitempty"` // gh host; empty means github.com
 	GitName  string `yaml:"git_name"`
 	GitEmail string `yaml:"git_email"`
-	SSHKey   string `yaml:"ssh_key,omitempty"`
+	// GitNameCommand and GitEmailCommand are shell commands whose output
+	// the hook uses instead of GitName and GitEmail, e.g. to read them from
+	// a secret manager. The static fields remain the fallback.
+	GitNameCommand  string `yaml:"git_name_command,omitempty"`
+	GitEmailCommand string `yaml:"git_email_command,omitempty"`
+	// CommitterName and CommitterEmail give commits a committer distinct
+	// from the author, e.g. a bot. When unset the committer mirrors the
+	// author.
+	CommitterName  string   `yaml:"committer_name,omitempty"`
+	CommitterEmail string   `yaml:"committer_email,omitempty"`
+	SSHKey         string   `yaml:"ssh_key,omitempty"`
+	SSHKeys        []string `yaml:"ssh_keys,omitempty"`
+	SSHHostAlias   string   `yaml:"ssh_host_alias,omitempty"` // Host entry in ~/.ssh/config
+	SigningKey     string   `yaml:"signing_key,omitempty"`
+	SigningFormat  string   `yaml:"signing_format,omitempty"` // openpgp, ssh, or x509
+	CloneProtocol  string   `yaml:"clone_protocol,omitempty"` // ssh or https; empty uses gh's git_protocol
+	// CredentialHelper set to "gh" makes git authenticate HTTPS through
+	// gh's own credential helper, configured in the profile's gitconfig
+	// fragment, instead of the hook exporting GIT_ASKPASS.
+	CredentialHelper string `yaml:"credential_helper,omitempty"`
+	Description      string `yaml:"description,omitempty"` // free-form label; informational only
 }
 
+// DefaultHost is the gh host of a profile that does not set one.
+const DefaultHost = "github.com"
+
+// GHHost returns the gh host the profile's account is on.
+func (p Profile) GHHost() string {
+	if p.Host == "" {
+		return DefaultHost
+	}
+	return p.Host
+}
+
+// AllSSHKeys returns every SSH key configured for the profile: ssh_key first,
+// followed by ssh_keys, with duplicates removed.
+func (p Profile) AllSSHKeys() []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, k := range append([]string{p.SSHKey}, p.SSHKeys...) {
+		if k == "" || seen[k] {
+			continue
+		}
+		seen[k] = true
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Committer returns the committer name and email for commits made as p:
+// committer_name and committer_email where set, otherwise git_name and
+// git_email.
+func (p Profile) Committer() (name, email string) {
+	return p.CommitterFor(p.GitName, p.GitEmail)
+}
+
+// CommitterFor returns the committer name and email for commits authored
+// as authorName and authorEmail: committer_name and committer_email where
+// set, otherwise the author's.
+func (p Profile) CommitterFor(authorName, authorEmail string) (name, email string) {
+	name, email = authorName, authorEmail
+	if p.CommitterName != "" {
+		name = p.CommitterName
+	}
+	if p.CommitterEmail != "" {
+		email = p.CommitterEmail
+	}
+	return name, email
+}
+
+// validSigningFormats are the values git accepts for gpg.format.
+var validSigningFormats = map[string]bool{
+	"openpgp": true,
+	"ssh":     true,
+	"x509":    true,
+}
+
+// Clone protocols a profile may set in clone_protocol.
+const (
+	CloneSSH   = "ssh"
+	CloneHTTPS = "https"
+)
+
+// CredentialGH is the credential_helper value that selects gh's credential
+// helper.
+const CredentialGH = "gh"
+
+// CheckCloneProtocol returns an error unless v is a valid clone_protocol
+// value. The empty string, meaning gh's own default, is valid.
+func CheckCloneProtocol(v string) error {
+	switch v {
+	case "", CloneSSH, CloneHTTPS:
+		return nil
+	}
+	return fmt.Errorf("clone_protocol must be %s or %s, not %q", CloneSSH, CloneHTTPS, v)
+}
+
+// ProfilesVersion is the current profiles.yml schema version. Files written
+// before versioning was introduced have no version and load as 0.
+const ProfilesVersion = 1
+
 // ProfilesFile is the top-level structure of profiles.yml.
 type ProfilesFile struct {
+	Version  int                `yaml:"version,omitempty"`
 	Profiles map[string]Profile `yaml:"profiles"`
 	Default  string             `yaml:"default,omitempty"`
+
+	// Orgs maps a GitHub org, or "host/org" for one host only, to the
+	// profile for repositories whose origin it owns. It applies when no
+	// binding matches.
+	Orgs map[string]string `yaml:"orgs,omitempty"`
 }
 
 // ProfilesPath returns the path to profiles.yml.
@@ -41,26 +148,64 @@ func LoadProfiles() (*ProfilesFile, error) {
 	return LoadProfilesFrom(path)
 }
 
-// LoadProfilesFrom reads profiles from the given path.
+// LoadProfilesFrom reads profiles from the given path. Files from older
+// versions are migrated (see MigrateProfiles) in memory, and written back
+// only when this process holds the config lock: readers such as the shell
+// hook never write, so they neither race a command nor touch the file's
+// mtime.
 func LoadProfilesFrom(path string) (*ProfilesFile, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return &ProfilesFile{Profiles: make(map[string]Profile)}, nil
+			return &ProfilesFile{Version: ProfilesVersion, Profiles: make(map[string]Profile)}, nil
 		}
 		return nil, fmt.Errorf("reading profiles: %w", err)
 	}
 
 	var pf ProfilesFile
-	if err := yaml.Unmarshal(data, &pf); err != nil {
-		return nil, fmt.Errorf("parsing profiles: %w", err)
+	if err := unmarshalYAML(data, &pf); err != nil {
+		return nil, &ParseError{Path: path, Kind: "profiles", Err: err}
 	}
 	if pf.Profiles == nil {
 		pf.Profiles = make(map[string]Profile)
 	}
+	if MigrateProfiles(&pf) && locksHeld.Load() > 0 {
+		// A failed write, e.g. to a read-only config directory, is not an
+		// error: the migration is repeated in memory on the next load.
+		_ = pf.SaveTo(path)
+	}
 	return &pf, nil
 }
 
+// MigrateProfiles upgrades pf in place to ProfilesVersion and reports
+// whether anything changed. It is idempotent.
+//
+// Version 1 records each profile's host explicitly: profiles without one
+// were created before GitHub Enterprise Server support and are on
+// github.com.
+func MigrateProfiles(pf *ProfilesFile) bool {
+	if pf.Version >= ProfilesVersion {
+		return false
+	}
+	for name, p := range pf.Profiles {
+		if p.Host == "" {
+			p.Host = DefaultHost
+			pf.Profiles[name] = p
+		}
+	}
+	pf.Version = ProfilesVersion
+	return true
+}
+
+// checkVersion returns an error if a file at version was written by a newer
+// gh-identity than one supporting up to supported.
+func checkVersion(file string, version, supported int) error {
+	if version > supported {
+		return fmt.Errorf("%s is version %d, newer than supported version %d — upgrade gh-identity before changing it", file, version, supported)
+	}
+	return nil
+}
+
 // Save writes the profiles file to disk.
 func (pf *ProfilesFile) Save() error {
 	path, err := ProfilesPath()
@@ -70,8 +215,14 @@ func (pf *ProfilesFile) Save() error {
 	return pf.SaveTo(path)
 }
 
-// SaveTo writes the profiles file to the given path.
+// SaveTo writes the profiles file to the given path, stamped with
+// ProfilesVersion. It refuses to write a file loaded from a newer schema
+// version, whose fields this binary may not know and would drop.
 func (pf *ProfilesFile) SaveTo(path string) error {
+	if err := checkVersion("profiles.yml", pf.Version, ProfilesVersion); err != nil {
+		return err
+	}
+	pf.Version = ProfilesVersion
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return fmt.Errorf("creating directory: %w", err)
 	}
@@ -80,7 +231,7 @@ func (pf *ProfilesFile) SaveTo(path string) error {
 	if err != nil {
 		return fmt.Errorf("marshalling profiles: %w", err)
 	}
-	if err := os.WriteFile(path, data, 0o644); err != nil {
+	if err := WriteFileAtomic(path, data, 0o644); err != nil {
 		return fmt.Errorf("writing profiles: %w", err)
 	}
 	return nil
@@ -109,6 +260,11 @@ func (pf *ProfilesFile) RemoveProfile(name string) error {
 	if pf.Default == name {
 		pf.Default = ""
 	}
+	for org, profile := range pf.Orgs {
+		if profile == name {
+			delete(pf.Orgs, org)
+		}
+	}
 	return nil
 }
 
@@ -116,15 +272,76 @@ func (pf *ProfilesFile) RemoveProfile(name string) error {
 func (pf *ProfilesFile) Validate() []string {
 	var errs []string
 	for name, p := range pf.Profiles {
+		for _, key := range ProfileFields() {
+			if err := p.checkField(key); err != nil {
+				errs = append(errs, fmt.Sprintf("profile %q: %v", name, err))
+			}
+		}
+	}
+	for org, profile := range pf.Orgs {
+		if _, ok := pf.Profiles[profile]; !ok {
+			errs = append(errs, fmt.Sprintf("org %q: profile %q not found", org, profile))
+		}
+	}
+	return errs
+}
+
+// checkField returns the problem with the field of p whose YAML key is key,
+// or nil if it is valid.
+func (p Profile) checkField(key string) error {
+	switch key {
+	case "gh_user":
 		if p.GHUser == "" {
-			errs = append(errs, fmt.Sprintf("profile %q: gh_user is required", name))
+			return errors.New("gh_user is required")
 		}
+	case "git_name":
 		if p.GitName == "" {
-			errs = append(errs, fmt.Sprintf("profile %q: git_name is required", name))
+			return errors.New("git_name is required")
 		}
+	case "git_email":
 		if p.GitEmail == "" {
-			errs = append(errs, fmt.Sprintf("profile %q: git_email is required", name))
+			return errors.New("git_email is required")
+		} else if reason := checkEmail(p.GitEmail); reason != "" {
+			return fmt.Errorf("git_email %q is invalid: %s", p.GitEmail, reason)
+		}
+	case "committer_email":
+		if p.CommitterEmail != "" {
+			if reason := checkEmail(p.CommitterEmail); reason != "" {
+				return fmt.Errorf("committer_email %q is invalid: %s", p.CommitterEmail, reason)
+			}
+		}
+	case "signing_format":
+		if p.SigningFormat != "" && !validSigningFormats[p.SigningFormat] {
+			return errors.New("signing_format must be one of openpgp, ssh, x509")
+		}
+	case "clone_protocol":
+		return CheckCloneProtocol(p.CloneProtocol)
+	case "credential_helper":
+		if p.CredentialHelper != "" && p.CredentialHelper != CredentialGH {
+			return fmt.Errorf("credential_helper must be %s or empty, not %q", CredentialGH, p.CredentialHelper)
 		}
 	}
-	return errs
+	return nil
+}
+
+// checkEmail performs a basic sanity check on an email address and returns
+// a description of the problem, or "" if it looks valid.
+func checkEmail(email string) string {
+	if strings.ContainsAny(email, " \t") {
+		return "contains whitespace"
+	}
+	if n := strings.Count(email, "@"); n != 1 {
+		return "must contain exactly one @"
+	}
+	local, domain, _ := strings.Cut(email, "@")
+	if local == "" {
+		return "missing local part before @"
+	}
+	if domain == "" {
+		return "missing domain after @"
+	}
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return "domain must contain a dot"
+	}
+	return ""
 }
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -38,6 +41,12 @@ func TestProfilesRoundTrip(t *testing.T) {
 	if len(loaded.Profiles) != 2 {
 		t.Fatalf("expected 2 profiles, got %d", len(loaded.Profiles))
 	}
+	if loaded.Version != ProfilesVersion {
+		t.Errorf("Version = %d, want %d", loaded.Version, ProfilesVersion)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "version: 1") {
+		t.Errorf("SaveTo did not stamp the version:\n%s", data)
+	}
 	if loaded.Default != "personal" {
 		t.Errorf("expected default %q, got %q", "personal", loaded.Default)
 	}
@@ -49,6 +58,153 @@ func TestProfilesRoundTrip(t *testing.T) {
 	}
 }
 
+func TestProfilesRoundTrip_Description(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.yml")
+	pf := &ProfilesFile{Profiles: map[string]Profile{
+		"work":     {GHUser: "user2", GitName: "User Two", GitEmail: "user2@company.com", Description: "Acme day job"},
+		"personal": {GHUser: "user1", GitName: "User One", GitEmail: "user1@example.com"},
+	}}
+	if err := pf.SaveTo(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "description:"); n != 1 {
+		t.Errorf("expected description only on the profile that has one, got %d in:\n%s", n, data)
+	}
+
+	loaded, err := LoadProfilesFrom(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.Profiles["work"].Description; got != "Acme day job" {
+		t.Errorf("Description = %q, want %q", got, "Acme day job")
+	}
+}
+
+func TestLoadProfilesFrom_Migrates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.yml")
+	old := `profiles:
+  personal:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com
+  corp:
+    gh_user: corp-user
+    host: ghe.corp.example
+    git_name: Corp User
+    git_email: corp@corp.example
+default: personal
+`
+	if err := os.WriteFile(path, []byte(old), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	pf, err := LoadProfilesFrom(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pf.Version != ProfilesVersion {
+		t.Errorf("Version = %d, want %d", pf.Version, ProfilesVersion)
+	}
+	if got := pf.Profiles["personal"].Host; got != DefaultHost {
+		t.Errorf("personal Host = %q, want %q", got, DefaultHost)
+	}
+	if got := pf.Profiles["corp"].Host; got != "ghe.corp.example" {
+		t.Errorf("corp Host = %q, want it unchanged", got)
+	}
+
+	// Without the config lock, as in the shell hook, nothing is written.
+	if data, _ := os.ReadFile(path); string(data) != old {
+		t.Errorf("unlocked load rewrote the file:\n%s", data)
+	}
+
+	// A command holding the lock writes the migration back.
+	t.Setenv("GH_IDENTITY_CONFIG_DIR", t.TempDir())
+	unlock, err := Lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadProfilesFrom(path)
+	unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrated, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(migrated), "version: 1") || !strings.Contains(string(migrated), "host: github.com") {
+		t.Errorf("migrated file not written back:\n%s", migrated)
+	}
+
+	// Loading again is a no-op.
+	if _, err := LoadProfilesFrom(path); err != nil {
+		t.Fatal(err)
+	}
+	again, _ := os.ReadFile(path)
+	if string(again) != string(migrated) {
+		t.Errorf("second load rewrote the file:\n%s", again)
+	}
+	if MigrateProfiles(pf) {
+		t.Error("MigrateProfiles reported a change for a current file")
+	}
+}
+
+func TestLoadProfilesFrom_MigratesReadOnly(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root ignores directory permissions")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "profiles.yml")
+	old := "profiles:\n  personal:\n    gh_user: user1\n    git_name: User One\n    git_email: user1@example.com\n"
+	if err := os.WriteFile(path, []byte(old), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dir, 0o555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(dir, 0o755) })
+
+	pf, err := LoadProfilesFrom(path)
+	if err != nil {
+		t.Fatalf("read-only dir should not fail the load: %v", err)
+	}
+	if pf.Version != ProfilesVersion || pf.Profiles["personal"].Host != DefaultHost {
+		t.Errorf("expected the in-memory profiles to be migrated, got %+v", pf)
+	}
+	if data, _ := os.ReadFile(path); string(data) != old {
+		t.Errorf("read-only file was modified:\n%s", data)
+	}
+}
+
+func TestLoadProfilesFrom_FutureVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.yml")
+	future := "version: 99\nprofiles:\n  personal:\n    gh_user: user1\n    git_name: User One\n    git_email: user1@example.com\n    hologram: true\n"
+	if err := os.WriteFile(path, []byte(future), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	pf, err := LoadProfilesFrom(path)
+	if err != nil {
+		t.Fatalf("a newer file should still load: %v", err)
+	}
+	if pf.Profiles["personal"].GHUser != "user1" {
+		t.Errorf("expected profile to load, got %+v", pf.Profiles)
+	}
+
+	pf.AddProfile("work", Profile{GHUser: "user2", GitName: "User Two", GitEmail: "user2@example.com"})
+	err = pf.SaveTo(path)
+	if err == nil || !strings.Contains(err.Error(), "upgrade gh-identity") {
+		t.Fatalf("SaveTo() error = %v, want refusal", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != future {
+		t.Errorf("future file was modified:\n%s", data)
+	}
+}
+
 func TestLoadProfilesFrom_NotExist(t *testing.T) {
 	pf, err := LoadProfilesFrom("/nonexistent/profiles.yml")
 	if err != nil {
@@ -109,7 +265,7 @@ func TestAddRemoveProfile(t *testing.T) {
 func TestValidate(t *testing.T) {
 	pf := &ProfilesFile{
 		Profiles: map[string]Profile{
-			"good": {GHUser: "u", GitName: "n", GitEmail: "e"},
+			"good": {GHUser: "u", GitName: "n", GitEmail: "e@example.com"},
 			"bad":  {GHUser: "", GitName: "", GitEmail: ""},
 		},
 	}
@@ -119,3 +275,120 @@ func TestValidate(t *testing.T) {
 		t.Errorf("expected 3 validation errors, got %d: %v", len(errs), errs)
 	}
 }
+
+func TestProfileSetField(t *testing.T) {
+	p := Profile{GHUser: "octo", GitName: "Octo", GitEmail: "octo@example.com"}
+
+	if err := p.SetField("git_email", "cat@example.com"); err != nil || p.GitEmail != "cat@example.com" {
+		t.Errorf("SetField(git_email) = %v, GitEmail = %q", err, p.GitEmail)
+	}
+	if err := p.SetField("ssh_keys", "~/.ssh/a, ~/.ssh/b,"); err != nil || len(p.SSHKeys) != 2 || p.SSHKeys[1] != "~/.ssh/b" {
+		t.Errorf("SetField(ssh_keys) = %v, SSHKeys = %v", err, p.SSHKeys)
+	}
+	if err := p.SetField("description", ""); err != nil {
+		t.Errorf("clearing an optional field: %v", err)
+	}
+
+	for _, tt := range []struct{ key, value string }{
+		{"gh_users", "octo"},      // unknown field
+		{"GitEmail", "a@b.co"},    // Go name, not the YAML key
+		{"git_email", "not-mail"}, // invalid value
+		{"git_name", ""},          // required
+		{"signing_format", "pgp"},
+		{"clone_protocol", "ftp"},
+		{"credential_helper", "store"},
+	} {
+		before := p
+		if err := p.SetField(tt.key, tt.value); err == nil {
+			t.Errorf("SetField(%q, %q) succeeded, want an error", tt.key, tt.value)
+		}
+		if p.GitEmail != before.GitEmail || p.GitName != before.GitName || p.SigningFormat != before.SigningFormat {
+			t.Errorf("SetField(%q, %q) changed the profile despite failing", tt.key, tt.value)
+		}
+	}
+
+	if fields := ProfileFields(); fields[0] != "gh_user" || len(fields) != reflect.TypeOf(Profile{}).NumField() {
+		t.Errorf("ProfileFields() = %v", fields)
+	}
+}
+
+func TestValidate_Email(t *testing.T) {
+	tests := []struct {
+		email string
+		valid bool
+	}{
+		{"john@example.com", true},
+		{"first.last+tag@sub.example.co.uk", true},
+		{"john@@example.com", false},
+		{"john.example.com", false},
+		{"@example.com", false},
+		{"john@", false},
+		{"john@example", false},
+		{"john@.example", false},
+		{"john@example.", false},
+		{"john doe@example.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			pf := &ProfilesFile{
+				Profiles: map[string]Profile{
+					"p": {GHUser: "u", GitName: "n", GitEmail: tt.email},
+				},
+			}
+			errs := pf.Validate()
+			if tt.valid && len(errs) != 0 {
+				t.Errorf("expected %q to be valid, got %v", tt.email, errs)
+			}
+			if !tt.valid {
+				if len(errs) != 1 {
+					t.Fatalf("expected 1 error for %q, got %v", tt.email, errs)
+				}
+				if !strings.Contains(errs[0], "git_email") || !strings.Contains(errs[0], "invalid") {
+					t.Errorf("unexpected error message: %s", errs[0])
+				}
+			}
+		})
+	}
+}
+
+func TestValidate_SigningFormat(t *testing.T) {
+	pf := &ProfilesFile{
+		Profiles: map[string]Profile{
+			"ssh": {GHUser: "u", GitName: "n", GitEmail: "e@example.com", SigningKey: "k", SigningFormat: "ssh"},
+			"bad": {GHUser: "u", GitName: "n", GitEmail: "e@example.com", SigningKey: "k", SigningFormat: "pgp"},
+		},
+	}
+
+	errs := pf.Validate()
+	if len(errs) != 1 {
+		t.Errorf("expected 1 validation error, got %d: %v", len(errs), errs)
+	}
+}
+
+func TestValidate_CloneProtocol(t *testing.T) {
+	pf := &ProfilesFile{
+		Profiles: map[string]Profile{
+			"ssh":   {GHUser: "u", GitName: "n", GitEmail: "e@example.com", CloneProtocol: "ssh"},
+			"https": {GHUser: "u", GitName: "n", GitEmail: "e@example.com", CloneProtocol: "https"},
+			"bad":   {GHUser: "u", GitName: "n", GitEmail: "e@example.com", CloneProtocol: "git"},
+		},
+	}
+
+	errs := pf.Validate()
+	if len(errs) != 1 || !strings.Contains(errs[0], `"bad"`) {
+		t.Errorf("expected 1 validation error for bad, got %v", errs)
+	}
+}
+
+func TestAllSSHKeys(t *testing.T) {
+	p := Profile{SSHKey: "~/.ssh/a", SSHKeys: []string{"~/.ssh/b", "~/.ssh/a", ""}}
+	got := p.AllSSHKeys()
+	want := []string{"~/.ssh/a", "~/.ssh/b"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("AllSSHKeys() = %v, want %v", got, want)
+	}
+
+	if keys := (Profile{}).AllSSHKeys(); len(keys) != 0 {
+		t.Errorf("expected no keys, got %v", keys)
+	}
+}
@@ -3,54 +3,177 @@
 package ghauth
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
 	"strings"
+	"sync"
+	"time"
 
 	gh "github.com/cli/go-gh/v2"
 )
 
+// DefaultHost is the host gh uses when none is specified.
+const DefaultHost = "github.com"
+
+// Account is a gh login: a user on a particular host. The same username can
+// be logged in on github.com and a GitHub Enterprise Server host at once.
+type Account struct {
+	Host string
+	User string
+}
+
+// String returns the account as "host/user".
+func (a Account) String() string {
+	return a.Host + "/" + a.User
+}
+
 // Auth is the interface for gh authentication operations.
 // Use the interface for testability; the default implementation shells out to gh.
 type Auth interface {
-	// Token returns the auth token for the given username.
-	Token(username string) (string, error)
-	// AuthenticatedUsers returns a list of authenticated gh usernames.
+	// Token returns the auth token of the given user on host.
+	Token(host, username string) (string, error)
+	// Accounts returns every authenticated gh account across all hosts.
+	Accounts() ([]Account, error)
+	// AuthenticatedUsers returns the distinct usernames of Accounts.
 	AuthenticatedUsers() ([]string, error)
 	// ActiveUser returns the currently active gh user.
 	ActiveUser() (string, error)
+	// TokenScopes returns the OAuth scopes granted to the user's token for
+	// host. It returns nil scopes for tokens that do not report any, such as
+	// fine-grained personal access tokens.
+	TokenScopes(host, username string) ([]string, error)
 }
 
 // execFn is the function signature for executing gh commands.
 type execFn func(args ...string) (bytes.Buffer, bytes.Buffer, error)
 
+// execTokenFn runs a gh command authenticated with the given token.
+type execTokenFn func(token string, args ...string) (bytes.Buffer, bytes.Buffer, error)
+
 // GHAuth is the default implementation using the gh CLI.
 type GHAuth struct {
-	exec execFn
+	exec      execFn
+	execToken execTokenFn
+	// retries is how many times a failed gh call is retried when the
+	// failure is not an authentication problem. Zero disables retrying.
+	retries int
+	// backoff is the wait before the first retry; it doubles on each
+	// further attempt.
+	backoff time.Duration
+
+	// mu guards the memoized lookups below. They live on the instance, so
+	// they last for one process and are never shared between CLI runs.
+	mu        sync.Mutex
+	tokens    map[Account]string
+	userInfos map[Account]*UserInfo
 }
 
+// Retry defaults for NewGHAuth. maxRetryWait caps the total time spent
+// waiting between attempts so the shell hook stays fast even when gh is
+// failing.
+const (
+	defaultRetries = 2
+	defaultBackoff = 50 * time.Millisecond
+	maxRetryWait   = 200 * time.Millisecond
+)
+
 // NewGHAuth returns a new default Auth implementation.
 func NewGHAuth() *GHAuth {
-	return &GHAuth{exec: ghExec}
+	return &GHAuth{exec: ghExec, execToken: ghExecToken, retries: defaultRetries, backoff: defaultBackoff}
+}
+
+// execRetry runs g.exec, retrying transient failures (a keyring that is
+// briefly locked, gh racing its own config write) with exponential backoff.
+// Authentication failures are returned at once since retrying cannot fix
+// them.
+func (g *GHAuth) execRetry(args ...string) (bytes.Buffer, bytes.Buffer, error) {
+	stdout, stderr, err := g.exec(args...)
+	wait, waited := g.backoff, time.Duration(0)
+	for attempt := 0; err != nil && attempt < g.retries && !isAuthError(stderr.String()); attempt++ {
+		if waited+wait > maxRetryWait {
+			break
+		}
+		slog.Debug("retrying gh", "args", args, "attempt", attempt+1, "wait", wait, "err", err)
+		time.Sleep(wait)
+		waited += wait
+		wait *= 2
+		stdout, stderr, err = g.exec(args...)
+	}
+	return stdout, stderr, err
+}
+
+// isAuthError reports whether gh's stderr describes a missing or rejected
+// login rather than a transient failure.
+func isAuthError(stderr string) bool {
+	s := strings.ToLower(stderr)
+	for _, marker := range []string{"not logged in", "no oauth token", "no token found", "authentication", "bad credentials", "http 401"} {
+		if strings.Contains(s, marker) {
+			return true
+		}
+	}
+	return false
 }
 
 // ghExec wraps gh.Exec.
 func ghExec(args ...string) (bytes.Buffer, bytes.Buffer, error) {
+	slog.Debug("running gh", "args", args)
 	return gh.Exec(args...)
 }
 
-// Token retrieves the auth token for the given username via `gh auth token -u <user>`.
-func (g *GHAuth) Token(username string) (string, error) {
-	stdout, stderr, err := g.exec("auth", "token", "-u", username)
+// ghExecToken runs gh with GH_TOKEN set in its environment, so the token
+// never appears on a command line.
+func ghExecToken(token string, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+	var stdout, stderr bytes.Buffer
+	ghExe, err := gh.Path()
 	if err != nil {
-		return "", fmt.Errorf("gh auth token -u %s: %s: %w", username, stderr.String(), err)
+		return stdout, stderr, err
+	}
+	slog.Debug("running gh", "args", args, "gh_token", "set")
+	cmd := exec.Command(ghExe, args...)
+	cmd.Env = append(os.Environ(), "GH_TOKEN="+token)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return stdout, stderr, fmt.Errorf("gh execution failed: %w", err)
 	}
-	return strings.TrimSpace(stdout.String()), nil
+	return stdout, stderr, nil
 }
 
-// AuthenticatedUsers returns the list of authenticated users via `gh auth status`.
-func (g *GHAuth) AuthenticatedUsers() ([]string, error) {
-	stdout, stderr, err := g.exec("auth", "status", "-a")
+// Token retrieves the auth token of the given user on host via
+// `gh auth token -h <host> -u <user>`. Successful lookups are memoized for
+// the life of g.
+func (g *GHAuth) Token(host, username string) (string, error) {
+	key := Account{Host: host, User: username}
+	g.mu.Lock()
+	token, ok := g.tokens[key]
+	g.mu.Unlock()
+	if ok {
+		return token, nil
+	}
+
+	stdout, stderr, err := g.execRetry("auth", "token", "-h", host, "-u", username)
+	if err != nil {
+		return "", fmt.Errorf("gh auth token -h %s -u %s: %s: %w", host, username, stderr.String(), err)
+	}
+	token = strings.TrimSpace(stdout.String())
+
+	g.mu.Lock()
+	if g.tokens == nil {
+		g.tokens = make(map[Account]string)
+	}
+	g.tokens[key] = token
+	g.mu.Unlock()
+	return token, nil
+}
+
+// Accounts returns every authenticated account via `gh auth status -a`.
+func (g *GHAuth) Accounts() ([]Account, error) {
+	stdout, stderr, err := g.execRetry("auth", "status", "-a")
 	if err != nil {
 		// gh auth status exits 1 if not logged in; check stderr.
 		output := stderr.String()
@@ -60,12 +183,35 @@ func (g *GHAuth) AuthenticatedUsers() ([]string, error) {
 		return nil, fmt.Errorf("gh auth status: %s: %w", output, err)
 	}
 
-	return parseAuthUsers(stdout.String() + stderr.String()), nil
+	return parseAccounts(stdout.String() + stderr.String()), nil
+}
+
+// AuthenticatedUsers returns the distinct authenticated usernames across all
+// hosts. Use Accounts when the host matters.
+func (g *GHAuth) AuthenticatedUsers() ([]string, error) {
+	accounts, err := g.Accounts()
+	if err != nil {
+		return nil, err
+	}
+	return Users(accounts), nil
+}
+
+// Users returns the distinct usernames in accounts, in order.
+func Users(accounts []Account) []string {
+	var users []string
+	seen := make(map[string]bool)
+	for _, a := range accounts {
+		if !seen[a.User] {
+			seen[a.User] = true
+			users = append(users, a.User)
+		}
+	}
+	return users
 }
 
 // ActiveUser returns the currently active gh user via `gh auth status`.
 func (g *GHAuth) ActiveUser() (string, error) {
-	stdout, stderr, err := g.exec("auth", "status")
+	stdout, stderr, err := g.execRetry("auth", "status")
 	if err != nil {
 		return "", fmt.Errorf("gh auth status: %s: %w", stderr.String(), err)
 	}
@@ -73,112 +219,219 @@ func (g *GHAuth) ActiveUser() (string, error) {
 	return parseActiveUser(combined)
 }
 
+// TokenScopes returns the scopes of the user's token for host from the
+// X-OAuth-Scopes header of `gh api -i user`. An error means the token was
+// rejected, e.g. because it expired or was revoked.
+func (g *GHAuth) TokenScopes(host, username string) ([]string, error) {
+	token, err := g.Token(host, username)
+	if err != nil {
+		return nil, err
+	}
+	stdout, stderr, err := g.execToken(token, "api", "--hostname", host, "-i", "user")
+	if err != nil {
+		return nil, fmt.Errorf("gh api user as %s on %s: %s: %w", username, host, strings.TrimSpace(stderr.String()), err)
+	}
+	return parseScopes(stdout.String()), nil
+}
+
+// parseScopes extracts the X-OAuth-Scopes header from `gh api -i` output.
+// It returns nil when the header is absent and an empty, non-nil slice when
+// the header is present but grants nothing.
+func parseScopes(output string) []string {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			break // end of headers
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || !strings.EqualFold(strings.TrimSpace(name), "X-OAuth-Scopes") {
+			continue
+		}
+		scopes := []string{}
+		for _, s := range strings.Split(value, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				scopes = append(scopes, s)
+			}
+		}
+		return scopes
+	}
+	return nil
+}
+
 // UserInfo holds information about a GitHub user.
 type UserInfo struct {
 	Name  string
 	Email string
 }
 
-// GetUserInfo retrieves the user's name and email from GitHub API.
-func (g *GHAuth) GetUserInfo(username string) (*UserInfo, error) {
+// GetUserInfo retrieves the name and email of the given user on host from
+// the GitHub API, authenticated with that account's token. Successful lookups
+// are memoized per account for the life of g; callers get their own copy.
+func (g *GHAuth) GetUserInfo(host, username string) (*UserInfo, error) {
+	key := Account{Host: host, User: username}
+	g.mu.Lock()
+	cached, ok := g.userInfos[key]
+	g.mu.Unlock()
+	if ok {
+		info := *cached
+		return &info, nil
+	}
+
+	token, err := g.Token(host, username)
+	if err != nil {
+		return nil, err
+	}
 	info := &UserInfo{}
 
 	// Get name from user profile
-	stdout, stderr, err := g.exec("api", "user", "-u", username)
+	stdout, stderr, err := g.execToken(token, "api", "--hostname", host, "user")
 	if err != nil {
 		return nil, fmt.Errorf("gh api user: %s: %w", stderr.String(), err)
 	}
 	info.Name = parseNameFromJSON(stdout.String())
 
 	// Get primary email
-	stdout, stderr, err = g.exec("api", "user/emails", "-u", username)
+	stdout, stderr, err = g.execToken(token, "api", "--hostname", host, "user/emails")
 	if err != nil {
 		return nil, fmt.Errorf("gh api user/emails: %s: %w", stderr.String(), err)
 	}
 	info.Email = parsePrimaryEmailFromJSON(stdout.String())
 
+	g.mu.Lock()
+	if g.userInfos == nil {
+		g.userInfos = make(map[Account]*UserInfo)
+	}
+	stored := *info
+	g.userInfos[key] = &stored
+	g.mu.Unlock()
 	return info, nil
 }
 
 // parseActiveUser extracts the active username from gh auth status output.
+// gh 2.40 and newer list every account on a host and mark the active one
+// with "Active account: true"; older versions list only the active one.
+// Marked accounts are preferred, and when several hosts are listed, the
+// github.com account wins.
 func parseActiveUser(output string) (string, error) {
-	// Look for "Logged in to github.com account <user>"
+	var accounts, active []Account
+	var last *Account
+	header := ""
 	for _, line := range strings.Split(output, "\n") {
-		line = strings.TrimSpace(line)
-		if strings.Contains(line, "account") {
-			parts := strings.Fields(line)
-			for i, p := range parts {
-				if p == "account" && i+1 < len(parts) {
-					user := strings.TrimRight(parts[i+1], "()")
-					return user, nil
-				}
+		if h, ok := hostHeader(line); ok {
+			header = h
+			continue
+		}
+		if a, ok := accountOnLine(line, header); ok {
+			accounts = append(accounts, a)
+			last = &accounts[len(accounts)-1]
+			continue
+		}
+		if last != nil && isActiveMarker(line) {
+			active = append(active, *last)
+		}
+	}
+
+	for _, list := range [][]Account{active, accounts} {
+		for _, a := range list {
+			if a.Host == DefaultHost {
+				return a.User, nil
 			}
 		}
+		if len(list) > 0 {
+			return list[0].User, nil
+		}
 	}
 	return "", fmt.Errorf("could not determine active user from gh auth status output")
 }
 
-// parseAuthUsers extracts usernames from gh auth status output.
-// The format varies across gh versions; we look for "account <user>" patterns.
-func parseAuthUsers(output string) []string {
-	var users []string
-	seen := make(map[string]bool)
+// isActiveMarker reports whether line is gh's "Active account: true" line.
+func isActiveMarker(line string) bool {
+	name, value, ok := strings.Cut(strings.TrimLeft(line, " \t-"), ":")
+	return ok && strings.TrimSpace(name) == "Active account" && strings.TrimSpace(value) == "true"
+}
+
+// parseAccounts extracts accounts from gh auth status output.
+// The format varies across gh versions; we look for "account <user>" patterns
+// and take the host from "Logged in to <host>", falling back to the most
+// recent unindented host header line, then github.com.
+func parseAccounts(output string) []Account {
+	var accounts []Account
+	seen := make(map[Account]bool)
+	header := ""
 	for _, line := range strings.Split(output, "\n") {
-		fields := strings.Fields(line)
-		for i, f := range fields {
-			if f == "account" && i+1 < len(fields) {
-				user := strings.TrimRight(fields[i+1], "()")
-				if !seen[user] {
-					seen[user] = true
-					users = append(users, user)
-				}
-			}
+		if h, ok := hostHeader(line); ok {
+			header = h
+			continue
+		}
+		if a, ok := accountOnLine(line, header); ok && !seen[a] {
+			seen[a] = true
+			accounts = append(accounts, a)
 		}
 	}
-	return users
+	return accounts
+}
+
+// hostHeader reports whether line is an unindented host header, such as
+// "github.com", and returns the host.
+func hostHeader(line string) (string, bool) {
+	fields := strings.Fields(line)
+	if len(fields) == 1 && line == strings.TrimLeft(line, " \t") {
+		return fields[0], true
+	}
+	return "", false
+}
+
+// accountOnLine returns the account named by an "account <user>" pattern in
+// line, under host header if the line does not name its own.
+func accountOnLine(line, header string) (Account, bool) {
+	fields := strings.Fields(line)
+	for i, f := range fields {
+		if f != "account" || i+1 >= len(fields) {
+			continue
+		}
+		a := Account{Host: header, User: strings.TrimRight(fields[i+1], "()")}
+		if i >= 2 && fields[i-2] == "to" {
+			a.Host = fields[i-1]
+		}
+		if a.Host == "" {
+			a.Host = DefaultHost
+		}
+		return a, true
+	}
+	return Account{}, false
+}
+
+// parseAuthUsers extracts the distinct usernames from gh auth status output.
+func parseAuthUsers(output string) []string {
+	return Users(parseAccounts(output))
 }
 
 // parseNameFromJSON extracts the name field from GitHub API /user response.
+// Returns "" if the name is null, missing, or the input is not valid JSON.
 func parseNameFromJSON(jsonStr string) string {
-	// Simple extraction: look for "name": "value"
-	for _, line := range strings.Split(jsonStr, "\n") {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, `"name":`) {
-			// Extract value between quotes after colon
-			parts := strings.SplitN(line, ":", 2)
-			if len(parts) == 2 {
-				value := strings.TrimSpace(parts[1])
-				value = strings.Trim(value, `",`)
-				// Return empty if null
-				if value == "null" {
-					return ""
-				}
-				return value
-			}
-		}
+	var user struct {
+		Name string `json:"name"`
 	}
-	return ""
+	if err := json.Unmarshal([]byte(jsonStr), &user); err != nil {
+		return ""
+	}
+	return user.Name
 }
 
 // parsePrimaryEmailFromJSON extracts the primary email from GitHub API /user/emails response.
+// Returns "" if no email is marked primary or the input is not valid JSON.
 func parsePrimaryEmailFromJSON(jsonStr string) string {
-	// Look for "email": "...", followed by "primary": true
-	lines := strings.Split(jsonStr, "\n")
-	for i, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, `"primary":`) && strings.Contains(line, "true") {
-			// Look backwards for the email field
-			for j := i - 1; j >= 0; j-- {
-				emailLine := strings.TrimSpace(lines[j])
-				if strings.HasPrefix(emailLine, `"email":`) {
-					parts := strings.SplitN(emailLine, ":", 2)
-					if len(parts) == 2 {
-						value := strings.TrimSpace(parts[1])
-						value = strings.Trim(value, `",`)
-						return value
-					}
-				}
-			}
+	var emails []struct {
+		Email   string `json:"email"`
+		Primary bool   `json:"primary"`
+	}
+	if err := json.Unmarshal([]byte(jsonStr), &emails); err != nil {
+		return ""
+	}
+	for _, e := range emails {
+		if e.Primary {
+			return e.Email
 		}
 	}
 	return ""
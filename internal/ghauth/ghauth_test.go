@@ -3,6 +3,7 @@ package ghauth
 import (
 	"bytes"
 	"fmt"
+	"reflect"
 	"testing"
 )
 
@@ -72,6 +73,55 @@ func TestParseAuthUsers(t *testing.T) {
 	}
 }
 
+func TestParseAccounts(t *testing.T) {
+	output := `github.com
+  ✓ Logged in to github.com account octo (keyring)
+  - Active account: true
+  ✓ Logged in to github.com account work-octo (keyring)
+  - Active account: false
+
+ghe.corp.example
+  ✓ Logged in to ghe.corp.example account octo (keyring)
+  - Active account: true
+`
+	got := parseAccounts(output)
+	want := []Account{
+		{Host: "github.com", User: "octo"},
+		{Host: "github.com", User: "work-octo"},
+		{Host: "ghe.corp.example", User: "octo"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parseAccounts() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("parseAccounts()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	if users := parseAuthUsers(output); len(users) != 2 {
+		t.Errorf("parseAuthUsers() = %v, want [octo work-octo]", users)
+	}
+	if s := got[2].String(); s != "ghe.corp.example/octo" {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestParseActiveUser_PrefersGitHubCom(t *testing.T) {
+	output := `ghe.corp.example
+  ✓ Logged in to ghe.corp.example account enterprise-user (keyring)
+github.com
+  ✓ Logged in to github.com account public-user (keyring)
+`
+	got, err := parseActiveUser(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "public-user" {
+		t.Errorf("parseActiveUser() = %q, want public-user", got)
+	}
+}
+
 func TestNewGHAuth(t *testing.T) {
 	auth := NewGHAuth()
 	if auth == nil {
@@ -81,7 +131,7 @@ func TestNewGHAuth(t *testing.T) {
 
 func TestGHAuth_Token(t *testing.T) {
 	g := &GHAuth{exec: mockExec("  gho_abc123\n", "", nil)}
-	tok, err := g.Token("user1")
+	tok, err := g.Token(DefaultHost, "user1")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -90,9 +140,35 @@ func TestGHAuth_Token(t *testing.T) {
 	}
 }
 
+// TestGHAuth_Token_Host tests that the token is looked up, and memoized, per
+// host: the same username on two hosts has two tokens.
+func TestGHAuth_Token_Host(t *testing.T) {
+	var calls [][]string
+	g := &GHAuth{exec: func(args ...string) (bytes.Buffer, bytes.Buffer, error) {
+		calls = append(calls, args)
+		return mockExec("tok-"+args[3]+"\n", "", nil)(args...)
+	}}
+	for _, host := range []string{DefaultHost, "ghe.corp.example", DefaultHost} {
+		tok, err := g.Token(host, "octo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok != "tok-"+host {
+			t.Errorf("Token(%q) = %q, want %q", host, tok, "tok-"+host)
+		}
+	}
+	want := [][]string{
+		{"auth", "token", "-h", DefaultHost, "-u", "octo"},
+		{"auth", "token", "-h", "ghe.corp.example", "-u", "octo"},
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("gh calls = %v, want %v", calls, want)
+	}
+}
+
 func TestGHAuth_Token_Error(t *testing.T) {
 	g := &GHAuth{exec: mockExec("", "no token found", fmt.Errorf("exit 1"))}
-	_, err := g.Token("baduser")
+	_, err := g.Token(DefaultHost, "baduser")
 	if err == nil {
 		t.Error("expected error")
 	}
@@ -153,6 +229,147 @@ func TestGHAuth_ActiveUser_Error(t *testing.T) {
 	}
 }
 
+// flakyExec fails the first failures calls with stderr, then succeeds with
+// stdout. It counts every call in *calls.
+func flakyExec(failures int, stdout, stderr string, calls *int) execFn {
+	return func(args ...string) (bytes.Buffer, bytes.Buffer, error) {
+		*calls++
+		if *calls <= failures {
+			return mockExec("", stderr, fmt.Errorf("exit 1"))(args...)
+		}
+		return mockExec(stdout, "", nil)(args...)
+	}
+}
+
+func TestGHAuth_Token_Memoized(t *testing.T) {
+	calls := 0
+	g := &GHAuth{exec: flakyExec(1, "gho_abc123\n", "keyring locked", &calls)}
+
+	// Failures are not cached.
+	if _, err := g.Token(DefaultHost, "user1"); err == nil {
+		t.Fatal("expected error")
+	}
+	for i := 0; i < 3; i++ {
+		tok, err := g.Token(DefaultHost, "user1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok != "gho_abc123" {
+			t.Errorf("Token() = %q, want gho_abc123", tok)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("gh called %d times, want 2", calls)
+	}
+
+	if _, err := g.Token(DefaultHost, "user2"); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("gh called %d times after a second user, want 3", calls)
+	}
+
+	// A fresh instance starts with an empty cache.
+	fresh := &GHAuth{exec: flakyExec(0, "gho_abc123\n", "", &calls)}
+	if _, err := fresh.Token(DefaultHost, "user1"); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 4 {
+		t.Errorf("gh called %d times for a new instance, want 4", calls)
+	}
+}
+
+func TestGHAuth_GetUserInfo_Memoized(t *testing.T) {
+	var calls []string
+	g := &GHAuth{
+		exec: func(args ...string) (bytes.Buffer, bytes.Buffer, error) {
+			return mockExec("tok-"+args[3]+"\n", "", nil)(args...)
+		},
+		execToken: func(token string, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+			calls = append(calls, token+" "+args[2]+" "+args[3])
+			if args[3] == "user" {
+				return mockExec(`{"name": "Octocat on `+args[2]+`"}`, "", nil)(args...)
+			}
+			return mockExec(`[{"email": "octocat@`+args[2]+`", "primary": true}]`, "", nil)(args...)
+		},
+	}
+
+	first, err := g.GetUserInfo(DefaultHost, "octocat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Name = "changed by caller"
+	second, err := g.GetUserInfo(DefaultHost, "octocat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Name != "Octocat on github.com" || second.Email != "octocat@github.com" {
+		t.Errorf("GetUserInfo() = %+v, want cached original", second)
+	}
+
+	// The same username on another host is another account.
+	corp, err := g.GetUserInfo("ghe.corp.example", "octocat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corp.Name != "Octocat on ghe.corp.example" || corp.Email != "octocat@ghe.corp.example" {
+		t.Errorf("GetUserInfo(ghe.corp.example) = %+v, want that host's user", corp)
+	}
+	want := []string{
+		"tok-github.com github.com user",
+		"tok-github.com github.com user/emails",
+		"tok-ghe.corp.example ghe.corp.example user",
+		"tok-ghe.corp.example ghe.corp.example user/emails",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("gh api calls = %q, want %q", calls, want)
+	}
+}
+
+func TestGHAuth_Retry(t *testing.T) {
+	tests := []struct {
+		name      string
+		retries   int
+		failures  int
+		stderr    string
+		wantErr   bool
+		wantCalls int
+	}{
+		{"transient failure recovers", 2, 1, "keyring locked", false, 2},
+		{"retries exhausted", 2, 5, "keyring locked", true, 3},
+		{"no retries", 0, 1, "keyring locked", true, 1},
+		{"auth error not retried", 2, 1, "no oauth token found for github.com account user1", true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			g := &GHAuth{exec: flakyExec(tt.failures, "gho_abc123\n", tt.stderr, &calls), retries: tt.retries}
+			tok, err := g.Token(DefaultHost, "user1")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Token() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && tok != "gho_abc123" {
+				t.Errorf("Token() = %q, want gho_abc123", tok)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("gh called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestGHAuth_Retry_CapsWait(t *testing.T) {
+	calls := 0
+	g := &GHAuth{exec: flakyExec(10, "", "keyring locked", &calls), retries: 10, backoff: maxRetryWait}
+	if _, err := g.ActiveUser(); err == nil {
+		t.Fatal("expected error")
+	}
+	// One wait of maxRetryWait fits the budget; the next would exceed it.
+	if calls != 2 {
+		t.Errorf("gh called %d times, want 2", calls)
+	}
+}
+
 func TestParseActiveUser(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -191,6 +408,47 @@ func TestParseActiveUser(t *testing.T) {
 			output:  "  something account",
 			wantErr: true,
 		},
+		{
+			name: "active account listed second",
+			output: `github.com
+  ✓ Logged in to github.com account octo (keyring)
+  - Active account: false
+  - Git operations protocol: https
+  - Token: gho_************************************
+  - Token scopes: 'gist', 'read:org', 'repo'
+
+  ✓ Logged in to github.com account work-octo (keyring)
+  - Active account: true
+  - Git operations protocol: ssh
+  - Token: gho_************************************
+`,
+			want: "work-octo",
+		},
+		{
+			name: "active github.com account beats other hosts",
+			output: `ghe.corp.example
+  ✓ Logged in to ghe.corp.example account enterprise-user (keyring)
+  - Active account: true
+
+github.com
+  ✓ Logged in to github.com account octo (keyring)
+  - Active account: false
+  ✓ Logged in to github.com account public-user (keyring)
+  - Active account: true
+`,
+			want: "public-user",
+		},
+		{
+			name: "active marker only on another host",
+			output: `github.com
+  ✓ Logged in to github.com account octo (keyring)
+  - Active account: false
+ghe.corp.example
+  ✓ Logged in to ghe.corp.example account enterprise-user (keyring)
+  - Active account: true
+`,
+			want: "enterprise-user",
+		},
 	}
 
 	for _, tt := range tests {
@@ -252,6 +510,16 @@ func TestParseNameFromJSON(t *testing.T) {
 }`,
 			want: "",
 		},
+		{
+			name: "compact json with comma in name",
+			json: `{"login":"user","name":"A, B"}`,
+			want: "A, B",
+		},
+		{
+			name: "escaped quotes in name",
+			json: `{"name":"Jane \"JD\" Doe"}`,
+			want: `Jane "JD" Doe`,
+		},
 		{
 			name: "empty json",
 			json: "",
@@ -313,6 +581,22 @@ func TestParsePrimaryEmailFromJSON(t *testing.T) {
 ]`,
 			want: "",
 		},
+		{
+			name: "compact json",
+			json: `[{"email":"a@example.com","primary":false},{"email":"b@example.com","primary":true}]`,
+			want: "b@example.com",
+		},
+		{
+			name: "primary before email",
+			json: `[
+  {
+    "primary": true,
+    "verified": true,
+    "email": "first@example.com"
+  }
+]`,
+			want: "first@example.com",
+		},
 		{
 			name: "empty array",
 			json: "[]",
@@ -379,7 +663,8 @@ func TestGHAuth_GetUserInfo(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			callCount := 0
 			g := &GHAuth{
-				exec: func(args ...string) (bytes.Buffer, bytes.Buffer, error) {
+				exec: mockExec("gho_abc123\n", "", nil),
+				execToken: func(token string, args ...string) (bytes.Buffer, bytes.Buffer, error) {
 					var stdout, stderr bytes.Buffer
 					callCount++
 					if callCount == 1 {
@@ -393,7 +678,7 @@ func TestGHAuth_GetUserInfo(t *testing.T) {
 				},
 			}
 
-			info, err := g.GetUserInfo("testuser")
+			info, err := g.GetUserInfo(DefaultHost, "testuser")
 			if tt.wantErr {
 				if err == nil {
 					t.Error("expected error, got nil")
@@ -413,3 +698,71 @@ func TestGHAuth_GetUserInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestParseScopes(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []string
+	}{
+		{
+			name:   "classic token",
+			output: "HTTP/2.0 200 OK\nContent-Type: application/json\nX-Oauth-Scopes: gist, read:org, repo\n\n{\"login\":\"user1\"}",
+			want:   []string{"gist", "read:org", "repo"},
+		},
+		{
+			name:   "no scopes granted",
+			output: "HTTP/2.0 200 OK\nX-Oauth-Scopes: \n\n{}",
+			want:   []string{},
+		},
+		{
+			name:   "fine-grained token",
+			output: "HTTP/2.0 200 OK\nContent-Type: application/json\n\n{\"note\":\"X-Oauth-Scopes: repo\"}",
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseScopes(tt.output)
+			if (got == nil) != (tt.want == nil) || fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("parseScopes() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGHAuth_TokenScopes(t *testing.T) {
+	var gotToken string
+	var gotArgs []string
+	g := &GHAuth{
+		exec: mockExec("gho_abc123\n", "", nil),
+		execToken: func(token string, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+			gotToken, gotArgs = token, args
+			var stdout bytes.Buffer
+			stdout.WriteString("HTTP/2.0 200 OK\nX-Oauth-Scopes: repo\n\n{}")
+			return stdout, bytes.Buffer{}, nil
+		},
+	}
+	scopes, err := g.TokenScopes("ghe.corp.example", "user1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scopes) != 1 || scopes[0] != "repo" {
+		t.Errorf("TokenScopes() = %v, want [repo]", scopes)
+	}
+	if gotToken != "gho_abc123" {
+		t.Errorf("token = %q, want the user's token", gotToken)
+	}
+	if fmt.Sprint(gotArgs) != "[api --hostname ghe.corp.example -i user]" {
+		t.Errorf("args = %v", gotArgs)
+	}
+
+	g.execToken = func(token string, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+		var stderr bytes.Buffer
+		stderr.WriteString("HTTP 401: Bad credentials")
+		return bytes.Buffer{}, stderr, fmt.Errorf("exit 1")
+	}
+	if _, err := g.TokenScopes(DefaultHost, "user1"); err == nil {
+		t.Error("expected error for a rejected token")
+	}
+}
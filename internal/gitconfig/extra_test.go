@@ -67,15 +67,78 @@ func TestRemoveProfileFragment_NotExist(t *testing.T) {
 }
 
 func TestGlobalGitconfigPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("GIT_CONFIG_GLOBAL", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	path, err := GlobalGitconfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(home, ".gitconfig"); path != want {
+		t.Errorf("GlobalGitconfigPath() = %q, want %q", path, want)
+	}
+}
+
+func TestGlobalGitconfigPath_GitConfigGlobal(t *testing.T) {
+	custom := filepath.Join(t.TempDir(), "custom-gitconfig")
+	t.Setenv("GIT_CONFIG_GLOBAL", custom)
+
 	path, err := GlobalGitconfigPath()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if path == "" {
-		t.Error("expected non-empty path")
+	if path != custom {
+		t.Errorf("GlobalGitconfigPath() = %q, want %q", path, custom)
+	}
+}
+
+func TestGlobalGitconfigPath_XDG(t *testing.T) {
+	home := t.TempDir()
+	xdg := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("GIT_CONFIG_GLOBAL", "")
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+
+	// Not used until the XDG file exists.
+	path, err := GlobalGitconfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(home, ".gitconfig"); path != want {
+		t.Errorf("GlobalGitconfigPath() = %q, want %q", path, want)
+	}
+
+	xdgPath := filepath.Join(xdg, "git", "config")
+	os.MkdirAll(filepath.Dir(xdgPath), 0o755)
+	os.WriteFile(xdgPath, []byte("[user]\n    name = Test\n"), 0o644)
+
+	path, err = GlobalGitconfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != xdgPath {
+		t.Errorf("GlobalGitconfigPath() = %q, want %q", path, xdgPath)
+	}
+}
+
+func TestGlobalGitconfigPath_XDGDefault(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("GIT_CONFIG_GLOBAL", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	xdgPath := filepath.Join(home, ".config", "git", "config")
+	os.MkdirAll(filepath.Dir(xdgPath), 0o755)
+	os.WriteFile(xdgPath, []byte(""), 0o644)
+
+	path, err := GlobalGitconfigPath()
+	if err != nil {
+		t.Fatal(err)
 	}
-	if !strings.HasSuffix(path, ".gitconfig") {
-		t.Errorf("expected path to end with .gitconfig, got %q", path)
+	if path != xdgPath {
+		t.Errorf("GlobalGitconfigPath() = %q, want %q", path, xdgPath)
 	}
 }
 
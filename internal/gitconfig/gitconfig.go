@@ -1,15 +1,18 @@
 // Package gitconfig manages per-profile gitconfig fragments and
-// includeIf directives in the user's global ~/.gitconfig.
+// includeIf directives in the user's global gitconfig (see GlobalGitconfigPath).
 package gitconfig
 
 import (
 	"bufio"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/dotbrains/gh-identity/internal/config"
+	"github.com/dotbrains/gh-identity/internal/resolve"
 )
 
 const (
@@ -28,17 +31,50 @@ func WriteProfileFragment(profileName string, p config.Profile) error {
 }
 
 // WriteProfileFragmentTo writes a profile gitconfig fragment to a specific path.
+// Signing settings are emitted only when the profile has a signing key, and
+// credential settings only when it uses gh's credential helper.
 func WriteProfileFragmentTo(path string, p config.Profile) error {
 	content := fmt.Sprintf("[user]\n    name = %s\n    email = %s\n", p.GitName, p.GitEmail)
+	if p.SigningKey != "" {
+		content += fmt.Sprintf("    signingkey = %s\n", signingKeyValue(p))
+		if p.SigningFormat != "" {
+			content += fmt.Sprintf("[gpg]\n    format = %s\n", p.SigningFormat)
+		}
+		content += "[commit]\n    gpgsign = true\n"
+	}
+	if p.CredentialHelper == config.CredentialGH {
+		content += credentialSection(p)
+	}
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return fmt.Errorf("creating directory: %w", err)
 	}
-	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+	slog.Debug("writing gitconfig fragment", "file", path)
+	if err := config.WriteFileAtomic(path, []byte(content), 0o644); err != nil {
 		return fmt.Errorf("writing gitconfig fragment: %w", err)
 	}
 	return nil
 }
 
+// credentialSection returns the settings that make git ask gh for the
+// credentials of p's host. The empty helper drops helpers configured
+// elsewhere, such as a keychain holding another account's token. gh only
+// answers for its active account, and refuses when that is not the
+// username asked for, so a push fails rather than using the wrong account.
+func credentialSection(p config.Profile) string {
+	return fmt.Sprintf("[credential \"https://%s\"]\n    helper =\n    helper = !gh auth git-credential\n    username = %s\n", p.GHHost(), p.GHUser)
+}
+
+// signingKeyValue returns the profile's signing key as git should see it.
+func signingKeyValue(p config.Profile) string {
+	if p.SigningFormat == "ssh" {
+		// SSH signing keys are file paths; git does not expand ~ for them.
+		if expanded, err := config.ExpandConfigPath(p.SigningKey); err == nil {
+			return expanded
+		}
+	}
+	return p.SigningKey
+}
+
 // RemoveProfileFragment deletes the gitconfig fragment for a profile.
 func RemoveProfileFragment(profileName string) error {
 	dir, err := config.GitConfigDir()
@@ -46,6 +82,7 @@ func RemoveProfileFragment(profileName string) error {
 		return err
 	}
 	path := filepath.Join(dir, profileName+".gitconfig")
+	slog.Debug("removing gitconfig fragment", "file", path)
 	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("removing gitconfig fragment: %w", err)
 	}
@@ -55,85 +92,271 @@ func RemoveProfileFragment(profileName string) error {
 // AddIncludeIf adds an includeIf directive to the global gitconfig.
 // gitconfigPath is the path to ~/.gitconfig (or equivalent).
 // dirPath is the bound directory, fragmentPath is the profile gitconfig fragment.
+// An existing section for dirPath is updated in place, and any duplicate
+// sections for it are merged away.
 func AddIncludeIf(gitconfigPath, dirPath, fragmentPath string) error {
+	return addIncludeIfSection(gitconfigPath, []string{gitdirDirective(dirPath)}, fragmentPath)
+}
+
+// RemoveIncludeIf removes an includeIf directive for the given directory from the global gitconfig.
+func RemoveIncludeIf(gitconfigPath, dirPath string) error {
+	return removeIncludeIfSection(gitconfigPath, []string{gitdirDirective(dirPath)})
+}
+
+// AddIncludeIfRemote adds hasconfig:remote.*.url includeIf directives for a
+// remote binding pattern (e.g. "github.com/acme") to the global gitconfig, so
+// repositories whose remote matches pick up the fragment wherever they live.
+// See RemoteURLGlobs for the URLs covered.
+func AddIncludeIfRemote(gitconfigPath, remotePattern, fragmentPath string) error {
+	return addIncludeIfSection(gitconfigPath, remoteDirectives(remotePattern), fragmentPath)
+}
+
+// RemoveIncludeIfRemote removes the includeIf directives for a remote binding
+// pattern from the global gitconfig.
+func RemoveIncludeIfRemote(gitconfigPath, remotePattern string) error {
+	return removeIncludeIfSection(gitconfigPath, remoteDirectives(remotePattern))
+}
+
+// RemoteURLGlobs returns the git URL globs matching a remote binding pattern,
+// in both HTTPS and scp-style SSH form. A pattern naming a repository
+// (host/owner/repo) matches that repository with or without ".git"; a
+// shorter one (host/owner) matches every repository beneath it.
+func RemoteURLGlobs(remotePattern string) []string {
+	hostPath := resolve.RemoteHostPath(remotePattern)
+	host, rest, ok := strings.Cut(hostPath, "/")
+	if !ok || host == "" || rest == "" {
+		return nil
+	}
+
+	bases := []string{"https://" + host + "/" + rest, "git@" + host + ":" + rest}
+	var globs []string
+	for _, base := range bases {
+		if strings.Count(rest, "/") >= 1 {
+			globs = append(globs, base, base+".git")
+		} else {
+			globs = append(globs, base+"/**")
+		}
+	}
+	return globs
+}
+
+func remoteDirectives(remotePattern string) []string {
+	var directives []string
+	for _, glob := range RemoteURLGlobs(remotePattern) {
+		directives = append(directives, fmt.Sprintf("[includeIf \"hasconfig:remote.*.url:%s\"]", glob))
+	}
+	return directives
+}
+
+// gitdirDirective returns the section header for a gitdir includeIf.
+func gitdirDirective(dirPath string) string {
 	// Ensure dirPath ends with / for gitdir matching.
 	if !strings.HasSuffix(dirPath, "/") {
 		dirPath += "/"
 	}
+	return fmt.Sprintf("[includeIf \"gitdir:%s\"]", dirPath)
+}
 
-	directive := fmt.Sprintf("[includeIf \"gitdir:%s\"]", dirPath)
-	pathLine := fmt.Sprintf("    path = %s", fragmentPath)
-
+// DuplicateIncludeIfs returns the section headers that appear more than once
+// in the given gitconfig. Git only honours the first copy of each.
+func DuplicateIncludeIfs(gitconfigPath string) ([]string, error) {
 	lines, err := readLines(gitconfigPath)
-	if err != nil && !os.IsNotExist(err) {
-		return err
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
 	}
 
-	// Check if the directive already exists.
-	for i, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		// Match with or without the marker suffix.
-		bare := strings.TrimSuffix(trimmed, " "+marker)
-		if bare == directive {
-			// Update the path line if it's the next line.
-			if i+1 < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i+1]), "path = ") {
-				lines[i+1] = pathLine
-				return writeLines(gitconfigPath, lines)
+	counts := make(map[string]int)
+	var order []string
+	for _, line := range lines {
+		header := sectionHeader(line)
+		if !strings.HasPrefix(header, "[includeIf ") {
+			continue
+		}
+		if counts[header] == 0 {
+			order = append(order, header)
+		}
+		counts[header]++
+	}
+
+	var dups []string
+	for _, header := range order {
+		if counts[header] > 1 {
+			dups = append(dups, header)
+		}
+	}
+	return dups, nil
+}
+
+// section is the span of a gitconfig section: the header line, and the
+// lines up to and including its last key. Trailing blank lines and comments
+// are left to whatever follows.
+type section struct {
+	start, end int // end is exclusive
+	path       int // index of the path key, or -1
+}
+
+// sectionHeader returns the trimmed section header on line without the
+// gh-identity marker, or "" if line is not a section header.
+func sectionHeader(line string) string {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "[") {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimSuffix(trimmed, marker))
+}
+
+// findSections returns every section whose header is directive.
+func findSections(lines []string, directive string) []section {
+	return matchSections(lines, func(line string) bool { return sectionHeader(line) == directive })
+}
+
+// findManagedIncludeIfs returns every includeIf section carrying the
+// gh-identity marker.
+func findManagedIncludeIfs(lines []string) []section {
+	return matchSections(lines, func(line string) bool {
+		return strings.Contains(line, marker) && strings.HasPrefix(sectionHeader(line), "[includeIf ")
+	})
+}
+
+// matchSections returns every section whose header line satisfies match.
+func matchSections(lines []string, match func(line string) bool) []section {
+	var result []section
+	for i := 0; i < len(lines); i++ {
+		if !match(lines[i]) {
+			continue
+		}
+		sec := section{start: i, end: i + 1, path: -1}
+		for j := i + 1; j < len(lines); j++ {
+			trimmed := strings.TrimSpace(lines[j])
+			if strings.HasPrefix(trimmed, "[") {
+				break
+			}
+			if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";") {
+				continue
+			}
+			sec.end = j + 1
+			if key, _, ok := strings.Cut(trimmed, "="); ok && strings.TrimSpace(key) == "path" && sec.path == -1 {
+				sec.path = j
 			}
 		}
+		result = append(result, sec)
+		i = sec.end - 1
 	}
+	return result
+}
+
+// removeSections deletes the given sections (in ascending order) from lines,
+// along with a blank line that would be left dangling next to each one.
+func removeSections(lines []string, secs []section) []string {
+	for i := len(secs) - 1; i >= 0; i-- {
+		start, end := secs[i].start, secs[i].end
+		if start > 0 && strings.TrimSpace(lines[start-1]) == "" &&
+			(end == len(lines) || strings.TrimSpace(lines[end]) == "") {
+			start--
+		} else if start == 0 && end < len(lines) && strings.TrimSpace(lines[end]) == "" {
+			end++
+		}
+		lines = append(lines[:start], lines[end:]...)
+	}
+	return lines
+}
+
+func addIncludeIfSection(gitconfigPath string, directives []string, fragmentPath string) error {
+	text, err := readConfigText(gitconfigPath)
+	if err != nil {
+		return err
+	}
+	lines := slices.Clone(text.lines)
+	for _, directive := range directives {
+		slog.Debug("setting includeIf", "file", gitconfigPath, "directive", directive, "path", fragmentPath)
+		lines = setSection(lines, directive, fragmentPath)
+	}
+	return text.update(gitconfigPath, lines)
+}
 
-	// Append new directive.
-	if len(lines) > 0 && lines[len(lines)-1] != "" {
+// setSection points the section for directive at fragmentPath, appending a
+// new managed section if there is none.
+func setSection(lines []string, directive, fragmentPath string) []string {
+	pathLine := fmt.Sprintf("    path = %s", fragmentPath)
+
+	// Update an existing section in place, dropping any duplicates of it.
+	if secs := findSections(lines, directive); len(secs) > 0 {
+		first := secs[0]
+		lines = removeSections(lines, secs[1:])
+		if first.path >= 0 {
+			lines[first.path] = pathLine
+		} else {
+			lines = append(lines[:first.start+1], append([]string{pathLine}, lines[first.start+1:]...)...)
+		}
+		return lines
+	}
+
+	// Append new directive. The separating blank line is always added, even
+	// after an existing blank, so removeSections takes back exactly this one.
+	if len(lines) > 0 {
 		lines = append(lines, "")
 	}
 	lines = append(lines, directive+" "+marker)
-	lines = append(lines, pathLine)
-
-	return writeLines(gitconfigPath, lines)
+	return append(lines, pathLine)
 }
 
-// RemoveIncludeIf removes an includeIf directive for the given directory from the global gitconfig.
-func RemoveIncludeIf(gitconfigPath, dirPath string) error {
-	if !strings.HasSuffix(dirPath, "/") {
-		dirPath += "/"
+func removeIncludeIfSection(gitconfigPath string, directives []string) error {
+	text, err := readConfigText(gitconfigPath)
+	if err != nil {
+		return err
+	}
+
+	lines := slices.Clone(text.lines)
+	for _, directive := range directives {
+		slog.Debug("removing includeIf", "file", gitconfigPath, "directive", directive)
+		lines = removeSections(lines, findSections(lines, directive))
 	}
+	return text.update(gitconfigPath, lines)
+}
 
-	directive := fmt.Sprintf("[includeIf \"gitdir:%s\"]", dirPath)
+// RemoveManagedIncludeIfs removes every includeIf directive gh-identity
+// manages, gitdir and remote alike, from the given gitconfig and returns how
+// many it removed. Sections without the gh-identity marker are left alone.
+func RemoveManagedIncludeIfs(gitconfigPath string) (int, error) {
+	text, err := readConfigText(gitconfigPath)
+	if err != nil {
+		return 0, err
+	}
+	secs := findManagedIncludeIfs(text.lines)
+	if len(secs) == 0 {
+		return 0, nil
+	}
+	slog.Debug("removing managed includeIfs", "file", gitconfigPath, "count", len(secs))
+	return len(secs), text.update(gitconfigPath, removeSections(slices.Clone(text.lines), secs))
+}
 
+// ListManagedRemoteIncludeIfs returns the URL globs of all hasconfig:remote
+// includeIf directives managed by gh-identity.
+func ListManagedRemoteIncludeIfs(gitconfigPath string) ([]string, error) {
 	lines, err := readLines(gitconfigPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil
+			return nil, nil
 		}
-		return err
+		return nil, err
 	}
 
-	var result []string
-	skip := false
+	const prefix = `[includeIf "hasconfig:remote.*.url:`
+	var globs []string
 	for _, line := range lines {
-		if strings.TrimSpace(strings.TrimSuffix(line, " "+marker)) == directive ||
-			strings.TrimSpace(line) == directive {
-			skip = true
+		trimmed := strings.TrimSpace(line)
+		if !strings.Contains(trimmed, marker) {
 			continue
 		}
-		if skip {
-			// Skip the path = line that follows the directive.
-			if strings.HasPrefix(strings.TrimSpace(line), "path = ") {
-				skip = false
-				continue
-			}
-			skip = false
+		header := sectionHeader(trimmed)
+		if strings.HasPrefix(header, prefix) && strings.HasSuffix(header, `"]`) {
+			globs = append(globs, header[len(prefix):len(header)-2])
 		}
-		result = append(result, line)
 	}
-
-	// Remove trailing blank lines.
-	for len(result) > 0 && result[len(result)-1] == "" {
-		result = result[:len(result)-1]
-	}
-
-	return writeLines(gitconfigPath, result)
+	return globs, nil
 }
 
 // ListManagedIncludeIfs returns all includeIf dirPaths managed by gh-identity.
@@ -165,12 +388,202 @@ func ListManagedIncludeIfs(gitconfigPath string) ([]string, error) {
 	return dirs, nil
 }
 
-// GlobalGitconfigPath returns the path to the user's global gitconfig.
+// ListManagedIncludeIfsWithPaths is ListManagedIncludeIfs with the fragment
+// each directive includes. Path is empty for a directive with no path line.
+func ListManagedIncludeIfsWithPaths(gitconfigPath string) ([]IncludeIf, error) {
+	includes, err := ParseIncludeIfs(gitconfigPath)
+	if err != nil {
+		return nil, err
+	}
+	var managed []IncludeIf
+	for _, inc := range includes {
+		if inc.Managed {
+			managed = append(managed, inc)
+		}
+	}
+	return managed, nil
+}
+
+// IncludeIf is a gitdir-conditional include parsed from a gitconfig file.
+type IncludeIf struct {
+	Dir     string // the gitdir: pattern, as written
+	Path    string // the included file, resolved to an absolute path
+	Managed bool   // true if the directive carries the gh-identity marker
+}
+
+// ParseIncludeIfs returns every [includeIf "gitdir:..."] directive in the
+// given gitconfig, both managed and hand-written. Relative include paths are
+// resolved against the gitconfig's directory, as git does.
+func ParseIncludeIfs(gitconfigPath string) ([]IncludeIf, error) {
+	lines, err := readLines(gitconfigPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var result []IncludeIf
+	var current *IncludeIf
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			current = nil
+			dir, ok := parseGitdirHeader(trimmed)
+			if !ok {
+				continue
+			}
+			result = append(result, IncludeIf{Dir: dir, Managed: strings.Contains(trimmed, marker)})
+			current = &result[len(result)-1]
+			continue
+		}
+		if current == nil || current.Path != "" {
+			continue
+		}
+		key, value, ok := strings.Cut(trimmed, "=")
+		if ok && strings.TrimSpace(key) == "path" {
+			current.Path = resolveIncludePath(gitconfigPath, strings.TrimSpace(value))
+		}
+	}
+	return result, nil
+}
+
+// InferBindings fills bindings.Inferred from the global gitconfig's
+// includeIf "gitdir:" directives that include a profile fragment from
+// config.GitConfigDir() but have no directory binding, e.g. ones written by
+// hand or left behind by an older version. The profile is taken from the
+// fragment's file name. Inference is best effort: an unreadable gitconfig
+// infers nothing.
+func InferBindings(bindings *config.BindingsFile) {
+	gcPath, err := GlobalGitconfigPath()
+	if err != nil {
+		return
+	}
+	includes, err := ParseIncludeIfs(gcPath)
+	if err != nil || len(includes) == 0 {
+		return
+	}
+	gitDir, err := config.GitConfigDir()
+	if err != nil {
+		return
+	}
+
+	bound := make(map[string]bool)
+	for _, b := range bindings.Bindings {
+		if b.IsRemote() {
+			continue
+		}
+		if p, err := config.ResolveConfigPath(b.Path); err == nil {
+			bound[config.FoldPath(p)] = true
+		}
+	}
+
+	for _, inc := range includes {
+		if filepath.Dir(inc.Path) != gitDir || !strings.HasSuffix(inc.Path, ".gitconfig") {
+			continue
+		}
+		// "gitdir:<dir>/" covers the whole tree under dir, which is what a
+		// directory binding means.
+		dir := strings.TrimSuffix(strings.TrimSuffix(inc.Dir, "/"), "/.git")
+		if dir == "" {
+			continue
+		}
+		if p, err := config.ResolvePath(dir); err == nil && bound[config.FoldPath(p)] {
+			continue
+		}
+		bindings.Inferred = append(bindings.Inferred, config.Binding{
+			Path:    dir,
+			Profile: strings.TrimSuffix(filepath.Base(inc.Path), ".gitconfig"),
+		})
+	}
+}
+
+// parseGitdirHeader extracts the pattern from an [includeIf "gitdir:<pattern>"]
+// section header. The case-insensitive gitdir/i: form is also accepted.
+func parseGitdirHeader(header string) (string, bool) {
+	header = strings.TrimSpace(strings.TrimSuffix(header, marker))
+	if !strings.HasPrefix(header, "[includeIf ") || !strings.HasSuffix(header, "]") {
+		return "", false
+	}
+	cond := strings.TrimSpace(header[len("[includeIf ") : len(header)-1])
+	cond = strings.Trim(cond, `"`)
+	for _, prefix := range []string{"gitdir:", "gitdir/i:"} {
+		if strings.HasPrefix(cond, prefix) {
+			return strings.TrimPrefix(cond, prefix), true
+		}
+	}
+	return "", false
+}
+
+// resolveIncludePath expands ~ and resolves a relative include path against
+// the directory containing the including gitconfig.
+func resolveIncludePath(gitconfigPath, p string) string {
+	p = strings.Trim(p, `"`)
+	if strings.HasPrefix(p, "~/") {
+		if expanded, err := config.ExpandPath(p); err == nil {
+			return expanded
+		}
+	}
+	if !filepath.IsAbs(p) {
+		return filepath.Join(filepath.Dir(gitconfigPath), p)
+	}
+	return p
+}
+
+// ReadFragmentUser returns the [user] name and email from a gitconfig file.
+func ReadFragmentUser(path string) (name, email string, err error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	inUser := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			inUser = strings.EqualFold(strings.Trim(trimmed, "[] "), "user")
+			continue
+		}
+		if !inUser {
+			continue
+		}
+		key, value, ok := strings.Cut(trimmed, "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "name":
+			name = value
+		case "email":
+			email = value
+		}
+	}
+	return name, email, nil
+}
+
+// GlobalGitconfigPath returns the path to the user's global gitconfig:
+// $GIT_CONFIG_GLOBAL if set, else an existing $XDG_CONFIG_HOME/git/config
+// (~/.config/git/config when XDG_CONFIG_HOME is unset), else ~/.gitconfig.
 func GlobalGitconfigPath() (string, error) {
+	if p := os.Getenv("GIT_CONFIG_GLOBAL"); p != "" {
+		return config.ExpandPath(p)
+	}
+
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("resolving home directory: %w", err)
 	}
+
+	xdg := os.Getenv("XDG_CONFIG_HOME")
+	if xdg == "" {
+		xdg = filepath.Join(home, ".config")
+	}
+	xdgPath := filepath.Join(xdg, "git", "config")
+	if _, err := os.Stat(xdgPath); err == nil {
+		return xdgPath, nil
+	}
+
 	return filepath.Join(home, ".gitconfig"), nil
 }
 
@@ -189,7 +602,51 @@ func readLines(path string) ([]string, error) {
 	return lines, scanner.Err()
 }
 
-func writeLines(path string, lines []string) error {
-	content := strings.Join(lines, "\n") + "\n"
-	return os.WriteFile(path, []byte(content), 0o644)
+// configText is a gitconfig file split into lines for editing. It remembers
+// the file's line ending and whether the last line was terminated, so the
+// lines an edit does not touch are written back byte for byte.
+type configText struct {
+	lines    []string
+	eol      string // "\n" or "\r\n"
+	finalEOL bool   // the last line ends with eol
+}
+
+// readConfigText reads path for editing. A missing file reads as empty.
+func readConfigText(path string) (*configText, error) {
+	text := &configText{eol: "\n", finalEOL: true}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return text, nil
+		}
+		return nil, err
+	}
+	if len(data) == 0 {
+		return text, nil
+	}
+
+	content := string(data)
+	if i := strings.Index(content, "\n"); i > 0 && content[i-1] == '\r' {
+		text.eol = "\r\n"
+	}
+	text.finalEOL = strings.HasSuffix(content, "\n")
+	content = strings.TrimSuffix(content, "\n")
+	for _, line := range strings.Split(content, "\n") {
+		text.lines = append(text.lines, strings.TrimSuffix(line, "\r"))
+	}
+	return text, nil
+}
+
+// update writes lines back to path in the file's original format, or does
+// nothing if they are unchanged. A file without a final newline keeps
+// lacking one, so removing an appended block restores it exactly.
+func (t *configText) update(path string, lines []string) error {
+	if slices.Equal(lines, t.lines) {
+		return nil
+	}
+	content := strings.Join(lines, t.eol)
+	if len(lines) > 0 && t.finalEOL {
+		content += t.eol
+	}
+	return config.WriteFileAtomic(path, []byte(content), 0o644)
 }
@@ -2,6 +2,7 @@ package gitconfig
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -36,6 +37,83 @@ func TestWriteProfileFragmentTo(t *testing.T) {
 	}
 }
 
+func TestWriteProfileFragmentTo_Signing(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("HOME", tmp)
+	path := filepath.Join(tmp, "work.gitconfig")
+
+	p := config.Profile{
+		GitName:       "Test User",
+		GitEmail:      "test@example.com",
+		SigningKey:    "~/.ssh/id_work.pub",
+		SigningFormat: "ssh",
+	}
+
+	if err := WriteProfileFragmentTo(path, p); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := string(data)
+	for _, want := range []string{
+		"signingkey = " + filepath.Join(tmp, ".ssh", "id_work.pub"),
+		"[gpg]\n    format = ssh",
+		"[commit]\n    gpgsign = true",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("fragment missing %q:\n%s", want, content)
+		}
+	}
+}
+
+func TestWriteProfileFragmentTo_NoSigning(t *testing.T) {
+	tmp := t.TempDir()
+	path := filepath.Join(tmp, "personal.gitconfig")
+
+	p := config.Profile{GitName: "Test User", GitEmail: "test@example.com"}
+	if err := WriteProfileFragmentTo(path, p); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "gpgsign") || strings.Contains(string(data), "signingkey") {
+		t.Errorf("unsigned profile should not emit signing config:\n%s", data)
+	}
+}
+
+func TestWriteProfileFragmentTo_CredentialHelper(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "work.gitconfig")
+
+	p := config.Profile{GHUser: "octo", Host: "ghe.corp.example", GitName: "Test User", GitEmail: "test@example.com", CredentialHelper: config.CredentialGH}
+	if err := WriteProfileFragmentTo(path, p); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	want := `[user]
+    name = Test User
+    email = test@example.com
+[credential "https://ghe.corp.example"]
+    helper =
+    helper = !gh auth git-credential
+    username = octo
+`
+	if string(data) != want {
+		t.Errorf("fragment = %q, want %q", data, want)
+	}
+
+	p.CredentialHelper = ""
+	if err := WriteProfileFragmentTo(path, p); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "credential") {
+		t.Errorf("profile without credential_helper should not emit credential config:\n%s", data)
+	}
+}
+
 func TestAddIncludeIf(t *testing.T) {
 	tmp := t.TempDir()
 	gcPath := filepath.Join(tmp, ".gitconfig")
@@ -122,9 +200,447 @@ func TestListManagedIncludeIfs(t *testing.T) {
 	}
 }
 
+func TestListManagedIncludeIfsWithPaths(t *testing.T) {
+	tmp := t.TempDir()
+	gcPath := filepath.Join(tmp, ".gitconfig")
+	if err := os.WriteFile(gcPath, []byte("[includeIf \"gitdir:/code/manual/\"]\n    path = manual.gitconfig\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_ = AddIncludeIf(gcPath, "/code/work", "/cfg/work.gitconfig")
+
+	got, err := ListManagedIncludeIfsWithPaths(gcPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected only the managed directive, got %+v", got)
+	}
+	if got[0].Dir != "/code/work/" || got[0].Path != "/cfg/work.gitconfig" {
+		t.Errorf("got %+v, want /code/work/ → /cfg/work.gitconfig", got[0])
+	}
+}
+
+func TestIncludeIfRoundTrip_PreservesFormatting(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"comments and custom sections", "# Global settings, tuned by hand.\n[user]\n\tname = Test User   ; inline comment\n\n\n[alias]\n  co = checkout\n# trailing comment\n"},
+		{"trailing blank lines", "[core]\n\teditor = vim\n\n\n"},
+		{"no final newline", "[core]\n\teditor = vim"},
+		{"crlf", "[core]\r\n\teditor = vim\r\n; note\r\n"},
+		{"empty", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gcPath := filepath.Join(t.TempDir(), ".gitconfig")
+			if err := os.WriteFile(gcPath, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := AddIncludeIf(gcPath, "/code/work", "/cfg/work.gitconfig"); err != nil {
+				t.Fatal(err)
+			}
+			added, _ := os.ReadFile(gcPath)
+			if !strings.HasPrefix(string(added), strings.TrimSuffix(tt.content, "\n")) {
+				t.Errorf("add changed existing content:\n%q", added)
+			}
+			if !strings.Contains(string(added), `[includeIf "gitdir:/code/work/"]`) {
+				t.Fatalf("includeIf not added:\n%q", added)
+			}
+
+			if err := RemoveIncludeIf(gcPath, "/code/work"); err != nil {
+				t.Fatal(err)
+			}
+			got, _ := os.ReadFile(gcPath)
+			if string(got) != tt.content {
+				t.Errorf("after add+remove:\n got %q\nwant %q", got, tt.content)
+			}
+		})
+	}
+}
+
+func TestRemoveIncludeIf_Absent(t *testing.T) {
+	gcPath := filepath.Join(t.TempDir(), ".gitconfig")
+	content := "[user]\n  name = Test\n\n\n"
+	if err := os.WriteFile(gcPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveIncludeIf(gcPath, "/code/none"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(gcPath); string(got) != content {
+		t.Errorf("removing an absent directive rewrote the file: %q", got)
+	}
+}
+
 func TestRemoveIncludeIf_NonExistent(t *testing.T) {
 	// Removing from nonexistent file should not error.
 	if err := RemoveIncludeIf("/nonexistent/.gitconfig", "/some/path"); err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
 }
+
+func TestParseIncludeIfs(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("HOME", tmp)
+	gcPath := filepath.Join(tmp, ".gitconfig")
+
+	content := `[user]
+    name = Default
+[includeIf "gitdir:~/code/work/"]
+    path = ~/.gitconfig-work
+[includeIf "gitdir/i:/src/oss/"]
+    path = oss.gitconfig
+[includeIf "onbranch:main"]
+    path = main.gitconfig
+[includeIf "gitdir:/managed/"] # managed by gh-identity
+    path = /cfg/git/managed.gitconfig
+`
+	if err := os.WriteFile(gcPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ParseIncludeIfs(gcPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []IncludeIf{
+		{Dir: "~/code/work/", Path: filepath.Join(tmp, ".gitconfig-work")},
+		{Dir: "/src/oss/", Path: filepath.Join(tmp, "oss.gitconfig")},
+		{Dir: "/managed/", Path: "/cfg/git/managed.gitconfig", Managed: true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ParseIncludeIfs() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ParseIncludeIfs()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestInferBindings(t *testing.T) {
+	home := t.TempDir()
+	configDir := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("GH_IDENTITY_CONFIG_DIR", configDir)
+	gcPath := filepath.Join(home, ".gitconfig")
+	t.Setenv("GIT_CONFIG_GLOBAL", gcPath)
+
+	content := `[includeIf "gitdir:/code/work/"] # managed by gh-identity
+    path = ` + filepath.Join(configDir, "git", "work.gitconfig") + `
+[includeIf "gitdir:/code/oss/"]
+    path = ` + filepath.Join(configDir, "git", "oss.gitconfig") + `
+[includeIf "gitdir:/code/bound/"] # managed by gh-identity
+    path = ` + filepath.Join(configDir, "git", "bound.gitconfig") + `
+[includeIf "gitdir:/code/other/"]
+    path = ~/.gitconfig-other
+`
+	if err := os.WriteFile(gcPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	bf := &config.BindingsFile{Bindings: []config.Binding{{Path: "/code/bound", Profile: "bound"}}}
+	InferBindings(bf)
+
+	want := []config.Binding{
+		{Path: "/code/work", Profile: "work"},
+		{Path: "/code/oss", Profile: "oss"},
+	}
+	if len(bf.Inferred) != len(want) {
+		t.Fatalf("Inferred = %+v, want %+v", bf.Inferred, want)
+	}
+	for i := range want {
+		if bf.Inferred[i] != want[i] {
+			t.Errorf("Inferred[%d] = %+v, want %+v", i, bf.Inferred[i], want[i])
+		}
+	}
+}
+
+func TestParseIncludeIfs_NotExist(t *testing.T) {
+	got, err := ParseIncludeIfs(filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != nil {
+		t.Errorf("expected nil, got %+v", got)
+	}
+}
+
+func TestReadFragmentUser(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "work.gitconfig")
+	content := `[core]
+    name = not-this
+[user]
+    name = "Work Person"
+    email = work@company.com
+[commit]
+    gpgsign = true
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	name, email, err := ReadFragmentUser(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "Work Person" || email != "work@company.com" {
+		t.Errorf("ReadFragmentUser() = %q, %q", name, email)
+	}
+}
+
+func TestAddIncludeIf_PathNotNextLine(t *testing.T) {
+	gcPath := filepath.Join(t.TempDir(), ".gitconfig")
+	os.WriteFile(gcPath, []byte(`[user]
+    name = Test
+[includeIf "gitdir:/code/work/"] # managed by gh-identity
+    # work identity
+    path = /cfg/old.gitconfig
+[core]
+    editor = vim
+`), 0o644)
+
+	if err := AddIncludeIf(gcPath, "/code/work", "/cfg/work.gitconfig"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(gcPath)
+	content := string(data)
+	if n := strings.Count(content, `[includeIf "gitdir:/code/work/"]`); n != 1 {
+		t.Errorf("expected 1 includeIf directive, got %d:\n%s", n, content)
+	}
+	if !strings.Contains(content, "    # work identity\n    path = /cfg/work.gitconfig\n[core]") {
+		t.Errorf("path not updated in place:\n%s", content)
+	}
+	if strings.Contains(content, "old.gitconfig") {
+		t.Errorf("old path still present:\n%s", content)
+	}
+}
+
+func TestAddIncludeIf_MergesDuplicates(t *testing.T) {
+	gcPath := filepath.Join(t.TempDir(), ".gitconfig")
+	os.WriteFile(gcPath, []byte(`[includeIf "gitdir:/code/work/"]
+    path = /cfg/a.gitconfig
+
+[includeIf "gitdir:/code/work/"] # managed by gh-identity
+    path = /cfg/b.gitconfig
+`), 0o644)
+
+	dups, err := DuplicateIncludeIfs(gcPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dups) != 1 || dups[0] != `[includeIf "gitdir:/code/work/"]` {
+		t.Errorf("DuplicateIncludeIfs() = %v", dups)
+	}
+
+	if err := AddIncludeIf(gcPath, "/code/work", "/cfg/work.gitconfig"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(gcPath)
+	if want := "[includeIf \"gitdir:/code/work/\"]\n    path = /cfg/work.gitconfig\n"; string(data) != want {
+		t.Errorf("gitconfig = %q, want %q", data, want)
+	}
+	if dups, _ := DuplicateIncludeIfs(gcPath); len(dups) != 0 {
+		t.Errorf("expected duplicates to be merged, got %v", dups)
+	}
+}
+
+func TestRemoveIncludeIf_KeepsFollowingComment(t *testing.T) {
+	gcPath := filepath.Join(t.TempDir(), ".gitconfig")
+	os.WriteFile(gcPath, []byte(`[includeIf "gitdir:/code/work/"] # managed by gh-identity
+    ; set by gh-identity
+    path = /cfg/work.gitconfig
+
+# editor settings
+[core]
+    editor = vim
+`), 0o644)
+
+	if err := RemoveIncludeIf(gcPath, "/code/work"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(gcPath)
+	if want := "# editor settings\n[core]\n    editor = vim\n"; string(data) != want {
+		t.Errorf("gitconfig = %q, want %q", data, want)
+	}
+}
+
+func TestRemoveManagedIncludeIfs(t *testing.T) {
+	gcPath := filepath.Join(t.TempDir(), ".gitconfig")
+	os.WriteFile(gcPath, []byte(`[user]
+    name = Me
+
+[includeIf "gitdir:/code/work/"] # managed by gh-identity
+    path = /cfg/work.gitconfig
+
+[includeIf "gitdir:/code/mine/"]
+    path = /cfg/mine.gitconfig
+
+[includeIf "gitdir:/code/work/"] # managed by gh-identity
+    path = /cfg/old.gitconfig
+
+[includeIf "hasconfig:remote.*.url:https://github.com/acme/**"] # managed by gh-identity
+    path = /cfg/work.gitconfig
+`), 0o644)
+
+	n, err := RemoveManagedIncludeIfs(gcPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("removed %d directives, want 3", n)
+	}
+	data, _ := os.ReadFile(gcPath)
+	if want := "[user]\n    name = Me\n\n[includeIf \"gitdir:/code/mine/\"]\n    path = /cfg/mine.gitconfig\n"; string(data) != want {
+		t.Errorf("gitconfig = %q, want %q", data, want)
+	}
+
+	if n, err := RemoveManagedIncludeIfs(filepath.Join(t.TempDir(), "missing")); err != nil || n != 0 {
+		t.Errorf("RemoveManagedIncludeIfs(missing) = %d, %v; want 0, nil", n, err)
+	}
+}
+
+func TestRemoteURLGlobs(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"github.com/Acme", []string{"https://github.com/Acme/**", "git@github.com:Acme/**"}},
+		{"git@github.com:acme/repo.git", []string{
+			"https://github.com/acme/repo", "https://github.com/acme/repo.git",
+			"git@github.com:acme/repo", "git@github.com:acme/repo.git",
+		}},
+		{"github.com", nil},
+	}
+	for _, tt := range tests {
+		got := RemoteURLGlobs(tt.pattern)
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("RemoteURLGlobs(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestAddIncludeIfRemote(t *testing.T) {
+	tmp := t.TempDir()
+	gcPath := filepath.Join(tmp, ".gitconfig")
+
+	_ = AddIncludeIf(gcPath, "/code/work", "/cfg/work.gitconfig")
+	for i := 0; i < 2; i++ {
+		if err := AddIncludeIfRemote(gcPath, "github.com/acme", "/cfg/work.gitconfig"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, _ := os.ReadFile(gcPath)
+	content := string(data)
+	if n := strings.Count(content, `[includeIf "hasconfig:remote.*.url:https://github.com/acme/**"] `+marker); n != 1 {
+		t.Errorf("expected 1 https directive, got %d:\n%s", n, content)
+	}
+	if !strings.Contains(content, `[includeIf "hasconfig:remote.*.url:git@github.com:acme/**"]`) {
+		t.Errorf("ssh directive not added:\n%s", content)
+	}
+
+	globs, err := ListManagedRemoteIncludeIfs(gcPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(globs) != 2 || globs[0] != "https://github.com/acme/**" {
+		t.Errorf("unexpected managed remote globs: %v", globs)
+	}
+	if dirs, _ := ListManagedIncludeIfs(gcPath); len(dirs) != 1 {
+		t.Errorf("expected 1 managed gitdir directive, got %v", dirs)
+	}
+
+	if err := RemoveIncludeIfRemote(gcPath, "github.com/acme"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(gcPath)
+	if strings.Contains(string(data), "hasconfig") {
+		t.Errorf("remote includeIf not removed:\n%s", data)
+	}
+	if !strings.Contains(string(data), "gitdir:/code/work/") {
+		t.Error("gitdir includeIf should be kept")
+	}
+}
+
+// initRepo creates a git repository in a temporary directory, skipping the
+// test when git is unavailable.
+func initRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	return dir
+}
+
+// localConfig returns the value of key in the repository's .git/config.
+func localConfig(t *testing.T, dir, key string) string {
+	t.Helper()
+	out, _ := exec.Command("git", "-C", dir, "config", "--local", "--get", key).Output()
+	return strings.TrimSpace(string(out))
+}
+
+func TestLocalIdentity(t *testing.T) {
+	dir := initRepo(t)
+
+	sub := filepath.Join(dir, "pkg")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	root, err := RepoRoot(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := filepath.EvalSymlinks(dir); root != want {
+		t.Errorf("RepoRoot() = %q, want %q", root, want)
+	}
+	if _, err := RepoRoot(t.TempDir()); err == nil {
+		t.Error("RepoRoot() succeeded outside a repository")
+	}
+
+	signing := config.Profile{GitName: "Work", GitEmail: "work@company.com", SigningKey: "ABC123", SigningFormat: "openpgp"}
+	if err := SetLocalIdentity(dir, signing); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{
+		"user.name":       "Work",
+		"user.email":      "work@company.com",
+		"user.signingkey": "ABC123",
+		"gpg.format":      "openpgp",
+		"commit.gpgsign":  "true",
+	} {
+		if got := localConfig(t, dir, key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+
+	// Switching to a profile without signing clears the signing settings.
+	if err := SetLocalIdentity(dir, config.Profile{GitName: "Me", GitEmail: "me@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := localConfig(t, dir, "user.email"); got != "me@example.com" {
+		t.Errorf("user.email = %q, want me@example.com", got)
+	}
+	if got := localConfig(t, dir, "commit.gpgsign"); got != "" {
+		t.Errorf("commit.gpgsign = %q, want it unset", got)
+	}
+
+	if err := UnsetLocalIdentity(dir); err != nil {
+		t.Fatal(err)
+	}
+	if got := localConfig(t, dir, "user.name"); got != "" {
+		t.Errorf("user.name = %q after unset", got)
+	}
+	// Unsetting again is not an error.
+	if err := UnsetLocalIdentity(dir); err != nil {
+		t.Errorf("second UnsetLocalIdentity() = %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package gitconfig
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+
+	"github.com/dotbrains/gh-identity/internal/config"
+)
+
+// localKeys are the settings SetLocalIdentity writes to a repository's
+// .git/config, mirroring what a profile fragment sets. UnsetLocalIdentity
+// removes all of them.
+var localKeys = []string{"user.name", "user.email", "user.signingkey", "gpg.format", "commit.gpgsign"}
+
+// RepoRoot returns the top-level directory of the git work tree containing
+// dir.
+func RepoRoot(dir string) (string, error) {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "", fmt.Errorf("%s is not in a git repository", dir)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// localIdentity returns the key/value pairs SetLocalIdentity writes for p.
+// Signing settings are included only when the profile has a signing key.
+func localIdentity(p config.Profile) [][2]string {
+	settings := [][2]string{{"user.name", p.GitName}, {"user.email", p.GitEmail}}
+	if p.SigningKey != "" {
+		settings = append(settings, [2]string{"user.signingkey", signingKeyValue(p)})
+		if p.SigningFormat != "" {
+			settings = append(settings, [2]string{"gpg.format", p.SigningFormat})
+		}
+		settings = append(settings, [2]string{"commit.gpgsign", "true"})
+	}
+	return settings
+}
+
+// SetLocalIdentity writes the profile's identity into the .git/config of
+// the repository at repoDir, replacing any identity settings already there.
+func SetLocalIdentity(repoDir string, p config.Profile) error {
+	if err := UnsetLocalIdentity(repoDir); err != nil {
+		return err
+	}
+	for _, kv := range localIdentity(p) {
+		if err := gitLocal(repoDir, kv[0], kv[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnsetLocalIdentity removes the identity settings SetLocalIdentity writes
+// from the .git/config of the repository at repoDir. Settings that are not
+// there are skipped.
+func UnsetLocalIdentity(repoDir string) error {
+	for _, key := range localKeys {
+		slog.Debug("unsetting local git config", "repo", repoDir, "key", key)
+		out, err := exec.Command("git", "-C", repoDir, "config", "--local", "--unset-all", key).CombinedOutput()
+		// Exit status 5 means the key was not set.
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 5 {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("git config --local --unset-all %s: %s: %w", key, strings.TrimSpace(string(out)), err)
+		}
+	}
+	return nil
+}
+
+// gitLocal sets key to value in the repository's .git/config.
+func gitLocal(repoDir, key, value string) error {
+	slog.Debug("setting local git config", "repo", repoDir, "key", key)
+	if out, err := exec.Command("git", "-C", repoDir, "config", "--local", key, value).CombinedOutput(); err != nil {
+		return fmt.Errorf("git config --local %s: %s: %w", key, strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package hook
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/dotbrains/gh-identity/internal/config"
+	"github.com/dotbrains/gh-identity/internal/gitconfig"
+	"github.com/dotbrains/gh-identity/internal/resolve"
+)
+
+const (
+	// cacheTTL bounds how long a cached resolution is trusted. Edits to the
+	// config files, the global gitconfig, and a repository's .gh-identity
+	// file or .git/config are caught by mtime; the TTL covers what is not,
+	// such as the origin of a worktree or a git_name_command's output.
+	cacheTTL = 5 * time.Minute
+
+	// cacheMaxEntries caps the cache size; the cache is reset when exceeded.
+	cacheMaxEntries = 256
+)
+
+// cacheFile is the on-disk hook cache. It is discarded wholesale when
+// profiles.yml, bindings.yml, or the global gitconfig (whose includeIfs are
+// inferred as bindings) change, or when it was written for a different
+// config directory.
+//
+// Entries are keyed by directory rather than by profile: resolving the
+// profile, including asking git for the origin remote, is most of the
+// hook's work, and a per-directory entry skips it.
+type cacheFile struct {
+	ConfigDir      string                `json:"config_dir"`
+	ProfilesMTime  int64                 `json:"profiles_mtime"`
+	BindingsMTime  int64                 `json:"bindings_mtime"`
+	GitconfigMTime int64                 `json:"gitconfig_mtime"`
+	Entries        map[string]cacheEntry `json:"entries"`
+}
+
+// cacheEntry is the resolved shell output for one directory and shell.
+// RepoMTimes are the mtimes of the directory's resolve.RepoFiles when the
+// entry was made; the entry is stale once they differ.
+type cacheEntry struct {
+	Output     string    `json:"output"`
+	Created    time.Time `json:"created"`
+	RepoMTimes []int64   `json:"repo_mtimes,omitempty"`
+}
+
+// now is a variable so tests can control cache expiry.
+var now = time.Now
+
+// ResolveCached is like Resolve but reuses a recent result for the same
+// directory and shell when the config files have not changed.
+func ResolveCached(dir string, shell ShellType) (string, error) {
+	cachePath, state, err := cacheState()
+	if err != nil {
+		return Resolve(dir, shell)
+	}
+
+	key := string(shell) + "\x00" + dir
+	cache := loadCache(cachePath)
+	if cache.ConfigDir != state.ConfigDir || cache.ProfilesMTime != state.ProfilesMTime ||
+		cache.BindingsMTime != state.BindingsMTime || cache.GitconfigMTime != state.GitconfigMTime {
+		cache = state
+	}
+	repoMTimes := mtimes(resolve.RepoFiles(dir))
+	if e, ok := cache.Entries[key]; ok && now().Sub(e.Created) < cacheTTL && slices.Equal(e.RepoMTimes, repoMTimes) {
+		return e.Output, nil
+	}
+
+	output, err := Resolve(dir, shell)
+	if err != nil {
+		return "", err
+	}
+
+	if cache.Entries == nil || len(cache.Entries) >= cacheMaxEntries {
+		cache.Entries = make(map[string]cacheEntry)
+	}
+	cache.Entries[key] = cacheEntry{Output: output, Created: now(), RepoMTimes: repoMTimes}
+	// A failed cache write only costs speed on the next call.
+	_ = saveCache(cachePath, cache)
+
+	return output, nil
+}
+
+// cacheState returns the cache file path and an empty cache stamped with the
+// current config directory and the mtimes of the config files and global
+// gitconfig.
+func cacheState() (string, cacheFile, error) {
+	cacheDir, err := config.CacheDir()
+	if err != nil {
+		return "", cacheFile{}, err
+	}
+	configDir, err := config.Dir()
+	if err != nil {
+		return "", cacheFile{}, err
+	}
+	profilesPath, err := config.ProfilesPath()
+	if err != nil {
+		return "", cacheFile{}, err
+	}
+	bindingsPath, err := config.BindingsPath()
+	if err != nil {
+		return "", cacheFile{}, err
+	}
+	gcPath, err := gitconfig.GlobalGitconfigPath()
+	if err != nil {
+		return "", cacheFile{}, err
+	}
+	state := cacheFile{
+		ConfigDir:      configDir,
+		ProfilesMTime:  mtime(profilesPath),
+		BindingsMTime:  mtime(bindingsPath),
+		GitconfigMTime: mtime(gcPath),
+	}
+	return filepath.Join(cacheDir, "hook.json"), state, nil
+}
+
+// mtime returns the file's modification time in nanoseconds, or 0 if missing.
+func mtime(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.ModTime().UnixNano()
+}
+
+// mtimes returns the mtime of each of paths, as mtime does.
+func mtimes(paths []string) []int64 {
+	if len(paths) == 0 {
+		return nil
+	}
+	out := make([]int64, len(paths))
+	for i, p := range paths {
+		out[i] = mtime(p)
+	}
+	return out
+}
+
+func loadCache(path string) cacheFile {
+	var c cacheFile
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return cacheFile{}
+	}
+	return c
+}
+
+func saveCache(path string, c cacheFile) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return config.WriteFileAtomic(path, data, 0o644)
+}
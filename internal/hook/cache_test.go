@@ -0,0 +1,219 @@
+package hook
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dotbrains/gh-identity/internal/config"
+)
+
+// tamperCache rewrites every cached entry's output so a cache hit is observable.
+func tamperCache(t *testing.T, output string) {
+	t.Helper()
+	cacheDir, err := config.CacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(cacheDir, "hook.json")
+	c := loadCache(path)
+	if len(c.Entries) == 0 {
+		t.Fatal("expected cache entries to exist")
+	}
+	for k, e := range c.Entries {
+		e.Output = output
+		c.Entries[k] = e
+	}
+	if err := saveCache(path, c); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveCached(t *testing.T) {
+	boundDir := t.TempDir()
+	// A current version keeps the first load from migrating profiles.yml,
+	// which would change its mtime and miss the cache once.
+	setupTestConfig(t,
+		`version: 1
+profiles:
+  personal:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com`,
+		`bindings:
+  - path: `+boundDir+`
+    profile: personal`,
+	)
+
+	first, err := ResolveCached(boundDir, Bash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := Resolve(boundDir, Bash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != want {
+		t.Errorf("ResolveCached() = %q, want %q", first, want)
+	}
+
+	// A fresh entry is served from the cache.
+	tamperCache(t, "cached\n")
+	got, err := ResolveCached(boundDir, Bash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "cached\n" {
+		t.Errorf("expected cache hit, got %q", got)
+	}
+
+	// A different shell is a different key.
+	got, err = ResolveCached(boundDir, Fish)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == "cached\n" {
+		t.Error("fish should not reuse the bash cache entry")
+	}
+}
+
+func TestResolveCached_Expires(t *testing.T) {
+	boundDir := t.TempDir()
+	setupTestConfig(t,
+		`profiles:
+  personal:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com`,
+		`bindings:
+  - path: `+boundDir+`
+    profile: personal`,
+	)
+
+	if _, err := ResolveCached(boundDir, Bash); err != nil {
+		t.Fatal(err)
+	}
+	tamperCache(t, "cached\n")
+
+	oldNow := now
+	now = func() time.Time { return time.Now().Add(cacheTTL + time.Second) }
+	t.Cleanup(func() { now = oldNow })
+
+	got, err := ResolveCached(boundDir, Bash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == "cached\n" {
+		t.Error("expired entry should not be served")
+	}
+}
+
+func TestResolveCached_InvalidatesOnConfigChange(t *testing.T) {
+	boundDir := t.TempDir()
+	configDir := setupTestConfig(t,
+		`profiles:
+  personal:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com`,
+		`bindings:
+  - path: `+boundDir+`
+    profile: personal`,
+	)
+
+	if _, err := ResolveCached(boundDir, Bash); err != nil {
+		t.Fatal(err)
+	}
+	tamperCache(t, "cached\n")
+
+	// Bump the bindings mtime.
+	bindingsPath := filepath.Join(configDir, "bindings.yml")
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(bindingsPath, future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ResolveCached(boundDir, Bash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == "cached\n" {
+		t.Error("cache should be invalidated when bindings.yml changes")
+	}
+}
+
+func TestResolveCached_InvalidatesOnRepoFileChange(t *testing.T) {
+	repo := t.TempDir()
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	setupTestConfig(t,
+		`profiles:
+  personal:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com
+  work:
+    gh_user: user2
+    git_name: User Two
+    git_email: user2@company.com`,
+		`bindings:
+  - path: `+repo+`
+    profile: personal`,
+	)
+
+	if _, err := ResolveCached(repo, Bash); err != nil {
+		t.Fatal(err)
+	}
+	tamperCache(t, "cached\n")
+
+	if err := os.WriteFile(filepath.Join(repo, ".gh-identity"), []byte("work\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ResolveCached(repo, Bash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == "cached\n" {
+		t.Error("cache should be invalidated when the repository's .gh-identity file changes")
+	}
+}
+
+func TestResolveCached_InvalidatesOnGitconfigChange(t *testing.T) {
+	boundDir := t.TempDir()
+	gcPath := filepath.Join(t.TempDir(), "gitconfig")
+	if err := os.WriteFile(gcPath, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", gcPath)
+	setupTestConfig(t,
+		`profiles:
+  personal:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com`,
+		`bindings:
+  - path: `+boundDir+`
+    profile: personal`,
+	)
+
+	if _, err := ResolveCached(boundDir, Bash); err != nil {
+		t.Fatal(err)
+	}
+	tamperCache(t, "cached\n")
+
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(gcPath, future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ResolveCached(boundDir, Bash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == "cached\n" {
+		t.Error("cache should be invalidated when the global gitconfig changes")
+	}
+}
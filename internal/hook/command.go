@@ -0,0 +1,40 @@
+package hook
+
+import (
+	"context"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// fieldCommandTimeout bounds a git_name_command or git_email_command run so
+// a locked password manager cannot hang the prompt. Tests shorten it.
+var fieldCommandTimeout = time.Second
+
+// commandValue runs command with sh and returns its trimmed stdout. It
+// returns fallback when command is empty, fails, times out, or prints
+// nothing.
+func commandValue(command, fallback string) string {
+	if command == "" {
+		return fallback
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fieldCommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	// Don't wait on children that inherited stdout after sh is killed.
+	cmd.WaitDelay = 100 * time.Millisecond
+	out, err := cmd.Output()
+	if err != nil {
+		slog.Debug("profile field command failed; using static value", "command", command, "err", err)
+		return fallback
+	}
+	value := strings.TrimSpace(string(out))
+	if value == "" {
+		slog.Debug("profile field command printed nothing; using static value", "command", command)
+		return fallback
+	}
+	return value
+}
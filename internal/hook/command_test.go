@@ -0,0 +1,65 @@
+package hook
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommandValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    string
+	}{
+		{"empty command", "", "static"},
+		{"output trimmed", "printf '  secret@example.com\\n'", "secret@example.com"},
+		{"failure falls back", "echo ignored; exit 1", "static"},
+		{"no output falls back", "true", "static"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := commandValue(tt.command, "static"); got != tt.want {
+				t.Errorf("commandValue(%q) = %q, want %q", tt.command, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandValue_Timeout(t *testing.T) {
+	old := fieldCommandTimeout
+	fieldCommandTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { fieldCommandTimeout = old })
+
+	start := time.Now()
+	if got := commandValue("sleep 5; echo late", "static"); got != "static" {
+		t.Errorf("commandValue = %q, want fallback", got)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("commandValue took %v, want it cut off by the timeout", elapsed)
+	}
+}
+
+func TestResolve_FieldCommands(t *testing.T) {
+	dir := t.TempDir()
+	setupTestConfig(t,
+		`profiles:
+  work:
+    gh_user: worker
+    git_name: Static Name
+    git_email: static@example.com
+    git_name_command: echo Vault Name
+    git_email_command: exit 1
+default: work`, "")
+
+	output, err := Resolve(dir, Bash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, `export GIT_AUTHOR_NAME="Vault Name"`) {
+		t.Errorf("expected name from command, got:\n%s", output)
+	}
+	if !strings.Contains(output, `export GIT_COMMITTER_EMAIL="static@example.com"`) {
+		t.Errorf("expected static email fallback, got:\n%s", output)
+	}
+}
@@ -3,10 +3,13 @@
 package hook
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/dotbrains/gh-identity/internal/config"
+	"github.com/dotbrains/gh-identity/internal/gitconfig"
 	"github.com/dotbrains/gh-identity/internal/resolve"
 )
 
@@ -17,6 +20,9 @@ const (
 	Fish ShellType = "fish"
 	Bash ShellType = "bash"
 	Zsh  ShellType = "zsh"
+	Nu   ShellType = "nu"
+
+	PowerShell ShellType = "powershell"
 )
 
 // EnvOutput holds the environment variables to export.
@@ -28,6 +34,21 @@ type EnvOutput struct {
 	GitCommitterEmail string
 	GHIdentityProfile string
 	GHSSHCommand      string // optional
+	GitAskPass        string // optional; set when the askpass helper is installed
+}
+
+// managedVars are the variables the hook and `switch` manage. They are all
+// cleared when no profile resolves, so leaving a bound tree restores the
+// base identity instead of keeping the last profile's.
+var managedVars = []string{
+	"GH_TOKEN",
+	"GIT_AUTHOR_NAME",
+	"GIT_AUTHOR_EMAIL",
+	"GIT_COMMITTER_NAME",
+	"GIT_COMMITTER_EMAIL",
+	"GH_IDENTITY_PROFILE",
+	"GIT_SSH_COMMAND",
+	"GIT_ASKPASS",
 }
 
 // Resolve loads config, resolves the binding for dir, and returns shell statements.
@@ -41,15 +62,17 @@ func Resolve(dir string, shell ShellType) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("loading bindings: %w", err)
 	}
+	gitconfig.InferBindings(bindings)
 
-	result, err := resolve.ForDirectory(dir, bindings, profiles.Default)
+	// No override: the hook sets GH_IDENTITY_PROFILE rather than obeying it.
+	result, err := resolve.Active(dir, bindings, profiles, "")
 	if err != nil {
 		return "", fmt.Errorf("resolving binding: %w", err)
 	}
 
 	if result.Profile == "" {
-		// No profile resolved; emit nothing.
-		return "", nil
+		// No profile resolved; clear anything a previous profile exported.
+		return FormatClear(shell), nil
 	}
 
 	profile, err := profiles.GetProfile(result.Profile)
@@ -57,29 +80,62 @@ func Resolve(dir string, shell ShellType) (string, error) {
 		return "", fmt.Errorf("getting profile %q: %w", result.Profile, err)
 	}
 
+	return Format(shell, ProfileEnv(result.Profile, profile)), nil
+}
+
+// ProfileEnv returns the variables that activate profile p, named name.
+// Both the hook and `switch` export these.
+func ProfileEnv(name string, p config.Profile) EnvOutput {
+	gitName := commandValue(p.GitNameCommand, p.GitName)
+	gitEmail := commandValue(p.GitEmailCommand, p.GitEmail)
+	committerName, committerEmail := p.CommitterFor(gitName, gitEmail)
 	env := EnvOutput{
-		GHUser:            profile.GHUser,
-		GitAuthorName:     profile.GitName,
-		GitAuthorEmail:    profile.GitEmail,
-		GitCommitterName:  profile.GitName,
-		GitCommitterEmail: profile.GitEmail,
-		GHIdentityProfile: result.Profile,
-	}
-
-	if profile.SSHKey != "" {
-		expanded, err := config.ExpandPath(profile.SSHKey)
-		if err == nil {
-			env.GHSSHCommand = fmt.Sprintf("ssh -i %s -o IdentitiesOnly=yes", expanded)
-		}
+		GHUser:            p.GHUser,
+		GitAuthorName:     gitName,
+		GitAuthorEmail:    gitEmail,
+		GitCommitterName:  committerName,
+		GitCommitterEmail: committerEmail,
+		GHIdentityProfile: name,
+		GHSSHCommand:      SSHCommand(p),
 	}
 
-	return formatOutput(shell, env), nil
+	// With gh's credential helper, the profile's gitconfig fragment
+	// handles HTTPS authentication.
+	if p.CredentialHelper == config.CredentialGH {
+		return env
+	}
+	if askPass, err := config.AskPassPath(); err == nil {
+		if _, err := os.Stat(askPass); err == nil {
+			env.GitAskPass = askPass
+		}
+	}
+	return env
 }
 
-func formatOutput(shell ShellType, env EnvOutput) string {
+// Format renders env as statements for shell.
+func Format(shell ShellType, env EnvOutput) string {
 	var b strings.Builder
 
 	switch shell {
+	case Nu:
+		return formatNu(env)
+	case PowerShell:
+		// Unset GH_TOKEN so it doesn't override gh auth's keyring token.
+		b.WriteString("Remove-Item Env:GH_TOKEN -ErrorAction SilentlyContinue\n")
+		// Switch gh CLI to the correct account.
+		fmt.Fprintf(&b, "gh auth switch --user %s 2>$null\n", env.GHUser)
+		writePowerShellExport(&b, "GIT_AUTHOR_NAME", env.GitAuthorName)
+		writePowerShellExport(&b, "GIT_AUTHOR_EMAIL", env.GitAuthorEmail)
+		writePowerShellExport(&b, "GIT_COMMITTER_NAME", env.GitCommitterName)
+		writePowerShellExport(&b, "GIT_COMMITTER_EMAIL", env.GitCommitterEmail)
+		writePowerShellExport(&b, "GH_IDENTITY_PROFILE", env.GHIdentityProfile)
+		if env.GHSSHCommand != "" {
+			writePowerShellExport(&b, "GIT_SSH_COMMAND", env.GHSSHCommand)
+		}
+		if env.GitAskPass != "" {
+			writePowerShellExport(&b, "GIT_ASKPASS", env.GitAskPass)
+		}
+		b.WriteString(formatUnset(shell, env.unsetOptional()))
 	case Fish:
 		// Unset GH_TOKEN so it doesn't override gh auth's keyring token.
 		b.WriteString("set -e GH_TOKEN 2>/dev/null\n")
@@ -93,6 +149,10 @@ func formatOutput(shell ShellType, env EnvOutput) string {
 		if env.GHSSHCommand != "" {
 			writeFishExport(&b, "GIT_SSH_COMMAND", env.GHSSHCommand)
 		}
+		if env.GitAskPass != "" {
+			writeFishExport(&b, "GIT_ASKPASS", env.GitAskPass)
+		}
+		b.WriteString(formatUnset(shell, env.unsetOptional()))
 	default: // bash, zsh
 		// Unset GH_TOKEN so it doesn't override gh auth's keyring token.
 		b.WriteString("unset GH_TOKEN 2>/dev/null\n")
@@ -106,11 +166,97 @@ func formatOutput(shell ShellType, env EnvOutput) string {
 		if env.GHSSHCommand != "" {
 			writePosixExport(&b, "GIT_SSH_COMMAND", env.GHSSHCommand)
 		}
+		if env.GitAskPass != "" {
+			writePosixExport(&b, "GIT_ASKPASS", env.GitAskPass)
+		}
+		b.WriteString(formatUnset(shell, env.unsetOptional()))
 	}
 
 	return b.String()
 }
 
+// unsetOptional returns the optional variables this profile leaves empty,
+// which must be cleared in case a previous profile set them.
+func (env EnvOutput) unsetOptional() []string {
+	var names []string
+	if env.GHSSHCommand == "" {
+		names = append(names, "GIT_SSH_COMMAND")
+	}
+	if env.GitAskPass == "" {
+		names = append(names, "GIT_ASKPASS")
+	}
+	return names
+}
+
+// FormatClear returns statements that remove every managed variable,
+// restoring the base identity.
+func FormatClear(shell ShellType) string {
+	return formatUnset(shell, managedVars)
+}
+
+// formatUnset returns statements that remove names from the environment.
+func formatUnset(shell ShellType, names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	switch shell {
+	case Nu:
+		data, err := json.Marshal(nuOutput{Env: map[string]string{}, Unset: names})
+		if err != nil {
+			return ""
+		}
+		return string(data) + "\n"
+	case Fish:
+		var b strings.Builder
+		for _, name := range names {
+			fmt.Fprintf(&b, "set -e %s 2>/dev/null\n", name)
+		}
+		return b.String()
+	case PowerShell:
+		var b strings.Builder
+		for _, name := range names {
+			fmt.Fprintf(&b, "Remove-Item Env:%s -ErrorAction SilentlyContinue\n", name)
+		}
+		return b.String()
+	default: // bash, zsh
+		return fmt.Sprintf("unset %s 2>/dev/null\n", strings.Join(names, " "))
+	}
+}
+
+// nuOutput is the record emitted for Nushell. Nushell cannot eval arbitrary
+// text, so the hook parses this with `from json`, hides Unset, runs
+// `gh auth switch` for GHUser when set, and applies Env with `load-env`.
+type nuOutput struct {
+	GHUser string            `json:"gh_user"`
+	Env    map[string]string `json:"env"`
+	Unset  []string          `json:"unset,omitempty"`
+}
+
+func formatNu(env EnvOutput) string {
+	out := nuOutput{
+		GHUser: env.GHUser,
+		Unset:  env.unsetOptional(),
+		Env: map[string]string{
+			"GIT_AUTHOR_NAME":     env.GitAuthorName,
+			"GIT_AUTHOR_EMAIL":    env.GitAuthorEmail,
+			"GIT_COMMITTER_NAME":  env.GitCommitterName,
+			"GIT_COMMITTER_EMAIL": env.GitCommitterEmail,
+			"GH_IDENTITY_PROFILE": env.GHIdentityProfile,
+		},
+	}
+	if env.GHSSHCommand != "" {
+		out.Env["GIT_SSH_COMMAND"] = env.GHSSHCommand
+	}
+	if env.GitAskPass != "" {
+		out.Env["GIT_ASKPASS"] = env.GitAskPass
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		return ""
+	}
+	return string(data) + "\n"
+}
+
 func writeFishExport(b *strings.Builder, key, value string) {
 	fmt.Fprintf(b, "set -gx %s %q\n", key, value)
 }
@@ -118,3 +264,9 @@ func writeFishExport(b *strings.Builder, key, value string) {
 func writePosixExport(b *strings.Builder, key, value string) {
 	fmt.Fprintf(b, "export %s=%q\n", key, value)
 }
+
+// writePowerShellExport uses a single-quoted string, in which PowerShell
+// expands nothing and a quote is escaped by doubling it.
+func writePowerShellExport(b *strings.Builder, key, value string) {
+	fmt.Fprintf(b, "$env:%s = '%s'\n", key, strings.ReplaceAll(value, "'", "''"))
+}
@@ -1,8 +1,11 @@
 package hook
 
 import (
+	"encoding/json"
 	"strings"
 	"testing"
+
+	"github.com/dotbrains/gh-identity/internal/config"
 )
 
 func TestFormatOutput_Fish(t *testing.T) {
@@ -15,7 +18,7 @@ func TestFormatOutput_Fish(t *testing.T) {
 		GHIdentityProfile: "personal",
 	}
 
-	output := formatOutput(Fish, env)
+	output := Format(Fish, env)
 
 	if !strings.Contains(output, "set -e GH_TOKEN") {
 		t.Error("missing fish GH_TOKEN unset")
@@ -41,7 +44,7 @@ func TestFormatOutput_Bash(t *testing.T) {
 		GHIdentityProfile: "personal",
 	}
 
-	output := formatOutput(Bash, env)
+	output := Format(Bash, env)
 
 	if !strings.Contains(output, "unset GH_TOKEN") {
 		t.Error("missing bash GH_TOKEN unset")
@@ -57,6 +60,130 @@ func TestFormatOutput_Bash(t *testing.T) {
 	}
 }
 
+func TestFormatOutput_Nu(t *testing.T) {
+	env := EnvOutput{
+		GHUser:            "testuser",
+		GitAuthorName:     "Test \"Q\" User",
+		GitAuthorEmail:    "test@example.com",
+		GitCommitterName:  "Test \"Q\" User",
+		GitCommitterEmail: "test@example.com",
+		GHIdentityProfile: "personal",
+		GHSSHCommand:      "ssh -i /home/user/.ssh/id_test -o IdentitiesOnly=yes",
+	}
+
+	output := Format(Nu, env)
+
+	var got nuOutput
+	if err := json.Unmarshal([]byte(output), &got); err != nil {
+		t.Fatalf("nu output is not valid JSON: %v\n%s", err, output)
+	}
+	if got.GHUser != "testuser" {
+		t.Errorf("gh_user = %q, want %q", got.GHUser, "testuser")
+	}
+	if got.Env["GH_IDENTITY_PROFILE"] != "personal" {
+		t.Error("missing GH_IDENTITY_PROFILE in nu env")
+	}
+	if got.Env["GIT_AUTHOR_NAME"] != `Test "Q" User` {
+		t.Errorf("GIT_AUTHOR_NAME = %q", got.Env["GIT_AUTHOR_NAME"])
+	}
+	if got.Env["GIT_SSH_COMMAND"] == "" {
+		t.Error("missing GIT_SSH_COMMAND in nu env")
+	}
+	if strings.Contains(output, "export ") || strings.Contains(output, "set -gx") {
+		t.Error("nu output should not contain POSIX or fish exports")
+	}
+}
+
+func TestFormatOutput_Committer(t *testing.T) {
+	env := EnvOutput{
+		GHUser:            "testuser",
+		GitAuthorName:     "Test User",
+		GitAuthorEmail:    "test@example.com",
+		GitCommitterName:  "Release Bot",
+		GitCommitterEmail: "bot@example.com",
+		GHIdentityProfile: "release",
+	}
+
+	for _, want := range []string{
+		`export GIT_AUTHOR_NAME="Test User"`,
+		`export GIT_AUTHOR_EMAIL="test@example.com"`,
+		`export GIT_COMMITTER_NAME="Release Bot"`,
+		`export GIT_COMMITTER_EMAIL="bot@example.com"`,
+	} {
+		if output := Format(Bash, env); !strings.Contains(output, want) {
+			t.Errorf("bash output missing %q:\n%s", want, output)
+		}
+	}
+	for _, want := range []string{
+		`set -gx GIT_AUTHOR_NAME "Test User"`,
+		`set -gx GIT_COMMITTER_NAME "Release Bot"`,
+		`set -gx GIT_COMMITTER_EMAIL "bot@example.com"`,
+	} {
+		if output := Format(Fish, env); !strings.Contains(output, want) {
+			t.Errorf("fish output missing %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestProfileEnv_Committer(t *testing.T) {
+	base := config.Profile{GHUser: "u", GitName: "Test User", GitEmail: "test@example.com"}
+	tests := []struct {
+		name                string
+		committerName       string
+		committerEmail      string
+		wantName, wantEmail string
+	}{
+		{"mirrors author when unset", "", "", "Test User", "test@example.com"},
+		{"both set", "Release Bot", "bot@example.com", "Release Bot", "bot@example.com"},
+		{"email only", "", "bot@example.com", "Test User", "bot@example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := base
+			p.CommitterName, p.CommitterEmail = tt.committerName, tt.committerEmail
+			env := ProfileEnv("p", p)
+			if env.GitAuthorName != "Test User" || env.GitAuthorEmail != "test@example.com" {
+				t.Errorf("author = %q <%s>, want the profile's git_name and git_email", env.GitAuthorName, env.GitAuthorEmail)
+			}
+			if env.GitCommitterName != tt.wantName || env.GitCommitterEmail != tt.wantEmail {
+				t.Errorf("committer = %q <%s>, want %q <%s>", env.GitCommitterName, env.GitCommitterEmail, tt.wantName, tt.wantEmail)
+			}
+		})
+	}
+}
+
+func TestFormatOutput_PowerShell(t *testing.T) {
+	env := EnvOutput{
+		GHUser:            "testuser",
+		GitAuthorName:     "Pat O'Brien",
+		GitAuthorEmail:    "pat@example.com",
+		GitCommitterName:  "Pat O'Brien",
+		GitCommitterEmail: "pat@example.com",
+		GHIdentityProfile: "personal",
+	}
+
+	output := Format(PowerShell, env)
+	for _, want := range []string{
+		"Remove-Item Env:GH_TOKEN -ErrorAction SilentlyContinue\n",
+		"gh auth switch --user testuser 2>$null\n",
+		"$env:GIT_AUTHOR_NAME = 'Pat O''Brien'\n",
+		"$env:GH_IDENTITY_PROFILE = 'personal'\n",
+		"Remove-Item Env:GIT_SSH_COMMAND -ErrorAction SilentlyContinue\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("powershell output missing %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "export ") || strings.Contains(output, "set -gx") {
+		t.Errorf("powershell output should not contain POSIX or fish syntax:\n%s", output)
+	}
+
+	clear := FormatClear(PowerShell)
+	if n := strings.Count(clear, "Remove-Item Env:"); n != len(managedVars) {
+		t.Errorf("FormatClear(PowerShell) removes %d variables, want %d:\n%s", n, len(managedVars), clear)
+	}
+}
+
 func TestFormatOutput_SSHCommand(t *testing.T) {
 	env := EnvOutput{
 		GHUser:            "testuser",
@@ -68,7 +195,7 @@ func TestFormatOutput_SSHCommand(t *testing.T) {
 		GHSSHCommand:      "ssh -i /home/user/.ssh/id_work -o IdentitiesOnly=yes",
 	}
 
-	output := formatOutput(Fish, env)
+	output := Format(Fish, env)
 	if !strings.Contains(output, "GIT_SSH_COMMAND") {
 		t.Error("missing GIT_SSH_COMMAND export when SSH key is set")
 	}
@@ -84,8 +211,11 @@ func TestFormatOutput_NoSSHCommand(t *testing.T) {
 		GHIdentityProfile: "work",
 	}
 
-	output := formatOutput(Fish, env)
-	if strings.Contains(output, "GIT_SSH_COMMAND") {
+	output := Format(Fish, env)
+	if strings.Contains(output, "set -gx GIT_SSH_COMMAND") {
 		t.Error("GIT_SSH_COMMAND should not be set when SSH key is empty")
 	}
+	if !strings.Contains(output, "set -e GIT_SSH_COMMAND") {
+		t.Error("GIT_SSH_COMMAND from a previous profile should be cleared")
+	}
 }
@@ -1,16 +1,21 @@
 package hook
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/dotbrains/gh-identity/internal/config"
 )
 
 func setupTestConfig(t *testing.T, profilesYAML, bindingsYAML string) string {
 	t.Helper()
 	dir := t.TempDir()
 	t.Setenv("GH_IDENTITY_CONFIG_DIR", dir)
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
 
 	if profilesYAML != "" {
 		if err := os.WriteFile(filepath.Join(dir, "profiles.yml"), []byte(profilesYAML), 0o644); err != nil {
@@ -102,8 +107,50 @@ func TestResolve_NoProfile(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if output != "" {
-		t.Errorf("expected empty output when no profile resolved, got %q", output)
+	for _, name := range managedVars {
+		if !strings.Contains(output, "set -e "+name+" ") {
+			t.Errorf("expected fish to unset %s when no profile resolved, got %q", name, output)
+		}
+	}
+	if strings.Contains(output, "set -gx") || strings.Contains(output, "gh auth switch") {
+		t.Errorf("expected only unset statements, got %q", output)
+	}
+}
+
+func TestResolve_NoProfileBash(t *testing.T) {
+	setupTestConfig(t,
+		`profiles: {}`,
+		`bindings: []`,
+	)
+
+	output, err := Resolve("/some/dir", Bash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "unset " + strings.Join(managedVars, " ") + " 2>/dev/null\n"
+	if output != want {
+		t.Errorf("Resolve() = %q, want %q", output, want)
+	}
+}
+
+func TestResolve_NoProfileNu(t *testing.T) {
+	setupTestConfig(t,
+		`profiles: {}`,
+		`bindings: []`,
+	)
+
+	output, err := Resolve("/some/dir", Nu)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got nuOutput
+	if err := json.Unmarshal([]byte(output), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", output, err)
+	}
+	if got.GHUser != "" || len(got.Env) != 0 || len(got.Unset) != len(managedVars) {
+		t.Errorf("unexpected nu output: %+v", got)
 	}
 }
 
@@ -116,8 +163,8 @@ func TestResolve_NoConfig(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if output != "" {
-		t.Errorf("expected empty output with no config, got %q", output)
+	if !strings.HasPrefix(output, "unset ") {
+		t.Errorf("expected unset statements with no config, got %q", output)
 	}
 }
 
@@ -144,3 +191,84 @@ default: test`,
 		t.Error("expected gh auth switch for zsh")
 	}
 }
+
+func TestResolve_AskPass(t *testing.T) {
+	boundDir := t.TempDir()
+	setupTestConfig(t,
+		`profiles:
+  personal:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com`,
+		`bindings:
+  - path: `+boundDir+`
+    profile: personal`,
+	)
+
+	output, err := Resolve(boundDir, Bash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(output, "export GIT_ASKPASS") {
+		t.Error("GIT_ASKPASS should not be exported when the helper is not installed")
+	}
+
+	binDir, err := config.InstallBinDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	askPass := filepath.Join(binDir, "gh-identity-askpass")
+	if err := os.MkdirAll(filepath.Dir(askPass), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(askPass, []byte("fake"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err = Resolve(boundDir, Bash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "export GIT_ASKPASS=\""+askPass+"\"") {
+		t.Errorf("expected GIT_ASKPASS export, got:\n%s", output)
+	}
+}
+
+// TestResolve_CredentialHelper tests that a profile using gh's credential
+// helper does not get GIT_ASKPASS, even with the askpass helper installed.
+func TestResolve_CredentialHelper(t *testing.T) {
+	boundDir := t.TempDir()
+	setupTestConfig(t,
+		`profiles:
+  personal:
+    gh_user: user1
+    git_name: User One
+    git_email: user1@example.com
+    credential_helper: gh`,
+		`bindings:
+  - path: `+boundDir+`
+    profile: personal`,
+	)
+
+	binDir, err := config.InstallBinDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(binDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(binDir, "gh-identity-askpass"), []byte("fake"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := Resolve(boundDir, Bash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(output, "export GIT_ASKPASS") {
+		t.Errorf("GIT_ASKPASS should not be exported with credential_helper: gh, got:\n%s", output)
+	}
+	if !strings.Contains(output, "unset GIT_SSH_COMMAND GIT_ASKPASS") {
+		t.Errorf("expected a previous profile's GIT_ASKPASS to be cleared, got:\n%s", output)
+	}
+}
@@ -0,0 +1,73 @@
+package hook
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/dotbrains/gh-identity/internal/config"
+)
+
+// sshConfigDump returns the effective ssh configuration for host, as printed
+// by `ssh -G`. It is a variable so tests can stub it out.
+var sshConfigDump = func(host string) ([]byte, error) {
+	return exec.Command("ssh", "-G", host).Output()
+}
+
+// SSHHostIdentities returns the identity files ~/.ssh/config assigns to the
+// given host alias. Files that do not exist are skipped, since `ssh -G` also
+// lists ssh's built-in defaults.
+func SSHHostIdentities(alias string) ([]string, error) {
+	out, err := sshConfigDump(alias)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		if !ok || key != "identityfile" {
+			continue
+		}
+		expanded, err := config.ExpandPath(value)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(expanded); err == nil {
+			files = append(files, expanded)
+		}
+	}
+	return files, scanner.Err()
+}
+
+// SSHCommand builds the GIT_SSH_COMMAND for a profile: one -i flag per SSH
+// key, followed by the identities of its ssh_host_alias. It returns "" when
+// the profile has no SSH settings.
+func SSHCommand(p config.Profile) string {
+	var files []string
+	for _, key := range p.AllSSHKeys() {
+		expanded, err := config.ExpandConfigPath(key)
+		if err != nil {
+			continue
+		}
+		files = append(files, expanded)
+	}
+	if p.SSHHostAlias != "" {
+		if ids, err := SSHHostIdentities(p.SSHHostAlias); err == nil {
+			files = append(files, ids...)
+		}
+	}
+	if len(files) == 0 {
+		return ""
+	}
+
+	parts := []string{"ssh"}
+	for _, f := range files {
+		parts = append(parts, "-i", f)
+	}
+	parts = append(parts, "-o", "IdentitiesOnly=yes")
+	return strings.Join(parts, " ")
+}
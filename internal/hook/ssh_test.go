@@ -0,0 +1,60 @@
+package hook
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dotbrains/gh-identity/internal/config"
+)
+
+func stubSSHConfig(t *testing.T, out string, err error) {
+	t.Helper()
+	old := sshConfigDump
+	sshConfigDump = func(host string) ([]byte, error) {
+		return []byte(out), err
+	}
+	t.Cleanup(func() { sshConfigDump = old })
+}
+
+func TestSSHCommand_MultipleKeys(t *testing.T) {
+	p := config.Profile{SSHKey: "/keys/a", SSHKeys: []string{"/keys/b"}}
+	got := SSHCommand(p)
+	want := "ssh -i /keys/a -i /keys/b -o IdentitiesOnly=yes"
+	if got != want {
+		t.Errorf("SSHCommand() = %q, want %q", got, want)
+	}
+}
+
+func TestSSHCommand_None(t *testing.T) {
+	if got := SSHCommand(config.Profile{}); got != "" {
+		t.Errorf("SSHCommand() = %q, want empty", got)
+	}
+}
+
+func TestSSHCommand_HostAlias(t *testing.T) {
+	keyDir := t.TempDir()
+	key := filepath.Join(keyDir, "id_work")
+	if err := os.WriteFile(key, []byte("key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	stubSSHConfig(t, "hostname github.com\nidentityfile "+key+"\nidentityfile "+filepath.Join(keyDir, "missing")+"\n", nil)
+
+	got := SSHCommand(config.Profile{SSHHostAlias: "github-work"})
+	want := "ssh -i " + key + " -o IdentitiesOnly=yes"
+	if got != want {
+		t.Errorf("SSHCommand() = %q, want %q", got, want)
+	}
+}
+
+func TestSSHHostIdentities_Error(t *testing.T) {
+	stubSSHConfig(t, "", fmt.Errorf("ssh not found"))
+
+	if _, err := SSHHostIdentities("github-work"); err == nil {
+		t.Error("expected error when ssh -G fails")
+	}
+	if got := SSHCommand(config.Profile{SSHHostAlias: "github-work"}); got != "" {
+		t.Errorf("SSHCommand() = %q, want empty", got)
+	}
+}
@@ -0,0 +1,92 @@
+package hook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dotbrains/gh-identity/internal/config"
+)
+
+// TokenWarnedVar is set in the shell once the hook has warned about a
+// GH_TOKEN it did not export, so the warning is printed once per session.
+const TokenWarnedVar = "GH_IDENTITY_GH_TOKEN_WARNED"
+
+// ConfigWarnedVar is set in the shell once the hook has reported an invalid
+// config file, so a broken file is reported once per session rather than at
+// every prompt.
+const ConfigWarnedVar = "GH_IDENTITY_CONFIG_WARNED"
+
+// ForeignToken reports whether GH_TOKEN is set while GH_IDENTITY_PROFILE is
+// not, meaning something other than gh-identity exported the token (CI or
+// a dotfile, typically). Such a token overrides the account gh-identity
+// selects.
+func ForeignToken() bool {
+	return os.Getenv("GH_TOKEN") != "" && os.Getenv("GH_IDENTITY_PROFILE") == ""
+}
+
+// WarnForeignToken writes a one-line warning to w when ForeignToken holds
+// and the shell has not been warned yet, and returns output extended to set
+// TokenWarnedVar. Otherwise output is returned unchanged.
+func WarnForeignToken(shell ShellType, output string, w io.Writer) string {
+	if !ForeignToken() || os.Getenv(TokenWarnedVar) != "" {
+		return output
+	}
+	fmt.Fprintln(w, "gh-identity: GH_TOKEN is set outside gh-identity and overrides the bound account; remove it from your shell startup files (see `gh identity doctor`)")
+	return appendEnv(shell, output, TokenWarnedVar, "1")
+}
+
+// WarnInvalidConfig handles a resolution error caused by a config file that
+// does not parse. It writes one actionable line to w, unless this shell was
+// already warned, and returns output that only sets ConfigWarnedVar: no
+// identity is exported until the file is fixed. ok is false for any other
+// error, which the caller reports as before.
+func WarnInvalidConfig(shell ShellType, err error, w io.Writer) (output string, ok bool) {
+	var pe *config.ParseError
+	if !errors.As(err, &pe) {
+		return "", false
+	}
+	if os.Getenv(ConfigWarnedVar) != "" {
+		return "", true
+	}
+	fmt.Fprintf(w, "gh-identity: %s is invalid, run `gh identity doctor`\n", filepath.Base(pe.Path))
+	return appendEnv(shell, "", ConfigWarnedVar, "1"), true
+}
+
+// appendEnv adds an exported variable to resolved hook output.
+func appendEnv(shell ShellType, output, key, value string) string {
+	var b strings.Builder
+	switch shell {
+	case Nu:
+		// Nushell expects a single record, so merge into it.
+		var out nuOutput
+		if strings.TrimSpace(output) != "" {
+			if err := json.Unmarshal([]byte(output), &out); err != nil {
+				return output
+			}
+		}
+		if out.Env == nil {
+			out.Env = map[string]string{}
+		}
+		out.Env[key] = value
+		data, err := json.Marshal(out)
+		if err != nil {
+			return output
+		}
+		return string(data) + "\n"
+	case Fish:
+		b.WriteString(output)
+		writeFishExport(&b, key, value)
+	case PowerShell:
+		b.WriteString(output)
+		writePowerShellExport(&b, key, value)
+	default: // bash, zsh
+		b.WriteString(output)
+		writePosixExport(&b, key, value)
+	}
+	return b.String()
+}
@@ -0,0 +1,98 @@
+package hook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWarnForeignToken(t *testing.T) {
+	t.Setenv("GH_TOKEN", "ghp_foreign")
+	t.Setenv("GH_IDENTITY_PROFILE", "")
+	t.Setenv(TokenWarnedVar, "")
+
+	var stderr bytes.Buffer
+	got := WarnForeignToken(Bash, "export A=\"1\"\n", &stderr)
+	if !strings.Contains(stderr.String(), "GH_TOKEN is set outside gh-identity") {
+		t.Errorf("expected a warning, got %q", stderr.String())
+	}
+	if strings.Count(stderr.String(), "\n") != 1 {
+		t.Errorf("expected a single line, got %q", stderr.String())
+	}
+	if want := "export A=\"1\"\nexport " + TokenWarnedVar + "=\"1\"\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+
+	got = WarnForeignToken(Fish, "", &bytes.Buffer{})
+	if !strings.Contains(got, "set -gx "+TokenWarnedVar+" \"1\"") {
+		t.Errorf("fish output = %q", got)
+	}
+
+	got = WarnForeignToken(Nu, `{"gh_user":"u","env":{"A":"1"}}`+"\n", &bytes.Buffer{})
+	var out nuOutput
+	if err := json.Unmarshal([]byte(got), &out); err != nil {
+		t.Fatalf("nu output is not a single record: %q: %v", got, err)
+	}
+	if out.GHUser != "u" || out.Env["A"] != "1" || out.Env[TokenWarnedVar] != "1" {
+		t.Errorf("nu output = %+v", out)
+	}
+
+	// Warned already, or the token is gh-identity's own: stay quiet.
+	for _, env := range []struct{ key, value string }{
+		{TokenWarnedVar, "1"},
+		{"GH_IDENTITY_PROFILE", "work"},
+	} {
+		t.Run(env.key, func(t *testing.T) {
+			t.Setenv(env.key, env.value)
+			stderr.Reset()
+			if got := WarnForeignToken(Bash, "x\n", &stderr); got != "x\n" || stderr.Len() != 0 {
+				t.Errorf("output = %q, stderr = %q; want no change", got, stderr.String())
+			}
+		})
+	}
+}
+
+func TestWarnInvalidConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GH_IDENTITY_CONFIG_DIR", dir)
+	t.Setenv(ConfigWarnedVar, "")
+	if err := os.WriteFile(filepath.Join(dir, "profiles.yml"), []byte("profiles: [unclosed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Resolve(t.TempDir(), Bash)
+	if err == nil {
+		t.Fatal("expected Resolve to fail on a malformed profiles.yml")
+	}
+
+	var stderr bytes.Buffer
+	got, ok := WarnInvalidConfig(Bash, err, &stderr)
+	if !ok {
+		t.Fatalf("expected %v to be handled as invalid config", err)
+	}
+	if want := "gh-identity: profiles.yml is invalid, run `gh identity doctor`\n"; stderr.String() != want {
+		t.Errorf("stderr = %q, want %q", stderr.String(), want)
+	}
+	if want := "export " + ConfigWarnedVar + "=\"1\"\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+
+	// Already warned in this shell: stay quiet.
+	t.Setenv(ConfigWarnedVar, "1")
+	stderr.Reset()
+	if got, ok := WarnInvalidConfig(Bash, err, &stderr); !ok || got != "" || stderr.Len() != 0 {
+		t.Errorf("WarnInvalidConfig() = %q, %v, stderr %q; want quiet", got, ok, stderr.String())
+	}
+
+	// Other errors, and no error, are left to the caller.
+	for _, e := range []error{nil, fmt.Errorf("loading profiles: %w", errors.New("permission denied"))} {
+		if _, ok := WarnInvalidConfig(Bash, e, &stderr); ok {
+			t.Errorf("WarnInvalidConfig(%v) handled an unrelated error", e)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package resolve
+
+import (
+	"log/slog"
+
+	"github.com/dotbrains/gh-identity/internal/config"
+)
+
+// Source identifies which precedence rule selected the active profile.
+type Source string
+
+const (
+	SourceNone        Source = "none"        // nothing applies
+	SourceEnvironment Source = "environment" // GH_IDENTITY_PROFILE (or another explicit override)
+	SourceRepoFile    Source = "repo-file"   // a .gh-identity file at the repository root
+	SourceBinding     Source = "binding"     // a directory, remote, or includeIf binding
+	SourceOrg         Source = "org"         // the orgs mapping for the origin's owner
+	SourceDefault     Source = "default"     // the default profile
+)
+
+// Resolution is the outcome of Active.
+type Resolution struct {
+	Result
+	Source Source
+
+	// Directory is what the directory resolves to on its own, ignoring the
+	// override. It equals Result unless Source is SourceEnvironment.
+	Directory Result
+}
+
+// Active applies gh-identity's profile precedence for dir:
+//
+//  1. override, normally the value of GH_IDENTITY_PROFILE
+//  2. a .gh-identity file at the root of dir's repository (see RepoProfile),
+//     if it names a configured profile; otherwise it is ignored
+//  3. the best directory, remote, or includeIf binding (see ForDirectory)
+//  4. the orgs mapping for the owner of dir's origin remote
+//  5. the default profile
+//
+// Every caller that needs "the active profile" goes through Active so the
+// precedence cannot drift between commands. The shell hook passes no
+// override: it is what exports GH_IDENTITY_PROFILE, and honoring the
+// previous directory's value would pin the shell to it.
+func Active(dir string, bindings *config.BindingsFile, profiles *config.ProfilesFile, override string) (Resolution, error) {
+	result, fromRepo, err := forDirectory(dir, bindings, profiles)
+	if err != nil {
+		return Resolution{}, err
+	}
+
+	res := Resolution{Result: result, Directory: result}
+	switch {
+	case override != "":
+		res.Result = Result{Profile: override}
+		res.Source = SourceEnvironment
+	case fromRepo:
+		res.Source = SourceRepoFile
+	case result.Org != "":
+		res.Source = SourceOrg
+	case result.IsDefault:
+		res.Source = SourceDefault
+	case result.Profile != "":
+		res.Source = SourceBinding
+	default:
+		res.Source = SourceNone
+	}
+	return res, nil
+}
+
+// forDirectory resolves dir without an override: a .gh-identity file naming
+// a configured profile wins, then a binding found by ForDirectory, then the
+// orgs mapping. fromRepo reports whether the file was used.
+func forDirectory(dir string, bindings *config.BindingsFile, profiles *config.ProfilesFile) (result Result, fromRepo bool, err error) {
+	if name, file := RepoProfile(dir); name != "" {
+		if _, ok := profiles.Profiles[name]; ok {
+			slog.Debug("resolved repository file", "dir", dir, "file", file, "profile", name)
+			return Result{Profile: name, RepoFile: file}, true, nil
+		}
+		slog.Debug("ignoring repository file naming an unknown profile", "dir", dir, "file", file, "profile", name)
+	}
+	result, err = ForDirectory(dir, bindings, profiles.Default)
+	if err != nil || (result.Profile != "" && !result.IsDefault) || len(profiles.Orgs) == 0 {
+		return result, false, err
+	}
+
+	// Only shell out to git when an org mapping could apply.
+	expanded, err := config.ResolvePath(dir)
+	if err != nil {
+		return Result{}, false, err
+	}
+	origin := originURL(expanded)
+	if r, ok := forOrg(origin, profiles.Orgs); ok {
+		slog.Debug("resolved org mapping", "dir", dir, "origin", origin, "org", r.Org, "profile", r.Profile)
+		return r, false, nil
+	}
+	return result, false, nil
+}
@@ -0,0 +1,78 @@
+package resolve
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// isGlob reports whether a binding path contains wildcards.
+func isGlob(p string) bool {
+	return strings.Contains(p, "*")
+}
+
+// globMatchesTree reports whether dir is equal to, or inside, a directory
+// matched by pattern. Each path segment is matched with filepath.Match, and a
+// "**" segment matches zero or more whole segments.
+func globMatchesTree(dir, pattern string) bool {
+	dirSegs := splitPath(filepath.Clean(dir))
+	patSegs := splitPath(filepath.Clean(pattern))
+
+	// The binding covers the whole tree below any matching ancestor.
+	for n := len(dirSegs); n >= 0; n-- {
+		if matchSegments(dirSegs[:n], patSegs) {
+			return true
+		}
+	}
+	return false
+}
+
+// globLiteralPrefix returns the depth of the wildcard-free leading segments
+// of pattern and the length of the text before its first wildcard, used to
+// rank glob bindings against each other and against plain bindings.
+func globLiteralPrefix(pattern string) (depth, length int) {
+	clean := filepath.Clean(pattern)
+	for _, s := range splitPath(clean) {
+		if isGlob(s) {
+			break
+		}
+		depth++
+	}
+	length = strings.Index(clean, "*")
+	if length < 0 {
+		length = len(clean)
+	}
+	return depth, length
+}
+
+func matchSegments(path, pat []string) bool {
+	if len(pat) == 0 {
+		return len(path) == 0
+	}
+	if pat[0] == "**" {
+		// Match zero or more segments.
+		for i := 0; i <= len(path); i++ {
+			if matchSegments(path[i:], pat[1:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 {
+		return false
+	}
+	ok, err := filepath.Match(pat[0], path[0])
+	if err != nil || !ok {
+		return false
+	}
+	return matchSegments(path[1:], pat[1:])
+}
+
+func splitPath(p string) []string {
+	var segs []string
+	for _, s := range strings.Split(p, string(filepath.Separator)) {
+		if s != "" {
+			segs = append(segs, s)
+		}
+	}
+	return segs
+}
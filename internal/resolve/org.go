@@ -0,0 +1,39 @@
+package resolve
+
+import (
+	"sort"
+	"strings"
+)
+
+// forOrg looks up the owner of the origin remote in orgs, which maps an org
+// name, or "host/org" for one host only, to a profile. Keys compare
+// case-insensitively, and a host-qualified key beats a bare one.
+func forOrg(origin string, orgs map[string]string) (Result, bool) {
+	parts := strings.SplitN(NormalizeRemote(origin), "/", 3)
+	if origin == "" || len(parts) < 2 || parts[1] == "" {
+		return Result{}, false
+	}
+	host, owner := parts[0], parts[1]
+
+	keys := make([]string, 0, len(orgs))
+	for k := range orgs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var bare string
+	for _, k := range keys {
+		switch strings.ToLower(strings.Trim(k, "/")) {
+		case host + "/" + owner:
+			return Result{Profile: orgs[k], Org: k}, true
+		case owner:
+			if bare == "" {
+				bare = k
+			}
+		}
+	}
+	if bare == "" {
+		return Result{}, false
+	}
+	return Result{Profile: orgs[bare], Org: bare}, true
+}
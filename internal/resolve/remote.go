@@ -0,0 +1,72 @@
+package resolve
+
+import (
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// originURL returns the origin remote URL of the git repository containing dir,
+// or "" if dir is not in a repository or has no origin. It is a variable so
+// tests can stub out the git call.
+var originURL = func(dir string) string {
+	out, err := exec.Command("git", "-C", dir, "config", "--get", "remote.origin.url").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// NormalizeRemote reduces a git remote URL to "host/owner/repo" form so that
+// HTTPS, SSH, and scp-style URLs for the same repository compare equal.
+// e.g. "git@github.com:owner/repo.git" → "github.com/owner/repo"
+func NormalizeRemote(url string) string {
+	return strings.ToLower(RemoteHostPath(url))
+}
+
+// RemoteHostPath is NormalizeRemote without the case folding, for callers
+// that need to rebuild URLs git will compare case-sensitively.
+func RemoteHostPath(url string) string {
+	url = strings.TrimSpace(url)
+	url = strings.TrimSuffix(url, "/")
+	url = strings.TrimSuffix(url, ".git")
+
+	if i := strings.Index(url, "://"); i != -1 {
+		// scheme://[user@]host[:port]/owner/repo
+		url = url[i+3:]
+		if at := strings.Index(url, "@"); at != -1 && at < strings.Index(url+"/", "/") {
+			url = url[at+1:]
+		}
+		if slash := strings.Index(url, "/"); slash != -1 {
+			host := url[:slash]
+			if colon := strings.Index(host, ":"); colon != -1 {
+				host = host[:colon]
+			}
+			url = host + url[slash:]
+		}
+	} else if colon := strings.Index(url, ":"); colon != -1 {
+		// scp-style: [user@]host:owner/repo
+		host := url[:colon]
+		if at := strings.Index(host, "@"); at != -1 {
+			host = host[at+1:]
+		}
+		url = host + "/" + strings.TrimPrefix(url[colon+1:], "/")
+	}
+
+	return url
+}
+
+// matchRemote reports whether the normalized remote matches pattern.
+// A pattern matches if it equals the remote, is a path prefix of it
+// (e.g. "github.com/acme" matches every acme repo), or glob-matches it.
+func matchRemote(remote, pattern string) bool {
+	pattern = NormalizeRemote(pattern)
+	if pattern == "" || remote == "" {
+		return false
+	}
+	if remote == pattern || strings.HasPrefix(remote, pattern+"/") {
+		return true
+	}
+	ok, err := path.Match(pattern, remote)
+	return err == nil && ok
+}
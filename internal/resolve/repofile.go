@@ -0,0 +1,81 @@
+package resolve
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dotbrains/gh-identity/internal/config"
+)
+
+// RepoFileName is the file a repository can commit at its root to name the
+// profile it should always use, like .nvmrc does for node versions.
+const RepoFileName = ".gh-identity"
+
+// RepoProfile finds the git repository containing dir and returns the
+// profile named by the .gh-identity file at its root, along with the file's
+// path. It returns "" when dir is not in a repository, the root has no such
+// file, or the file names no profile. The file's first line that is neither
+// blank nor a # comment is the profile name.
+func RepoProfile(dir string) (profile, file string) {
+	expanded, err := config.ResolvePath(dir)
+	if err != nil {
+		return "", ""
+	}
+	root, ok := gitRoot(expanded)
+	if !ok {
+		return "", ""
+	}
+
+	file = filepath.Join(root, RepoFileName)
+	f, err := os.Open(file)
+	if err != nil {
+		return "", ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return line, file
+		}
+	}
+	return "", ""
+}
+
+// RepoFiles returns the files of dir's repository whose changes can change
+// how dir resolves: the .gh-identity file at its root and, in an ordinary
+// clone, .git/config, which holds the origin remote. The files need not
+// exist. It returns nil when dir is not in a repository. The shell hook's
+// cache stamps entries with their modification times.
+func RepoFiles(dir string) []string {
+	expanded, err := config.ResolvePath(dir)
+	if err != nil {
+		return nil
+	}
+	root, ok := gitRoot(expanded)
+	if !ok {
+		return nil
+	}
+	// In worktrees and submodules .git is a file and the config lives
+	// elsewhere; cacheTTL covers those.
+	return []string{filepath.Join(root, RepoFileName), filepath.Join(root, ".git", "config")}
+}
+
+// gitRoot walks up from dir to the nearest directory containing .git, a
+// directory in ordinary clones and a file in worktrees and submodules. It
+// checks the filesystem instead of running git to keep the shell hook fast.
+func gitRoot(dir string) (string, bool) {
+	for {
+		if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
@@ -3,6 +3,7 @@
 package resolve
 
 import (
+	"log/slog"
 	"path/filepath"
 	"strings"
 
@@ -11,58 +12,184 @@ import (
 
 // Result holds the outcome of a binding resolution.
 type Result struct {
-	Profile   string // profile name, or "" if no match
-	BoundPath string // the binding path that matched, or ""
-	IsDefault bool   // true if the default profile was used (no binding match)
+	Profile       string // profile name, or "" if no match
+	BoundPath     string // the binding path that matched, or ""
+	RemotePattern string // the remote pattern that matched, or ""
+	IncludeIf     string // the includeIf gitdir of an inferred binding that matched, or ""
+	Org           string // the orgs key in profiles.yml that matched, or ""
+	RepoFile      string // the .gh-identity file that named the profile, or ""
+	IsDefault     bool   // true if the default profile was used (no binding match)
 }
 
-// ForDirectory resolves the active profile for the given directory.
-// It walks up from dir to /, finding the deepest binding match.
-// If no binding matches, it falls back to the default profile.
-func ForDirectory(dir string, bindings *config.BindingsFile, defaultProfile string) (Result, error) {
-	expanded, err := config.ExpandPath(dir)
+// Candidate is a directory binding considered while resolving a directory.
+type Candidate struct {
+	Binding config.Binding
+	Matches bool // the binding covers the directory
+	Glob    bool // the binding path contains wildcards
+	Exact   bool // the binding path is the directory itself, not an ancestor
+	Depth   int  // ranking depth; only meaningful when Matches is true
+	Literal int  // length of the wildcard-free part of the path; breaks ties
+}
+
+// Candidates evaluates every directory binding against dir, in bindings
+// order. Remote bindings are not included.
+func Candidates(dir string, bindings *config.BindingsFile) ([]Candidate, error) {
+	var paths config.PathCache
+	expanded, err := paths.ResolvePath(dir)
 	if err != nil {
-		return Result{}, err
+		return nil, err
 	}
+	return candidatesFor(expanded, bindings.Bindings, paths.ResolveConfigPath), nil
+}
 
-	var bestMatch string
-	var bestPath string
-	bestDepth := -1
-
-	for _, b := range bindings.Bindings {
-		bPath, err := config.ExpandPath(b.Path)
+// candidatesFor evaluates the directory bindings in list against the
+// resolved directory expanded. Binding paths are resolved with resolvePath:
+// bindings.yml paths may hold $VAR references, includeIf gitdirs may not.
+func candidatesFor(expanded string, list []config.Binding, resolvePath func(string) (string, error)) []Candidate {
+	folded := config.FoldPath(filepath.Clean(expanded))
+	var candidates []Candidate
+	for _, b := range list {
+		if b.IsRemote() {
+			continue
+		}
+		bPath, err := resolvePath(b.Path)
 		if err != nil {
 			continue
 		}
 
-		if isSubpath(expanded, bPath) {
-			depth := strings.Count(bPath, string(filepath.Separator))
-			if depth > bestDepth {
-				bestDepth = depth
-				bestMatch = b.Profile
-				bestPath = b.Path
-			}
+		c := Candidate{Binding: b, Glob: isGlob(bPath)}
+		if c.Glob {
+			c.Matches = globMatchesTree(folded, config.FoldPath(bPath))
+			c.Depth, c.Literal = globLiteralPrefix(bPath)
+		} else {
+			parent := config.FoldPath(filepath.Clean(bPath))
+			c.Matches = isFoldedSubpath(folded, parent)
+			c.Exact = c.Matches && folded == parent
+			c.Depth = strings.Count(bPath, string(filepath.Separator))
+			c.Literal = len(filepath.Clean(bPath))
 		}
+		slog.Debug("binding candidate", "dir", expanded, "binding", bPath, "profile", b.Profile,
+			"matches", c.Matches, "glob", c.Glob, "depth", c.Depth)
+		candidates = append(candidates, c)
+	}
+	return candidates
+}
+
+// ForDirectory resolves the active profile for the given directory.
+// Symlinks in dir and in binding paths are resolved before comparing.
+// It walks up from dir to /, finding the deepest binding match. Binding paths
+// containing "*" are globs ("**" spans segments) ranked by the depth of their
+// wildcard-free prefix. Bindings of equal depth are ranked, in order: a
+// binding of dir itself beats one of an ancestor, which beats a glob; then
+// the longer wildcard-free prefix wins; then the earlier binding in
+// bindings.yml.
+// If no directory binding matches, it tries remote bindings against the
+// repository's origin URL, preferring the longest pattern.
+// Bindings inferred from gitconfig includeIfs (bindings.Inferred) are tried
+// next. If nothing matches, it falls back to the default profile.
+func ForDirectory(dir string, bindings *config.BindingsFile, defaultProfile string) (Result, error) {
+	// The directory and binding paths are resolved once per call; the cache
+	// shares symlink lookups between bindings with common ancestors.
+	var paths config.PathCache
+	expanded, err := paths.ResolvePath(dir)
+	if err != nil {
+		return Result{}, err
 	}
 
-	if bestMatch != "" {
+	if best, ok := bestCandidate(candidatesFor(expanded, bindings.Bindings, paths.ResolveConfigPath)); ok {
+		slog.Debug("resolved directory binding", "dir", dir, "binding", best.Binding.Path, "profile", best.Binding.Profile)
 		return Result{
-			Profile:   bestMatch,
-			BoundPath: bestPath,
+			Profile:   best.Binding.Profile,
+			BoundPath: best.Binding.Path,
 		}, nil
 	}
 
+	// Only shell out to git when a remote binding could apply.
+	if hasRemoteBindings(bindings) {
+		origin := originURL(expanded)
+		if r, ok := forRemote(origin, bindings); ok {
+			slog.Debug("resolved remote binding", "dir", dir, "origin", origin, "pattern", r.RemotePattern, "profile", r.Profile)
+			return r, nil
+		}
+		slog.Debug("no remote binding matched", "dir", dir, "origin", origin)
+	}
+
+	if len(bindings.Inferred) > 0 {
+		if best, ok := bestCandidate(candidatesFor(expanded, bindings.Inferred, paths.ResolvePath)); ok {
+			slog.Debug("resolved includeIf binding", "dir", dir, "gitdir", best.Binding.Path, "profile", best.Binding.Profile)
+			return Result{
+				Profile:   best.Binding.Profile,
+				IncludeIf: best.Binding.Path,
+			}, nil
+		}
+	}
+
+	slog.Debug("no binding matched; using default profile", "dir", dir, "profile", defaultProfile)
 	return Result{
 		Profile:   defaultProfile,
 		IsDefault: defaultProfile != "",
 	}, nil
 }
 
+func hasRemoteBindings(bindings *config.BindingsFile) bool {
+	for _, b := range bindings.Bindings {
+		if b.IsRemote() {
+			return true
+		}
+	}
+	return false
+}
+
+// bestCandidate picks the deepest matching candidate, breaking ties as
+// described on ForDirectory. Candidates are in bindings order, so keeping
+// the current best on a full tie makes the earlier binding win.
+func bestCandidate(candidates []Candidate) (Candidate, bool) {
+	var best Candidate
+	found := false
+	for _, c := range candidates {
+		if !c.Matches {
+			continue
+		}
+		if !found || outranks(c, best) {
+			best = c
+			found = true
+		}
+	}
+	return best, found
+}
+
+// outranks reports whether matching candidate c ranks strictly above best.
+func outranks(c, best Candidate) bool {
+	if c.Depth != best.Depth {
+		return c.Depth > best.Depth
+	}
+	if c.kind() != best.kind() {
+		return c.kind() < best.kind()
+	}
+	return c.Literal > best.Literal
+}
+
+// kind orders matches for tie-breaking: 0 for an exact binding, 1 for an
+// ancestor binding, 2 for a glob.
+func (c Candidate) kind() int {
+	switch {
+	case c.Exact:
+		return 0
+	case !c.Glob:
+		return 1
+	default:
+		return 2
+	}
+}
+
 // isSubpath reports whether child is equal to or a subdirectory of parent.
+// The comparison ignores case on case-insensitive filesystems.
 func isSubpath(child, parent string) bool {
-	child = filepath.Clean(child)
-	parent = filepath.Clean(parent)
+	return isFoldedSubpath(config.FoldPath(filepath.Clean(child)), config.FoldPath(filepath.Clean(parent)))
+}
 
+// isFoldedSubpath is isSubpath for paths already cleaned and folded.
+func isFoldedSubpath(child, parent string) bool {
 	if child == parent {
 		return true
 	}
@@ -71,3 +198,25 @@ func isSubpath(child, parent string) bool {
 	parentPrefix := parent + string(filepath.Separator)
 	return strings.HasPrefix(child, parentPrefix)
 }
+
+// forRemote finds the most specific remote binding matching the origin URL.
+func forRemote(url string, bindings *config.BindingsFile) (Result, bool) {
+	remote := NormalizeRemote(url)
+	if remote == "" {
+		return Result{}, false
+	}
+
+	var best config.Binding
+	for _, b := range bindings.Bindings {
+		if !b.IsRemote() || !matchRemote(remote, b.RemotePattern) {
+			continue
+		}
+		if len(b.RemotePattern) > len(best.RemotePattern) {
+			best = b
+		}
+	}
+	if best.Profile == "" {
+		return Result{}, false
+	}
+	return Result{Profile: best.Profile, RemotePattern: best.RemotePattern}, true
+}
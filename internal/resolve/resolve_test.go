@@ -1,7 +1,12 @@
 package resolve
 
 import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/dotbrains/gh-identity/internal/config"
@@ -117,3 +122,750 @@ func TestIsSubpath(t *testing.T) {
 		}
 	}
 }
+
+func setCaseInsensitive(t *testing.T, v bool) {
+	t.Helper()
+	old := config.CaseInsensitiveFS
+	config.CaseInsensitiveFS = v
+	t.Cleanup(func() { config.CaseInsensitiveFS = old })
+}
+
+func TestIsSubpath_CaseFolding(t *testing.T) {
+	tests := []struct {
+		child           string
+		parent          string
+		caseInsensitive bool
+		want            bool
+	}{
+		{"/users/me/code/repo", "/Users/Me/Code", true, true},
+		{"/Users/Me/Code", "/users/me/code", true, true},
+		{"/users/me/codex", "/Users/Me/Code", true, false},
+		{"/users/me/code/repo", "/Users/Me/Code", false, false},
+		{"/Users/Me/Code/repo", "/Users/Me/Code", false, true},
+	}
+	for _, tt := range tests {
+		setCaseInsensitive(t, tt.caseInsensitive)
+		if got := isSubpath(tt.child, tt.parent); got != tt.want {
+			t.Errorf("isSubpath(%q, %q) [case-insensitive=%v] = %v, want %v", tt.child, tt.parent, tt.caseInsensitive, got, tt.want)
+		}
+	}
+}
+
+func TestForDirectory_CaseInsensitive(t *testing.T) {
+	setCaseInsensitive(t, true)
+	bf := &config.BindingsFile{
+		Bindings: []config.Binding{{Path: "/Nonexistent/Users/Me/Code", Profile: "work"}},
+	}
+
+	result, err := ForDirectory("/nonexistent/users/me/code/repo", bf, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Profile != "work" {
+		t.Errorf("Profile = %q, want %q", result.Profile, "work")
+	}
+}
+
+func TestNormalizeRemote(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://github.com/Acme/repo.git", "github.com/acme/repo"},
+		{"https://github.com/acme/repo", "github.com/acme/repo"},
+		{"git@github.com:acme/repo.git", "github.com/acme/repo"},
+		{"ssh://git@github.com/acme/repo.git", "github.com/acme/repo"},
+		{"ssh://git@ghe.corp.com:2222/acme/repo", "ghe.corp.com/acme/repo"},
+		{"https://user@github.com/acme/repo/", "github.com/acme/repo"},
+		{"github.com/acme", "github.com/acme"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeRemote(tt.url); got != tt.want {
+			t.Errorf("NormalizeRemote(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestMatchRemote(t *testing.T) {
+	remote := "github.com/acme/repo"
+	tests := []struct {
+		pattern string
+		want    bool
+	}{
+		{"github.com/acme", true},
+		{"github.com/acme/repo", true},
+		{"git@github.com:acme/repo.git", true},
+		{"github.com/acme/*", true},
+		{"github.com/*/repo", true},
+		{"github.com/ac", false},
+		{"github.com/other", false},
+		{"gitlab.com/acme", false},
+	}
+	for _, tt := range tests {
+		if got := matchRemote(remote, tt.pattern); got != tt.want {
+			t.Errorf("matchRemote(%q, %q) = %v, want %v", remote, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func stubOriginURL(t *testing.T, url string) {
+	t.Helper()
+	old := originURL
+	originURL = func(string) string { return url }
+	t.Cleanup(func() { originURL = old })
+}
+
+func TestSuggest(t *testing.T) {
+	profiles := &config.ProfilesFile{Profiles: map[string]config.Profile{
+		"personal": {GHUser: "Octocat"},
+		"corp":     {GHUser: "dev", Host: "github.corp.example"},
+		"oss":      {GHUser: "dev"},
+		"work":     {GHUser: "worker"},
+	}}
+	bindings := &config.BindingsFile{Bindings: []config.Binding{
+		{RemotePattern: "github.com/acme", Profile: "work"},
+	}}
+
+	tests := []struct {
+		origin string
+		want   string
+	}{
+		{"git@github.com:octocat/hello.git", "personal"},
+		{"https://github.com/acme/widgets", "work"},
+		{"https://github.corp.example/dev/tool.git", "corp"},
+		{"git@github.com:dev/tool.git", "oss"},
+		{"git@github-work:dev/tool.git", ""},
+		{"https://github.com/stranger/repo", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		stubOriginURL(t, tt.origin)
+		if got := Suggest(t.TempDir(), bindings, profiles); got != tt.want {
+			t.Errorf("Suggest(origin %q) = %q, want %q", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestForDirectory_RemoteMatch(t *testing.T) {
+	stubOriginURL(t, "git@github.com:acme/widgets.git")
+	dir := filepath.Join(t.TempDir(), "scattered", "widgets")
+
+	bf := &config.BindingsFile{
+		Bindings: []config.Binding{
+			{RemotePattern: "github.com/acme", Profile: "work"},
+			{RemotePattern: "github.com/acme/widgets", Profile: "widgets"},
+			{RemotePattern: "github.com/other", Profile: "other"},
+		},
+	}
+
+	result, err := ForDirectory(dir, bf, "personal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Profile != "widgets" {
+		t.Errorf("Profile = %q, want %q (longest pattern)", result.Profile, "widgets")
+	}
+	if result.RemotePattern != "github.com/acme/widgets" {
+		t.Errorf("RemotePattern = %q", result.RemotePattern)
+	}
+	if result.IsDefault || result.BoundPath != "" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestForDirectory_DirectoryBeatsRemote(t *testing.T) {
+	stubOriginURL(t, "https://github.com/acme/widgets")
+	tmp := t.TempDir()
+	dir := filepath.Join(tmp, "code", "widgets")
+
+	bf := &config.BindingsFile{
+		Bindings: []config.Binding{
+			{RemotePattern: "github.com/acme", Profile: "work"},
+			{Path: tmp, Profile: "personal"},
+		},
+	}
+
+	result, err := ForDirectory(dir, bf, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Profile != "personal" {
+		t.Errorf("Profile = %q, want %q", result.Profile, "personal")
+	}
+	if result.RemotePattern != "" {
+		t.Errorf("RemotePattern = %q, want empty", result.RemotePattern)
+	}
+}
+
+func TestForDirectory_RemoteNoMatchFallsBack(t *testing.T) {
+	stubOriginURL(t, "")
+	dir := t.TempDir()
+
+	bf := &config.BindingsFile{
+		Bindings: []config.Binding{
+			{RemotePattern: "github.com/acme", Profile: "work"},
+		},
+	}
+
+	result, err := ForDirectory(dir, bf, "personal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Profile != "personal" || !result.IsDefault {
+		t.Errorf("expected default fallback, got %+v", result)
+	}
+}
+
+func TestForDirectory_Glob(t *testing.T) {
+	tmp := t.TempDir()
+	work := filepath.Join(tmp, "work")
+
+	bf := &config.BindingsFile{
+		Bindings: []config.Binding{
+			{Path: filepath.Join(work, "*"), Profile: "work"},
+		},
+	}
+
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{filepath.Join(work, "repo"), "work"},
+		{filepath.Join(work, "repo", "src", "pkg"), "work"},
+		{work, "personal"},
+		{filepath.Join(tmp, "other", "repo"), "personal"},
+	}
+	for _, tt := range tests {
+		result, err := ForDirectory(tt.dir, bf, "personal")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Profile != tt.want {
+			t.Errorf("ForDirectory(%q).Profile = %q, want %q", tt.dir, result.Profile, tt.want)
+		}
+	}
+}
+
+func TestForDirectory_DoubleStarGlob(t *testing.T) {
+	tmp := t.TempDir()
+
+	bf := &config.BindingsFile{
+		Bindings: []config.Binding{
+			{Path: filepath.Join(tmp, "**", "acme-*"), Profile: "acme"},
+		},
+	}
+
+	for _, dir := range []string{
+		filepath.Join(tmp, "acme-api"),
+		filepath.Join(tmp, "a", "b", "acme-web", "src"),
+	} {
+		result, err := ForDirectory(dir, bf, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Profile != "acme" {
+			t.Errorf("ForDirectory(%q).Profile = %q, want %q", dir, result.Profile, "acme")
+		}
+	}
+
+	result, err := ForDirectory(filepath.Join(tmp, "a", "other"), bf, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Profile != "" {
+		t.Errorf("Profile = %q, want no match", result.Profile)
+	}
+}
+
+func TestForDirectory_GlobPrecedence(t *testing.T) {
+	tmp := t.TempDir()
+	work := filepath.Join(tmp, "work")
+	repo := filepath.Join(work, "repo")
+
+	tests := []struct {
+		name     string
+		bindings []config.Binding
+		want     string
+	}{
+		{
+			name: "exact beats glob",
+			bindings: []config.Binding{
+				{Path: filepath.Join(work, "*"), Profile: "glob"},
+				{Path: repo, Profile: "exact"},
+			},
+			want: "exact",
+		},
+		{
+			name: "prefix beats glob at same depth",
+			bindings: []config.Binding{
+				{Path: filepath.Join(work, "*"), Profile: "glob"},
+				{Path: work, Profile: "prefix"},
+			},
+			want: "prefix",
+		},
+		{
+			name: "glob beats shallower prefix",
+			bindings: []config.Binding{
+				{Path: tmp, Profile: "prefix"},
+				{Path: filepath.Join(work, "*"), Profile: "glob"},
+			},
+			want: "glob",
+		},
+		{
+			name: "longer glob prefix wins",
+			bindings: []config.Binding{
+				{Path: filepath.Join(tmp, "*", "repo"), Profile: "short"},
+				{Path: filepath.Join(work, "re*"), Profile: "long"},
+			},
+			want: "long",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ForDirectory(repo, &config.BindingsFile{Bindings: tt.bindings}, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Profile != tt.want {
+				t.Errorf("Profile = %q, want %q", result.Profile, tt.want)
+			}
+		})
+	}
+}
+
+func TestForDirectory_EqualDepthTieBreak(t *testing.T) {
+	tmp := t.TempDir()
+	work := filepath.Join(tmp, "work")
+	repo := filepath.Join(work, "repo")
+
+	tests := []struct {
+		name     string
+		bindings []config.Binding
+		want     string
+	}{
+		{
+			name: "exact beats glob of equal depth",
+			bindings: []config.Binding{
+				{Path: filepath.Join(repo, "**"), Profile: "glob"},
+				{Path: repo, Profile: "exact"},
+			},
+			want: "exact",
+		},
+		{
+			name: "longer literal prefix wins between globs",
+			bindings: []config.Binding{
+				{Path: filepath.Join(work, "*"), Profile: "short"},
+				{Path: filepath.Join(work, "re*"), Profile: "long"},
+			},
+			want: "long",
+		},
+		{
+			name: "same directory in two forms: first wins",
+			bindings: []config.Binding{
+				{Path: work + string(filepath.Separator), Profile: "first"},
+				{Path: work, Profile: "second"},
+			},
+			want: "first",
+		},
+		{
+			name: "same directory in two forms, reversed: first wins",
+			bindings: []config.Binding{
+				{Path: work, Profile: "first"},
+				{Path: work + string(filepath.Separator), Profile: "second"},
+			},
+			want: "first",
+		},
+		{
+			name: "identical globs: first wins",
+			bindings: []config.Binding{
+				{Path: filepath.Join(work, "*"), Profile: "first"},
+				{Path: filepath.Join(work, "*"), Profile: "second"},
+			},
+			want: "first",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ForDirectory(repo, &config.BindingsFile{Bindings: tt.bindings}, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Profile != tt.want {
+				t.Errorf("Profile = %q, want %q", result.Profile, tt.want)
+			}
+		})
+	}
+}
+
+func TestForDirectory_Symlink(t *testing.T) {
+	tmp := t.TempDir()
+	real := filepath.Join(tmp, "real", "code")
+	if err := os.MkdirAll(filepath.Join(real, "repo"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(tmp, "link")
+	if err := os.Symlink(filepath.Join(tmp, "real"), link); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+
+	// Bind via the symlink, resolve via the real path.
+	bf := &config.BindingsFile{}
+	if err := bf.AddBinding(filepath.Join(link, "code"), "work"); err != nil {
+		t.Fatal(err)
+	}
+	result, err := ForDirectory(filepath.Join(real, "repo"), bf, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Profile != "work" {
+		t.Errorf("Profile = %q, want %q", result.Profile, "work")
+	}
+
+	// A binding stored in symlinked form still matches the real path.
+	bf = &config.BindingsFile{
+		Bindings: []config.Binding{{Path: filepath.Join(link, "code"), Profile: "work"}},
+	}
+	result, err = ForDirectory(filepath.Join(real, "repo"), bf, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Profile != "work" {
+		t.Errorf("Profile = %q, want %q", result.Profile, "work")
+	}
+}
+
+func TestCandidates(t *testing.T) {
+	tmp := t.TempDir()
+	parent := filepath.Join(tmp, "code")
+	child := filepath.Join(parent, "org")
+	glob := filepath.Join(parent, "*")
+
+	bf := &config.BindingsFile{
+		Bindings: []config.Binding{
+			{Path: parent, Profile: "a"},
+			{Path: glob, Profile: "b"},
+			{Path: filepath.Join(tmp, "elsewhere"), Profile: "c"},
+			{RemotePattern: "github.com/acme", Profile: "d"},
+		},
+	}
+
+	got, err := Candidates(filepath.Join(child, "repo"), bf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected 3 directory candidates, got %d: %+v", len(got), got)
+	}
+	if !got[0].Matches || got[0].Glob {
+		t.Errorf("plain parent should match: %+v", got[0])
+	}
+	if !got[1].Matches || !got[1].Glob || got[1].Depth != got[0].Depth {
+		t.Errorf("glob should match at its literal-prefix depth: %+v", got[1])
+	}
+	if got[2].Matches {
+		t.Errorf("unrelated binding should not match: %+v", got[2])
+	}
+}
+
+func TestForDirectory_RelativeBindingIndependentOfCWD(t *testing.T) {
+	tmp := t.TempDir()
+	configDir := filepath.Join(tmp, "config")
+	project := filepath.Join(configDir, "code", "work")
+	other := filepath.Join(tmp, "elsewhere")
+	for _, d := range []string{project, other} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bindingsPath := filepath.Join(configDir, "bindings.yml")
+	if err := os.WriteFile(bindingsPath, []byte("bindings:\n  - path: code/work\n    profile: work\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cwd := range []string{configDir, other} {
+		t.Run(filepath.Base(cwd), func(t *testing.T) {
+			t.Chdir(cwd)
+			bf, err := config.LoadBindingsFrom(bindingsPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := ForDirectory(project, bf, "default")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Profile != "work" {
+				t.Errorf("Profile = %q, want %q", result.Profile, "work")
+			}
+			if result.BoundPath != project {
+				t.Errorf("BoundPath = %q, want %q", result.BoundPath, project)
+			}
+		})
+	}
+}
+
+func TestForDirectory_Inferred(t *testing.T) {
+	tmp := t.TempDir()
+	work := filepath.Join(tmp, "work")
+	oss := filepath.Join(tmp, "oss")
+	for _, d := range []string{filepath.Join(work, "repo"), oss} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bf := &config.BindingsFile{
+		Bindings: []config.Binding{{Path: oss, Profile: "personal"}},
+		Inferred: []config.Binding{
+			{Path: work, Profile: "work"},
+			{Path: tmp, Profile: "fallback"},
+		},
+	}
+
+	result, err := ForDirectory(filepath.Join(work, "repo"), bf, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Profile != "work" || result.IncludeIf != work || result.BoundPath != "" {
+		t.Errorf("ForDirectory() = %+v, want inferred work binding", result)
+	}
+
+	// A real binding beats an inferred one, even a shallower inferred match.
+	result, err = ForDirectory(oss, bf, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Profile != "personal" || result.IncludeIf != "" {
+		t.Errorf("ForDirectory() = %+v, want the real binding", result)
+	}
+}
+
+func TestForDirectory_DebugLog(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	tmp := t.TempDir()
+	dir := filepath.Join(tmp, "code", "work")
+	bf := &config.BindingsFile{
+		Bindings: []config.Binding{
+			{Path: filepath.Join(tmp, "code"), Profile: "personal"},
+			{Path: dir, Profile: "work"},
+		},
+	}
+
+	if _, err := ForDirectory(dir, bf, ""); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if n := strings.Count(out, "msg=\"binding candidate\""); n != 2 {
+		t.Errorf("logged %d candidates, want 2:\n%s", n, out)
+	}
+	if !strings.Contains(out, "msg=\"resolved directory binding\"") || !strings.Contains(out, "profile=work") {
+		t.Errorf("resolution not logged:\n%s", out)
+	}
+}
+
+func TestActive(t *testing.T) {
+	tmp := t.TempDir()
+	bound := filepath.Join(tmp, "work")
+	bf := &config.BindingsFile{Bindings: []config.Binding{{Path: bound, Profile: "work"}}}
+
+	tests := []struct {
+		name        string
+		dir         string
+		defaultName string
+		override    string
+		wantProfile string
+		wantSource  Source
+		wantDir     string
+	}{
+		{"binding", bound, "personal", "", "work", SourceBinding, "work"},
+		{"default", tmp, "personal", "", "personal", SourceDefault, "personal"},
+		{"none", tmp, "", "", "", SourceNone, ""},
+		{"environment beats binding", bound, "personal", "other", "other", SourceEnvironment, "work"},
+		{"environment beats default", tmp, "personal", "other", "other", SourceEnvironment, "personal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profiles := &config.ProfilesFile{Default: tt.defaultName}
+			res, err := Active(tt.dir, bf, profiles, tt.override)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Profile != tt.wantProfile || res.Source != tt.wantSource {
+				t.Errorf("Active() = %q from %s, want %q from %s", res.Profile, res.Source, tt.wantProfile, tt.wantSource)
+			}
+			if res.Directory.Profile != tt.wantDir {
+				t.Errorf("Directory.Profile = %q, want %q", res.Directory.Profile, tt.wantDir)
+			}
+			if tt.wantSource == SourceEnvironment && res.BoundPath != "" {
+				t.Errorf("overridden result kept BoundPath %q", res.BoundPath)
+			}
+		})
+	}
+}
+
+func TestActive_Org(t *testing.T) {
+	tmp := t.TempDir()
+	bound := filepath.Join(tmp, "work")
+	bf := &config.BindingsFile{Bindings: []config.Binding{{Path: bound, Profile: "work"}}}
+	profiles := &config.ProfilesFile{
+		Default: "personal",
+		Orgs: map[string]string{
+			"Acme":                     "acme",
+			"github.corp.example/acme": "corp",
+		},
+	}
+
+	tests := []struct {
+		name        string
+		dir         string
+		origin      string
+		wantProfile string
+		wantSource  Source
+		wantOrg     string
+	}{
+		{"org beats default", tmp, "git@github.com:acme/widgets.git", "acme", SourceOrg, "Acme"},
+		{"host-qualified org wins", tmp, "https://github.corp.example/ACME/widgets", "corp", SourceOrg, "github.corp.example/acme"},
+		{"binding beats org", bound, "git@github.com:acme/widgets.git", "work", SourceBinding, ""},
+		{"unmapped org", tmp, "git@github.com:other/widgets.git", "personal", SourceDefault, ""},
+		{"no origin", tmp, "", "personal", SourceDefault, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubOriginURL(t, tt.origin)
+			res, err := Active(tt.dir, bf, profiles, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Profile != tt.wantProfile || res.Source != tt.wantSource || res.Org != tt.wantOrg {
+				t.Errorf("Active() = %q from %s (org %q), want %q from %s (org %q)",
+					res.Profile, res.Source, res.Org, tt.wantProfile, tt.wantSource, tt.wantOrg)
+			}
+		})
+	}
+}
+
+func TestRepoProfile(t *testing.T) {
+	tmp := t.TempDir()
+	repo := filepath.Join(tmp, "repo")
+	sub := filepath.Join(repo, "pkg", "sub")
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := RepoProfile(sub); got != "" {
+		t.Errorf("RepoProfile() without a file = %q, want empty", got)
+	}
+
+	file := filepath.Join(repo, RepoFileName)
+	if err := os.WriteFile(file, []byte("# identity for this repo\n\n  work  \n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, gotFile := RepoProfile(sub)
+	if got != "work" || gotFile != file {
+		t.Errorf("RepoProfile() = %q, %q; want work, %s", got, gotFile, file)
+	}
+
+	// A file above the repository root is not the repository's.
+	if err := os.WriteFile(filepath.Join(tmp, RepoFileName), []byte("personal\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(file); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := RepoProfile(sub); got != "" {
+		t.Errorf("RepoProfile() picked up a file outside the repository: %q", got)
+	}
+}
+
+func TestActive_RepoFile(t *testing.T) {
+	tmp := t.TempDir()
+	repo := filepath.Join(tmp, "repo")
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	bf := &config.BindingsFile{Bindings: []config.Binding{{Path: tmp, Profile: "personal"}}}
+	profiles := &config.ProfilesFile{Profiles: map[string]config.Profile{
+		"personal": {GHUser: "user1"},
+		"work":     {GHUser: "user2"},
+	}}
+
+	writeRepoFile := func(name string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(repo, RepoFileName), []byte(name+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeRepoFile("work")
+	res, err := Active(repo, bf, profiles, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Profile != "work" || res.Source != SourceRepoFile || res.RepoFile == "" {
+		t.Errorf("Active() = %+v, want work from the repository file", res)
+	}
+	if res.Directory.Profile != "work" {
+		t.Errorf("Directory.Profile = %q, want work", res.Directory.Profile)
+	}
+
+	res, err = Active(repo, bf, profiles, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Profile != "other" || res.Source != SourceEnvironment {
+		t.Errorf("override should beat the repository file, got %q from %s", res.Profile, res.Source)
+	}
+
+	// A profile this machine doesn't have falls back to the bindings.
+	writeRepoFile("ghost")
+	res, err = Active(repo, bf, profiles, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Profile != "personal" || res.Source != SourceBinding {
+		t.Errorf("Active() = %q from %s, want personal from binding", res.Profile, res.Source)
+	}
+}
+
+// BenchmarkForDirectory resolves a directory against many bindings, most of
+// which do not match, as the shell hook does on every prompt.
+func BenchmarkForDirectory(b *testing.B) {
+	tmp := b.TempDir()
+	b.Setenv("HOME", tmp)
+	var bindings []config.Binding
+	for i := range 50 {
+		dir := filepath.Join(tmp, "code", fmt.Sprintf("org%d", i))
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			b.Fatal(err)
+		}
+		bindings = append(bindings,
+			config.Binding{Path: dir, Profile: fmt.Sprintf("p%d", i)},
+			config.Binding{Path: fmt.Sprintf("~/clients/c%d/*", i), Profile: fmt.Sprintf("c%d", i)},
+		)
+	}
+	bf := &config.BindingsFile{
+		Bindings: bindings,
+		Inferred: []config.Binding{{Path: filepath.Join(tmp, "inferred"), Profile: "inferred"}},
+	}
+	dir := filepath.Join(tmp, "code", "org42", "repo", "src")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		b.Fatal(err)
+	}
+
+	for b.Loop() {
+		result, err := ForDirectory(dir, bf, "default")
+		if err != nil {
+			b.Fatal(err)
+		}
+		if result.Profile != "p42" {
+			b.Fatalf("Profile = %q, want %q", result.Profile, "p42")
+		}
+	}
+}
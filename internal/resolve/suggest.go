@@ -0,0 +1,61 @@
+package resolve
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/dotbrains/gh-identity/internal/config"
+)
+
+// Suggest guesses which profile the repository containing dir belongs to
+// from its origin remote, for offering as a default when binding it. A
+// remote binding matching the origin wins; otherwise the owner in the origin
+// URL is compared with each profile's gh_user, narrowing by host if several
+// match. It returns "" when dir has no origin or no single profile fits.
+func Suggest(dir string, bindings *config.BindingsFile, profiles *config.ProfilesFile) string {
+	expanded, err := config.ResolvePath(dir)
+	if err != nil {
+		return ""
+	}
+	origin := originURL(expanded)
+	if origin == "" {
+		return ""
+	}
+
+	if r, ok := forRemote(origin, bindings); ok {
+		if _, exists := profiles.Profiles[r.Profile]; exists {
+			slog.Debug("suggesting profile from remote binding", "origin", origin, "pattern", r.RemotePattern, "profile", r.Profile)
+			return r.Profile
+		}
+	}
+
+	parts := strings.SplitN(NormalizeRemote(origin), "/", 3)
+	if len(parts) < 2 {
+		return ""
+	}
+	host, owner := parts[0], parts[1]
+
+	var matches []string
+	for name, p := range profiles.Profiles {
+		if strings.EqualFold(p.GHUser, owner) {
+			matches = append(matches, name)
+		}
+	}
+	if len(matches) > 1 {
+		// The same username on several hosts: keep the profile for this one.
+		var onHost []string
+		for _, name := range matches {
+			if strings.EqualFold(profiles.Profiles[name].GHHost(), host) {
+				onHost = append(onHost, name)
+			}
+		}
+		matches = onHost
+	}
+	sort.Strings(matches)
+	slog.Debug("profiles matching origin owner", "origin", origin, "owner", owner, "profiles", matches)
+	if len(matches) != 1 {
+		return ""
+	}
+	return matches[0]
+}
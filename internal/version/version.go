@@ -0,0 +1,51 @@
+// Package version reports the build version shared by the gh-identity
+// binaries, so the CLI can tell when the installed hook is out of date.
+package version
+
+import (
+	"runtime/debug"
+)
+
+// Version is set at release time with
+// -ldflags "-X github.com/dotbrains/gh-identity/internal/version.Version=v1.2.3".
+var Version string
+
+// String returns the build version: Version when set, otherwise the module
+// version or VCS revision recorded by the Go toolchain, or "dev" when the
+// build carries neither.
+func String() string {
+	if Version != "" {
+		return Version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "dev"
+	}
+	return fromBuildInfo(info)
+}
+
+func fromBuildInfo(info *debug.BuildInfo) string {
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+	var revision string
+	dirty := false
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			dirty = s.Value == "true"
+		}
+	}
+	if revision == "" {
+		return "dev"
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if dirty {
+		revision += "-dirty"
+	}
+	return revision
+}
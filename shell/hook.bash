@@ -2,7 +2,9 @@
 # Source this file or install via: gh identity init
 
 __gh_identity_hook() {
-    local hook_bin="$HOME/.config/gh-identity/bin/gh-identity-hook"
+    local hook_bin="${XDG_DATA_HOME:-$HOME/.local/share}/gh-identity/bin/gh-identity-hook"
+    # Fall back to the pre-XDG install location.
+    [[ -x "$hook_bin" ]] || hook_bin="$HOME/.config/gh-identity/bin/gh-identity-hook"
     if [[ -x "$hook_bin" ]]; then
         eval "$("$hook_bin" --shell bash)"
     fi
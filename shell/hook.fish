@@ -2,7 +2,11 @@
 # Source this file or install via: gh identity init
 
 function __gh_identity_hook --on-variable PWD
-    set -l hook_bin "$HOME/.config/gh-identity/bin/gh-identity-hook"
+    set -l data_home "$HOME/.local/share"
+    set -q XDG_DATA_HOME; and set data_home $XDG_DATA_HOME
+    set -l hook_bin "$data_home/gh-identity/bin/gh-identity-hook"
+    # Fall back to the pre-XDG install location.
+    test -x "$hook_bin"; or set hook_bin "$HOME/.config/gh-identity/bin/gh-identity-hook"
     if test -x "$hook_bin"
         eval ($hook_bin --shell fish)
     end